@@ -67,45 +67,34 @@ func (cmd *Command) Execute(args []string) error {
 		return fmt.Errorf("FIT is not initialized in the image")
 	}
 
-	entryHeaders := &fit.EntryHeaders{
-		Version: fit.EntryVersion(0x1000),
+	spec := fit.EntrySpec{
+		Type:     fit.EntryTypeSkip,
+		Version:  fit.EntryVersion(0x1000),
+		Checksum: cmd.Checksum,
 	}
-	entryHeaders.TypeAndIsChecksumValid.SetType(fit.EntryTypeSkip)
 
 	if cmd.AddressPointer != nil {
-		entryHeaders.Address = fit.Address64(*cmd.AddressPointer)
+		spec.Address = fit.Address64(*cmd.AddressPointer)
 	}
 	if cmd.AddressOffset != nil {
 		fileSize, err := file.Seek(0, io.SeekEnd)
 		if err != nil {
 			return fmt.Errorf("unable to determine the file size: %w", err)
 		}
-		entryHeaders.Address.SetOffset(*cmd.AddressOffset, uint64(fileSize))
+		spec.Address.SetOffset(*cmd.AddressOffset, uint64(fileSize))
 	}
 	if cmd.Size != nil {
-		entryHeaders.Size.SetUint32(*cmd.Size)
+		spec.Size = *cmd.Size
 	}
 	if cmd.IsChecksumValid != nil {
-		entryHeaders.TypeAndIsChecksumValid.SetIsChecksumValid(*cmd.IsChecksumValid)
+		spec.IsChecksumValid = *cmd.IsChecksumValid
 	}
 	if cmd.Type != nil {
-		entryHeaders.TypeAndIsChecksumValid.SetType(fit.EntryType(*cmd.Type))
-	}
-	if entryHeaders.TypeAndIsChecksumValid.IsChecksumValid() {
-		entryHeaders.Checksum = entryHeaders.CalculateChecksum()
-	}
-
-	if cmd.Checksum != nil {
-		entryHeaders.Checksum = *cmd.Checksum
+		spec.Type = fit.EntryType(*cmd.Type)
 	}
 
-	if table[0].Type() != fit.EntryTypeFITHeaderEntry {
-		return fmt.Errorf("the first entry should be of type 0x00")
-	}
-	table = append(table, *entryHeaders)
-	table[0].Size.SetUint32(uint32(len(table)))
-	if _, err := table.WriteToFirmwareImage(file); err != nil {
-		return fmt.Errorf("unable to write FIT into a firmware: %w", err)
+	if err := table.AppendEntry(file, spec); err != nil {
+		return fmt.Errorf("unable to add the entry to FIT: %w", err)
 	}
 	return nil
 }
@@ -59,7 +59,10 @@ func (cmd *Command) Execute(args []string) error {
 
 	var fitOffset uint64
 	if cmd.Pointer != nil {
-		fitOffset = fit.Address64(*cmd.Pointer).Offset(uint64(fileSize))
+		fitOffset, err = fit.AddressToOffset(*cmd.Pointer, uint64(fileSize))
+		if err != nil {
+			return fmt.Errorf("invalid FIT pointer: %w", err)
+		}
 	}
 	if cmd.PointerFromOffset != nil {
 		fitOffset = *cmd.PointerFromOffset
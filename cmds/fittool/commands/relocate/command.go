@@ -0,0 +1,121 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package relocate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/cmds/fittool/commands"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit/consts"
+)
+
+var _ commands.Command = (*Command)(nil)
+
+type Command struct {
+	UEFIPath          string  `short:"f" long:"uefi" description:"path to UEFI image" required:"true"`
+	Pointer           *uint64 `short:"p" long:"pointer" description:"the new FIT pointer value"`
+	PointerFromOffset *uint64 `long:"pointer-from-offset" description:"the new FIT pointer value defined by an offset from the beginning of the image"`
+}
+
+// ShortDescription explains what this command does in one line
+func (cmd *Command) ShortDescription() string {
+	return "moves FIT to a new address and shifts the addresses of its entries accordingly"
+}
+
+// LongDescription explains what this verb does (without limitation in amount of lines)
+func (cmd *Command) LongDescription() string {
+	return "The addresses of the entries referring to the image are shifted by the same delta as the table, " +
+		"so the data they refer to is expected to be moved together with the table (for example, when " +
+		"the BIOS region is repositioned). If the table is already present at the new address, " +
+		"it is read from there."
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd *Command) Execute(args []string) error {
+	if len(args) != 0 {
+		return commands.ErrArgs{Err: fmt.Errorf("there are extra arguments")}
+	}
+
+	if cmd.PointerFromOffset == nil && cmd.Pointer == nil {
+		return commands.ErrArgs{Err: fmt.Errorf("either '--pointer' or '--pointer-from-offset' is required")}
+	}
+	if cmd.PointerFromOffset != nil && cmd.Pointer != nil {
+		return commands.ErrArgs{Err: fmt.Errorf("it does not make sense to use '--pointer' and '--pointer-from-offset' together")}
+	}
+
+	file, err := os.OpenFile(cmd.UEFIPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open the firmware image file '%s': %w", cmd.UEFIPath, err)
+	}
+	defer file.Close()
+
+	fileSize, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("unable to detect file size (through seek): %w", err)
+	}
+
+	var newAddr uint64
+	if cmd.Pointer != nil {
+		newAddr = *cmd.Pointer
+	}
+	if cmd.PointerFromOffset != nil {
+		newAddr = fit.CalculatePhysAddrFromOffset(*cmd.PointerFromOffset, uint64(fileSize))
+	}
+	newOffset, err := fit.AddressToOffset(newAddr, uint64(fileSize))
+	if err != nil {
+		return fmt.Errorf("invalid FIT pointer: %w", err)
+	}
+
+	table, err := readTableAt(file, newOffset)
+	if err != nil {
+		table, err = fit.GetTableFrom(file)
+		if err != nil {
+			return fmt.Errorf("unable to get FIT: %w", err)
+		}
+	}
+	oldTable := append(fit.Table{}, table...)
+
+	shifted, err := table.Relocate(file, newAddr)
+	if err != nil {
+		return fmt.Errorf("unable to relocate FIT: %w", err)
+	}
+
+	for _, idx := range shifted {
+		fmt.Printf("entry #%d (%s): %s -> %s\n", idx, table[idx].Type(), oldTable[idx].Address, table[idx].Address)
+	}
+	return nil
+}
+
+// readTableAt parses the FIT at the offset, which is expected to start with
+// the FIT header entry.
+func readTableAt(file io.ReadSeeker, offset uint64) (fit.Table, error) {
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+	hdr, err := fit.ParseEntryHeadersFrom(file)
+	if err != nil {
+		return nil, err
+	}
+	magic := fit.Address64(binary.LittleEndian.Uint64([]byte(consts.FITHeadersMagic)))
+	if hdr.Type() != fit.EntryTypeFITHeaderEntry || hdr.Address != magic || hdr.Size.Uint32() == 0 {
+		return nil, fmt.Errorf("no FIT header entry at 0x%x", offset)
+	}
+	table := fit.Table{*hdr}
+	for len(table) < int(hdr.Size.Uint32()) {
+		entryHeaders, err := fit.ParseEntryHeadersFrom(file)
+		if err != nil {
+			return nil, err
+		}
+		table = append(table, *entryHeaders)
+	}
+	return table, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package setsacm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/cmds/fittool/commands"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+)
+
+var _ commands.Command = (*Command)(nil)
+
+type Command struct {
+	UEFIPath    string  `description:"path to UEFI image" required:"true" short:"f" long:"uefi"`
+	EntryNumber uint    `description:"FIT entry number of the startup AC module" required:"true" short:"n" long:"entry-number"`
+	TXTSVN      *uint16 `description:"the value for field 'TXTSVN'" long:"txt-svn"`
+	SESVN       *uint16 `description:"the value for field 'SESVN'" long:"se-svn"`
+}
+
+// ShortDescription explains what this command does in one line
+func (cmd *Command) ShortDescription() string {
+	return "overwrites fields of a startup AC module in the UEFI image"
+}
+
+// LongDescription explains what this verb does (without limitation in amount of lines)
+func (cmd *Command) LongDescription() string {
+	return "The module is compiled back into its data segment in place, so the size of the module cannot change."
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd *Command) Execute(args []string) error {
+	if len(args) != 0 {
+		return commands.ErrArgs{Err: fmt.Errorf("there are extra arguments")}
+	}
+
+	file, err := os.OpenFile(cmd.UEFIPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open the firmware image file '%s': %w", cmd.UEFIPath, err)
+	}
+	defer file.Close()
+
+	entries, err := fit.GetEntriesFrom(file)
+	if err != nil {
+		return fmt.Errorf("unable to get FIT entries: %w", err)
+	}
+	if int(cmd.EntryNumber) >= len(entries) {
+		return commands.ErrArgs{Err: fmt.Errorf("entry number %d is out of range, FIT has %d entries", cmd.EntryNumber, len(entries))}
+	}
+	entry, ok := entries[cmd.EntryNumber].(*fit.EntrySACM)
+	if !ok {
+		return commands.ErrArgs{Err: fmt.Errorf("entry #%d is not a startup AC module, but %T", cmd.EntryNumber, entries[cmd.EntryNumber])}
+	}
+
+	data, err := entry.ParseData()
+	if err != nil {
+		return fmt.Errorf("unable to parse the startup AC module: %w", err)
+	}
+	common := data.GetCommon()
+	if cmd.TXTSVN != nil {
+		common.TXTSVN = fit.TXTSVN(*cmd.TXTSVN)
+	}
+	if cmd.SESVN != nil {
+		common.SESVN = fit.SESVN(*cmd.SESVN)
+	}
+
+	oldSize := len(entry.DataSegmentBytes)
+	if err := entry.SetData(data); err != nil {
+		return fmt.Errorf("unable to compile the startup AC module: %w", err)
+	}
+	if len(entry.DataSegmentBytes) != oldSize {
+		return fmt.Errorf("the size of the startup AC module changed from %d to %d bytes", oldSize, len(entry.DataSegmentBytes))
+	}
+
+	fileSize, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("unable to determine the file size: %w", err)
+	}
+	offset, err := fit.AddressToOffset(entry.Headers.Address.Pointer(), uint64(fileSize))
+	if err != nil {
+		return fmt.Errorf("unable to locate the startup AC module: %w", err)
+	}
+	if _, err := file.WriteAt(entry.DataSegmentBytes, int64(offset)); err != nil {
+		return fmt.Errorf("unable to write the startup AC module at offset %#x: %w", offset, err)
+	}
+	if _, err := entries.Table().WriteToFirmwareImage(file); err != nil {
+		return fmt.Errorf("unable to write FIT into a firmware: %w", err)
+	}
+	return nil
+}
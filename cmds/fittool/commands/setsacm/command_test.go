@@ -0,0 +1,73 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package setsacm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestImage writes an image with a FIT containing a startup AC module
+// as entry #1.
+func writeTestImage(t *testing.T) string {
+	const imageSize = 0x10000
+
+	sacm, err := os.ReadFile("../../../../pkg/intel/metadata/fit/testdata/sacm_v3.bin")
+	require.NoError(t, err)
+	sacmEntry := &fit.EntrySACM{}
+	sacmEntry.Headers.TypeAndIsChecksumValid.SetType(fit.EntryTypeStartupACModuleEntry)
+	sacmEntry.Headers.Address.SetOffset(0x2000, imageSize)
+
+	entries := fit.Entries{&fit.EntryFITHeaderEntry{}, sacmEntry}
+	require.NoError(t, entries.RecalculateHeaders())
+	image := make([]byte, imageSize)
+	require.NoError(t, entries.Inject(image, 0x1000))
+	copy(image[0x2000:], sacm)
+
+	path := filepath.Join(t.TempDir(), "image.rom")
+	require.NoError(t, os.WriteFile(path, image, 0644))
+	return path
+}
+
+func readSACM(t *testing.T, path string) *fit.EntrySACMData {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	entries, err := fit.GetEntriesFrom(f)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.IsType(t, &fit.EntrySACM{}, entries[1])
+	data, err := entries[1].(*fit.EntrySACM).ParseData()
+	require.NoError(t, err)
+	return data
+}
+
+func TestSetTXTSVN(t *testing.T) {
+	path := writeTestImage(t)
+	orig := readSACM(t, path)
+
+	txtSVN := uint16(orig.GetTXTSVN()) + 1
+	cmd := &Command{UEFIPath: path, EntryNumber: 1, TXTSVN: &txtSVN}
+	require.NoError(t, cmd.Execute(nil))
+
+	data := readSACM(t, path)
+	require.Equal(t, fit.TXTSVN(txtSVN), data.GetTXTSVN())
+	require.Equal(t, orig.GetSESVN(), data.GetSESVN())
+}
+
+func TestSetNotSACM(t *testing.T) {
+	path := writeTestImage(t)
+	orig := readSACM(t, path).GetTXTSVN()
+	txtSVN := uint16(orig) + 1
+	for _, n := range []uint{0, 2} {
+		cmd := &Command{UEFIPath: path, EntryNumber: n, TXTSVN: &txtSVN}
+		require.Error(t, cmd.Execute(nil))
+	}
+	require.Equal(t, orig, readSACM(t, path).GetTXTSVN())
+}
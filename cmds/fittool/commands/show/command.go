@@ -20,6 +20,7 @@ type Command struct {
 	UEFIPath    string  `short:"f" long:"uefi" description:"path to UEFI image" required:"true"`
 	Format      *string `long:"format" description:"output format [text, json]"`
 	IncludeData *bool   `long:"include-data" description:"print also data section referenced by the FIT headers"`
+	Raw         *bool   `long:"raw" description:"print raw numeric values of the FIT headers (text format only)"`
 }
 
 type Format int
@@ -72,6 +73,11 @@ func (cmd *Command) Execute(args []string) error {
 		}
 	}
 
+	raw := cmd.Raw != nil && *cmd.Raw
+	if raw && (includeData || format != FormatText) {
+		return commands.ErrArgs{Err: fmt.Errorf("--raw could be used only with the text format and without --include-data")}
+	}
+
 	file, err := os.OpenFile(cmd.UEFIPath, os.O_RDWR, 0)
 	if err != nil {
 		return fmt.Errorf("unable to open the firmware image file '%s': %w", cmd.UEFIPath, err)
@@ -86,6 +92,8 @@ func (cmd *Command) Execute(args []string) error {
 	case FormatText:
 		if includeData {
 			fmt.Printf("%s", entries.String())
+		} else if raw {
+			fmt.Printf("%s", entries.Table().RawString())
 		} else {
 			fmt.Printf("%s", entries.Table().String())
 		}
@@ -0,0 +1,176 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/cmds/fittool/commands"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/show"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+)
+
+var _ commands.Command = (*Command)(nil)
+
+type Command struct {
+	UEFIPath string  `short:"f" long:"uefi" description:"path to UEFI image" required:"true"`
+	Format   *string `long:"format" description:"output format [text, json]"`
+}
+
+// Problem is an inconsistency found in the FIT.
+type Problem struct {
+	// Entry is the row number of the entry the problem is about, or -1 if
+	// the problem is about the whole table.
+	Entry       int
+	Description string
+}
+
+func (p Problem) String() string {
+	if p.Entry < 0 {
+		return p.Description
+	}
+	return fmt.Sprintf("entry #%d: %s", p.Entry, p.Description)
+}
+
+// ShortDescription explains what this command does in one line
+func (cmd *Command) ShortDescription() string {
+	return "checks the consistency of the FIT entries"
+}
+
+// LongDescription explains what this verb does (without limitation in amount of lines)
+func (cmd *Command) LongDescription() string {
+	return "Verifies the FIT table checksum, that the FIT header entry is the first one, " +
+		"and that the data referenced by the entries is inside the image and does not overlap. " +
+		"Exits with an error if any problem is found."
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd *Command) Execute(args []string) error {
+	if len(args) != 0 {
+		return commands.ErrArgs{Err: fmt.Errorf("there are extra arguments")}
+	}
+
+	format := show.FormatText
+	if cmd.Format != nil {
+		format = show.ParseFormat(*cmd.Format)
+		if format == show.FormatUndefined {
+			return commands.ErrArgs{Err: fmt.Errorf("unknown format '%s'", *cmd.Format)}
+		}
+	}
+
+	image, err := os.ReadFile(cmd.UEFIPath)
+	if err != nil {
+		return fmt.Errorf("unable to read the firmware image file '%s': %w", cmd.UEFIPath, err)
+	}
+
+	problems, err := Validate(image)
+	if err != nil {
+		return err
+	}
+	return PrintProblems(os.Stdout, format, problems)
+}
+
+// PrintProblems writes the problems to w in the given format, or "OK" if
+// there are none in the text format. It returns an error if there is any
+// problem.
+func PrintProblems(w io.Writer, format show.Format, problems []Problem) error {
+	switch format {
+	case show.FormatText:
+		for _, problem := range problems {
+			fmt.Fprintln(w, problem)
+		}
+		if len(problems) == 0 {
+			fmt.Fprintln(w, "OK")
+		}
+	case show.FormatJSON:
+		if problems == nil {
+			problems = []Problem{}
+		}
+		b, err := json.Marshal(problems)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(w, "%s\n", b)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in the FIT", len(problems))
+	}
+	return nil
+}
+
+// Validate returns the inconsistencies found in the FIT of the image. An
+// error is returned only if the FIT cannot be found or parsed at all.
+func Validate(image []byte) ([]Problem, error) {
+	firmware := bytes.NewReader(image)
+	startIdx, endIdx, err := fit.GetHeadersTableRangeFrom(firmware)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the FIT: %w", err)
+	}
+	entries, err := fit.GetEntriesFrom(firmware)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get FIT entries: %w", err)
+	}
+
+	var problems []Problem
+	if err := fit.ValidateTableChecksum(image[startIdx:endIdx]); err != nil {
+		problems = append(problems, Problem{Entry: -1, Description: err.Error()})
+	}
+	if len(entries) == 0 || entries[0].GetEntryBase().Headers.Type() != fit.EntryTypeFITHeaderEntry {
+		problems = append(problems, Problem{Entry: -1, Description: fmt.Sprintf("the first entry is not a %s", fit.EntryTypeFITHeaderEntry)})
+	}
+
+	type dataRange struct {
+		entry      int
+		start, end uint64
+	}
+	var ranges []dataRange
+	for idx, entry := range entries {
+		hdr := entry.GetEntryBase().Headers
+		if idx > 0 && hdr.Type() == fit.EntryTypeFITHeaderEntry {
+			problems = append(problems, Problem{Entry: idx, Description: fmt.Sprintf("unexpected %s", fit.EntryTypeFITHeaderEntry)})
+			continue
+		}
+		if hdr.Type() == fit.EntryTypeFITHeaderEntry || hdr.Type() == fit.EntryTypeSkip {
+			continue
+		}
+
+		size, err := fit.EntryDataSegmentSize(entry, firmware)
+		if err != nil {
+			problems = append(problems, Problem{Entry: idx, Description: fmt.Sprintf("unable to get the data size: %v", err)})
+			continue
+		}
+		if size == 0 {
+			// the data is stored in the headers
+			continue
+		}
+		offset, err := fit.AddressToOffset(hdr.Address.Pointer(), uint64(len(image)))
+		if err != nil {
+			problems = append(problems, Problem{Entry: idx, Description: err.Error()})
+			continue
+		}
+		if offset+size > uint64(len(image)) {
+			problems = append(problems, Problem{Entry: idx, Description: fmt.Sprintf(
+				"data 0x%x-0x%x is beyond the image of size 0x%x", offset, offset+size, len(image))})
+			continue
+		}
+
+		for _, r := range ranges {
+			if offset < r.end && r.start < offset+size {
+				problems = append(problems, Problem{Entry: idx, Description: fmt.Sprintf(
+					"data 0x%x-0x%x overlaps the data of entry #%d", offset, offset+size, r.entry)})
+			}
+		}
+		ranges = append(ranges, dataRange{entry: idx, start: offset, end: offset + size})
+	}
+	return problems, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	imageSize   = 0x10000
+	tableOffset = 0x1000
+)
+
+// makeTestImage returns an image with a FIT containing two BIOS startup
+// modules of 0x100 bytes at offsets 0x2000 and 0x3000.
+func makeTestImage(t *testing.T) []byte {
+	entries := fit.Entries{&fit.EntryFITHeaderEntry{}}
+	for _, offset := range []uint64{0x2000, 0x3000} {
+		module := &fit.EntryBIOSStartupModuleEntry{}
+		module.DataSegmentBytes = make([]byte, 0x100)
+		module.Headers.Address.SetOffset(offset, imageSize)
+		entries = append(entries, module)
+	}
+	require.NoError(t, entries.RecalculateHeaders())
+	image := make([]byte, imageSize)
+	require.NoError(t, entries.Inject(image, tableOffset))
+	return image
+}
+
+// setEntryOffset overwrites the address of the entry #idx of the FIT of image.
+func setEntryOffset(image []byte, idx int, offset uint64) {
+	binary.LittleEndian.PutUint64(image[tableOffset+16*idx:], fit.CalculatePhysAddrFromOffset(offset, imageSize))
+}
+
+func TestValidate(t *testing.T) {
+	image := makeTestImage(t)
+	problems, err := Validate(image)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	path := filepath.Join(t.TempDir(), "image.rom")
+	require.NoError(t, os.WriteFile(path, image, 0644))
+	require.NoError(t, (&Command{UEFIPath: path}).Execute(nil))
+
+	setEntryOffset(image, 2, 0x2080)
+	problems, err = Validate(image)
+	require.NoError(t, err)
+	require.Contains(t, problems, Problem{Entry: 2, Description: "data 0x2080-0x2180 overlaps the data of entry #1"})
+
+	setEntryOffset(image, 2, imageSize-0x80)
+	problems, err = Validate(image)
+	require.NoError(t, err)
+	require.Contains(t, problems, Problem{Entry: 2, Description: "data 0xff80-0x10080 is beyond the image of size 0x10000"})
+
+	require.NoError(t, os.WriteFile(path, image, 0644))
+	format := "json"
+	require.Error(t, (&Command{UEFIPath: path, Format: &format}).Execute(nil))
+}
+
+func TestValidateHeaderEntry(t *testing.T) {
+	image := makeTestImage(t)
+	// turn the FIT header entry into a BIOS startup module
+	image[tableOffset+14] = byte(fit.EntryTypeBIOSStartupModuleEntry)
+	problems, err := Validate(image)
+	require.NoError(t, err)
+	require.Contains(t, problems, Problem{Entry: -1, Description: "the first entry is not a " + fit.EntryTypeFITHeaderEntry.String()})
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/cmds/fittool/commands"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/show"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/validate"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+)
+
+var _ commands.Command = (*Command)(nil)
+
+type Command struct {
+	UEFIPath string  `short:"f" long:"uefi" description:"path to UEFI image" required:"true"`
+	ACM      *bool   `long:"acm" description:"verify also the signatures of the startup AC modules"`
+	Format   *string `long:"format" description:"output format [text, json]"`
+}
+
+// ShortDescription explains what this command does in one line
+func (cmd *Command) ShortDescription() string {
+	return "verifies the FIT table checksum and the consistency of the FIT entries"
+}
+
+// LongDescription explains what this verb does (without limitation in amount of lines)
+func (cmd *Command) LongDescription() string {
+	return "Verifies the FIT table checksum (only if the bit C_V of the FIT header entry is set),\n" +
+		"that the FIT header entry is the first one, and that the data referenced by the\n" +
+		"entries is inside the image and does not overlap. Exits with an error if any\n" +
+		"problem is found.\n" +
+		"With --acm the RSA signature of every startup AC module is verified against\n" +
+		"the public key embedded into the module. Modules with a header version other\n" +
+		"than 0.0 and 3.0 are reported as unsupported."
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd *Command) Execute(args []string) error {
+	if len(args) != 0 {
+		return commands.ErrArgs{Err: fmt.Errorf("there are extra arguments")}
+	}
+
+	format := show.FormatText
+	if cmd.Format != nil {
+		format = show.ParseFormat(*cmd.Format)
+		if format == show.FormatUndefined {
+			return commands.ErrArgs{Err: fmt.Errorf("unknown format '%s'", *cmd.Format)}
+		}
+	}
+
+	image, err := os.ReadFile(cmd.UEFIPath)
+	if err != nil {
+		return fmt.Errorf("unable to read the firmware image file '%s': %w", cmd.UEFIPath, err)
+	}
+
+	problems, err := validate.Validate(image)
+	if err != nil {
+		return err
+	}
+	if cmd.ACM != nil && *cmd.ACM {
+		// keep the JSON output parsable, the result of each module goes to stderr
+		var w io.Writer = os.Stdout
+		if format == show.FormatJSON {
+			w = os.Stderr
+		}
+		acmProblems, err := verifyACMs(bytes.NewReader(image), w)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, acmProblems...)
+	}
+
+	return validate.PrintProblems(os.Stdout, format, problems)
+}
+
+// verifyACMs writes the result of the signature verification of every startup
+// AC module to w: OK, FAIL or unsupported for header versions which cannot be
+// verified. It returns a problem for every module which fails the verification.
+func verifyACMs(firmware io.ReadSeeker, w io.Writer) ([]validate.Problem, error) {
+	entries, err := fit.GetEntriesFrom(firmware)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get FIT entries: %w", err)
+	}
+
+	var problems []validate.Problem
+	for idx, entry := range entries {
+		sacm, ok := entry.(*fit.EntrySACM)
+		if !ok {
+			continue
+		}
+		data, err := sacm.ParseData()
+		if err == nil {
+			err = data.VerifySignature()
+		}
+		var errVersion *fit.ErrUnknownACMHeaderVersion
+		switch {
+		case err == nil:
+			fmt.Fprintf(w, "ACM #%d: OK\n", idx)
+		case errors.As(err, &errVersion):
+			fmt.Fprintf(w, "ACM #%d: unsupported header version %#v\n", idx, errVersion.ACHeaderVersion)
+		default:
+			fmt.Fprintf(w, "ACM #%d: FAIL: %v\n", idx, err)
+			problems = append(problems, validate.Problem{Entry: idx, Description: fmt.Sprintf("invalid startup AC module signature: %v", err)})
+		}
+	}
+	return problems, nil
+}
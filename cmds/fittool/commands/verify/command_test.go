@@ -0,0 +1,55 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyACMs(t *testing.T) {
+	const imageSize = 0x10000
+
+	v0, err := os.ReadFile("../../../../pkg/intel/metadata/fit/testdata/sacm_v0.bin")
+	require.NoError(t, err)
+	v3, err := os.ReadFile("../../../../pkg/intel/metadata/fit/testdata/sacm_v3.bin")
+	require.NoError(t, err)
+	tampered := append([]byte{}, v3...)
+	tampered[len(tampered)-1] ^= 0xff
+	v4 := append([]byte{}, v3...)
+	binary.LittleEndian.PutUint32(v4[fit.EntrySACMDataCommon{}.HeaderVersionBinaryOffset():], 0x00040000)
+
+	modules := map[uint64][]byte{0x2000: v0, 0x3000: tampered, 0x4000: v4}
+	entries := fit.Entries{&fit.EntryFITHeaderEntry{}}
+	for _, offset := range []uint64{0x2000, 0x3000, 0x4000} {
+		sacm := &fit.EntrySACM{}
+		sacm.Headers.TypeAndIsChecksumValid.SetType(fit.EntryTypeStartupACModuleEntry)
+		sacm.Headers.Address.SetOffset(offset, imageSize)
+		entries = append(entries, sacm)
+	}
+	require.NoError(t, entries.RecalculateHeaders())
+	image := make([]byte, imageSize)
+	require.NoError(t, entries.Inject(image, 0x1000))
+	for offset, module := range modules {
+		copy(image[offset:], module)
+	}
+
+	var out bytes.Buffer
+	problems, err := verifyACMs(bytes.NewReader(image), &out)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Equal(t, 2, problems[0].Entry)
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	require.Equal(t, "ACM #1: OK", string(lines[0]))
+	require.True(t, bytes.HasPrefix(lines[1], []byte("ACM #2: FAIL: ")), string(lines[1]))
+	require.True(t, bytes.HasPrefix(lines[2], []byte("ACM #3: unsupported header version ")), string(lines[2]))
+}
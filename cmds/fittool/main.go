@@ -13,13 +13,20 @@
 //     fittool add_raw_headers -f UEFI_FILE [options]
 //     fittool set_raw_headers -f UEFI_FILE -n ENTRY_ID [options]
 //     fittool remove_headers -f UEFI_FILE -n ENTRY_ID [options]
+//     fittool disable -f UEFI_FILE -n ENTRY_ID
+//     fittool relocate -f UEFI_FILE [options]
+//     fittool set_sacm -f UEFI_FILE -n ENTRY_ID [options]
 //     fittool show -f UEFI_FILE [options]
+//     fittool verify -f UEFI_FILE [options]
+//     fittool validate -f UEFI_FILE [options]
 //
 // An example:
 //     fittool init -f firmware.fd
 //     fittool add_raw_headers -f firmware.fd --type 2 --address $((16#100000)) --size $((16#20000))
 //     fittool set_raw_headers -f firmware.fd -n 1 --type $((16#7F))
 //     fittool remove_headers -f firmware.fd -n 1
+//     fittool disable -f firmware.fd -n 1
+//     fittool set_sacm -f firmware.fd -n 1 --txt-svn 2
 //     fittool show -f firmware.fd --format=json --include-data | jq -r '.[] | select(.Headers.Type == 2) | .DataParsed.EntrySACMDataInterface.TXTSVN'
 //
 // Description:
@@ -27,7 +34,12 @@
 //     add_raw_headers: Add raw headers to FIT
 //     set_raw_headers: Overwrite the row # ENTRY_ID with specified RAW headers
 //     remove_headers:  Remove headers from row entry # ENTRY_ID
+//     disable:         Make the firmware skip row entry # ENTRY_ID, keeping the table layout
+//     relocate:        Move the FIT and shift the addresses of its entries
+//     set_sacm:        Overwrite fields of the startup AC module of row entry # ENTRY_ID
 //     show:            Print FIT
+//     verify:          Verify the FIT table checksum and the consistency of the FIT entries (and optionally the ACM signatures)
+//     validate:        Check the consistency of the FIT entries
 //
 // For more advanced key manifest and boot policy manifest management see also Converged Security Suite:
 // * https://github.com/9elements/converged-security-suite
@@ -43,10 +55,15 @@ import (
 
 	"github.com/linuxboot/fiano/cmds/fittool/commands"
 	"github.com/linuxboot/fiano/cmds/fittool/commands/addrawheaders"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/disable"
 	_init "github.com/linuxboot/fiano/cmds/fittool/commands/init"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/relocate"
 	"github.com/linuxboot/fiano/cmds/fittool/commands/removeheaders"
 	"github.com/linuxboot/fiano/cmds/fittool/commands/setrawheaders"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/setsacm"
 	"github.com/linuxboot/fiano/cmds/fittool/commands/show"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/validate"
+	"github.com/linuxboot/fiano/cmds/fittool/commands/verify"
 )
 
 var (
@@ -56,6 +73,11 @@ var (
 		"add_raw_headers": &addrawheaders.Command{},
 		"set_raw_headers": &setrawheaders.Command{},
 		"remove_headers":  &removeheaders.Command{},
+		"disable":         &disable.Command{},
+		"relocate":        &relocate.Command{},
+		"set_sacm":        &setsacm.Command{},
+		"verify":          &verify.Command{},
+		"validate":        &validate.Command{},
 	}
 )
 
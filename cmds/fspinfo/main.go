@@ -44,6 +44,9 @@ func extractFirstFSPHeader(b []byte) (*fsp.CommonInfoHeader, error) {
 		return nil, fmt.Errorf("cannot parse section: %v", err)
 	}
 	// the section header size is 4, so skip it to get the data
+	if len(sec.Buf()) < 4 {
+		return nil, fmt.Errorf("section is too short: %d bytes", len(sec.Buf()))
+	}
 	hdr, err := fsp.NewInfoHeader(sec.Buf()[4:])
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse FSP Info Header: %v", err)
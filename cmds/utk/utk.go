@@ -7,6 +7,7 @@
 // Synopsis:
 //
 //	utk BIOS OPERATIONS...
+//	utk jsondiff A.json B.json
 //
 // Examples:
 //
@@ -25,6 +26,9 @@
 //	# Re-assemble the directory into an image:
 //	utk winterfell/ save winterfell2.rom
 //
+//	# Compare two JSON dumps, ignoring formatting and ordering:
+//	utk jsondiff winterfell.json winterfell/summary.json
+//
 //	# Remove two files by their GUID and replace shell with Linux:
 //	utk winterfell.rom \
 //	  remove 12345678-9abc-def0-1234-567890abcdef \
@@ -38,9 +42,32 @@
 //	        stdout.
 //	`table`: Dump GUIDs and sizes to a compact table. This is only for human
 //	         consumption and the format may change without notice.
+//	`modules`: List executable modules with their type, UI name,
+//	           architecture and dependency expression. Use `modules-json`
+//	           to get the same list as JSON.
+//	`attack-surface`: Dump the number and size of the executable modules
+//	                  of the SEC, PEI, DXE and SMM phases, and how many of
+//	                  them are signed, as JSON.
+//	`apriori`: List the modules of the PEI and DXE apriori files in dispatch
+//	           order as JSON.
+//	`bom --format (csv|json)`: Export a bill of materials with the GUID, UI
+//	                           name, version, type and size of every file.
+//	`compression-report`: Dump the compressed and decompressed sizes of all
+//	                      compressed sections as JSON, sorted by savings.
+//	`verify-compression`: Decompress all compressed sections and list the
+//	                      ones which fail as JSON. Exits with an error if
+//	                      any section fails.
+//	`overlap-audit`: List the files whose declared size overlaps the next
+//	                 file, the free space or the end of their firmware
+//	                 volume as JSON. Exits with an error if any is found.
 //	`find (GUID|NAME)`: Dump the JSON of one or more files. The file is
 //	                    found by a regex match to its GUID or name in the UI
 //	                    section.
+//	`find_content STRING`: Dump the JSON of the sections whose decompressed
+//	                       content contains STRING.
+//	`unreferenced`: List the PEI and DXE modules which are neither in an
+//	                apriori file nor in a dependency expression as JSON. This
+//	                is a heuristic, the modules are only possibly unused.
 //	`remove (GUID|NAME)`: Remove the first file which matches the given GUID
 //	                      or NAME. The same matching rules and exit status
 //	                      are used as `find`.
@@ -48,12 +75,25 @@
 //	                            given GUID or NAME with the contents of
 //	                            FILE. The same matching rules and exit
 //	                            status are used as `find`.
+//	`replace_raw (GUID|NAME) FILE`: Replace the content of the raw or
+//	                                freeform section of the file which
+//	                                matches the given GUID or NAME with
+//	                                the contents of FILE, e.g. a logo or
+//	                                an ACPI table. The file must match
+//	                                exactly once and hold exactly one
+//	                                such section.
+//	`set-smbios-string TYPE FIELD VALUE`: Set the string FIELD, e.g.
+//	                                      ProductName, of the SMBIOS
+//	                                      structures of TYPE embedded in raw
+//	                                      sections to VALUE.
 //	`save FILE`: Save the current state of the image to the give file.
 //	             Remember that operations are applied left-to-right, so only
 //	             the operations to the left are included in the new image.
 //	`extract DIR`: Extract the BIOS to the given directory. Remember that
 //	               operations are applied left-to-right, so only the
 //	               operations to the left are included in the new image.
+//	`extract-acpi DIR`: Write the ACPI tables found in raw and freeform
+//	                    sections to DIR as SIGNATURE_OEMID.aml files.
 package main
 
 import (
@@ -73,7 +113,7 @@ type config struct {
 
 func parseArguments() (config, []string, error) {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: utk [flags] <file name> [0 or more operations]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: utk [flags] <file name> [0 or more operations]\n       utk jsondiff <a.json> <b.json>\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(flag.CommandLine.Output(), "\nOperations:\n%s", visitors.ListCLI())
 	}
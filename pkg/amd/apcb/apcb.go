@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"strings"
 )
 
@@ -32,6 +33,31 @@ import (
 //
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// ParseOption is an optional argument of the functions parsing an APCB binary
+type ParseOption interface {
+	apply(*parseConfig)
+}
+
+type parseConfig struct {
+	IgnoreChecksum bool
+}
+
+// ParseOptionIgnoreChecksum disables the verification of the APCB checksum when parsing
+// an APCB binary. It allows to inspect or modify an APCB binary with a stale checksum.
+type ParseOptionIgnoreChecksum bool
+
+func (opt ParseOptionIgnoreChecksum) apply(cfg *parseConfig) {
+	cfg.IgnoreChecksum = bool(opt)
+}
+
+func getParseConfig(opts []ParseOption) parseConfig {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
+}
+
 // TokenID is a unique token identifier
 type TokenID uint32
 
@@ -134,11 +160,16 @@ type Token struct {
 	ID           TokenID
 	PriorityMask PriorityMask
 	BoardMask    uint16
-	Value        interface{} // One of the following bool, uint8, uint16, uint32
+	Value        interface{} // One of the following bool, uint8, uint16, uint32, uint64
 }
 
-// NumValue returns Token's value as uint32
+// NumValue returns Token's value as uint32, uint64 values are truncated (see NumValue64)
 func (t Token) NumValue() uint32 {
+	return uint32(t.NumValue64())
+}
+
+// NumValue64 returns Token's value as uint64
+func (t Token) NumValue64() uint64 {
 	if t.Value == nil {
 		panic("Value is nil")
 	}
@@ -150,44 +181,162 @@ func (t Token) NumValue() uint32 {
 	return result
 }
 
-// ParseAPCBBinaryTokens returns all tokens contained in the APCB Binary
-func ParseAPCBBinaryTokens(apcbBinary []byte) ([]Token, error) {
-	_, remainBytes, err := parseAPCBHeader(apcbBinary)
+// APCB is the layout of an APCB binary: the token groups with their types and
+// tokens. All offsets are relative to the beginning of the APCB binary.
+type APCB struct {
+	Size     uint32
+	Checksum uint8
+	Groups   []Group
+}
+
+// Group is a group of token types in an APCB binary
+type Group struct {
+	Offset  uint32
+	ID      uint16
+	Version uint16
+	// HeaderSize is the size of the group header, types follow it
+	HeaderSize uint16
+	// Size is the size of the group, including the header
+	Size  uint32
+	Types []Type
+}
+
+// Type is a token type in an APCB group, all its tokens share the priority
+// and board masks
+type Type struct {
+	Offset     uint32
+	GroupID    uint16
+	TypeID     uint16
+	InstanceID uint16
+	// Size is the size of the type, including the header
+	Size          uint16
+	ContextType   uint8
+	ContextFormat uint8
+	// UnitSize is the size of a single token in bytes
+	UnitSize     uint8
+	PriorityMask PriorityMask
+	KeySize      uint8
+	KeyPos       uint8
+	BoardMask    uint16
+	Tokens       []TokenEntry
+}
+
+// TokenEntry is a token with its location in the APCB binary
+type TokenEntry struct {
+	Offset uint32
+	Token
+}
+
+// Tokens returns all tokens of the APCB in the order they appear in the binary
+func (a *APCB) Tokens() []Token {
+	var result []Token
+	for _, group := range a.Groups {
+		for _, t := range group.Types {
+			for _, entry := range t.Tokens {
+				result = append(result, entry.Token)
+			}
+		}
+	}
+	return result
+}
+
+// ParseAPCB parses the token groups of an APCB binary
+func ParseAPCB(apcbBinary []byte, opts ...ParseOption) (*APCB, error) {
+	header, remainBytes, err := parseAPCBHeader(apcbBinary, getParseConfig(opts))
 	if err != nil {
 		return nil, err
 	}
 
-	// We should iterate through each groups->types and collect all the tokens we encounter
-	var result []Token
+	result := &APCB{
+		Size:     header.V2Header.SizeOfAPCB,
+		Checksum: header.V2Header.CheckSumByte,
+	}
+	bodyOffset := uint32(binary.Size(header))
 	err = iterateTokenGroups(remainBytes, func(groupHeader groupHeader, groupOffset uint32) error {
-		groupData := remainBytes[groupOffset+uint32(groupHeader.SizeOfHeader) : groupOffset+groupHeader.SizeOfGroup]
-		return iterateTypes(groupData, func(typeHeader typeHeaderV3, typeOffset uint32) error {
-			typeData := groupData[typeOffset+uint32(binary.Size(typeHeader)) : typeOffset+uint32(typeHeader.SizeOfType)]
-			return iterateTokens(typeData, typeHeader, func(tokenPairOffset uint32, tp tokenPair) error {
+		group := Group{
+			Offset:     bodyOffset + groupOffset,
+			ID:         uint16(groupHeader.GroupID),
+			Version:    groupHeader.Version,
+			HeaderSize: groupHeader.SizeOfHeader,
+			Size:       groupHeader.SizeOfGroup,
+		}
+		groupDataOffset := groupOffset + uint32(groupHeader.SizeOfHeader)
+		groupData := remainBytes[groupDataOffset : groupOffset+groupHeader.SizeOfGroup]
+		err := iterateTypes(groupData, func(typeHeader typeHeaderV3, typeOffset uint32) error {
+			t := Type{
+				Offset:        bodyOffset + groupDataOffset + typeOffset,
+				GroupID:       uint16(typeHeader.GroupID),
+				TypeID:        uint16(typeHeader.TypeID),
+				InstanceID:    typeHeader.InstanceID,
+				Size:          typeHeader.SizeOfType,
+				ContextType:   uint8(typeHeader.ContextType),
+				ContextFormat: uint8(typeHeader.ContextFormat),
+				UnitSize:      typeHeader.UnitSize,
+				PriorityMask:  typeHeader.PriorityMask,
+				KeySize:       typeHeader.KeySize,
+				KeyPos:        typeHeader.KeyPos,
+				BoardMask:     typeHeader.BoardMask,
+			}
+			typeDataOffset := typeOffset + uint32(binary.Size(typeHeader))
+			typeData := groupData[typeDataOffset : typeOffset+uint32(typeHeader.SizeOfType)]
+			err := iterateTokens(typeData, typeHeader, func(tokenPairOffset uint32, tp tokenPair) error {
 				val, err := processValue(typeHeader.TypeID, tp.Value)
 				if err != nil {
 					return err
 				}
-				result = append(result, Token{
-					ID:           tp.ID,
-					PriorityMask: typeHeader.PriorityMask,
-					BoardMask:    typeHeader.BoardMask,
-					Value:        val,
+				t.Tokens = append(t.Tokens, TokenEntry{
+					Offset: bodyOffset + groupDataOffset + typeDataOffset + tokenPairOffset,
+					Token: Token{
+						ID:           tp.ID,
+						PriorityMask: typeHeader.PriorityMask,
+						BoardMask:    typeHeader.BoardMask,
+						Value:        val,
+					},
 				})
 				return nil
 			})
+			if err != nil {
+				return err
+			}
+			group.Types = append(group.Types, t)
+			return nil
 		})
+		if err != nil {
+			return err
+		}
+		result.Groups = append(result.Groups, group)
+		return nil
 	})
-	return result, err
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseAPCBBinaryTokens returns all tokens contained in the APCB Binary
+func ParseAPCBBinaryTokens(apcbBinary []byte, opts ...ParseOption) ([]Token, error) {
+	apcb, err := ParseAPCB(apcbBinary, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return apcb.Tokens(), nil
+}
+
+// UpsertToken inserts a new token or updates current into apcb binary.
+// The checksum of the APCB binary is recalculated.
+func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, newValue interface{}, apcbBinary []byte, opts ...ParseOption) error {
+	if err := upsertToken(tokenID, priorityMask, boardMask, newValue, apcbBinary, getParseConfig(opts)); err != nil {
+		return err
+	}
+	return UpdateChecksum(apcbBinary)
 }
 
-// UpsertToken inserts a new token or updates current into apcb binary
-func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, newValue interface{}, apcbBinary []byte) error {
+func upsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, newValue interface{}, apcbBinary []byte, cfg parseConfig) error {
 	typeID, numValue, err := parseValue(newValue)
 	if err != nil {
 		return err
 	}
-	header, remainBytes, err := parseAPCBHeader(apcbBinary)
+	header, remainBytes, err := parseAPCBHeader(apcbBinary, cfg)
 	if err != nil {
 		return err
 	}
@@ -206,7 +355,6 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 		matchedTypeOffset  uint32
 		matchedTokenOffset uint32
 	)
-	tokenBytesCount := uint32(binary.Size(tokenPair{}))
 
 	var tokenChanged bool
 	err = iterateTokenGroups(remainBytes, func(groupHeader groupHeader, groupOffset uint32) error {
@@ -230,7 +378,7 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 			typeData := groupData[typeOffset+uint32(binary.Size(typeHeader)) : typeOffset+uint32(typeHeader.SizeOfType)]
 			return iterateTokens(typeData, typeHeader, func(tokenPairOffset uint32, tp tokenPair) error {
 				if tp.ID <= tokenID {
-					matchedTokenOffset = tokenPairOffset + tokenBytesCount
+					matchedTokenOffset = tokenPairOffset + uint32(typeHeader.UnitSize)
 				}
 				if tp.ID != tokenID {
 					return nil
@@ -238,7 +386,7 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 				newTokenPair := tp
 				newTokenPair.Value = numValue
 
-				if err := writeFixedBuffer(typeData[tokenPairOffset:], newTokenPair); err != nil {
+				if err := newTokenPair.write(newFixedSizeBuffer(typeData[tokenPairOffset:]), typeHeader.UnitSize); err != nil {
 					return err
 				}
 				tokenChanged = true
@@ -257,6 +405,7 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 		insertionOffset uint32
 		addedBytes      uint32
 		writeNewToken   func(wb io.Writer) error
+		tokenBytesCount uint32
 	)
 
 	// Add headers to offsets
@@ -267,6 +416,7 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 
 	if matchedTypeHeader != nil {
 		// case 1: There exists a type for a upserted token
+		tokenBytesCount = uint32(matchedTypeHeader.UnitSize)
 		insertionOffset = matchedGroupOffset + matchedTypeOffset + uint32(binary.Size(matchedTypeHeader)) + matchedTokenOffset
 		addedBytes = tokenBytesCount
 
@@ -275,7 +425,7 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 				ID:    tokenID,
 				Value: numValue,
 			}
-			if err := binary.Write(wb, binary.LittleEndian, newToken); err != nil {
+			if err := newToken.write(wb, matchedTypeHeader.UnitSize); err != nil {
 				return fmt.Errorf("failed to write inserted token pair: '%w'", err)
 			}
 			return nil
@@ -304,6 +454,9 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 		}
 	}
 
+	if matchedTypeHeader != nil && uint32(matchedTypeHeader.SizeOfType)+tokenBytesCount > math.MaxUint16 {
+		return fmt.Errorf("impossible to insert a new token because size of type '%d' would overflow", matchedTypeHeader.SizeOfType)
+	}
 	if header.V2Header.SizeOfAPCB+addedBytes > uint32(len(apcbBinary)) {
 		return fmt.Errorf(
 			"impossible to insert a new token because apcb binary length is small, required: '%d', have: '%d'",
@@ -338,12 +491,145 @@ func UpsertToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, n
 	return nil
 }
 
+// DeleteToken removes the token from every type that matches priorityMask and boardMask.
+// Types and groups that are left without tokens are removed as well.
+// The checksum of the APCB binary is recalculated.
+func DeleteToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, apcbBinary []byte, opts ...ParseOption) error {
+	cfg := getParseConfig(opts)
+	var deleted bool
+	for {
+		found, err := deleteFirstToken(tokenID, priorityMask, boardMask, apcbBinary, cfg)
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+		deleted = true
+		if err := UpdateChecksum(apcbBinary); err != nil {
+			return err
+		}
+	}
+	if !deleted {
+		return fmt.Errorf("token '0x%X' with priority mask '%s' and board mask '0x%X' is not found", tokenID, priorityMask, boardMask)
+	}
+	return nil
+}
+
+func deleteFirstToken(tokenID TokenID, priorityMask PriorityMask, boardMask uint16, apcbBinary []byte, cfg parseConfig) (bool, error) {
+	header, remainBytes, err := parseAPCBHeader(apcbBinary, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		found              bool
+		matchedGroupHeader groupHeader
+		matchedGroupOffset uint32
+		matchedTypeHeader  typeHeaderV3
+		matchedTypeOffset  uint32
+		matchedTokenOffset uint32
+	)
+	err = iterateTokenGroups(remainBytes, func(groupHeader groupHeader, groupOffset uint32) error {
+		if found {
+			return nil
+		}
+		groupData := remainBytes[groupOffset+uint32(groupHeader.SizeOfHeader) : groupOffset+groupHeader.SizeOfGroup]
+		return iterateTypes(groupData, func(typeHeader typeHeaderV3, typeOffset uint32) error {
+			if found || typeHeader.BoardMask&boardMask == 0 || typeHeader.PriorityMask&priorityMask == 0 {
+				return nil
+			}
+			typeData := groupData[typeOffset+uint32(binary.Size(typeHeader)) : typeOffset+uint32(typeHeader.SizeOfType)]
+			return iterateTokens(typeData, typeHeader, func(tokenPairOffset uint32, tp tokenPair) error {
+				if found || tp.ID != tokenID {
+					return nil
+				}
+				found = true
+				matchedGroupHeader = groupHeader
+				matchedGroupOffset = groupOffset
+				matchedTypeHeader = typeHeader
+				matchedTypeOffset = typeOffset
+				matchedTokenOffset = tokenPairOffset
+				return nil
+			})
+		})
+	})
+	if err != nil || !found {
+		return false, err
+	}
+
+	// Make offsets relative to the beginning of the APCB binary
+	groupOffset := uint32(binary.Size(header)) + matchedGroupOffset
+	typeOffset := groupOffset + uint32(matchedGroupHeader.SizeOfHeader) + matchedTypeOffset
+	typeHeaderSize := uint32(binary.Size(matchedTypeHeader))
+
+	// Remove the token, or the whole type if it is the last token in it,
+	// or the whole group if it is the last type in it.
+	removalOffset := typeOffset + typeHeaderSize + matchedTokenOffset
+	removedBytes := uint32(matchedTypeHeader.UnitSize)
+	removeType := uint32(matchedTypeHeader.SizeOfType) == typeHeaderSize+removedBytes
+	if removeType {
+		removalOffset = typeOffset
+		removedBytes = uint32(matchedTypeHeader.SizeOfType)
+	}
+	removeGroup := removeType && matchedGroupHeader.SizeOfGroup == uint32(matchedGroupHeader.SizeOfHeader)+removedBytes
+	if removeGroup {
+		removalOffset = groupOffset
+		removedBytes = matchedGroupHeader.SizeOfGroup
+	}
+
+	// Shift the remaining bytes down over the removed ones
+	copy(apcbBinary[removalOffset:], apcbBinary[removalOffset+removedBytes:header.V2Header.SizeOfAPCB])
+
+	// Fix sizes of touched elements
+	if !removeType {
+		matchedTypeHeader.SizeOfType -= uint16(removedBytes)
+		if err := writeFixedBuffer(apcbBinary[typeOffset:], matchedTypeHeader); err != nil {
+			return false, fmt.Errorf("failed to update token type: '%w'", err)
+		}
+	}
+	if !removeGroup {
+		matchedGroupHeader.SizeOfGroup -= removedBytes
+		if err := writeFixedBuffer(apcbBinary[groupOffset:], matchedGroupHeader); err != nil {
+			return false, fmt.Errorf("failed to update token group: '%w'", err)
+		}
+	}
+	header.V2Header.SizeOfAPCB -= removedBytes
+	if err := writeFixedBuffer(apcbBinary, header); err != nil {
+		return false, fmt.Errorf("failed to update APCB binary header: '%w'", err)
+	}
+	return true, nil
+}
+
+// UpdateChecksum recalculates the checksum byte of the APCB header, so that the
+// bytes of the APCB sum up to zero. UpsertToken and DeleteToken update the checksum
+// themselves, it should be called if the APCB binary is modified by other means.
+func UpdateChecksum(apcbBinary []byte) error {
+	header, _, err := readAPCBHeader(apcbBinary)
+	if err != nil {
+		return err
+	}
+	const checksumOffset = 16 // offset of CheckSumByte in headerV2
+	apcbBinary[checksumOffset] = 0
+	apcbBinary[checksumOffset] = -checksum(apcbBinary[:header.V2Header.SizeOfAPCB])
+	return nil
+}
+
+// checksum returns the sum of all bytes, which is zero for an APCB with a valid checksum
+func checksum(b []byte) uint8 {
+	var sum uint8
+	for _, v := range b {
+		sum += v
+	}
+	return sum
+}
+
 func constructNewTypeForToken(
 	tokenID TokenID,
 	priorityMask PriorityMask,
 	boardMask uint16,
 	typeID tokenType,
-	value uint32,
+	value uint64,
 ) (uint32, func(wb io.Writer) error) {
 	newTypeHeader := typeHeaderV3{
 		GroupID:       tokensGroupID,
@@ -353,7 +639,7 @@ func constructNewTypeForToken(
 		ContextFormat: sortAscByUnitSizeContextFormat,
 		BoardMask:     boardMask,
 		PriorityMask:  priorityMask,
-		UnitSize:      8, // hardcode for tokenV3ContextType
+		UnitSize:      tokenUnitSize(typeID),
 		KeySize:       uint8(binary.Size(tokenID)),
 		KeyPos:        0,
 	}
@@ -361,14 +647,13 @@ func constructNewTypeForToken(
 		ID:    tokenID,
 		Value: value,
 	}
-	newTokenBytesCount := uint32(binary.Size(newToken))
-	newTypeHeader.SizeOfType = uint16(binary.Size(newTypeHeader)) + uint16(newTokenBytesCount)
+	newTypeHeader.SizeOfType = uint16(binary.Size(newTypeHeader)) + uint16(newTypeHeader.UnitSize)
 
 	return uint32(newTypeHeader.SizeOfType), func(wb io.Writer) error {
 		if err := binary.Write(wb, binary.LittleEndian, newTypeHeader); err != nil {
 			return fmt.Errorf("failed to write inserted type: '%w'", err)
 		}
-		if err := binary.Write(wb, binary.LittleEndian, newToken); err != nil {
+		if err := newToken.write(wb, newTypeHeader.UnitSize); err != nil {
 			return fmt.Errorf("failed to write inserted token pair: '%w'", err)
 		}
 		return nil
@@ -380,7 +665,7 @@ func constructNewGroupForToken(
 	priorityMask PriorityMask,
 	boardMask uint16,
 	typeID tokenType,
-	value uint32,
+	value uint64,
 ) (uint32, func(wb io.Writer) error) {
 	newTypeLength, insertNewTypeWithToken := constructNewTypeForToken(
 		tokenID,
@@ -408,7 +693,21 @@ func constructNewGroupForToken(
 	}
 }
 
-func parseAPCBHeader(apcbBinary []byte) (headerV3, []byte, error) {
+func parseAPCBHeader(apcbBinary []byte, cfg parseConfig) (headerV3, []byte, error) {
+	header, body, err := readAPCBHeader(apcbBinary)
+	if err != nil || cfg.IgnoreChecksum {
+		return header, body, err
+	}
+	if sum := checksum(apcbBinary[:header.V2Header.SizeOfAPCB]); sum != 0 {
+		return header, nil, fmt.Errorf(
+			"APCB checksum mismatch, bytes sum up to '0x%X' instead of zero, checksum byte is '0x%X'",
+			sum, header.V2Header.CheckSumByte)
+	}
+	return header, body, nil
+}
+
+// readAPCBHeader parses the APCB header without verifying the checksum
+func readAPCBHeader(apcbBinary []byte) (headerV3, []byte, error) {
 	var header headerV3
 	if err := binary.Read(bytes.NewBuffer(apcbBinary), binary.LittleEndian, &header); err != nil {
 		return header, nil, fmt.Errorf("failed to read input header: %w", err)
@@ -443,6 +742,11 @@ func parseAPCBHeader(apcbBinary []byte) (headerV3, []byte, error) {
 		)
 	}
 
+	if header.V2Header.SizeOfAPCB < uint32(binary.Size(header)) {
+		return header, nil, fmt.Errorf("size of APCB '%d' is less than size of APCB header '%d'",
+			header.V2Header.SizeOfAPCB, binary.Size(header))
+	}
+
 	return header, apcbBinary[uint32(binary.Size(header)):header.V2Header.SizeOfAPCB], nil
 }
 
@@ -463,6 +767,9 @@ func iterateTokenGroups(apcbBody []byte, onGroupFound func(groupHeader groupHead
 		if groupHeader.SizeOfGroup > uint32(len(remainBytes)) {
 			return fmt.Errorf("size of group exceeds the length of remaining data '%d' > '%d'", groupHeader.SizeOfGroup, len(remainBytes))
 		}
+		if uint32(groupHeader.SizeOfHeader) < groupHeaderSize || uint32(groupHeader.SizeOfHeader) > groupHeader.SizeOfGroup {
+			return fmt.Errorf("invalid size of group header '%d', expected to be in range ['%d', '%d']", groupHeader.SizeOfHeader, groupHeaderSize, groupHeader.SizeOfGroup)
+		}
 		if groupHeader.GroupID == tokensGroupID {
 			if err := onGroupFound(groupHeader, offset); err != nil {
 				return err
@@ -501,7 +808,10 @@ func iterateTypes(group []byte, onTypeFound func(typeHeader typeHeaderV3, offset
 }
 
 func iterateTokens(typeData []byte, typeHeader typeHeaderV3, onTokenFound func(offset uint32, tp tokenPair) error) error {
-	tokenPairSize := binary.Size(tokenPair{})
+	tokenPairSize := int(typeHeader.UnitSize)
+	if err := checkTokenUnitSize(typeHeader.UnitSize); err != nil {
+		return err
+	}
 	if len(typeData)%tokenPairSize != 0 {
 		return fmt.Errorf("incorrect APCB type header SizeOfType: '%d'", typeHeader.SizeOfType)
 	}
@@ -509,8 +819,8 @@ func iterateTokens(typeData []byte, typeHeader typeHeaderV3, onTokenFound func(o
 	b := bytes.NewReader(typeData)
 	tokensCount := len(typeData) / tokenPairSize
 	for i := 0; i < tokensCount; i++ {
-		var token tokenPair
-		if err := binary.Read(b, binary.LittleEndian, &token); err != nil {
+		token, err := readTokenPair(b, typeHeader.UnitSize)
+		if err != nil {
 			return fmt.Errorf("failed to read token pair: '%v'", err)
 		}
 		if err := onTokenFound(uint32(i*tokenPairSize), token); err != nil {
@@ -520,7 +830,7 @@ func iterateTokens(typeData []byte, typeHeader typeHeaderV3, onTokenFound func(o
 	return nil
 }
 
-func processValue(tokenType tokenType, val uint32) (interface{}, error) {
+func processValue(tokenType tokenType, val uint64) (interface{}, error) {
 	switch tokenType {
 	case booleanTokenType:
 		return val&1 != 0, nil
@@ -529,12 +839,14 @@ func processValue(tokenType tokenType, val uint32) (interface{}, error) {
 	case twoBytesTokenType:
 		return uint16(val & 0xffff), nil
 	case fourBytesTokenType:
+		return uint32(val & 0xffffffff), nil
+	case eightBytesTokenType:
 		return val, nil
 	}
 	return nil, fmt.Errorf("unknown token type: '%d'", tokenType)
 }
 
-func parseValue(v interface{}) (tokenType, uint32, error) {
+func parseValue(v interface{}) (tokenType, uint64, error) {
 	switch value := v.(type) {
 	case bool:
 		if value {
@@ -542,15 +854,63 @@ func parseValue(v interface{}) (tokenType, uint32, error) {
 		}
 		return booleanTokenType, 0, nil
 	case uint8:
-		return oneByteTokenType, uint32(value), nil
+		return oneByteTokenType, uint64(value), nil
 	case uint16:
-		return twoBytesTokenType, uint32(value), nil
+		return twoBytesTokenType, uint64(value), nil
 	case uint32:
-		return fourBytesTokenType, value, nil
+		return fourBytesTokenType, uint64(value), nil
+	case uint64:
+		return eightBytesTokenType, value, nil
 	}
 	return 0, 0, fmt.Errorf("unknown type: '%T'", v)
 }
 
+// tokenUnitSize returns the size of a token pair of a type with tokens of the given type
+func tokenUnitSize(typeID tokenType) uint8 {
+	if typeID == eightBytesTokenType {
+		return uint8(binary.Size(TokenID(0)) + binary.Size(uint64(0)))
+	}
+	return uint8(binary.Size(TokenID(0)) + binary.Size(uint32(0)))
+}
+
+func checkTokenUnitSize(unitSize uint8) error {
+	if unitSize != tokenUnitSize(fourBytesTokenType) && unitSize != tokenUnitSize(eightBytesTokenType) {
+		return fmt.Errorf("unsupported APCB token unit size: '%d'", unitSize)
+	}
+	return nil
+}
+
+func readTokenPair(r io.Reader, unitSize uint8) (tokenPair, error) {
+	var tp tokenPair
+	if err := binary.Read(r, binary.LittleEndian, &tp.ID); err != nil {
+		return tp, err
+	}
+	if unitSize == tokenUnitSize(eightBytesTokenType) {
+		err := binary.Read(r, binary.LittleEndian, &tp.Value)
+		return tp, err
+	}
+	var value uint32
+	err := binary.Read(r, binary.LittleEndian, &value)
+	tp.Value = uint64(value)
+	return tp, err
+}
+
+func (tp tokenPair) write(w io.Writer, unitSize uint8) error {
+	if err := checkTokenUnitSize(unitSize); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, tp.ID); err != nil {
+		return err
+	}
+	if unitSize == tokenUnitSize(eightBytesTokenType) {
+		return binary.Write(w, binary.LittleEndian, tp.Value)
+	}
+	if tp.Value > math.MaxUint32 {
+		return fmt.Errorf("token value '0x%X' does not fit into unit size '%d'", tp.Value, unitSize)
+	}
+	return binary.Write(w, binary.LittleEndian, uint32(tp.Value))
+}
+
 type fixedSizeBuffer struct {
 	buffer []byte
 	offset int
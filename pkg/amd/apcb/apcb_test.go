@@ -41,6 +41,38 @@ func TestParsingTokens(t *testing.T) {
 	require.Equal(t, uint32(0), token.NumValue())
 }
 
+func TestParseAPCB(t *testing.T) {
+	apcbBinary, err := getFile("apcb_binary.xz")
+	require.NoError(t, err)
+
+	apcb, err := ParseAPCB(apcbBinary)
+	require.NoError(t, err)
+	require.True(t, apcb.Size <= uint32(len(apcbBinary)))
+	require.NotEmpty(t, apcb.Groups)
+
+	tokens, err := ParseAPCBBinaryTokens(apcbBinary)
+	require.NoError(t, err)
+	require.Equal(t, tokens, apcb.Tokens())
+
+	// offsets must point at the corresponding headers and tokens in the binary
+	for _, group := range apcb.Groups {
+		require.Equal(t, uint32(tokenGroupSignature), binary.LittleEndian.Uint32(apcbBinary[group.Offset:]))
+		require.Equal(t, group.ID, binary.LittleEndian.Uint16(apcbBinary[group.Offset+4:]))
+		require.NotEmpty(t, group.Types)
+		for _, typ := range group.Types {
+			require.True(t, typ.Offset >= group.Offset+uint32(group.HeaderSize))
+			require.True(t, typ.Offset+uint32(typ.Size) <= group.Offset+group.Size)
+			require.Equal(t, typ.GroupID, binary.LittleEndian.Uint16(apcbBinary[typ.Offset:]))
+			require.Equal(t, typ.TypeID, binary.LittleEndian.Uint16(apcbBinary[typ.Offset+2:]))
+			for _, entry := range typ.Tokens {
+				require.Equal(t, uint32(entry.ID), binary.LittleEndian.Uint32(apcbBinary[entry.Offset:]))
+				require.Equal(t, typ.PriorityMask, entry.PriorityMask)
+				require.Equal(t, typ.BoardMask, entry.BoardMask)
+			}
+		}
+	}
+}
+
 func TestUpsertToken(t *testing.T) {
 	t.Run("update_existing_token", func(t *testing.T) {
 		apcbBinary, err := getFile("apcb_binary.xz")
@@ -79,12 +111,13 @@ func TestUpsertToken(t *testing.T) {
 		require.NoError(t, err)
 		require.NotEmpty(t, apcbBinary)
 
-		h, _, err := parseAPCBHeader(apcbBinary)
+		h, _, err := parseAPCBHeader(apcbBinary, parseConfig{})
 		require.NoError(t, err)
 		h.V2Header.SizeOfAPCB = uint32(binary.Size(h))
 
 		resultBuffer := make([]byte, binary.Size(h)+1000)
 		require.NoError(t, writeFixedBuffer(resultBuffer, h))
+		require.NoError(t, UpdateChecksum(resultBuffer))
 		tokens, err := ParseAPCBBinaryTokens(resultBuffer)
 		require.NoError(t, err)
 		require.Empty(t, tokens)
@@ -102,6 +135,110 @@ func TestUpsertToken(t *testing.T) {
 	})
 }
 
+func TestDeleteToken(t *testing.T) {
+	t.Run("delete_token_in_the_middle", func(t *testing.T) {
+		apcbBinary, err := getFile("apcb_binary.xz")
+		require.NoError(t, err)
+		tokens, err := ParseAPCBBinaryTokens(apcbBinary)
+		require.NoError(t, err)
+
+		// Pick a token that has neighbours within the same type
+		sameType := func(a, b Token) bool {
+			return fmt.Sprintf("%T", a.Value) == fmt.Sprintf("%T", b.Value) &&
+				a.PriorityMask == b.PriorityMask && a.BoardMask == b.BoardMask
+		}
+		idx := -1
+		for i := 1; i < len(tokens)-1 && idx < 0; i++ {
+			if sameType(tokens[i-1], tokens[i]) && sameType(tokens[i], tokens[i+1]) {
+				idx = i
+			}
+		}
+		require.NotEqual(t, -1, idx)
+		deleted := tokens[idx]
+		expected := append(append([]Token{}, tokens[:idx]...), tokens[idx+1:]...)
+
+		sizeBefore := binary.LittleEndian.Uint32(apcbBinary[8:])
+		require.NoError(t, DeleteToken(deleted.ID, 0xff, 0xffff, apcbBinary))
+		typeID, _, err := parseValue(deleted.Value)
+		require.NoError(t, err)
+		require.Equal(t, sizeBefore-uint32(tokenUnitSize(typeID)), binary.LittleEndian.Uint32(apcbBinary[8:]))
+
+		tokens, err = ParseAPCBBinaryTokens(apcbBinary)
+		require.NoError(t, err)
+		require.Equal(t, expected, tokens)
+
+		require.Error(t, DeleteToken(deleted.ID, 0xff, 0xffff, apcbBinary))
+	})
+
+	t.Run("delete_only_token_in_type", func(t *testing.T) {
+		apcbBinary, err := getFile("apcb_binary.xz")
+		require.NoError(t, err)
+		h, _, err := parseAPCBHeader(apcbBinary, parseConfig{})
+		require.NoError(t, err)
+		headerSize := uint32(binary.Size(h))
+		h.V2Header.SizeOfAPCB = headerSize
+
+		resultBuffer := make([]byte, binary.Size(h)+1000)
+		require.NoError(t, writeFixedBuffer(resultBuffer, h))
+		require.NoError(t, UpdateChecksum(resultBuffer))
+		require.NoError(t, UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint32(0xffffffff), resultBuffer))
+		sizeWithOneType := binary.LittleEndian.Uint32(resultBuffer[8:])
+		require.NoError(t, UpsertToken(0xFFFFBBBB, 0xff, 0xffff, bool(true), resultBuffer))
+
+		// Removing the only boolean token removes its type, but keeps the group
+		require.NoError(t, DeleteToken(0xFFFFBBBB, 0xff, 0xffff, resultBuffer))
+		require.Equal(t, sizeWithOneType, binary.LittleEndian.Uint32(resultBuffer[8:]))
+		tokens, err := ParseAPCBBinaryTokens(resultBuffer)
+		require.NoError(t, err)
+		require.Equal(t, []Token{{ID: 0xFFFFAAAA, PriorityMask: 0xff, BoardMask: 0xffff, Value: uint32(0xffffffff)}}, tokens)
+
+		// Removing the last token removes the group as well
+		require.NoError(t, DeleteToken(0xFFFFAAAA, 0xff, 0xffff, resultBuffer))
+		require.Equal(t, headerSize, binary.LittleEndian.Uint32(resultBuffer[8:]))
+		tokens, err = ParseAPCBBinaryTokens(resultBuffer)
+		require.NoError(t, err)
+		require.Empty(t, tokens)
+	})
+}
+
+func TestEightBytesTokens(t *testing.T) {
+	apcbBinary, err := getFile("apcb_binary.xz")
+	require.NoError(t, err)
+	tokens, err := ParseAPCBBinaryTokens(apcbBinary)
+	require.NoError(t, err)
+	tokensCount := len(tokens)
+
+	// The first uint64 token creates a type with 12 bytes units, the others are inserted into it in order
+	require.NoError(t, UpsertToken(0xFFFFBBBB, 0xff, 0xffff, uint64(0x1122334455667788), apcbBinary))
+	require.NoError(t, UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint64(0xffffffffffffffff), apcbBinary))
+	require.NoError(t, UpsertToken(0xFFFFCCCC, 0xff, 0xffff, uint64(1), apcbBinary))
+	require.NoError(t, UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint64(0xAABBCCDDEEFF0011), apcbBinary))
+
+	tokens, err = ParseAPCBBinaryTokens(apcbBinary)
+	require.NoError(t, err)
+	require.Len(t, tokens, tokensCount+3)
+	require.Equal(t, []Token{
+		{ID: 0xFFFFAAAA, PriorityMask: 0xff, BoardMask: 0xffff, Value: uint64(0xAABBCCDDEEFF0011)},
+		{ID: 0xFFFFBBBB, PriorityMask: 0xff, BoardMask: 0xffff, Value: uint64(0x1122334455667788)},
+		{ID: 0xFFFFCCCC, PriorityMask: 0xff, BoardMask: 0xffff, Value: uint64(1)},
+	}, tokens[tokensCount:])
+
+	token := findToken(0xFFFFBBBB, tokens)
+	require.NotNil(t, token)
+	require.Equal(t, uint64(0x1122334455667788), token.NumValue64())
+	require.Equal(t, uint32(0x55667788), token.NumValue())
+
+	require.NoError(t, DeleteToken(0xFFFFBBBB, 0xff, 0xffff, apcbBinary))
+	tokens, err = ParseAPCBBinaryTokens(apcbBinary)
+	require.NoError(t, err)
+	require.Len(t, tokens, tokensCount+2)
+	require.Nil(t, findToken(0xFFFFBBBB, tokens))
+	require.Equal(t, uint64(1), findToken(0xFFFFCCCC, tokens).NumValue64())
+
+	_, err = ParseAPCBBinaryTokens(malformedAPCB(t, func(gh *groupHeader, th *typeHeaderV3) { th.UnitSize = 5 }))
+	require.Error(t, err)
+}
+
 func findToken(tokenID TokenID, tokens []Token) *Token {
 	for _, token := range tokens {
 		if token.ID == tokenID {
@@ -111,6 +248,47 @@ func findToken(tokenID TokenID, tokens []Token) *Token {
 	return nil
 }
 
+func TestUpdateChecksum(t *testing.T) {
+	apcbBinary, err := getFile("apcb_binary.xz")
+	require.NoError(t, err)
+
+	require.NoError(t, UpsertToken(0x3E7D5274, 0xff, 0xffff, uint32(0x1234), apcbBinary))
+	require.NoError(t, UpdateChecksum(apcbBinary))
+
+	var sum uint8
+	for _, b := range apcbBinary[:binary.LittleEndian.Uint32(apcbBinary[8:])] {
+		sum += b
+	}
+	require.Zero(t, sum)
+
+	require.Error(t, UpdateChecksum(make([]byte, 16)))
+}
+
+func TestChecksumValidation(t *testing.T) {
+	apcbBinary, err := getFile("apcb_binary.xz")
+	require.NoError(t, err)
+	apcbSize := func() uint32 { return binary.LittleEndian.Uint32(apcbBinary[8:]) }
+
+	require.NoError(t, UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint32(0x1234), apcbBinary))
+	require.Zero(t, checksum(apcbBinary[:apcbSize()]))
+	require.NoError(t, DeleteToken(0xFFFFAAAA, 0xff, 0xffff, apcbBinary))
+	require.Zero(t, checksum(apcbBinary[:apcbSize()]))
+
+	// Modify the last token value without fixing the checksum
+	apcbBinary[apcbSize()-1] ^= 0xff
+	_, err = ParseAPCBBinaryTokens(apcbBinary)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+	require.Error(t, UpsertToken(0x3E7D5274, 0xff, 0xffff, uint32(0x1234), apcbBinary))
+
+	_, err = ParseAPCBBinaryTokens(apcbBinary, ParseOptionIgnoreChecksum(true))
+	require.NoError(t, err)
+	require.NoError(t, UpsertToken(0x3E7D5274, 0xff, 0xffff, uint32(0x1234), apcbBinary, ParseOptionIgnoreChecksum(true)))
+
+	_, err = ParseAPCBBinaryTokens(apcbBinary)
+	require.NoError(t, err)
+}
+
 func getFile(filename string) ([]byte, error) {
 	compressedImage, err := os.ReadFile(path.Join("testdata", filename))
 	if err != nil {
@@ -129,3 +307,72 @@ func getFile(filename string) ([]byte, error) {
 
 	return decompressedImage, nil
 }
+
+// FuzzParseAPCBBinaryTokens checks that crafted APCB binaries are rejected
+// with an error instead of a panic, both when parsing and when upserting
+// tokens.
+//
+// To run the fuzzer:
+// go test -run XXX -fuzz=FuzzParseAPCBBinaryTokens ./pkg/amd/apcb
+func FuzzParseAPCBBinaryTokens(f *testing.F) {
+	apcbBinary, err := getFile("apcb_binary.xz")
+	require.NoError(f, err)
+	f.Add(apcbBinary)
+
+	h, _, err := parseAPCBHeader(apcbBinary, parseConfig{})
+	require.NoError(f, err)
+	h.V2Header.SizeOfAPCB = uint32(binary.Size(h))
+	headerOnly := make([]byte, binary.Size(h)+64)
+	require.NoError(f, writeFixedBuffer(headerOnly, h))
+	require.NoError(f, UpdateChecksum(headerOnly))
+	f.Add(headerOnly)
+
+	for _, seed := range [][]byte{
+		malformedAPCB(f, func(gh *groupHeader, th *typeHeaderV3) { gh.SizeOfHeader = uint16(gh.SizeOfGroup + 1) }),
+		malformedAPCB(f, func(gh *groupHeader, th *typeHeaderV3) { gh.SizeOfHeader = 0 }),
+		malformedAPCB(f, func(gh *groupHeader, th *typeHeaderV3) { th.SizeOfType = 0xffff }),
+		malformedAPCB(f, func(gh *groupHeader, th *typeHeaderV3) { th.SizeOfType++ }),
+		malformedAPCB(f, func(gh *groupHeader, th *typeHeaderV3) { th.UnitSize = 0 }),
+	} {
+		f.Add(seed)
+	}
+
+	// Let the fuzzer reach the parsing of groups and types of binaries with a stale checksum
+	ignoreChecksum := ParseOptionIgnoreChecksum(true)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		_, _ = ParseAPCBBinaryTokens(b, ignoreChecksum)
+
+		b = append(b, make([]byte, 64)...)
+		_ = UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint32(0xffffffff), b, ignoreChecksum)
+		_ = UpsertToken(0xFFFFBBBB, 0xff, 0xffff, bool(true), b, ignoreChecksum)
+		_, _ = ParseAPCBBinaryTokens(b, ignoreChecksum)
+	})
+}
+
+// malformedAPCB returns an APCB binary with a single token group and type,
+// whose headers are modified by corrupt.
+func malformedAPCB(tb testing.TB, corrupt func(gh *groupHeader, th *typeHeaderV3)) []byte {
+	var h headerV3
+	h.V2Header.Signature = headerV2Signature
+	h.Signature2 = headerV3Signature
+	h.SignatureEnding = headerV3EndingSignature
+	h.V2Header.SizeOfAPCB = uint32(binary.Size(h))
+	b := make([]byte, binary.Size(h)+64)
+	require.NoError(tb, writeFixedBuffer(b, h))
+	require.NoError(tb, UpdateChecksum(b))
+	require.NoError(tb, UpsertToken(0xFFFFAAAA, 0xff, 0xffff, uint32(1), b))
+
+	headerSize := uint32(binary.Size(h))
+	var gh groupHeader
+	require.NoError(tb, binary.Read(bytes.NewReader(b[headerSize:]), binary.LittleEndian, &gh))
+	var th typeHeaderV3
+	typeOffset := headerSize + uint32(gh.SizeOfHeader)
+	require.NoError(tb, binary.Read(bytes.NewReader(b[typeOffset:]), binary.LittleEndian, &th))
+
+	corrupt(&gh, &th)
+	require.NoError(tb, writeFixedBuffer(b[headerSize:], gh))
+	require.NoError(tb, writeFixedBuffer(b[typeOffset:], th))
+	require.NoError(tb, UpdateChecksum(b))
+	return b
+}
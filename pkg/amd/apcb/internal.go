@@ -112,10 +112,11 @@ const (
 type tokenType uint16
 
 const (
-	booleanTokenType   tokenType = 0
-	oneByteTokenType   tokenType = 1
-	twoBytesTokenType  tokenType = 2
-	fourBytesTokenType tokenType = 4
+	booleanTokenType    tokenType = 0
+	oneByteTokenType    tokenType = 1
+	twoBytesTokenType   tokenType = 2
+	fourBytesTokenType  tokenType = 4
+	eightBytesTokenType tokenType = 8
 )
 
 type groupID uint16
@@ -133,7 +134,7 @@ type typeHeaderV3 struct {
 
 	ContextType   contextType
 	ContextFormat contextFormat
-	// UnitSize determines size in byte. Applicable when ContextType = 2, value should be 8.
+	// UnitSize determines size in byte. Applicable when ContextType = 2, value should be 8 (12 for eight bytes tokens).
 	UnitSize     uint8
 	PriorityMask PriorityMask
 	// KeySize defines sorting key size. Should be smaller than or equal to UnitSize. Applicable when ContextFormat = 1. (or != 0)
@@ -144,7 +145,9 @@ type typeHeaderV3 struct {
 	BoardMask uint16
 }
 
+// tokenPair is a token stored in a type. In the binary the ID is followed by
+// the value, which takes the rest of the UnitSize of the type: 4 or 8 bytes.
 type tokenPair struct {
 	ID    TokenID
-	Value uint32
+	Value uint64
 }
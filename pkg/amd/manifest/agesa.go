@@ -0,0 +1,74 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+// agesaVersionSignatures are the markers that precede the AGESA version string
+// in the BIOS image. "AGESA!V9" is used by modern AGESA releases and is followed
+// by a NUL-terminated version string, "!!!AGESA" is used by the older ones.
+var agesaVersionSignatures = [][]byte{
+	[]byte("AGESA!V9"),
+	[]byte("!!!AGESA"),
+}
+
+// maxAGESAVersionLength limits the amount of bytes read after the signature
+const maxAGESAVersionLength = 128
+
+// FindAGESAVersion looks for the AGESA version string in the image and returns it
+// together with the range of the string in the image
+func FindAGESAVersion(image []byte) (string, bytes2.Range, error) {
+	for _, signature := range agesaVersionSignatures {
+		var offset uint64
+		data := image
+		for {
+			idx := bytes.Index(data, signature)
+			if idx == -1 {
+				break
+			}
+
+			start := uint64(idx + len(signature))
+			version, r := parseAGESAVersion(data[start:])
+			if len(version) > 0 {
+				r.Offset += offset + start
+				return version, r, nil
+			}
+			data = data[start:]
+			offset += start
+		}
+	}
+	return "", bytes2.Range{}, fmt.Errorf("AGESA version is not found")
+}
+
+// parseAGESAVersion reads a NUL-terminated string that follows AGESA signature.
+// It returns the string and its range relatively to the beginning of data.
+func parseAGESAVersion(data []byte) (string, bytes2.Range) {
+	var skip int
+	for skip < len(data) && (data[skip] == 0 || data[skip] == ' ') {
+		skip++
+	}
+	data = data[skip:]
+	if len(data) > maxAGESAVersionLength {
+		data = data[:maxAGESAVersionLength]
+	}
+
+	end := bytes.IndexByte(data, 0)
+	if end == -1 {
+		return "", bytes2.Range{}
+	}
+	for _, c := range data[:end] {
+		if c < 0x20 || c > 0x7e {
+			return "", bytes2.Range{}
+		}
+	}
+	version := strings.TrimRight(string(data[:end]), " ")
+	return version, bytes2.Range{Offset: uint64(skip), Length: uint64(len(version))}
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFindAGESAVersion(t *testing.T) {
+	image := bytes.Repeat([]byte{0xff}, 0x1000)
+	marker := []byte("AGESA!V9\x00CezannePI-FP6 1.0.0.8\x00")
+	copy(image[0x800:], marker)
+
+	version, r, err := FindAGESAVersion(image)
+	if err != nil {
+		t.Fatalf("Expected no error when looking for AGESA version, got: %v", err)
+	}
+	if version != "CezannePI-FP6 1.0.0.8" {
+		t.Errorf("Unexpected AGESA version: '%s'", version)
+	}
+	if r.Offset != 0x800+9 {
+		t.Errorf("Unexpected AGESA version offset: 0x%x", r.Offset)
+	}
+	if string(image[r.Offset:r.Offset+r.Length]) != version {
+		t.Errorf("AGESA version range does not match the version string")
+	}
+}
+
+func TestFindAGESAVersionLegacy(t *testing.T) {
+	image := bytes.Repeat([]byte{0xff}, 0x1000)
+	// signature without a terminated string should be skipped
+	copy(image[0x100:], []byte("AGESA!V9\xff\xff"))
+	copy(image[0x400:], []byte("!!!AGESA V5 RV PI 1.0.0.1 \x00"))
+
+	version, _, err := FindAGESAVersion(image)
+	if err != nil {
+		t.Fatalf("Expected no error when looking for AGESA version, got: %v", err)
+	}
+	if version != "V5 RV PI 1.0.0.1" {
+		t.Errorf("Unexpected AGESA version: '%s'", version)
+	}
+}
+
+func TestFindAGESAVersionNotFound(t *testing.T) {
+	if _, _, err := FindAGESAVersion(bytes.Repeat([]byte{0xff}, 0x100)); err == nil {
+		t.Errorf("Expected an error for an image without AGESA version")
+	}
+}
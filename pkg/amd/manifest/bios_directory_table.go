@@ -82,6 +82,10 @@ type BIOSDirectoryTable struct {
 	BIOSDirectoryTableHeader
 
 	Entries []BIOSDirectoryTableEntry
+
+	// Range is the location of the table in the image. It is set when the
+	// table is found in an image, not by ParseBIOSDirectoryTable.
+	Range bytes2.Range
 }
 
 func (b BIOSDirectoryTable) String() string {
@@ -144,7 +148,8 @@ func FindBIOSDirectoryTable(image []byte) (*BIOSDirectoryTable, bytes2.Range, er
 			offset += shift
 			continue
 		}
-		return table, bytes2.Range{Offset: offset + uint64(idx), Length: bytesRead}, err
+		table.Range = bytes2.Range{Offset: offset + uint64(idx), Length: bytesRead}
+		return table, table.Range, err
 	}
 	return nil, bytes2.Range{}, fmt.Errorf("BIOSDirectoryTable is not found")
 }
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strings"
 
 	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
 )
@@ -31,7 +32,156 @@ type EmbeddedFirmwareStructure struct {
 	Reserved2                                      uint32
 	BIOSDirectoryTableFamily17hModels60h3FhPointer uint32
 
-	Reserved3 [30]byte
+	Reserved3 [20]byte
+
+	SPIReadModeFamily15hModels60h6Fh   SPIReadMode
+	SPIFastSpeedFamily15hModels60h6Fh  SPIFastSpeed
+	Reserved4                          uint8
+	SPIReadModeFamily17hModels00h2Fh   SPIReadMode
+	SPIFastSpeedFamily17hModels00h2Fh  SPIFastSpeed
+	QPRDummyCycleFamily17hModels00h2Fh uint8
+	Reserved5                          uint8
+	// The following fields are also used by later generations
+	SPIReadModeFamily17hModels30h3Fh  SPIReadMode
+	SPIFastSpeedFamily17hModels30h3Fh SPIFastSpeed
+	MicronDetectFamily17hModels30h3Fh uint8
+}
+
+// SPIConfigUnset is the value of the SPI configuration fields of the EFS left
+// to their default
+const SPIConfigUnset = 0xff
+
+// SPIReadMode is the SPI read mode configured in the EFS
+type SPIReadMode uint8
+
+// SPI read modes of the EFS
+const (
+	SPIReadModeNormal33M SPIReadMode = 0
+	SPIReadModeDualIO112 SPIReadMode = 2
+	SPIReadModeQuadIO114 SPIReadMode = 3
+	SPIReadModeDualIO122 SPIReadMode = 4
+	SPIReadModeQuadIO144 SPIReadMode = 5
+	SPIReadModeNormal66M SPIReadMode = 6
+	SPIReadModeFastRead  SPIReadMode = 7
+	SPIReadModeUnset     SPIReadMode = SPIConfigUnset
+)
+
+func (m SPIReadMode) String() string {
+	switch m {
+	case SPIReadModeNormal33M:
+		return "Normal read (up to 33MHz)"
+	case SPIReadModeDualIO112:
+		return "Dual IO (1-1-2)"
+	case SPIReadModeQuadIO114:
+		return "Quad IO (1-1-4)"
+	case SPIReadModeDualIO122:
+		return "Dual IO (1-2-2)"
+	case SPIReadModeQuadIO144:
+		return "Quad IO (1-4-4)"
+	case SPIReadModeNormal66M:
+		return "Normal read (up to 66MHz)"
+	case SPIReadModeFastRead:
+		return "Fast read"
+	case SPIReadModeUnset:
+		return "Default"
+	}
+	return fmt.Sprintf("Unknown (0x%x)", uint8(m))
+}
+
+// SPIFastSpeed is the SPI fast read speed configured in the EFS
+type SPIFastSpeed uint8
+
+// SPI fast read speeds of the EFS
+const (
+	SPIFastSpeed66M   SPIFastSpeed = 0
+	SPIFastSpeed33M   SPIFastSpeed = 1
+	SPIFastSpeed22M   SPIFastSpeed = 2
+	SPIFastSpeed16M   SPIFastSpeed = 3
+	SPIFastSpeed100M  SPIFastSpeed = 4
+	SPIFastSpeed800K  SPIFastSpeed = 5
+	SPIFastSpeedUnset SPIFastSpeed = SPIConfigUnset
+)
+
+func (s SPIFastSpeed) String() string {
+	switch s {
+	case SPIFastSpeed66M:
+		return "66.66MHz"
+	case SPIFastSpeed33M:
+		return "33.33MHz"
+	case SPIFastSpeed22M:
+		return "22.22MHz"
+	case SPIFastSpeed16M:
+		return "16.66MHz"
+	case SPIFastSpeed100M:
+		return "100MHz"
+	case SPIFastSpeed800K:
+		return "800KHz"
+	case SPIFastSpeedUnset:
+		return "Default"
+	}
+	return fmt.Sprintf("Unknown (0x%x)", uint8(s))
+}
+
+// SPIConfig is the SPI flash configuration of the EFS for one processor generation.
+// The EFS does not record the size of the flash nor the number of chips, so these
+// have to be checked against the target board separately.
+type SPIConfig struct {
+	Generation string
+	ReadMode   SPIReadMode
+	FastSpeed  SPIFastSpeed
+	// QPRDummyCycle is only defined for Family 17h Models 00h-2Fh, it is SPIConfigUnset otherwise
+	QPRDummyCycle uint8
+	// MicronDetect is only defined from Family 17h Models 30h-3Fh on, it is SPIConfigUnset otherwise
+	MicronDetect uint8
+}
+
+func (c SPIConfig) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s: read mode: %s, fast speed: %s", c.Generation, c.ReadMode, c.FastSpeed)
+	if c.QPRDummyCycle != SPIConfigUnset {
+		fmt.Fprintf(&s, ", QPR dummy cycle: 0x%x", c.QPRDummyCycle)
+	}
+	if c.MicronDetect != SPIConfigUnset {
+		fmt.Fprintf(&s, ", Micron detect: 0x%x", c.MicronDetect)
+	}
+	return s.String()
+}
+
+// SPIConfigs returns the SPI flash configurations of the processor generations
+// the EFS sets at least one SPI field for
+func (efs *EmbeddedFirmwareStructure) SPIConfigs() []SPIConfig {
+	configs := []SPIConfig{
+		{
+			Generation:    "Family 15h Models 60h-6Fh",
+			ReadMode:      efs.SPIReadModeFamily15hModels60h6Fh,
+			FastSpeed:     efs.SPIFastSpeedFamily15hModels60h6Fh,
+			QPRDummyCycle: SPIConfigUnset,
+			MicronDetect:  SPIConfigUnset,
+		},
+		{
+			Generation:    "Family 17h Models 00h-2Fh",
+			ReadMode:      efs.SPIReadModeFamily17hModels00h2Fh,
+			FastSpeed:     efs.SPIFastSpeedFamily17hModels00h2Fh,
+			QPRDummyCycle: efs.QPRDummyCycleFamily17hModels00h2Fh,
+			MicronDetect:  SPIConfigUnset,
+		},
+		{
+			Generation:    "Family 17h Models 30h-3Fh and later",
+			ReadMode:      efs.SPIReadModeFamily17hModels30h3Fh,
+			FastSpeed:     efs.SPIFastSpeedFamily17hModels30h3Fh,
+			QPRDummyCycle: SPIConfigUnset,
+			MicronDetect:  efs.MicronDetectFamily17hModels30h3Fh,
+		},
+	}
+
+	var result []SPIConfig
+	for _, c := range configs {
+		if c.ReadMode != SPIReadModeUnset || c.FastSpeed != SPIFastSpeedUnset ||
+			c.QPRDummyCycle != SPIConfigUnset || c.MicronDetect != SPIConfigUnset {
+			result = append(result, c)
+		}
+	}
+	return result
 }
 
 // FindEmbeddedFirmwareStructure locates and parses Embedded Firmware Structure
@@ -5,6 +5,8 @@
 package manifest
 
 import (
+	"bytes"
+	"encoding/binary"
 	"testing"
 )
 
@@ -123,3 +125,45 @@ func (f *dummyFirmware) OffsetToPhysAddr(offset uint64) uint64 {
 	}
 	return result
 }
+
+func TestEmbeddedFirmwareStructureSPIConfig(t *testing.T) {
+	image := make([]byte, embeddedFirmwareStructureLength)
+	binary.LittleEndian.PutUint32(image[0:], EmbeddedFirmwareStructureSignature)
+	for i := 0x40; i < embeddedFirmwareStructureLength; i++ {
+		image[i] = SPIConfigUnset
+	}
+	// Family 17h Models 30h-3Fh and later: Quad IO (1-4-4) at 100MHz, other generations unset
+	image[0x47] = uint8(SPIReadModeQuadIO144)
+	image[0x48] = uint8(SPIFastSpeed100M)
+
+	amdFw, err := NewAMDFirmware(newDummyFirmware(image, t).addMapping(0xfffa0000, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	configs := amdFw.PSPFirmware().SPIConfig
+	if len(configs) != 1 {
+		t.Fatalf("got %d SPI configurations, expected 1: %v", len(configs), configs)
+	}
+	expected := SPIConfig{
+		Generation:    "Family 17h Models 30h-3Fh and later",
+		ReadMode:      SPIReadModeQuadIO144,
+		FastSpeed:     SPIFastSpeed100M,
+		QPRDummyCycle: SPIConfigUnset,
+		MicronDetect:  SPIConfigUnset,
+	}
+	if configs[0] != expected {
+		t.Errorf("got SPI configuration %+v, expected %+v", configs[0], expected)
+	}
+	if s := configs[0].String(); s != "Family 17h Models 30h-3Fh and later: read mode: Quad IO (1-4-4), fast speed: 100MHz" {
+		t.Errorf("unexpected SPI configuration string: %q", s)
+	}
+
+	// A zeroed EFS configures normal reads at 66.66MHz for all generations
+	efs, _, err := ParseEmbeddedFirmwareStructure(bytes.NewReader(append(image[:4:4], make([]byte, embeddedFirmwareStructureLength-4)...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if configs := efs.SPIConfigs(); len(configs) != 3 {
+		t.Errorf("got %d SPI configurations for a zeroed EFS, expected 3", len(configs))
+	}
+}
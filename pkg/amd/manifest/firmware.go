@@ -17,19 +17,34 @@ type Firmware interface {
 	OffsetToPhysAddr(offset uint64) uint64
 }
 
-// PSPFirmware contains essential parts of the AMD's PSP firmware internals
+// PSPFirmware contains essential parts of the AMD's PSP firmware internals.
+// The location of each directory table in the image is held in its Range field.
 type PSPFirmware struct {
 	EmbeddedFirmware      EmbeddedFirmwareStructure
 	EmbeddedFirmwareRange bytes2.Range
+	// SPIConfig holds the SPI flash configurations set in the EFS, one per processor generation
+	SPIConfig []SPIConfig
 
-	PSPDirectoryLevel1      *PSPDirectoryTable
+	// PSPComboDirectory is set for a combo image supporting several programs, the PSP
+	// directories are then the ones of its first entry, see AMDFirmware.SelectPSPComboEntry
+	PSPComboDirectory *PSPComboDirectory
+
+	PSPDirectoryLevel1 *PSPDirectoryTable
+	// Deprecated: use PSPDirectoryLevel1.Range
 	PSPDirectoryLevel1Range bytes2.Range
-	PSPDirectoryLevel2      *PSPDirectoryTable
+	// PSPDirectoryLevel2 is the preferred one of PSPDirectoriesLevel2
+	PSPDirectoryLevel2 *PSPDirectoryTable
+	// Deprecated: use PSPDirectoryLevel2.Range
 	PSPDirectoryLevel2Range bytes2.Range
+	// PSPDirectoriesLevel2 holds all the PSP directories level 2 referenced by level 1,
+	// e.g. the A and B (recovery) copies, in the order of preference
+	PSPDirectoriesLevel2 []*PSPDirectoryTable
 
-	BIOSDirectoryLevel1      *BIOSDirectoryTable
+	BIOSDirectoryLevel1 *BIOSDirectoryTable
+	// Deprecated: use BIOSDirectoryLevel1.Range
 	BIOSDirectoryLevel1Range bytes2.Range
 	BIOSDirectoryLevel2      *BIOSDirectoryTable
+	// Deprecated: use BIOSDirectoryLevel2.Range
 	BIOSDirectoryLevel2Range bytes2.Range
 }
 
@@ -66,42 +81,33 @@ func parsePSPFirmware(firmware Firmware) (*PSPFirmware, error) {
 	}
 	result.EmbeddedFirmware = *efs
 	result.EmbeddedFirmwareRange = r
+	result.SPIConfig = efs.SPIConfigs()
 
 	var pspDirectoryLevel1 *PSPDirectoryTable
-	var pspDirectoryLevel1Range bytes2.Range
 	if efs.PSPDirectoryTablePointer != 0 && efs.PSPDirectoryTablePointer < uint32(len(image)) {
 		var length uint64
 		pspDirectoryLevel1, length, err = ParsePSPDirectoryTable(image[efs.PSPDirectoryTablePointer:])
 		if err == nil {
-			pspDirectoryLevel1Range.Offset = uint64(efs.PSPDirectoryTablePointer)
-			pspDirectoryLevel1Range.Length = length
+			pspDirectoryLevel1.Range = bytes2.Range{Offset: uint64(efs.PSPDirectoryTablePointer), Length: length}
+		} else if combo, length, err := ParsePSPComboDirectory(image[efs.PSPDirectoryTablePointer:]); err == nil {
+			combo.Range = bytes2.Range{Offset: uint64(efs.PSPDirectoryTablePointer), Length: length}
+			result.PSPComboDirectory = combo
+			for _, entry := range combo.Entries {
+				if table, err := parsePSPDirectoryLevel1At(firmware, entry.Location); err == nil {
+					pspDirectoryLevel1 = table
+					break
+				}
+			}
 		}
 	}
 	if pspDirectoryLevel1 == nil {
-		pspDirectoryLevel1, pspDirectoryLevel1Range, _ = FindPSPDirectoryTable(image)
+		pspDirectoryLevel1, _, _ = FindPSPDirectoryTable(image)
 	}
 	if pspDirectoryLevel1 != nil {
-		result.PSPDirectoryLevel1 = pspDirectoryLevel1
-		result.PSPDirectoryLevel1Range = pspDirectoryLevel1Range
-
-		for _, entry := range pspDirectoryLevel1.Entries {
-			if entry.Type != PSPDirectoryTableLevel2Entry {
-				continue
-			}
-			if entry.LocationOrValue != 0 && entry.LocationOrValue < uint64(len(image)) {
-				pspDirectoryLevel2, length, err := ParsePSPDirectoryTable(image[entry.LocationOrValue:])
-				if err == nil {
-					result.PSPDirectoryLevel2 = pspDirectoryLevel2
-					result.PSPDirectoryLevel2Range.Offset = entry.LocationOrValue
-					result.PSPDirectoryLevel2Range.Length = length
-				}
-			}
-			break
-		}
+		result.setPSPDirectoryLevel1(image, pspDirectoryLevel1)
 	}
 
 	var biosDirectoryLevel1 *BIOSDirectoryTable
-	var biosDirectoryLevel1Range bytes2.Range
 
 	biosDirectoryOffsets := []uint32{
 		efs.BIOSDirectoryTableFamily17hModels00h0FhPointer,
@@ -118,36 +124,148 @@ func parsePSPFirmware(firmware Firmware) (*PSPFirmware, error) {
 		if err != nil {
 			continue
 		}
-		biosDirectoryLevel1Range.Offset = uint64(offset)
-		biosDirectoryLevel1Range.Length = length
+		biosDirectoryLevel1.Range = bytes2.Range{Offset: uint64(offset), Length: length}
 		break
 	}
 
 	if biosDirectoryLevel1 == nil {
-		biosDirectoryLevel1, biosDirectoryLevel1Range, _ = FindBIOSDirectoryTable(image)
+		biosDirectoryLevel1, _, _ = FindBIOSDirectoryTable(image)
 	}
 
 	if biosDirectoryLevel1 != nil {
 		result.BIOSDirectoryLevel1 = biosDirectoryLevel1
-		result.BIOSDirectoryLevel1Range = biosDirectoryLevel1Range
 
-		for _, entry := range biosDirectoryLevel1.Entries {
-			if entry.Type != BIOSDirectoryTableLevel2Entry {
+		biosDirectoryLevel2, err := findBIOSDirectoryLevel2(firmware, biosDirectoryLevel1)
+		if err == nil {
+			result.BIOSDirectoryLevel2 = biosDirectoryLevel2
+		}
+	}
+
+	result.setDirectoryRanges()
+	return &result, nil
+}
+
+// setDirectoryRanges fills the deprecated range fields from the ranges of the directory tables
+func (p *PSPFirmware) setDirectoryRanges() {
+	p.PSPDirectoryLevel1Range, p.PSPDirectoryLevel2Range = bytes2.Range{}, bytes2.Range{}
+	p.BIOSDirectoryLevel1Range, p.BIOSDirectoryLevel2Range = bytes2.Range{}, bytes2.Range{}
+	if p.PSPDirectoryLevel1 != nil {
+		p.PSPDirectoryLevel1Range = p.PSPDirectoryLevel1.Range
+	}
+	if p.PSPDirectoryLevel2 != nil {
+		p.PSPDirectoryLevel2Range = p.PSPDirectoryLevel2.Range
+	}
+	if p.BIOSDirectoryLevel1 != nil {
+		p.BIOSDirectoryLevel1Range = p.BIOSDirectoryLevel1.Range
+	}
+	if p.BIOSDirectoryLevel2 != nil {
+		p.BIOSDirectoryLevel2Range = p.BIOSDirectoryLevel2.Range
+	}
+}
+
+// setPSPDirectoryLevel1 sets PSP directory level 1 together with the level 2 directories
+// it references
+func (p *PSPFirmware) setPSPDirectoryLevel1(image []byte, level1 *PSPDirectoryTable) {
+	p.PSPDirectoryLevel1 = level1
+
+	p.PSPDirectoriesLevel2 = findPSPDirectoriesLevel2(image, level1)
+	p.PSPDirectoryLevel2 = nil
+	if len(p.PSPDirectoriesLevel2) > 0 {
+		p.PSPDirectoryLevel2 = p.PSPDirectoriesLevel2[0]
+	}
+}
+
+// parsePSPDirectoryLevel1At parses the PSP directory level 1 found at the address, e.g. the
+// location of a PSP combo directory entry
+func parsePSPDirectoryLevel1At(firmware Firmware, addr uint64) (*PSPDirectoryTable, error) {
+	offset, ok := resolveDirectoryAddress(firmware, addr)
+	if !ok {
+		return nil, fmt.Errorf("PSP directory address 0x%x is outside of the image", addr)
+	}
+	table, length, err := ParsePSPDirectoryTable(firmware.ImageBytes()[offset:])
+	if err != nil {
+		return nil, err
+	}
+	if table.PSPCookie != PSPDirectoryTableCookie {
+		return nil, fmt.Errorf("PSP directory at 0x%x is not a level 1 directory", offset)
+	}
+	table.Range = bytes2.Range{Offset: offset, Length: length}
+	return table, nil
+}
+
+// pspDirectoryLevel2Entries are the types of the PSP directory level 1 entries pointing to
+// a level 2 directory, in the order of preference: the recovery copy of an A/B layout is
+// used only when no normal level 2 directory is found
+var pspDirectoryLevel2Entries = []PSPDirectoryTableEntryType{
+	PSPDirectoryTableLevel2Entry,
+	PSPDirectoryTableLevel2AEntry,
+	PSPDirectoryTableLevel2BEntry,
+}
+
+// findPSPDirectoriesLevel2 follows the level 2 pointer entries of PSP directory level 1
+// and returns all the valid PSP directories level 2 in the order of preference. A directory
+// referenced by several entries is returned once.
+func findPSPDirectoriesLevel2(image []byte, level1 *PSPDirectoryTable) []*PSPDirectoryTable {
+	var result []*PSPDirectoryTable
+	found := make(map[uint64]bool)
+	for _, entryType := range pspDirectoryLevel2Entries {
+		for _, entry := range level1.Entries {
+			if entry.Type != entryType {
 				continue
 			}
-			if entry.SourceAddress != 0 && entry.SourceAddress < uint64(len(image)) {
-				biosDirectoryLevel2, length, err := ParseBIOSDirectoryTable(image[entry.SourceAddress:])
-				if err == nil {
-					result.BIOSDirectoryLevel2 = biosDirectoryLevel2
-					result.BIOSDirectoryLevel2Range.Offset = entry.SourceAddress
-					result.BIOSDirectoryLevel2Range.Length = length
-				}
+			if entry.LocationOrValue == 0 || entry.LocationOrValue >= uint64(len(image)) || found[entry.LocationOrValue] {
+				continue
 			}
-			break
+			table, length, err := ParsePSPDirectoryTable(image[entry.LocationOrValue:])
+			if err != nil {
+				continue
+			}
+			table.IsRecovery = entryType == PSPDirectoryTableLevel2BEntry
+			table.Range = bytes2.Range{Offset: entry.LocationOrValue, Length: length}
+			found[entry.LocationOrValue] = true
+			result = append(result, table)
 		}
 	}
+	return result
+}
 
-	return &result, nil
+// findBIOSDirectoryLevel2 follows the level 2 pointer entries of BIOS directory level 1
+// and returns the first valid BIOS directory level 2
+func findBIOSDirectoryLevel2(firmware Firmware, level1 *BIOSDirectoryTable) (*BIOSDirectoryTable, error) {
+	image := firmware.ImageBytes()
+	for _, entry := range level1.Entries {
+		if entry.Type != BIOSDirectoryTableLevel2Entry {
+			continue
+		}
+		offset, ok := resolveDirectoryAddress(firmware, entry.SourceAddress)
+		if !ok {
+			continue
+		}
+		table, length, err := ParseBIOSDirectoryTable(image[offset:])
+		if err != nil || table.BIOSCookie != BIOSDirectoryTableLevel2Cookie {
+			continue
+		}
+		table.Range = bytes2.Range{Offset: offset, Length: length}
+		return table, nil
+	}
+	return nil, fmt.Errorf("BIOS directory level 2 is not found")
+}
+
+// resolveDirectoryAddress converts the address of a directory to the offset in the image.
+// The address is either already an offset or a physical address of the memory mapped image.
+func resolveDirectoryAddress(firmware Firmware, addr uint64) (uint64, bool) {
+	imageSize := uint64(len(firmware.ImageBytes()))
+	if addr == 0 {
+		return 0, false
+	}
+	if addr < imageSize {
+		return addr, true
+	}
+	if addr >= basePhysAddr {
+		return 0, false
+	}
+	offset := firmware.PhysAddrToOffset(addr)
+	return offset, offset < imageSize
 }
 
 // NewAMDFirmware returns an AMDFirmware structure or an error if internal firmware structures cannot be parsed
@@ -160,6 +278,20 @@ func NewAMDFirmware(firmware Firmware) (*AMDFirmware, error) {
 
 }
 
+// SelectPSPComboEntry returns the firmware of the program of the given PSP combo directory
+// entry: its PSP directories are the ones the entry points to, while the other structures,
+// e.g. the BIOS directories, are shared with a.
+func (a *AMDFirmware) SelectPSPComboEntry(entry PSPComboDirectoryEntry) (*AMDFirmware, error) {
+	table, err := parsePSPDirectoryLevel1At(a.firmware, entry.Location)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse PSP directory of combo entry with ID 0x%x: %w", entry.ID, err)
+	}
+	pspFirmware := *a.pspFirmware
+	pspFirmware.setPSPDirectoryLevel1(a.firmware.ImageBytes(), table)
+	pspFirmware.setDirectoryRanges()
+	return &AMDFirmware{firmware: a.firmware, pspFirmware: &pspFirmware}, nil
+}
+
 // FirmwareImage implements Firmware given image content.
 type FirmwareImage []byte
 
@@ -172,14 +304,23 @@ func (img FirmwareImage) ImageBytes() []byte {
 	return []byte(img)
 }
 
+// DefaultAddressMapping returns the physical address at which an image of the
+// given size starts. The firmware is mapped so that its last byte sits at
+// 0xFFFFFFFF.
+//
+// The mapping is the well-known 0xff000000 only for 16 MiB images: a smaller
+// image, e.g. of 8 MiB, starts at 0xff800000, so physical addresses found in
+// its structures cannot be converted with a hardcoded 0xff000000.
+func DefaultAddressMapping(imageSize uint64) uint64 {
+	return basePhysAddr - imageSize
+}
+
 // PhysAddrToOffset maps a physical address to the offset in the image.
 func (img FirmwareImage) PhysAddrToOffset(physAddr uint64) uint64 {
-	startAddr := uint64(basePhysAddr - len(img))
-	return physAddr - startAddr
+	return physAddr - DefaultAddressMapping(uint64(len(img)))
 }
 
 // OffsetToPhysAddr maps an offset in the image to the physical address.
 func (img FirmwareImage) OffsetToPhysAddr(offset uint64) uint64 {
-	startAddr := uint64(basePhysAddr - len(img))
-	return offset + startAddr
+	return offset + DefaultAddressMapping(uint64(len(img)))
 }
@@ -0,0 +1,241 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// putBIOSDirectory writes a BIOS directory with the given cookie and entries
+// of the given type and source address into image at offset.
+func putBIOSDirectory(image []byte, offset uint64, cookie uint32, entries map[BIOSDirectoryTableEntryType]uint64) uint64 {
+	b := image[offset:]
+	binary.LittleEndian.PutUint32(b[0:], cookie)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(entries)))
+	pos := uint64(binary.Size(BIOSDirectoryTableHeader{}))
+	for entryType, addr := range entries {
+		b[pos] = uint8(entryType)
+		binary.LittleEndian.PutUint32(b[pos+4:], 0x100)
+		binary.LittleEndian.PutUint64(b[pos+8:], addr)
+		pos += 24
+	}
+	return pos
+}
+
+func TestBIOSDirectoryLevel2(t *testing.T) {
+	const (
+		efsAddr     = 0xfffa0000
+		level1Addr  = 0x100
+		level2Addr  = 0x200
+		level2Phys  = 0xfffa0200
+		imageLength = 0x400
+	)
+
+	for _, tc := range []struct {
+		name    string
+		pointer uint64
+	}{
+		{"offset", level2Addr},
+		{"physical_address", level2Phys},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			image := make([]byte, imageLength)
+			binary.LittleEndian.PutUint32(image[0:], EmbeddedFirmwareStructureSignature)
+			binary.LittleEndian.PutUint32(image[24:], level1Addr)
+			putBIOSDirectory(image, level1Addr, BIOSDirectoryTableCookie, map[BIOSDirectoryTableEntryType]uint64{
+				BIOSDirectoryTableLevel2Entry: tc.pointer,
+			})
+			level2Length := putBIOSDirectory(image, level2Addr, BIOSDirectoryTableLevel2Cookie, map[BIOSDirectoryTableEntryType]uint64{
+				BIOSRTMVolumeEntry: 0x300,
+			})
+
+			firmware := newDummyFirmware(image, t).
+				addMapping(efsAddr, 0).
+				addMapping(level2Phys, level2Addr)
+			amdFw, err := NewAMDFirmware(firmware)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			pspFw := amdFw.PSPFirmware()
+			if pspFw.BIOSDirectoryLevel1 == nil || pspFw.BIOSDirectoryLevel1.Range.Offset != level1Addr {
+				t.Fatalf("BIOS directory level 1 is not found at %#x", level1Addr)
+			}
+			if pspFw.BIOSDirectoryLevel2 == nil {
+				t.Fatalf("BIOS directory level 2 is not found")
+			}
+			if r := pspFw.BIOSDirectoryLevel2.Range; r.Offset != level2Addr || r.Length != level2Length {
+				t.Errorf("unexpected BIOS directory level 2 range: %+v", r)
+			}
+			if pspFw.BIOSDirectoryLevel1Range != pspFw.BIOSDirectoryLevel1.Range || pspFw.BIOSDirectoryLevel2Range != pspFw.BIOSDirectoryLevel2.Range {
+				t.Errorf("the deprecated BIOS directory ranges do not match the tables")
+			}
+			if entries := pspFw.BIOSDirectoryLevel2.Entries; len(entries) != 1 || entries[0].Type != BIOSRTMVolumeEntry {
+				t.Errorf("unexpected BIOS directory level 2 entries: %+v", entries)
+			}
+		})
+	}
+}
+
+func TestBIOSDirectoryLevel2WrongCookie(t *testing.T) {
+	image := make([]byte, 0x400)
+	binary.LittleEndian.PutUint32(image[0:], EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[24:], 0x100)
+	putBIOSDirectory(image, 0x100, BIOSDirectoryTableCookie, map[BIOSDirectoryTableEntryType]uint64{
+		BIOSDirectoryTableLevel2Entry: 0x200,
+	})
+	putBIOSDirectory(image, 0x200, BIOSDirectoryTableCookie, nil)
+
+	amdFw, err := NewAMDFirmware(newDummyFirmware(image, t).addMapping(0xfffa0000, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if amdFw.PSPFirmware().BIOSDirectoryLevel2 != nil {
+		t.Errorf("a level 1 directory is accepted as BIOS directory level 2")
+	}
+}
+
+// putPSPDirectory writes a PSP directory with the given cookie and entries
+// into image at offset.
+func putPSPDirectory(image []byte, offset uint64, cookie uint32, entries []PSPDirectoryTableEntry) {
+	b := image[offset:]
+	binary.LittleEndian.PutUint32(b[0:], cookie)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(entries)))
+	pos := uint64(binary.Size(PSPDirectoryTableHeader{}))
+	for _, entry := range entries {
+		b[pos] = uint8(entry.Type)
+		binary.LittleEndian.PutUint32(b[pos+4:], entry.Size)
+		binary.LittleEndian.PutUint64(b[pos+8:], entry.LocationOrValue)
+		pos += PSPDirectoryTableEntrySize
+	}
+}
+
+func TestPSPDirectoryLevel2Recovery(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		entries      []PSPDirectoryTableEntry
+		expectedAddr uint64
+		isRecovery   bool
+	}{
+		{
+			name:         "normal",
+			entries:      []PSPDirectoryTableEntry{{Type: PSPDirectoryTableLevel2Entry, Size: 0x100, LocationOrValue: 0x200}},
+			expectedAddr: 0x200,
+		},
+		{
+			name:         "recovery_only",
+			entries:      []PSPDirectoryTableEntry{{Type: PSPDirectoryTableLevel2BEntry, Size: 0x100, LocationOrValue: 0x300}},
+			expectedAddr: 0x300,
+			isRecovery:   true,
+		},
+		{
+			name: "a_b_layout",
+			entries: []PSPDirectoryTableEntry{
+				{Type: PSPDirectoryTableLevel2BEntry, Size: 0x100, LocationOrValue: 0x300},
+				{Type: PSPDirectoryTableLevel2AEntry, Size: 0x100, LocationOrValue: 0x200},
+			},
+			expectedAddr: 0x200,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			image := make([]byte, 0x400)
+			binary.LittleEndian.PutUint32(image[0:], EmbeddedFirmwareStructureSignature)
+			binary.LittleEndian.PutUint32(image[20:], 0x100)
+			putPSPDirectory(image, 0x100, PSPDirectoryTableCookie, tc.entries)
+			putPSPDirectory(image, 0x200, PSPDirectoryTableLevel2Cookie, nil)
+			putPSPDirectory(image, 0x300, PSPDirectoryTableLevel2Cookie, nil)
+
+			amdFw, err := NewAMDFirmware(newDummyFirmware(image, t).addMapping(0xfffa0000, 0))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pspFw := amdFw.PSPFirmware()
+			if pspFw.PSPDirectoryLevel2 == nil {
+				t.Fatalf("PSP directory level 2 is not found")
+			}
+			if pspFw.PSPDirectoryLevel2.Range.Offset != tc.expectedAddr {
+				t.Errorf("unexpected PSP directory level 2 offset: %#x, expected: %#x", pspFw.PSPDirectoryLevel2.Range.Offset, tc.expectedAddr)
+			}
+			if pspFw.PSPDirectoryLevel2.IsRecovery != tc.isRecovery {
+				t.Errorf("unexpected recovery flag: %v", pspFw.PSPDirectoryLevel2.IsRecovery)
+			}
+			if pspFw.PSPDirectoryLevel1.IsRecovery {
+				t.Errorf("PSP directory level 1 is flagged as recovery")
+			}
+		})
+	}
+}
+
+func TestPSPDirectoriesLevel2(t *testing.T) {
+	image := make([]byte, 0x500)
+	binary.LittleEndian.PutUint32(image[0:], EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPDirectory(image, 0x100, PSPDirectoryTableCookie, []PSPDirectoryTableEntry{
+		{Type: PSPDirectoryTableLevel2BEntry, Size: 0x100, LocationOrValue: 0x300},
+		{Type: PSPDirectoryTableLevel2BEntry, Size: 0x100, LocationOrValue: 0x400},
+		{Type: PSPDirectoryTableLevel2AEntry, Size: 0x100, LocationOrValue: 0x200},
+		// a directory referenced twice is reported once
+		{Type: PSPDirectoryTableLevel2BEntry, Size: 0x100, LocationOrValue: 0x400},
+	})
+	putPSPDirectory(image, 0x200, PSPDirectoryTableLevel2Cookie, []PSPDirectoryTableEntry{{Type: 0x08, Size: 0x10, LocationOrValue: 0x20}})
+	putPSPDirectory(image, 0x300, PSPDirectoryTableLevel2Cookie, []PSPDirectoryTableEntry{{Type: 0x08, Size: 0x10, LocationOrValue: 0x30}})
+	putPSPDirectory(image, 0x400, PSPDirectoryTableLevel2Cookie, []PSPDirectoryTableEntry{{Type: 0x08, Size: 0x10, LocationOrValue: 0x40}})
+
+	amdFw, err := NewAMDFirmware(newDummyFirmware(image, t).addMapping(0xfffa0000, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pspFw := amdFw.PSPFirmware()
+
+	expected := []struct {
+		offset     uint64
+		isRecovery bool
+	}{
+		{0x200, false},
+		{0x300, true},
+		{0x400, true},
+	}
+	if len(pspFw.PSPDirectoriesLevel2) != len(expected) {
+		t.Fatalf("unexpected number of PSP directories level 2: %d, expected: %d", len(pspFw.PSPDirectoriesLevel2), len(expected))
+	}
+	for idx, directory := range pspFw.PSPDirectoriesLevel2 {
+		if directory.Range.Offset != expected[idx].offset {
+			t.Errorf("unexpected offset of PSP directory level 2 #%d: %#x, expected: %#x", idx, directory.Range.Offset, expected[idx].offset)
+		}
+		if directory.Range.Length == 0 {
+			t.Errorf("PSP directory level 2 #%d has zero length", idx)
+		}
+		if directory.IsRecovery != expected[idx].isRecovery {
+			t.Errorf("unexpected recovery flag of PSP directory level 2 #%d: %v", idx, directory.IsRecovery)
+		}
+		if len(directory.Entries) != 1 || directory.Entries[0].LocationOrValue != expected[idx].offset/0x10 {
+			t.Errorf("unexpected entries of PSP directory level 2 #%d: %v", idx, directory.Entries)
+		}
+	}
+	if pspFw.PSPDirectoryLevel2 != pspFw.PSPDirectoriesLevel2[0] {
+		t.Errorf("PSP directory level 2 is not the preferred one")
+	}
+	if pspFw.PSPDirectoryLevel1Range != pspFw.PSPDirectoryLevel1.Range || pspFw.PSPDirectoryLevel2Range != pspFw.PSPDirectoryLevel2.Range {
+		t.Errorf("the deprecated PSP directory ranges do not match the tables")
+	}
+}
+
+func TestDefaultAddressMapping(t *testing.T) {
+	if got := DefaultAddressMapping(16 << 20); got != 0xff000000 {
+		t.Errorf("mapping of a 16 MiB image: got 0x%x, expected 0xff000000", got)
+	}
+	if got := DefaultAddressMapping(8 << 20); got != 0xff800000 {
+		t.Errorf("mapping of an 8 MiB image: got 0x%x, expected 0xff800000", got)
+	}
+
+	img := FirmwareImage(make([]byte, 8<<20))
+	if got := img.PhysAddrToOffset(0xff800100); got != 0x100 {
+		t.Errorf("offset of 0xff800100: got 0x%x, expected 0x100", got)
+	}
+	if got := img.OffsetToPhysAddr(0x100); got != 0xff800100 {
+		t.Errorf("physical address of 0x100: got 0x%x, expected 0xff800100", got)
+	}
+}
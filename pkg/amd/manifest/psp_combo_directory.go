@@ -0,0 +1,110 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+// PSPComboDirectoryCookie is a special identifier of the PSP combo directory. A combo image
+// supports several programs, e.g. processor families or dies, and the combo directory points
+// to the PSP directory level 1 of each of them.
+const PSPComboDirectoryCookie = 0x50535032 // "2PSP"
+
+// PSPComboIDSelect tells what the ID of a PSP combo directory entry is compared with
+type PSPComboIDSelect uint32
+
+const (
+	// PSPComboIDSelectPSPID denotes an entry matched against the PSP ID of the processor
+	PSPComboIDSelectPSPID PSPComboIDSelect = 0
+	// PSPComboIDSelectChipFamilyID denotes an entry matched against the chip family ID
+	PSPComboIDSelectChipFamilyID PSPComboIDSelect = 1
+)
+
+func (s PSPComboIDSelect) String() string {
+	switch s {
+	case PSPComboIDSelectPSPID:
+		return "PSP ID"
+	case PSPComboIDSelectChipFamilyID:
+		return "Chip family ID"
+	}
+	return fmt.Sprintf("Unknown(0x%x)", uint32(s))
+}
+
+// PSPComboDirectoryHeader represents the header of the PSP combo directory
+type PSPComboDirectoryHeader struct {
+	PSPCookie    uint32
+	Checksum     uint32
+	TotalEntries uint32
+	LookUpMode   uint32
+	Reserved     [16]byte
+}
+
+// PSPComboDirectoryEntry represents a single entry of the PSP combo directory,
+// Location points to the PSP directory level 1 of the program
+type PSPComboDirectoryEntry struct {
+	IDSelect PSPComboIDSelect
+	ID       uint32
+	Location uint64
+}
+
+const PSPComboDirectoryEntrySize = 16
+
+// PSPComboDirectory represents the PSP combo directory header with all entries
+type PSPComboDirectory struct {
+	PSPComboDirectoryHeader
+
+	Entries []PSPComboDirectoryEntry
+
+	// Range is the location of the directory in the image. It is set when the
+	// directory is found in an image, not by ParsePSPComboDirectory.
+	Range bytes2.Range
+}
+
+func (p PSPComboDirectory) String() string {
+	var s strings.Builder
+	cookieBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(cookieBytes, p.PSPCookie)
+	fmt.Fprintf(&s, "PSP Cookie: 0x%x (%s)\n", p.PSPCookie, cookieBytes)
+	fmt.Fprintf(&s, "Checksum: %d\n", p.Checksum)
+	fmt.Fprintf(&s, "Total Entries: %d\n", p.TotalEntries)
+	fmt.Fprintf(&s, "Look Up Mode: %d\n\n", p.LookUpMode)
+	fmt.Fprintf(&s, "%-14s | %-10s | %-10s\n", "ID Select", "ID", "Location")
+	fmt.Fprintf(&s, "%s\n", "------------------------------------------")
+	for _, entry := range p.Entries {
+		fmt.Fprintf(&s, "%-14s | 0x%-8x | 0x%-10x\n", entry.IDSelect, entry.ID, entry.Location)
+	}
+	return s.String()
+}
+
+// ParsePSPComboDirectory converts input bytes into PSPComboDirectory
+func ParsePSPComboDirectory(data []byte) (*PSPComboDirectory, uint64, error) {
+	var directory PSPComboDirectory
+	var totalLength uint64
+
+	r := bytes.NewBuffer(data)
+	if err := readAndCountSize(r, binary.LittleEndian, &directory.PSPComboDirectoryHeader, &totalLength); err != nil {
+		return nil, 0, err
+	}
+	if directory.PSPCookie != PSPComboDirectoryCookie {
+		return nil, 0, fmt.Errorf("incorrect cookie: %d", directory.PSPCookie)
+	}
+
+	sizeRequired := uint64(directory.TotalEntries) * PSPComboDirectoryEntrySize
+	if uint64(r.Len()) < sizeRequired {
+		return nil, 0, fmt.Errorf("not enough data, required: %d, actual: %d", sizeRequired+totalLength, len(data))
+	}
+
+	directory.Entries = make([]PSPComboDirectoryEntry, directory.TotalEntries)
+	if err := readAndCountSize(r, binary.LittleEndian, directory.Entries, &totalLength); err != nil {
+		return nil, 0, err
+	}
+	return &directory, totalLength, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+var pspComboDirectoryDataChunk = []byte{
+	0x32, 0x50, 0x53, 0x50,
+	0x00, 0x00, 0x00, 0x00,
+	0x02, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+
+	0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x0a, 0xbc,
+	0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+
+	0x01, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x0b, 0xbc,
+	0x00, 0x30, 0xfa, 0xff, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestPSPComboDirectoryHeaderSize(t *testing.T) {
+	const expectedPSPComboDirectoryHeaderSize = 0x20
+	actualSize := binary.Size(PSPComboDirectoryHeader{})
+	if actualSize != expectedPSPComboDirectoryHeaderSize {
+		t.Errorf("PSPComboDirectoryHeader is incorrect: %d, expected %d", actualSize, expectedPSPComboDirectoryHeaderSize)
+	}
+}
+
+func TestParsePSPComboDirectory(t *testing.T) {
+	directory, length, err := ParsePSPComboDirectory(pspComboDirectoryDataChunk)
+	if err != nil {
+		t.Fatalf("Failed to parse PSP combo directory: %v", err)
+	}
+	if length != uint64(len(pspComboDirectoryDataChunk)) {
+		t.Errorf("PSP combo directory length is incorrect: %d, expected: %d", length, len(pspComboDirectoryDataChunk))
+	}
+	expected := []PSPComboDirectoryEntry{
+		{IDSelect: PSPComboIDSelectPSPID, ID: 0xbc0a0000, Location: 0x1000},
+		{IDSelect: PSPComboIDSelectChipFamilyID, ID: 0xbc0b0000, Location: 0xfffa3000},
+	}
+	if len(directory.Entries) != len(expected) {
+		t.Fatalf("PSP combo directory has %d entries, expected %d", len(directory.Entries), len(expected))
+	}
+	for idx, entry := range directory.Entries {
+		if entry != expected[idx] {
+			t.Errorf("PSP combo directory entry %d is incorrect: %+v, expected: %+v", idx, entry, expected[idx])
+		}
+	}
+
+	t.Run("not_enough_data", func(t *testing.T) {
+		if _, _, err := ParsePSPComboDirectory(pspComboDirectoryDataChunk[:len(pspComboDirectoryDataChunk)-1]); err == nil {
+			t.Errorf("Expected an error when parsing a truncated PSP combo directory")
+		}
+	})
+	t.Run("psp_directory", func(t *testing.T) {
+		if _, _, err := ParsePSPComboDirectory(pspDirectoryTableDataChunk); err == nil {
+			t.Errorf("Expected an error when parsing a PSP directory as a combo directory")
+		}
+	})
+}
@@ -32,6 +32,12 @@ const (
 	PSPBootloaderFirmwareEntry PSPDirectoryTableEntryType = 0x01
 	// PSPDirectoryTableLevel2Entry denotes an entry that points to PSP Directory table level 2
 	PSPDirectoryTableLevel2Entry PSPDirectoryTableEntryType = 0x40
+	// PSPDirectoryTableLevel2AEntry denotes an entry that points to the primary PSP Directory table level 2
+	// of an A/B recovery layout
+	PSPDirectoryTableLevel2AEntry PSPDirectoryTableEntryType = 0x48
+	// PSPDirectoryTableLevel2BEntry denotes an entry that points to the recovery PSP Directory table level 2
+	// of an A/B recovery layout
+	PSPDirectoryTableLevel2BEntry PSPDirectoryTableEntryType = 0x4A
 )
 
 // PSPDirectoryTableEntry represents a single entry in PSP Directory Table
@@ -61,6 +67,15 @@ type PSPDirectoryTable struct {
 	PSPDirectoryTableHeader
 
 	Entries []PSPDirectoryTableEntry
+
+	// IsRecovery is set for a level 2 directory reached through a
+	// PSPDirectoryTableLevel2BEntry, which the PSP boots only when the
+	// primary copy fails to validate
+	IsRecovery bool
+
+	// Range is the location of the table in the image. It is set when the
+	// table is found in an image, not by ParsePSPDirectoryTable.
+	Range bytes2.Range
 }
 
 func (p PSPDirectoryTable) String() string {
@@ -70,7 +85,8 @@ func (p PSPDirectoryTable) String() string {
 	fmt.Fprintf(&s, "PSP Cookie: 0x%x (%s)\n", p.PSPCookie, cookieBytes)
 	fmt.Fprintf(&s, "Checksum: %d\n", p.Checksum)
 	fmt.Fprintf(&s, "Total Entries: %d\n", p.TotalEntries)
-	fmt.Fprintf(&s, "Additional Info: 0x%x\n\n", p.AdditionalInfo)
+	fmt.Fprintf(&s, "Additional Info: 0x%x\n", p.AdditionalInfo)
+	fmt.Fprintf(&s, "Recovery: %v\n\n", p.IsRecovery)
 	fmt.Fprintf(&s, "%-5s | %-8s | %-5s | %-10s | %-10s\n",
 		"Type",
 		"Subprogram",
@@ -110,7 +126,8 @@ func FindPSPDirectoryTable(image []byte) (*PSPDirectoryTable, bytes2.Range, erro
 			offset += shift
 			continue
 		}
-		return table, bytes2.Range{Offset: offset + uint64(idx), Length: length}, err
+		table.Range = bytes2.Range{Offset: offset + uint64(idx), Length: length}
+		return table, table.Range, err
 	}
 	return nil, bytes2.Range{}, fmt.Errorf("PSPDirectoryTable is not found")
 }
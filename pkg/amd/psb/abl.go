@@ -0,0 +1,88 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"fmt"
+	"sort"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+// ablStageCount is the number of AGESA bootloader stages, they are stored in PSP directory
+// entries AGESABinary0Entry to AGESABinary0Entry+7
+const ablStageCount = 8
+
+// ABLStage describes an AGESA bootloader (ABL) stage stored in PSP directory
+type ABLStage struct {
+	// Index is the number of the stage, starting from 0
+	Index     uint8
+	EntryType amd_manifest.PSPDirectoryTableEntryType
+	Range     bytes2.Range
+
+	// Version is the image version from the PSP binary header of the stage. It is
+	// only set if HasVersion is true, i.e. the stage starts with a valid PSP binary header
+	Version    uint32
+	HasVersion bool
+
+	// Data is the raw content of the entry
+	Data []byte
+}
+
+// String returns a one-line description of the stage
+func (s ABLStage) String() string {
+	version := "unknown"
+	if s.HasVersion {
+		version = fmt.Sprintf("0x%x", s.Version)
+	}
+	return fmt.Sprintf("ABL%d (%s): offset 0x%x, size 0x%x, version %s",
+		s.Index, PSPEntryType(s.EntryType), s.Range.Offset, s.Range.Length, version)
+}
+
+// GetABLStages returns the AGESA bootloader stages of PSP directory of the given level sorted by
+// the stage index
+func GetABLStages(amdFw *amd_manifest.AMDFirmware, level uint) ([]ABLStage, error) {
+	table, err := getPSPTable(amdFw.PSPFirmware(), level)
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		directory, err := GetPSPDirectoryOfLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newErrNotFound(newDirectoryItem(directory))
+	}
+
+	var result []ABLStage
+	for _, entry := range table.Entries {
+		if entry.Type < AGESABinary0Entry || entry.Type >= AGESABinary0Entry+ablStageCount {
+			continue
+		}
+		data, err := ExtractPSPEntry(amdFw, level, entry.Type)
+		if err != nil {
+			return nil, err
+		}
+		stage := ABLStage{
+			Index:     uint8(entry.Type - AGESABinary0Entry),
+			EntryType: entry.Type,
+			Range:     bytes2.Range{Offset: entry.LocationOrValue, Length: uint64(entry.Size)},
+			Data:      data,
+		}
+		if len(data) >= pspHeaderSize {
+			if hdr, err := newPspHeader(data); err == nil && hdr.Version() == pspBinaryCookie {
+				stage.Version = hdr.ImageVersion()
+				stage.HasVersion = true
+			}
+		}
+		result = append(result, stage)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	if len(result) == 0 {
+		return nil, newErrNotFound(newPSPDirectoryEntryItem(uint8(level), AGESABinary0Entry))
+	}
+	return result, nil
+}
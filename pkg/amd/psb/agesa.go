@@ -0,0 +1,37 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// GetAGESAVersion returns the AGESA version string embedded into the BIOS binary.
+// BIOS RTM volumes referenced by BIOS directories are searched first (level 2 takes precedence),
+// if none of them contains the version the whole image is scanned.
+func GetAGESAVersion(amdFw *amd_manifest.AMDFirmware) (string, error) {
+	image := amdFw.Firmware().ImageBytes()
+	for _, biosLevel := range []uint{2, 1} {
+		entries, err := GetBIOSEntries(amdFw.PSPFirmware(), biosLevel, amd_manifest.BIOSRTMVolumeEntry)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			data, err := GetRangeBytes(image, entry.SourceAddress, uint64(entry.Size))
+			if err != nil {
+				continue
+			}
+			if version, _, err := amd_manifest.FindAGESAVersion(data); err == nil {
+				return version, nil
+			}
+		}
+	}
+
+	version, _, err := amd_manifest.FindAGESAVersion(image)
+	if err != nil {
+		return "", newErrNotFound(nil)
+	}
+	return version, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/linuxboot/fiano/pkg/amd/apcb"
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// UpdateAPCB writes a modified APCB, e.g. edited with apcb.UpsertToken, into the APCB data entry
+// of the BIOS directory of the given level and instance. The modified firmware is written into `w`.
+//
+// The modified APCB must not be larger than the entry, the rest of the entry is kept as is. The
+// checksum of the APCB header is recalculated before the APCB is validated, so modifiedAPCB may
+// have a stale checksum. modifiedAPCB itself is not modified.
+func UpdateAPCB(amdFw *amd_manifest.AMDFirmware, biosLevel uint, instance uint8, modifiedAPCB []byte, w io.Writer, opts ...PatchOption) (int, error) {
+	item := newBIOSDirectoryEntryItem(uint8(biosLevel), amd_manifest.APCBDataEntry, instance)
+	entry, err := ExtractBIOSEntry(amdFw, biosLevel, amd_manifest.APCBDataEntry, instance)
+	if err != nil {
+		return 0, err
+	}
+	if len(modifiedAPCB) > len(entry) {
+		return 0, newErrInvalidFormatWithItem(item, fmt.Errorf("modified APCB of %d bytes does not fit into the entry of %d bytes", len(modifiedAPCB), len(entry)))
+	}
+
+	updated := make([]byte, len(entry))
+	copy(updated, entry)
+	copy(updated, modifiedAPCB)
+	if err := apcb.UpdateChecksum(updated); err != nil {
+		return 0, newErrInvalidFormatWithItem(item, err)
+	}
+	if _, err := apcb.ParseAPCBBinaryTokens(updated); err != nil {
+		return 0, newErrInvalidFormatWithItem(item, fmt.Errorf("invalid modified APCB: %w", err))
+	}
+	return PatchBIOSEntry(amdFw, biosLevel, amd_manifest.APCBDataEntry, instance, bytes.NewReader(updated), w, opts...)
+}
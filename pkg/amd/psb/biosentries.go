@@ -10,7 +10,6 @@ import (
 	"os"
 
 	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
-	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 )
@@ -160,15 +159,11 @@ func OutputBIOSEntries(amdFw *amd_manifest.AMDFirmware) error {
 func ValidateRTM(amdFw *amd_manifest.AMDFirmware, biosLevel uint) (*SignatureValidationResult, error) {
 	pspFw := amdFw.PSPFirmware()
 
-	// Get the byte range we'll need on the BIOS depending on the level
-	var biosDirectoryRange bytes2.Range
 	var directory DirectoryType
 	switch biosLevel {
 	case 1:
-		biosDirectoryRange = pspFw.BIOSDirectoryLevel1Range
 		directory = BIOSDirectoryLevel1
 	case 2:
-		biosDirectoryRange = pspFw.BIOSDirectoryLevel2Range
 		directory = BIOSDirectoryLevel2
 	default:
 		return nil, fmt.Errorf("cannot extract raw BIOS entry, invalid BIOS Directory Level requested: %d", biosLevel)
@@ -194,6 +189,11 @@ func ValidateRTM(amdFw *amd_manifest.AMDFirmware, biosLevel uint) (*SignatureVal
 	// BIOS directory table
 	firmwareBytes := amdFw.Firmware().ImageBytes()
 
+	// Get the byte range we'll need on the BIOS depending on the level
+	biosDirectoryRange, err := GetDirectoryRange(pspFw, directory)
+	if err != nil {
+		return nil, err
+	}
 	biosDirectoryStart := biosDirectoryRange.Offset
 	biosDirectoryEnd := biosDirectoryStart + biosDirectoryRange.Length
 
@@ -208,8 +208,12 @@ func ValidateRTM(amdFw *amd_manifest.AMDFirmware, biosLevel uint) (*SignatureVal
 	 * RTM Volume + Level 1 Header + Level 2 Header
 	 */
 	if biosLevel == 2 {
-		biosDirectoryLevel1Start := pspFw.BIOSDirectoryLevel1Range.Offset
-		biosDirectoryLevel1End := biosDirectoryLevel1Start + pspFw.BIOSDirectoryLevel1Range.Length
+		biosDirectoryLevel1Range, err := GetDirectoryRange(pspFw, BIOSDirectoryLevel1)
+		if err != nil {
+			return nil, err
+		}
+		biosDirectoryLevel1Start := biosDirectoryLevel1Range.Offset
+		biosDirectoryLevel1End := biosDirectoryLevel1Start + biosDirectoryLevel1Range.Length
 
 		if err := checkBoundaries(biosDirectoryLevel1Start, biosDirectoryLevel1End, firmwareBytes); err != nil {
 			return nil, newErrInvalidFormatWithItem(newDirectoryItem(BIOSDirectoryLevel1),
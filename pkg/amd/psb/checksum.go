@@ -0,0 +1,116 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+// directoryChecksumOffset is the offset of the checksum field in both PSP and BIOS directory headers
+const directoryChecksumOffset = 4
+
+type directoryChecksum struct {
+	directory DirectoryType
+	location  bytes2.Range
+	// checksum points to the checksum field of the parsed directory table
+	checksum  *uint32
+	calculate func([]byte) uint32
+}
+
+func getDirectoryChecksums(pspFirmware *amd_manifest.PSPFirmware) []directoryChecksum {
+	var result []directoryChecksum
+	if pspFirmware.PSPDirectoryLevel1 != nil {
+		result = append(result, directoryChecksum{
+			directory: PSPDirectoryLevel1,
+			location:  pspFirmware.PSPDirectoryLevel1.Range,
+			checksum:  &pspFirmware.PSPDirectoryLevel1.Checksum,
+			calculate: amd_manifest.CalculatePSPDirectoryCheckSum,
+		})
+	}
+	for _, pspDirectory := range pspFirmware.PSPDirectoriesLevel2 {
+		result = append(result, directoryChecksum{
+			directory: PSPDirectoryLevel2,
+			location:  pspDirectory.Range,
+			checksum:  &pspDirectory.Checksum,
+			calculate: amd_manifest.CalculatePSPDirectoryCheckSum,
+		})
+	}
+	if pspFirmware.BIOSDirectoryLevel1 != nil {
+		result = append(result, directoryChecksum{
+			directory: BIOSDirectoryLevel1,
+			location:  pspFirmware.BIOSDirectoryLevel1.Range,
+			checksum:  &pspFirmware.BIOSDirectoryLevel1.Checksum,
+			calculate: amd_manifest.CalculateBiosDirectoryCheckSum,
+		})
+	}
+	if pspFirmware.BIOSDirectoryLevel2 != nil {
+		result = append(result, directoryChecksum{
+			directory: BIOSDirectoryLevel2,
+			location:  pspFirmware.BIOSDirectoryLevel2.Range,
+			checksum:  &pspFirmware.BIOSDirectoryLevel2.Checksum,
+			calculate: amd_manifest.CalculateBiosDirectoryCheckSum,
+		})
+	}
+	return result
+}
+
+func getDirectoryBytes(image []byte, dc directoryChecksum) ([]byte, error) {
+	data, err := GetRangeBytes(image, dc.location.Offset, dc.location.Length)
+	if err != nil {
+		return nil, addFirmwareItemToError(err, newDirectoryItem(dc.directory))
+	}
+	if len(data) < directoryChecksumOffset+4 {
+		return nil, newErrInvalidFormatWithItem(newDirectoryItem(dc.directory), fmt.Errorf("directory is too short: %d", len(data)))
+	}
+	return data, nil
+}
+
+// fixDirectoryChecksums writes the correct checksums of all directories into image.
+// The parsed directory tables are updated only if updateTables is set.
+func fixDirectoryChecksums(pspFirmware *amd_manifest.PSPFirmware, image []byte, updateTables bool) error {
+	for _, dc := range getDirectoryChecksums(pspFirmware) {
+		data, err := getDirectoryBytes(image, dc)
+		if err != nil {
+			return err
+		}
+		checksum := dc.calculate(data)
+		binary.LittleEndian.PutUint32(data[directoryChecksumOffset:], checksum)
+		if updateTables {
+			*dc.checksum = checksum
+		}
+	}
+	return nil
+}
+
+// FixDirectoryChecksums recalculates the checksums of all PSP and BIOS directories and writes
+// them into the directory headers. The firmware image is modified in place, so it must not be
+// backed by a read-only memory mapping.
+func FixDirectoryChecksums(amdFw *amd_manifest.AMDFirmware) error {
+	return fixDirectoryChecksums(amdFw.PSPFirmware(), amdFw.Firmware().ImageBytes(), true)
+}
+
+// ValidateDirectoryChecksums checks that the checksums of all PSP and BIOS directories
+// match their content.
+func ValidateDirectoryChecksums(amdFw *amd_manifest.AMDFirmware) error {
+	image := amdFw.Firmware().ImageBytes()
+	for _, dc := range getDirectoryChecksums(amdFw.PSPFirmware()) {
+		data, err := getDirectoryBytes(image, dc)
+		if err != nil {
+			return err
+		}
+		actual := binary.LittleEndian.Uint32(data[directoryChecksumOffset:])
+		if expected := dc.calculate(data); actual != expected {
+			return newErrInvalidFormatWithItem(
+				newDirectoryItem(dc.directory),
+				fmt.Errorf("checksum mismatch, expected 0x%x, got 0x%x", expected, actual),
+			)
+		}
+	}
+	return nil
+}
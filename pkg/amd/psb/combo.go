@@ -0,0 +1,33 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"fmt"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// SelectComboEntry returns the firmware of the program with the given PSP ID or chip family
+// ID in a combo image, e.g. of a multi-die system. The PSP directories of the result are the
+// ones of the selected program, so that the other functions of the package operate on them.
+func SelectComboEntry(amdFw *amd_manifest.AMDFirmware, pspID uint32) (*amd_manifest.AMDFirmware, error) {
+	combo := amdFw.PSPFirmware().PSPComboDirectory
+	if combo == nil {
+		return nil, fmt.Errorf("firmware is not a combo image, no PSP combo directory found")
+	}
+	item := PSPComboEntryItem{ID: pspID}
+	for _, entry := range combo.Entries {
+		if entry.ID != pspID {
+			continue
+		}
+		selected, err := amdFw.SelectPSPComboEntry(entry)
+		if err != nil {
+			return nil, newErrInvalidFormatWithItem(item, err)
+		}
+		return selected, nil
+	}
+	return nil, newErrNotFound(item)
+}
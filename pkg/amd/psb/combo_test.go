@@ -0,0 +1,105 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func putPSPComboDirectory(image []byte, offset uint64, entries []amd_manifest.PSPComboDirectoryEntry) {
+	b := image[offset:]
+	binary.LittleEndian.PutUint32(b[0:], amd_manifest.PSPComboDirectoryCookie)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(entries)))
+	pos := uint64(binary.Size(amd_manifest.PSPComboDirectoryHeader{}))
+	for _, entry := range entries {
+		binary.LittleEndian.PutUint32(b[pos:], uint32(entry.IDSelect))
+		binary.LittleEndian.PutUint32(b[pos+4:], entry.ID)
+		binary.LittleEndian.PutUint64(b[pos+8:], entry.Location)
+		pos += amd_manifest.PSPComboDirectoryEntrySize
+	}
+}
+
+func TestSelectComboEntry(t *testing.T) {
+	// the image is mapped right below 4GB, so that the embedded firmware structure is found at 0xfffa0000
+	image := make([]byte, 0x60000)
+	binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPComboDirectory(image, 0x100, []amd_manifest.PSPComboDirectoryEntry{
+		{IDSelect: amd_manifest.PSPComboIDSelectPSPID, ID: 0xBC0A0000, Location: 0x1000},
+		// the second program is referenced by its physical address
+		{IDSelect: amd_manifest.PSPComboIDSelectPSPID, ID: 0xBC0B0000, Location: 0xfffa3000},
+	})
+	putPSPDirectory(image, 0x1000, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: amd_manifest.AMDPublicKeyEntry, Size: 0x100, LocationOrValue: 0x10000},
+		{Type: amd_manifest.PSPDirectoryTableLevel2Entry, Size: 0x1000, LocationOrValue: 0x2000},
+	})
+	putPSPDirectory(image, 0x2000, amd_manifest.PSPDirectoryTableLevel2Cookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: amd_manifest.PSPBootloaderFirmwareEntry, Size: 0x200, LocationOrValue: 0x11000},
+	})
+	putPSPDirectory(image, 0x3000, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: amd_manifest.AMDPublicKeyEntry, Size: 0x100, LocationOrValue: 0x20000},
+	})
+
+	amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+	require.NoError(t, err)
+	pspFirmware := amdFw.PSPFirmware()
+	require.NotNil(t, pspFirmware.PSPComboDirectory)
+	require.Len(t, pspFirmware.PSPComboDirectory.Entries, 2)
+	// the first program is used by default
+	require.Equal(t, uint64(0x1000), pspFirmware.PSPDirectoryLevel1.Range.Offset)
+
+	t.Run("first", func(t *testing.T) {
+		selected, err := SelectComboEntry(amdFw, 0xBC0A0000)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0x1000), selected.PSPFirmware().PSPDirectoryLevel1.Range.Offset)
+		require.Equal(t, uint64(0x2000), selected.PSPFirmware().PSPDirectoryLevel2.Range.Offset)
+
+		entries, err := ListEntries(selected.PSPFirmware(), PSPDirectoryLevel2)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, uint64(0x11000), entries[0].Offset)
+	})
+
+	t.Run("second", func(t *testing.T) {
+		selected, err := SelectComboEntry(amdFw, 0xBC0B0000)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0x3000), selected.PSPFirmware().PSPDirectoryLevel1.Range.Offset)
+		require.Nil(t, selected.PSPFirmware().PSPDirectoryLevel2)
+		require.Empty(t, selected.PSPFirmware().PSPDirectoriesLevel2)
+
+		entries, err := ListEntries(selected.PSPFirmware(), PSPDirectoryLevel1)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, uint64(0x20000), entries[0].Offset)
+
+		// the receiver is left untouched
+		require.Equal(t, uint64(0x1000), amdFw.PSPFirmware().PSPDirectoryLevel1.Range.Offset)
+	})
+
+	t.Run("unknown_id", func(t *testing.T) {
+		_, err := SelectComboEntry(amdFw, 0xBC0C0000)
+		var notFound ErrNotFound
+		require.True(t, errors.As(err, &notFound))
+		require.Equal(t, PSPComboEntryItem{ID: 0xBC0C0000}, notFound.GetItem())
+	})
+}
+
+func TestSelectComboEntryNotCombo(t *testing.T) {
+	image := make([]byte, 0x60000)
+	binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPDirectory(image, 0x100, amd_manifest.PSPDirectoryTableCookie, nil)
+
+	amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+	require.NoError(t, err)
+	require.Nil(t, amdFw.PSPFirmware().PSPComboDirectory)
+	_, err = SelectComboEntry(amdFw, 0xBC0A0000)
+	require.Error(t, err)
+}
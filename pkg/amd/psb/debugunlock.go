@@ -0,0 +1,85 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"fmt"
+	"strings"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// DebugUnlockEntry is a PSP directory entry taking part in unlocking the debug features of the PSP
+type DebugUnlockEntry struct {
+	Level           uint
+	Type            PSPEntryType
+	Size            uint32
+	LocationOrValue uint64
+	// Populated is set if the region of the entry holds data, images usually reserve an erased
+	// region for the debug unlock token
+	Populated bool
+}
+
+// DebugUnlockReport lists the debug unlock entries of the PSP directories
+type DebugUnlockReport struct {
+	Entries []DebugUnlockEntry
+	// TokenPresent is set if the region of a debug unlock token entry holds data. The token is not validated,
+	// the PSP accepts it only if it is signed by the AMD secure debug key and bound to the chip.
+	TokenPresent bool
+}
+
+// String returns a string representation of the debug unlock report
+func (r *DebugUnlockReport) String() string {
+	var s strings.Builder
+	if r.TokenPresent {
+		fmt.Fprintf(&s, "WARNING: PSP debug unlock token is present\n")
+	} else {
+		fmt.Fprintf(&s, "PSP debug unlock token is not present\n")
+	}
+	for _, entry := range r.Entries {
+		fmt.Fprintf(&s, "PSP Directory Level %d: %s (0x%x), size %d, location 0x%x, populated: %v\n",
+			entry.Level, entry.Type, uint8(entry.Type), entry.Size, entry.LocationOrValue, entry.Populated)
+	}
+	return s.String()
+}
+
+// isDebugUnlockEntry tells whether the entry type is one of those used to unlock the debug features
+func isDebugUnlockEntry(entryType amd_manifest.PSPDirectoryTableEntryType) bool {
+	switch entryType {
+	case AMDSecureDebugKeyEntry, UnlockDebugImageEntry, TokenUnlockDataEntry:
+		return true
+	}
+	return false
+}
+
+// GetDebugUnlockReport collects the debug unlock entries of both PSP directory levels
+func GetDebugUnlockReport(amdFw *amd_manifest.AMDFirmware) *DebugUnlockReport {
+	image := amdFw.Firmware().ImageBytes()
+	pspFirmware := amdFw.PSPFirmware()
+	report := &DebugUnlockReport{}
+	for level, directory := range []*amd_manifest.PSPDirectoryTable{pspFirmware.PSPDirectoryLevel1, pspFirmware.PSPDirectoryLevel2} {
+		if directory == nil {
+			continue
+		}
+		for _, entry := range directory.Entries {
+			if !isDebugUnlockEntry(entry.Type) {
+				continue
+			}
+			data, err := GetRangeBytes(image, entry.LocationOrValue, uint64(entry.Size))
+			populated := err == nil && !isFreeSpace(data)
+			report.Entries = append(report.Entries, DebugUnlockEntry{
+				Level:           uint(level + 1),
+				Type:            PSPEntryType(entry.Type),
+				Size:            entry.Size,
+				LocationOrValue: entry.LocationOrValue,
+				Populated:       populated,
+			})
+			if entry.Type == TokenUnlockDataEntry && populated {
+				report.TokenPresent = true
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// SetEFSDirectoryPointer updates the pointers of the embedded firmware structure that refer
+// to a level 1 directory, so that they point to a copy of the directory placed at newOffset.
+// The directory table itself is not moved, it must already be present at newOffset.
+// Pointers stored as physical addresses are kept as physical addresses. The modified
+// firmware is written into `w` writer object.
+func SetEFSDirectoryPointer(amdFw *amd_manifest.AMDFirmware, directory DirectoryType, newOffset uint64, w io.Writer) (int, error) {
+	pspFirmware := amdFw.PSPFirmware()
+	firmware := amdFw.Firmware()
+	image := firmware.ImageBytes()
+
+	var expectedCookie uint32
+	switch directory {
+	case PSPDirectoryLevel1:
+		expectedCookie = amd_manifest.PSPDirectoryTableCookie
+	case BIOSDirectoryLevel1:
+		expectedCookie = amd_manifest.BIOSDirectoryTableCookie
+	default:
+		return 0, fmt.Errorf("%s is not referenced by the embedded firmware structure", directory)
+	}
+	oldRange, err := GetDirectoryRange(pspFirmware, directory)
+	if err != nil {
+		return 0, err
+	}
+
+	// validate the target
+	if newOffset >= uint64(len(image)) {
+		return 0, newErrInvalidFormatWithItem(newDirectoryItem(directory),
+			fmt.Errorf("new location 0x%x is beyond the image of size 0x%x", newOffset, len(image)))
+	}
+	// the table is parsed from the remainder of the image, so it cannot end beyond the image
+	if directory == PSPDirectoryLevel1 {
+		_, _, err = amd_manifest.ParsePSPDirectoryTable(image[newOffset:])
+	} else {
+		_, _, err = amd_manifest.ParseBIOSDirectoryTable(image[newOffset:])
+	}
+	if err == nil && binary.LittleEndian.Uint32(image[newOffset:]) != expectedCookie {
+		err = fmt.Errorf("unexpected cookie 0x%x", binary.LittleEndian.Uint32(image[newOffset:]))
+	}
+	if err != nil {
+		return 0, newErrInvalidFormatWithItem(newDirectoryItem(directory), fmt.Errorf("no directory at 0x%x: %w", newOffset, err))
+	}
+
+	efs := pspFirmware.EmbeddedFirmware
+	var pointers []*uint32
+	if directory == PSPDirectoryLevel1 {
+		pointers = []*uint32{&efs.PSPDirectoryTablePointer}
+	} else {
+		pointers = []*uint32{
+			&efs.BIOSDirectoryTableFamily17hModels00h0FhPointer,
+			&efs.BIOSDirectoryTableFamily17hModels10h1FhPointer,
+			&efs.BIOSDirectoryTableFamily17hModels30h3FhPointer,
+			&efs.BIOSDirectoryTableFamily17hModels60h3FhPointer,
+		}
+	}
+	updated := false
+	for _, pointer := range pointers {
+		value := uint64(*pointer)
+		switch {
+		case value == 0:
+			continue
+		case value == oldRange.Offset:
+			*pointer = uint32(newOffset)
+		case value >= uint64(len(image)) && firmware.PhysAddrToOffset(value) == oldRange.Offset:
+			physAddr := firmware.OffsetToPhysAddr(newOffset)
+			if physAddr > 0xffffffff {
+				return 0, fmt.Errorf("physical address 0x%x of the new location exceeds 32 bits", physAddr)
+			}
+			*pointer = uint32(physAddr)
+		default:
+			continue
+		}
+		updated = true
+	}
+	if !updated {
+		return 0, newErrInvalidFormatWithItem(newDirectoryItem(directory),
+			fmt.Errorf("no embedded firmware structure pointer refers to 0x%x", oldRange.Offset))
+	}
+
+	var efsBytes bytes.Buffer
+	if err := binary.Write(&efsBytes, binary.LittleEndian, &efs); err != nil {
+		return 0, fmt.Errorf("could not serialize the embedded firmware structure: %w", err)
+	}
+	result := make([]byte, len(image))
+	copy(result, image)
+	copy(result[pspFirmware.EmbeddedFirmwareRange.Offset:], efsBytes.Bytes())
+
+	n, err := w.Write(result)
+	if err != nil {
+		return n, fmt.Errorf("could not write the modified firmware: %w", err)
+	}
+	return n, nil
+}
+
+// EFSPointerStatus is the result of the validation of a directory pointer of the
+// embedded firmware structure.
+type EFSPointerStatus struct {
+	// Name is the name of the pointer field in the embedded firmware structure
+	Name string
+	// Value is the raw pointer, either an offset or a physical address
+	Value uint32
+	// Offset is the offset of the directory within the image, if the pointer could be resolved
+	Offset uint64
+	// Err describes why the pointer is broken, it is nil for a valid pointer
+	Err error
+}
+
+// ValidateEFS checks that every directory pointer set in the embedded firmware structure
+// refers to a directory of the expected type with all its entries within the image.
+// Pointers set to zero or 0xffffffff are considered unset and are not reported.
+// The status of each set pointer is returned; the error lists the broken ones.
+func ValidateEFS(amdFw *amd_manifest.AMDFirmware) ([]EFSPointerStatus, error) {
+	firmware := amdFw.Firmware()
+	imageSize := uint64(len(firmware.ImageBytes()))
+	efs := amdFw.PSPFirmware().EmbeddedFirmware
+
+	pointers := []struct {
+		name      string
+		value     uint32
+		directory DirectoryType
+	}{
+		{"PSPDirectoryTablePointer", efs.PSPDirectoryTablePointer, PSPDirectoryLevel1},
+		{"BIOSDirectoryTableFamily17hModels00h0FhPointer", efs.BIOSDirectoryTableFamily17hModels00h0FhPointer, BIOSDirectoryLevel1},
+		{"BIOSDirectoryTableFamily17hModels10h1FhPointer", efs.BIOSDirectoryTableFamily17hModels10h1FhPointer, BIOSDirectoryLevel1},
+		{"BIOSDirectoryTableFamily17hModels30h3FhPointer", efs.BIOSDirectoryTableFamily17hModels30h3FhPointer, BIOSDirectoryLevel1},
+		{"BIOSDirectoryTableFamily17hModels60h3FhPointer", efs.BIOSDirectoryTableFamily17hModels60h3FhPointer, BIOSDirectoryLevel1},
+	}
+
+	var result []EFSPointerStatus
+	var broken []string
+	for _, pointer := range pointers {
+		if pointer.value == 0 || pointer.value == 0xffffffff {
+			continue
+		}
+		status := EFSPointerStatus{Name: pointer.name, Value: pointer.value}
+		offset, ok := resolveEFSAddress(firmware, uint64(pointer.value))
+		if ok {
+			status.Offset = offset
+			status.Err = validateEFSDirectory(firmware, offset, pointer.directory)
+		} else {
+			status.Err = fmt.Errorf("0x%x is beyond the image of size 0x%x", pointer.value, imageSize)
+		}
+		if status.Err != nil {
+			status.Err = newErrInvalidFormatWithItem(newDirectoryItem(pointer.directory), status.Err)
+			broken = append(broken, pointer.name)
+		}
+		result = append(result, status)
+	}
+	if len(broken) > 0 {
+		return result, newErrInvalidFormat(fmt.Errorf("broken embedded firmware structure pointers: %s", strings.Join(broken, ", ")))
+	}
+	return result, nil
+}
+
+// resolveEFSAddress converts an address found in the embedded firmware structure or in
+// a directory, which is either an offset or a physical address, into an image offset
+func resolveEFSAddress(firmware amd_manifest.Firmware, addr uint64) (uint64, bool) {
+	imageSize := uint64(len(firmware.ImageBytes()))
+	if addr < imageSize {
+		return addr, true
+	}
+	if addr > 0xffffffff {
+		return 0, false
+	}
+	offset := firmware.PhysAddrToOffset(addr)
+	return offset, offset < imageSize
+}
+
+// biosMemoryEntryTypes are the types of BIOS directory entries whose location is
+// a destination in memory rather than a location in the flash
+var biosMemoryEntryTypes = map[amd_manifest.BIOSDirectoryTableEntryType]bool{
+	amd_manifest.APOBBinaryEntry: true,
+}
+
+// validateEFSDirectory checks that a level 1 directory of the given type is located at
+// offset and that the entries it refers to are within the image. Only the entries of
+// the types known to be memory destinations may be located outside of the image.
+func validateEFSDirectory(firmware amd_manifest.Firmware, offset uint64, directory DirectoryType) error {
+	image := firmware.ImageBytes()
+
+	type entryRange struct {
+		typ      uint8
+		location uint64
+		size     uint32
+		memory   bool
+	}
+	var entries []entryRange
+	var cookie, expectedCookie uint32
+	if directory == PSPDirectoryLevel1 {
+		table, _, err := amd_manifest.ParsePSPDirectoryTable(image[offset:])
+		if err != nil {
+			return fmt.Errorf("no directory at 0x%x: %w", offset, err)
+		}
+		cookie, expectedCookie = table.PSPCookie, amd_manifest.PSPDirectoryTableCookie
+		for _, e := range table.Entries {
+			entries = append(entries, entryRange{uint8(e.Type), e.LocationOrValue, e.Size, false})
+		}
+	} else {
+		table, _, err := amd_manifest.ParseBIOSDirectoryTable(image[offset:])
+		if err != nil {
+			return fmt.Errorf("no directory at 0x%x: %w", offset, err)
+		}
+		cookie, expectedCookie = table.BIOSCookie, amd_manifest.BIOSDirectoryTableCookie
+		for _, e := range table.Entries {
+			entries = append(entries, entryRange{uint8(e.Type), e.SourceAddress, e.Size, biosMemoryEntryTypes[e.Type]})
+		}
+	}
+	if cookie != expectedCookie {
+		return fmt.Errorf("unexpected cookie 0x%x at 0x%x", cookie, offset)
+	}
+
+	for _, e := range entries {
+		// entries without a size hold a value instead of a location
+		if e.size == 0 || e.size == 0xffffffff {
+			continue
+		}
+		if e.memory {
+			continue
+		}
+		start, ok := resolveEFSAddress(firmware, e.location)
+		if !ok {
+			return fmt.Errorf("entry 0x%x at 0x%x is beyond the image of size 0x%x", e.typ, e.location, len(image))
+		}
+		if err := checkBoundaries(start, start+uint64(e.size), image); err != nil {
+			return fmt.Errorf("entry 0x%x is out of bounds: %w", e.typ, err)
+		}
+	}
+	return nil
+}
@@ -188,17 +188,18 @@ func GetBIOSEntry(
 	return result, nil
 }
 
-// GetPSPEntries returns all entries of a certain type from PSP directory
+// GetPSPEntries returns all entries of a certain type from PSP directory. The entries of all
+// the PSP directories of the level are returned, e.g. of both the A and B level 2 directories.
 func GetPSPEntries(
 	pspFirmware *amd_manifest.PSPFirmware,
 	pspLevel uint,
 	entryID amd_manifest.PSPDirectoryTableEntryType,
 ) ([]amd_manifest.PSPDirectoryTableEntry, error) {
-	pspTable, err := getPSPTable(pspFirmware, pspLevel)
+	pspDirectories, err := getPSPDirectories(pspFirmware, pspLevel)
 	if err != nil {
 		return nil, err
 	}
-	if pspTable == nil {
+	if len(pspDirectories) == 0 {
 		directory, err := GetPSPDirectoryOfLevel(pspLevel)
 		if err != nil {
 			return nil, fmt.Errorf("unknown psp directory of level %d", pspLevel)
@@ -206,38 +207,63 @@ func GetPSPEntries(
 		return nil, newErrNotFound(newDirectoryItem(directory))
 	}
 	var entries []amd_manifest.PSPDirectoryTableEntry
-	for _, entry := range pspTable.Entries {
-		if entry.Type == entryID {
-			entries = append(entries, entry)
+	for _, pspDirectory := range pspDirectories {
+		for _, entry := range pspDirectory.Entries {
+			if entry.Type == entryID {
+				entries = append(entries, entry)
+			}
 		}
 	}
 	return entries, nil
 }
 
-// GetPSPEntry returns a singe entry of a certain type from PSP directory, returns error if multiple entries are found
+// GetPSPEntry returns a singe entry of a certain type from PSP directory, returns error if multiple entries are found.
+// If there are several PSP directories of the level, the entry is taken from the preferred directory containing it.
 func GetPSPEntry(
 	pspFirmware *amd_manifest.PSPFirmware,
 	pspLevel uint,
 	entryID amd_manifest.PSPDirectoryTableEntryType,
 ) (*amd_manifest.PSPDirectoryTableEntry, error) {
-	entries, err := GetPSPEntries(pspFirmware, pspLevel, entryID)
+	entry, _, err := findPSPEntry(pspFirmware, pspLevel, entryID)
+	return entry, err
+}
+
+// findPSPEntry returns a single entry of a certain type from the preferred PSP directory of the level
+// containing it, together with that directory
+func findPSPEntry(
+	pspFirmware *amd_manifest.PSPFirmware,
+	pspLevel uint,
+	entryID amd_manifest.PSPDirectoryTableEntryType,
+) (*amd_manifest.PSPDirectoryTableEntry, *amd_manifest.PSPDirectoryTable, error) {
+	pspDirectories, err := getPSPDirectories(pspFirmware, pspLevel)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if len(entries) == 0 {
-		return nil, newErrNotFound(newPSPDirectoryEntryItem(uint8(pspLevel), entryID))
+	directory, err := GetPSPDirectoryOfLevel(pspLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown psp directory of level %d", pspLevel)
 	}
-	if len(entries) > 1 {
-		directory, err := GetPSPDirectoryOfLevel(pspLevel)
-		if err != nil {
-			return nil, fmt.Errorf("unknown psp directory of level %d", pspLevel)
+	if len(pspDirectories) == 0 {
+		return nil, nil, newErrNotFound(newDirectoryItem(directory))
+	}
+	for _, pspDirectory := range pspDirectories {
+		var entries []amd_manifest.PSPDirectoryTableEntry
+		for _, entry := range pspDirectory.Entries {
+			if entry.Type == entryID {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) > 1 {
+			return nil, nil, newErrInvalidFormatWithItem(
+				newDirectoryItem(directory),
+				fmt.Errorf("multiple entriers %x are found in PSP directory level %d", entryID, pspLevel),
+			)
+		}
+		if len(entries) == 1 {
+			return &entries[0], pspDirectory, nil
 		}
-		return nil, newErrInvalidFormatWithItem(
-			newDirectoryItem(directory),
-			fmt.Errorf("multiple entriers %x are found in PSP directory level %d", entryID, pspLevel),
-		)
 	}
-	return &entries[0], err
+	return nil, nil, newErrNotFound(newPSPDirectoryEntryItem(uint8(pspLevel), entryID))
 }
 
 // GetEntries returns a list of specific type PSP entries
@@ -268,6 +294,70 @@ func GetEntries(pspFirmware *amd_manifest.PSPFirmware, directory DirectoryType,
 	return entries, nil
 }
 
+// EntryInfo describes an entry of a PSP or BIOS directory
+type EntryInfo struct {
+	Type uint32
+	// Instance is always 0 for PSP directory entries
+	Instance uint8
+	Offset   uint64
+	Size     uint32
+	// Name is the human-readable name of the entry type, empty if the type is unknown
+	Name string
+}
+
+// ListEntries returns all the entries of a directory in the order of the table, which is
+// useful to find out which entry types are present in an unknown image. The entries of all
+// the PSP directories of the level are returned, e.g. of both the A and B level 2 directories.
+func ListEntries(pspFirmware *amd_manifest.PSPFirmware, directory DirectoryType) ([]EntryInfo, error) {
+	var entries []EntryInfo
+	entryName := func(name string) string {
+		if name == "UNKNOWN" {
+			return ""
+		}
+		return name
+	}
+	switch directory {
+	case PSPDirectoryLevel1, PSPDirectoryLevel2:
+		pspDirectories, err := getPSPDirectories(pspFirmware, directory.Level())
+		if err != nil {
+			return nil, err
+		}
+		if len(pspDirectories) == 0 {
+			return nil, newErrNotFound(newDirectoryItem(directory))
+		}
+		for _, pspDirectory := range pspDirectories {
+			for _, entry := range pspDirectory.Entries {
+				entries = append(entries, EntryInfo{
+					Type:   uint32(entry.Type),
+					Offset: entry.LocationOrValue,
+					Size:   entry.Size,
+					Name:   entryName(PSPEntryType(entry.Type).String()),
+				})
+			}
+		}
+	case BIOSDirectoryLevel1, BIOSDirectoryLevel2:
+		biosTable, err := getBIOSTable(pspFirmware, directory.Level())
+		if err != nil {
+			return nil, err
+		}
+		if biosTable == nil {
+			return nil, newErrNotFound(newDirectoryItem(directory))
+		}
+		for _, entry := range biosTable.Entries {
+			entries = append(entries, EntryInfo{
+				Type:     uint32(entry.Type),
+				Instance: entry.Instance,
+				Offset:   entry.SourceAddress,
+				Size:     entry.Size,
+				Name:     entryName(BIOSEntryType(entry.Type).String()),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsopprted directory type: %s", directory)
+	}
+	return entries, nil
+}
+
 // GetRangeBytes converts firmware range to continues bytes sequence
 // TODO: should be moved to fiano's bytes2
 func GetRangeBytes(image []byte, start, length uint64) ([]byte, error) {
@@ -278,21 +368,37 @@ func GetRangeBytes(image []byte, start, length uint64) ([]byte, error) {
 	return image[start:end], nil
 }
 
+// checkEntryLocation checks that the data of an entry lies within the image and does not
+// overlap the table of the directory holding the entry, which is a sign of a corrupt directory
+func checkEntryLocation(image []byte, entry bytes2.Range, directory DirectoryType, directoryRange bytes2.Range) error {
+	if err := checkBoundaries(entry.Offset, entry.End(), image); err != nil {
+		return fmt.Errorf("entry at [0x%x:0x%x] of %s at [0x%x:0x%x] is beyond the image of size 0x%x",
+			entry.Offset, entry.End(), directory, directoryRange.Offset, directoryRange.End(), len(image))
+	}
+	if entry.Intersect(directoryRange) {
+		return fmt.Errorf("entry at [0x%x:0x%x] overlaps its directory %s at [0x%x:0x%x]",
+			entry.Offset, entry.End(), directory, directoryRange.Offset, directoryRange.End())
+	}
+	return nil
+}
+
 // ExtractPSPEntry extracts a single generic raw entry from PSP Directory.
 // Returns an error if multiple entries are found as PSP directory is supposed to have no more than a single entry for each type
 func ExtractPSPEntry(amdFw *amd_manifest.AMDFirmware, pspLevel uint, entryID amd_manifest.PSPDirectoryTableEntryType) ([]byte, error) {
-	entry, err := GetPSPEntry(amdFw.PSPFirmware(), pspLevel, entryID)
+	entry, pspDirectory, err := findPSPEntry(amdFw.PSPFirmware(), pspLevel, entryID)
 	if err != nil {
 		return nil, err
 	}
-	data, err := GetRangeBytes(amdFw.Firmware().ImageBytes(), entry.LocationOrValue, uint64(entry.Size))
+	directory, err := GetPSPDirectoryOfLevel(pspLevel)
 	if err != nil {
-		if errInvalidFormat, ok := err.(ErrInvalidFormat); ok {
-			return nil, newErrInvalidFormatWithItem(newPSPDirectoryEntryItem(uint8(pspLevel), entryID), errInvalidFormat.Unwrap())
-		}
 		return nil, err
 	}
-	return data, nil
+	image := amdFw.Firmware().ImageBytes()
+	location := bytes2.Range{Offset: entry.LocationOrValue, Length: uint64(entry.Size)}
+	if err := checkEntryLocation(image, location, directory, pspDirectory.Range); err != nil {
+		return nil, newErrInvalidFormatWithItem(newPSPDirectoryEntryItem(uint8(pspLevel), entryID), err)
+	}
+	return image[location.Offset:location.End()], nil
 }
 
 // ExtractBIOSEntry extracts a single generic raw entry from BIOS Directory.
@@ -301,14 +407,20 @@ func ExtractBIOSEntry(amdFw *amd_manifest.AMDFirmware, biosLevel uint, entryID a
 	if err != nil {
 		return nil, err
 	}
-	data, err := GetRangeBytes(amdFw.Firmware().ImageBytes(), entry.SourceAddress, uint64(entry.Size))
+	directory, err := GetBIOSDirectoryOfLevel(biosLevel)
+	if err != nil {
+		return nil, err
+	}
+	biosTable, err := getBIOSTable(amdFw.PSPFirmware(), biosLevel)
 	if err != nil {
-		if errInvalidFormat, ok := err.(ErrInvalidFormat); ok {
-			return nil, newErrInvalidFormatWithItem(newBIOSDirectoryEntryItem(uint8(biosLevel), entryID, instance), errInvalidFormat.Unwrap())
-		}
 		return nil, err
 	}
-	return data, nil
+	image := amdFw.Firmware().ImageBytes()
+	location := bytes2.Range{Offset: entry.SourceAddress, Length: uint64(entry.Size)}
+	if err := checkEntryLocation(image, location, directory, biosTable.Range); err != nil {
+		return nil, newErrInvalidFormatWithItem(newBIOSDirectoryEntryItem(uint8(biosLevel), entryID, instance), err)
+	}
+	return image[location.Offset:location.End()], nil
 }
 
 // DumpPSPEntry dumps an entry from PSP Directory
@@ -329,9 +441,58 @@ func DumpBIOSEntry(amdFw *amd_manifest.AMDFirmware, biosLevel uint, entryID amd_
 	return w.Write(data)
 }
 
+// GetDirectoryRange returns the range of a directory table (header and entries) within the firmware image
+func GetDirectoryRange(pspFirmware *amd_manifest.PSPFirmware, directory DirectoryType) (bytes2.Range, error) {
+	var (
+		found bool
+		r     bytes2.Range
+	)
+	switch directory {
+	case PSPDirectoryLevel1:
+		if found = pspFirmware.PSPDirectoryLevel1 != nil; found {
+			r = pspFirmware.PSPDirectoryLevel1.Range
+		}
+	case PSPDirectoryLevel2:
+		if found = pspFirmware.PSPDirectoryLevel2 != nil; found {
+			r = pspFirmware.PSPDirectoryLevel2.Range
+		}
+	case BIOSDirectoryLevel1:
+		if found = pspFirmware.BIOSDirectoryLevel1 != nil; found {
+			r = pspFirmware.BIOSDirectoryLevel1.Range
+		}
+	case BIOSDirectoryLevel2:
+		if found = pspFirmware.BIOSDirectoryLevel2 != nil; found {
+			r = pspFirmware.BIOSDirectoryLevel2.Range
+		}
+	default:
+		return bytes2.Range{}, fmt.Errorf("unsupported directory type: %s", directory)
+	}
+	if !found {
+		return bytes2.Range{}, fmt.Errorf("%s is not found", directory)
+	}
+	return r, nil
+}
+
+// DumpDirectoryTable writes the raw bytes of a directory table (header and entries, not the data
+// the entries refer to) into w. Returns the range of the table within the firmware image.
+func DumpDirectoryTable(amdFw *amd_manifest.AMDFirmware, directory DirectoryType, w io.Writer) (bytes2.Range, error) {
+	r, err := GetDirectoryRange(amdFw.PSPFirmware(), directory)
+	if err != nil {
+		return bytes2.Range{}, err
+	}
+	data, err := GetRangeBytes(amdFw.Firmware().ImageBytes(), r.Offset, r.Length)
+	if err != nil {
+		return bytes2.Range{}, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return bytes2.Range{}, fmt.Errorf("could not write %s: %w", directory, err)
+	}
+	return r, nil
+}
+
 // PatchPSPEntry takes an AmdFirmware object and modifies one entry in PSP directory.
 // The modified entry is read from `r` reader object, while the modified firmware is written into `w` writer object.
-func PatchPSPEntry(amdFw *amd_manifest.AMDFirmware, pspLevel uint, entryID amd_manifest.PSPDirectoryTableEntryType, r io.Reader, w io.Writer) (int, error) {
+func PatchPSPEntry(amdFw *amd_manifest.AMDFirmware, pspLevel uint, entryID amd_manifest.PSPDirectoryTableEntryType, r io.Reader, w io.Writer, opts ...PatchOption) (int, error) {
 	entry, err := GetPSPEntry(amdFw.PSPFirmware(), pspLevel, entryID)
 	if err != nil {
 		return 0, err
@@ -339,12 +500,12 @@ func PatchPSPEntry(amdFw *amd_manifest.AMDFirmware, pspLevel uint, entryID amd_m
 
 	start := entry.LocationOrValue
 	end := start + uint64(entry.Size)
-	return patchEntry(amdFw, start, end, r, w)
+	return patchEntry(amdFw, start, end, r, w, opts...)
 }
 
 // PatchBIOSEntry takes an AmdFirmware object and modifies one entry in BIOS directory.
 // The modified entry is read from `r` reader object, while the modified firmware is written into `w` writer object.
-func PatchBIOSEntry(amdFw *amd_manifest.AMDFirmware, biosLevel uint, entryID amd_manifest.BIOSDirectoryTableEntryType, instance uint8, r io.Reader, w io.Writer) (int, error) {
+func PatchBIOSEntry(amdFw *amd_manifest.AMDFirmware, biosLevel uint, entryID amd_manifest.BIOSDirectoryTableEntryType, instance uint8, r io.Reader, w io.Writer, opts ...PatchOption) (int, error) {
 	entry, err := GetBIOSEntry(amdFw.PSPFirmware(), biosLevel, entryID, instance)
 	if err != nil {
 		return 0, err
@@ -352,10 +513,37 @@ func PatchBIOSEntry(amdFw *amd_manifest.AMDFirmware, biosLevel uint, entryID amd
 
 	start := entry.SourceAddress
 	end := start + uint64(entry.Size)
-	return patchEntry(amdFw, start, end, r, w)
+	return patchEntry(amdFw, start, end, r, w, opts...)
+}
+
+// PatchOption is an optional argument of PatchPSPEntry and PatchBIOSEntry
+type PatchOption interface {
+	apply(*patchConfig)
+}
+
+type patchConfig struct {
+	FixDirectoryChecksums bool
+}
+
+// PatchOptionFixDirectoryChecksums defines if checksums of all directories
+// should be recalculated in the written firmware image
+type PatchOptionFixDirectoryChecksums bool
+
+func (opt PatchOptionFixDirectoryChecksums) apply(cfg *patchConfig) {
+	cfg.FixDirectoryChecksums = bool(opt)
+}
+
+func getPatchConfig(opts []PatchOption) patchConfig {
+	var cfg patchConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
 }
 
-func patchEntry(amdFw *amd_manifest.AMDFirmware, start, end uint64, r io.Reader, w io.Writer) (int, error) {
+func patchEntry(amdFw *amd_manifest.AMDFirmware, start, end uint64, r io.Reader, w io.Writer, opts ...PatchOption) (int, error) {
+	cfg := getPatchConfig(opts)
+
 	modifiedEntry, err := io.ReadAll(r)
 	if err != nil {
 		return 0, fmt.Errorf("could not read modified entry: %w", err)
@@ -372,6 +560,23 @@ func patchEntry(amdFw *amd_manifest.AMDFirmware, start, end uint64, r io.Reader,
 		return 0, newErrInvalidFormat(fmt.Errorf("cannot write the entry to the firmware image, entry size check fail, expected %d, modified entry is %d", uint64(size), uint64(len(modifiedEntry))))
 	}
 
+	if cfg.FixDirectoryChecksums {
+		// The directories may be stored anywhere in the image, so the patched image
+		// is assembled in a separate buffer where the checksums can be updated.
+		patched := make([]byte, 0, len(firmwareBytes))
+		patched = append(patched, firmwareBytes[:start]...)
+		patched = append(patched, modifiedEntry...)
+		patched = append(patched, firmwareBytes[end:]...)
+		if err := fixDirectoryChecksums(amdFw.PSPFirmware(), patched, false); err != nil {
+			return 0, err
+		}
+		n, err := w.Write(patched)
+		if err != nil {
+			return n, fmt.Errorf("could not write entry to system file :  %w", err)
+		}
+		return n, nil
+	}
+
 	firmwareBytesFirstSection := firmwareBytes[0:start]
 	firmwareBytesSecondSection := firmwareBytes[end:]
 
@@ -5,9 +5,11 @@
 package psb
 
 import (
+	"encoding/binary"
 	"strings"
 	"testing"
 
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,3 +34,135 @@ func TestDirectoryType(t *testing.T) {
 	_, err := DirectoryTypeFromString("No such directory type")
 	require.Error(t, err)
 }
+
+func putPSPDirectory(image []byte, offset uint64, cookie uint32, entries []amd_manifest.PSPDirectoryTableEntry) {
+	b := image[offset:]
+	binary.LittleEndian.PutUint32(b[0:], cookie)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(entries)))
+	pos := uint64(binary.Size(amd_manifest.PSPDirectoryTableHeader{}))
+	for _, entry := range entries {
+		b[pos] = uint8(entry.Type)
+		binary.LittleEndian.PutUint32(b[pos+4:], entry.Size)
+		binary.LittleEndian.PutUint64(b[pos+8:], entry.LocationOrValue)
+		pos += amd_manifest.PSPDirectoryTableEntrySize
+	}
+}
+
+func TestGetPSPEntriesMultipleLevel2Directories(t *testing.T) {
+	// the image is mapped right below 4GB, so that the embedded firmware structure is found at 0xfffa0000
+	image := make([]byte, 0x60000)
+	binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPDirectory(image, 0x100, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: amd_manifest.PSPDirectoryTableLevel2BEntry, Size: 0x1000, LocationOrValue: 0x2000},
+		{Type: amd_manifest.PSPDirectoryTableLevel2AEntry, Size: 0x1000, LocationOrValue: 0x1000},
+	})
+	putPSPDirectory(image, 0x1000, amd_manifest.PSPDirectoryTableLevel2Cookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: SMUOffChipFirmwareEntry, Size: 0x100, LocationOrValue: 0x10000},
+	})
+	putPSPDirectory(image, 0x2000, amd_manifest.PSPDirectoryTableLevel2Cookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: SMUOffChipFirmwareEntry, Size: 0x100, LocationOrValue: 0x20000},
+		{Type: UnlockDebugImageEntry, Size: 0x100, LocationOrValue: 0x21000},
+	})
+
+	amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+	require.NoError(t, err)
+	pspFirmware := amdFw.PSPFirmware()
+	require.Len(t, pspFirmware.PSPDirectoriesLevel2, 2)
+
+	entries, err := GetPSPEntries(pspFirmware, 2, SMUOffChipFirmwareEntry)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(0x10000), entries[0].LocationOrValue)
+	require.Equal(t, uint64(0x20000), entries[1].LocationOrValue)
+
+	// a single entry is taken from the preferred directory containing it
+	entry, err := GetPSPEntry(pspFirmware, 2, SMUOffChipFirmwareEntry)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x10000), entry.LocationOrValue)
+	entry, err = GetPSPEntry(pspFirmware, 2, UnlockDebugImageEntry)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x21000), entry.LocationOrValue)
+
+	_, err = GetPSPEntry(pspFirmware, 2, AGESABinary0Entry)
+	require.IsType(t, ErrNotFound{}, err)
+}
+
+func TestExtractPSPEntryLocation(t *testing.T) {
+	image := make([]byte, 0x60000)
+	binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPDirectory(image, 0x100, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: SMUOffChipFirmwareEntry, Size: 0x100, LocationOrValue: 0x1000},
+		{Type: UnlockDebugImageEntry, Size: 0x100, LocationOrValue: 0x5ff80},
+		{Type: AGESABinary0Entry, Size: 0x100, LocationOrValue: 0x80},
+	})
+	amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x100), amdFw.PSPFirmware().PSPDirectoryLevel1.Range.Offset)
+
+	data, err := ExtractPSPEntry(amdFw, 1, SMUOffChipFirmwareEntry)
+	require.NoError(t, err)
+	require.Len(t, data, 0x100)
+
+	_, err = ExtractPSPEntry(amdFw, 1, UnlockDebugImageEntry)
+	require.IsType(t, ErrInvalidFormat{}, err)
+	require.Contains(t, err.Error(), "beyond the image")
+
+	_, err = ExtractPSPEntry(amdFw, 1, AGESABinary0Entry)
+	require.IsType(t, ErrInvalidFormat{}, err)
+	require.Contains(t, err.Error(), "overlaps its directory")
+}
+
+func TestListEntries(t *testing.T) {
+	image := make([]byte, 0x60000)
+	binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+	binary.LittleEndian.PutUint32(image[20:], 0x100)
+	putPSPDirectory(image, 0x100, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+		{Type: AMDPublicKeyEntry, Size: 0x440, LocationOrValue: 0x1000},
+		{Type: 0xee, Size: 0x100, LocationOrValue: 0x2000},
+	})
+	amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+	require.NoError(t, err)
+
+	entries, err := ListEntries(amdFw.PSPFirmware(), PSPDirectoryLevel1)
+	require.NoError(t, err)
+	require.Equal(t, []EntryInfo{
+		{Type: uint32(AMDPublicKeyEntry), Offset: 0x1000, Size: 0x440, Name: "AMD_PUBLIC_KEYS"},
+		{Type: 0xee, Offset: 0x2000, Size: 0x100},
+	}, entries)
+
+	_, err = ListEntries(amdFw.PSPFirmware(), BIOSDirectoryLevel1)
+	require.IsType(t, ErrNotFound{}, err)
+}
+
+func TestDecodeEntryLocation(t *testing.T) {
+	const imageSize = 0x1000000
+	for _, tc := range []struct {
+		name           string
+		additionalInfo uint32
+		location       uint64
+		offset         uint64
+		inImage        bool
+		fails          bool
+	}{
+		{name: "physical_offset", location: 0x2000, offset: 0x2000, inImage: true},
+		{name: "physical_mapped", location: 0xff002000, offset: 0x2000, inImage: true},
+		{name: "physical_outside", location: 0x80000000, offset: 0x80000000},
+		{name: "flash_offset", additionalInfo: 1 << 29, location: 0x2000, offset: 0x2000, inImage: true},
+		{name: "entry_flash_offset", additionalInfo: 2 << 29, location: 1<<62 | 0x2000, offset: 0x2000, inImage: true},
+		{name: "entry_directory_relative", additionalInfo: 2 << 29, location: 2<<62 | 0x2000, offset: 0x12000, inImage: true},
+		{name: "entry_slot_relative", additionalInfo: 2 << 29, location: 3<<62 | 0x2000, fails: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			offset, inImage, err := decodeEntryLocation(tc.additionalInfo, tc.location, 0x10000, imageSize)
+			if tc.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.offset, offset)
+			require.Equal(t, tc.inImage, inImage)
+		})
+	}
+}
@@ -103,6 +103,15 @@ func newPSPDirectoryEntryItem(level uint8, entry amd_manifest.PSPDirectoryTableE
 	}
 }
 
+// PSPComboEntryItem determines an entry of the PSP combo directory by its ID
+type PSPComboEntryItem struct {
+	ID uint32
+}
+
+func (comboEntry PSPComboEntryItem) String() string {
+	return fmt.Sprintf("entry with ID 0x%X of psp combo directory", comboEntry.ID)
+}
+
 // ErrNotFound describes a situation when firmware item is not found
 type ErrNotFound struct {
 	item FirmwareItem
@@ -185,8 +194,8 @@ func addFirmwareItemToError(err error, item FirmwareItem) error {
 
 	var invalidFormatErr ErrInvalidFormat
 	if errors.As(err, &invalidFormatErr) {
-		if notFoundErr.item == nil {
-			return ErrNotFound{item: item}
+		if invalidFormatErr.item == nil {
+			return ErrInvalidFormat{item: item, err: invalidFormatErr.err}
 		}
 		return err
 	}
@@ -12,6 +12,7 @@ package psb
 import (
 	"bytes"
 	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -37,6 +38,20 @@ func (kid *KeyID) String() string {
 	return kid.Hex()
 }
 
+// KeyFingerprint is the SHA-256 digest of the exponent and the modulus of a key,
+// which identifies the key material itself unlike the self-declared KeyID
+type KeyFingerprint [sha256.Size]byte
+
+// Hex returns a hexadecimal string representation of a KeyFingerprint
+func (f KeyFingerprint) Hex() string {
+	return fmt.Sprintf("%x", f[:])
+}
+
+// String returns the hexadecimal string representation of a KeyFingerprint
+func (f KeyFingerprint) String() string {
+	return f.Hex()
+}
+
 // KeyIDs represents a list of KeyID
 type KeyIDs []KeyID
 
@@ -77,6 +92,44 @@ const (
 	PSBSignBIOS KeyUsageFlag = 8
 )
 
+var keyUsageFlagNames = map[KeyUsageFlag]string{
+	SignAMDBootloaderPSPSMU: "SignAMDBootloaderPSPSMU",
+	SignBIOS:                "SignBIOS",
+	SignAMDOEMPSP:           "SignAMDOEMPSP",
+	PSBSignBIOS:             "PSBSignBIOS",
+}
+
+// String returns the name of a known key usage flag or its hexadecimal value
+func (flag KeyUsageFlag) String() string {
+	if name, ok := keyUsageFlagNames[flag]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%x)", uint32(flag))
+}
+
+// KeyAlgorithm describes the algorithm of a key. It is identified by the
+// version of the key format: AMD Family 17h and 19h processors only support
+// RSA keys, stored with version 1. Newer platforms introduce other formats
+// for non-RSA key material.
+type KeyAlgorithm uint32
+
+const (
+	// KeyAlgorithmRSA is an RSA key made of an exponent and a modulus
+	KeyAlgorithmRSA KeyAlgorithm = 1
+)
+
+var keyAlgorithmNames = map[KeyAlgorithm]string{
+	KeyAlgorithmRSA: "RSA",
+}
+
+// String returns the name of a known key algorithm or its hexadecimal value
+func (alg KeyAlgorithm) String() string {
+	if name, ok := keyAlgorithmNames[alg]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%x)", uint32(alg))
+}
+
 // KeyData represents the binary format (as it is stored in an image) of the information associated with a key
 type KeyData struct {
 	VersionID       uint32
@@ -93,6 +146,17 @@ type KeyData struct {
 // Key structure extracted from the firmware
 type Key struct {
 	data KeyData
+	// certificate is the signed data which certifies the key, nil if unknown
+	certificate *keyCertificate
+}
+
+// keyCertificate holds the signed data which certified a key when it was
+// extracted, either the key token itself or the whole key database, so that
+// the signature can be checked again later on.
+type keyCertificate struct {
+	signingKeyID KeyID
+	signedData   []byte
+	signature    []byte
 }
 
 // PlatformBindingInfo describes information of BIOS Signing Key to Platform Binding information
@@ -321,6 +385,11 @@ func NewTokenKey(buff *bytes.Buffer, keySet KeySet) (*Key, error) {
 	if _, err := NewSignedBlob(reverse(signature), raw[:lenSigned], signingKey); err != nil {
 		return nil, fmt.Errorf("could not validate the signature of token key: %w", err)
 	}
+	key.certificate = &keyCertificate{
+		signingKeyID: signingKeyID,
+		signedData:   raw[:lenSigned],
+		signature:    reverse(signature),
+	}
 	return key, nil
 }
 
@@ -396,17 +465,33 @@ func NewKeyFromDatabase(buff *bytes.Buffer) (*Key, error) {
 	return &key, nil
 }
 
+// Algorithm returns the algorithm of the key, based on the version of its format
+func (k *Key) Algorithm() KeyAlgorithm {
+	return KeyAlgorithm(k.data.VersionID)
+}
+
+// Fingerprint returns the SHA-256 digest of the exponent followed by the
+// modulus, both as they are stored in the firmware (little endian)
+func (k *Key) Fingerprint() KeyFingerprint {
+	h := sha256.New()
+	h.Write(k.data.Exponent)
+	h.Write(k.data.Modulus)
+	var f KeyFingerprint
+	copy(f[:], h.Sum(nil))
+	return f
+}
+
 // String returns a string representation of the key
 func (k *Key) String() string {
 	var s strings.Builder
 
 	pubKey, err := k.Get()
 	if err != nil {
-		fmt.Fprintf(&s, "could not get RSA key from raw bytes: %v\n", err)
+		fmt.Fprintf(&s, "could not get key from raw bytes: %v\n", err)
 		return s.String()
 	}
 
-	fmt.Fprintf(&s, "Version ID: 0x%x\n", k.data.VersionID)
+	fmt.Fprintf(&s, "Version ID: 0x%x (%s)\n", k.data.VersionID, k.Algorithm())
 	fmt.Fprintf(&s, "Key ID: 0x%s\n", k.data.KeyID.Hex())
 	fmt.Fprintf(&s, "Certifying Key ID: 0x%x\n", k.data.CertifyingKeyID)
 	fmt.Fprintf(&s, "Key Usage Flag: 0x%x\n", k.data.KeyUsageFlag)
@@ -428,31 +513,42 @@ func (k *Key) String() string {
 	return s.String()
 }
 
-// Get returns the PublicKey object from golang standard library.
-// AMD Milan supports only RSA Keys (2048, 4096), future platforms
-// might add support for additional key types.
+// Get returns the PublicKey object from golang standard library, according
+// to the algorithm of the key. AMD Milan supports only RSA Keys (2048, 4096),
+// for which *rsa.PublicKey is returned. Keys of other algorithms are reported
+// with an error.
 func (k *Key) Get() (interface{}, error) {
-	if err := k.checkValid(); err != nil {
-		return nil, err
-	}
+	switch alg := k.Algorithm(); alg {
+	case KeyAlgorithmRSA:
+		if err := k.checkValidRSA(); err != nil {
+			return nil, err
+		}
 
-	N := big.NewInt(0)
-	E := big.NewInt(0)
+		N := big.NewInt(0)
+		E := big.NewInt(0)
 
-	// modulus and exponent are read as little endian
-	rsaPk := rsa.PublicKey{N: N.SetBytes(reverse(k.data.Modulus)), E: int(E.SetBytes(reverse(k.data.Exponent)).Int64())}
-	return &rsaPk, nil
+		// modulus and exponent are read as little endian
+		rsaPk := rsa.PublicKey{N: N.SetBytes(reverse(k.data.Modulus)), E: int(E.SetBytes(reverse(k.data.Exponent)).Int64())}
+		return &rsaPk, nil
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %s of key %s", alg, k.data.KeyID.Hex())
+	}
 }
 
-// SignatureSize returns the size of the signature
+// SignatureSize returns the size of the signature produced by the key
 func (k *Key) SignatureSize() (int, error) {
-	if err := k.checkValid(); err != nil {
-		return 0, err
+	switch alg := k.Algorithm(); alg {
+	case KeyAlgorithmRSA:
+		if err := k.checkValidRSA(); err != nil {
+			return 0, err
+		}
+		return len(k.data.Modulus), nil
+	default:
+		return 0, fmt.Errorf("unsupported key algorithm %s of key %s", alg, k.data.KeyID.Hex())
 	}
-	return len(k.data.Modulus), nil
 }
 
-func (k *Key) checkValid() error {
+func (k *Key) checkValidRSA() error {
 	if len(k.data.Exponent) == 0 {
 		return fmt.Errorf("invalid key: exponent size is 0")
 	}
@@ -462,10 +558,41 @@ func (k *Key) checkValid() error {
 	return nil
 }
 
+// GetRootKey extracts the AMD root key from the firmware and validates that it
+// is self-signed, i.e. that its certifying key ID is its own key ID. The root
+// key is the anchor of the chain of trust: it is trusted as long as it matches
+// an external reference, which is up to the caller to check.
+//
+// The root key is stored only in the level 1 PSP directory, it is looked up
+// there if the directory of the requested level does not have it.
+func GetRootKey(amdFw *amd_manifest.AMDFirmware, level uint) (*Key, error) {
+	pubKeyBytes, err := ExtractPSPEntry(amdFw, level, AMDPublicKeyEntry)
+	if err != nil && level != 1 && errors.As(err, &ErrNotFound{}) {
+		level = 1
+		pubKeyBytes, err = ExtractPSPEntry(amdFw, level, AMDPublicKeyEntry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not extract raw PSP entry for AMD Public Key: %w", err)
+	}
+	rootKey, err := NewRootKey(bytes.NewBuffer(pubKeyBytes))
+	if err != nil {
+		return nil, addFirmwareItemToError(err, newPSPDirectoryEntryItem(uint8(level), AMDPublicKeyEntry))
+	}
+	if _, err := rootKey.Get(); err != nil {
+		return nil, addFirmwareItemToError(newErrInvalidFormat(fmt.Errorf("invalid AMD root key: %w", err)),
+			newPSPDirectoryEntryItem(uint8(level), AMDPublicKeyEntry))
+	}
+	return rootKey, nil
+}
+
 // GetKeys returns all the keys known to the system in the form of a KeySet.
 // The firmware itself contains a key database, but that is not comprehensive
 // of all the keys known to the system (e.g. additional keys might be OEM key,
 // ABL signing key, etc.).
+//
+// The signature of the key database is validated against the AMD root key: a
+// tampered database is reported with a SignatureCheckError, and a database which
+// is not signed by the AMD root key with an UnknownSigningKeyError.
 func GetKeys(amdFw *amd_manifest.AMDFirmware, level uint) (KeySet, error) {
 	keySet := NewKeySet()
 	err := getKeysFromDatabase(amdFw, level, keySet)
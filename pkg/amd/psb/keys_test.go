@@ -7,8 +7,12 @@ package psb
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"testing"
 
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -19,6 +23,8 @@ var (
 	rsaCommonExponentSHA256 = [32]uint8{0xc8, 0xa2, 0x22, 0xa2, 0x60, 0xf3, 0x57, 0xf5, 0xfd, 0x2b, 0x6d, 0x22, 0x49, 0x2, 0x2e, 0xef, 0xea, 0xa2, 0x8, 0xbd, 0x12, 0x13, 0x7, 0x89, 0xa2, 0x60, 0x0, 0x9b, 0x6a, 0xea, 0x58, 0xbb}
 	// Key ID of the root key belonging to AMD
 	rootKeyID = Buf16B{0x94, 0xc3, 0x8e, 0x41, 0x77, 0xd0, 0x47, 0x92, 0x92, 0xa7, 0xae, 0x67, 0x1d, 0x08, 0x3f, 0xb6}
+	// Fingerprint of the root key belonging to AMD
+	rootKeyFingerprint = KeyFingerprint{0x52, 0x01, 0x49, 0x6b, 0x22, 0x32, 0xd8, 0x1c, 0xf2, 0xae, 0x75, 0xb8, 0x67, 0xe7, 0xb8, 0x51, 0x6f, 0x81, 0xa3, 0xd4, 0xd0, 0xdc, 0x87, 0x61, 0x38, 0x05, 0x19, 0x27, 0xa5, 0xa2, 0x0b, 0xab}
 	// KeyID of the OEM signing key
 	oemKeyID = Buf16B{0xef, 0x99, 0x1d, 0xb4, 0x41, 0x42, 0x44, 0x67, 0x92, 0x65, 0x92, 0x3d, 0xe8, 0xbc, 0x51, 0xd8}
 	// KeyID of the signing key for SMU off chip (0x08, 0x12) firmware and MP5 firmware (0x2A)
@@ -124,7 +130,7 @@ func (suite *KeySuite) TestOEMKeyFields() {
 func (suite *KeySuite) TestKeyDBParsing() {
 
 	keySet := NewKeySet()
-	err := parseKeyDatabase(keyDB, keySet)
+	err := parseKeyDatabase(keyDB, keySet, nil)
 	assert.NoError(suite.T(), err)
 
 	assert.Equal(suite.T(), 7, len(keySet.AllKeyIDs()))
@@ -144,6 +150,135 @@ func (suite *KeySuite) TestKeyDBParsing() {
 
 }
 
+func (suite *KeySuite) TestKeySetList() {
+	rootKey, err := NewRootKey(bytes.NewBuffer(amdRootKey))
+	require.NoError(suite.T(), err)
+
+	keySet := NewKeySet()
+	require.NoError(suite.T(), keySet.AddKey(rootKey, AMDRootKey))
+	oemKey, err := NewTokenKey(bytes.NewBuffer(oemSigningKey), keySet)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), keySet.AddKey(oemKey, OEMKey))
+	require.NoError(suite.T(), parseKeyDatabase(keyDB, keySet, nil))
+
+	list := keySet.List()
+	require.Len(suite.T(), list, 9)
+	for idx := 1; idx < len(list); idx++ {
+		assert.True(suite.T(), list[idx-1].KeyID.Hex() < list[idx].KeyID.Hex())
+	}
+
+	usageFlags := make(map[KeyUsageFlag]bool)
+	for _, info := range list {
+		usageFlags[info.KeyUsageFlag] = true
+		switch info.KeyID {
+		case KeyID(rootKeyID):
+			assert.Equal(suite.T(), AMDRootKey, info.KeyType)
+			assert.Equal(suite.T(), SignAMDBootloaderPSPSMU, info.KeyUsageFlag)
+			assert.Equal(suite.T(), uint32(4096), info.ModulusSize)
+		case KeyID(oemKeyID):
+			assert.Equal(suite.T(), OEMKey, info.KeyType)
+			assert.Equal(suite.T(), KeyID(rootKeyID), info.CertifyingKeyID)
+			assert.Equal(suite.T(), PSBSignBIOS, info.KeyUsageFlag)
+		default:
+			assert.Equal(suite.T(), KeyDatabaseKey, info.KeyType)
+		}
+	}
+	assert.True(suite.T(), len(usageFlags) > 1)
+
+	tbl := keySet.Table()
+	assert.Contains(suite.T(), tbl, fmt.Sprintf("%x", oemKeyID))
+	assert.Contains(suite.T(), tbl, "PSBSignBIOS")
+	assert.Contains(suite.T(), tbl, "SignAMDBootloaderPSPSMU")
+}
+
+func TestKeyUsageFlagString(t *testing.T) {
+	assert.Equal(t, "SignAMDBootloaderPSPSMU", SignAMDBootloaderPSPSMU.String())
+	assert.Equal(t, "SignBIOS", SignBIOS.String())
+	assert.Equal(t, "SignAMDOEMPSP", SignAMDOEMPSP.String())
+	assert.Equal(t, "PSBSignBIOS", PSBSignBIOS.String())
+	assert.Equal(t, "Unknown(0x5)", KeyUsageFlag(5).String())
+}
+
+func TestKeyAlgorithm(t *testing.T) {
+	rootKey, err := NewRootKey(bytes.NewBuffer(amdRootKey))
+	require.NoError(t, err)
+	assert.Equal(t, KeyAlgorithmRSA, rootKey.Algorithm())
+	signatureSize, err := rootKey.SignatureSize()
+	require.NoError(t, err)
+	assert.Equal(t, 512, signatureSize)
+
+	unknownKey := &Key{data: rootKey.data}
+	unknownKey.data.VersionID = 0x42
+	assert.Equal(t, "Unknown(0x42)", unknownKey.Algorithm().String())
+
+	_, err = unknownKey.Get()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported key algorithm Unknown(0x42)")
+	_, err = unknownKey.SignatureSize()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported key algorithm Unknown(0x42)")
+}
+
+func TestGetRootKey(t *testing.T) {
+	newFirmware := func(rootKey []byte) *amd_manifest.AMDFirmware {
+		image := make([]byte, 0x60000)
+		binary.LittleEndian.PutUint32(image[0:], amd_manifest.EmbeddedFirmwareStructureSignature)
+		binary.LittleEndian.PutUint32(image[20:], 0x100)
+		putPSPDirectory(image, 0x100, amd_manifest.PSPDirectoryTableCookie, []amd_manifest.PSPDirectoryTableEntry{
+			{Type: AMDPublicKeyEntry, Size: uint32(len(rootKey)), LocationOrValue: 0x1000},
+		})
+		copy(image[0x1000:], rootKey)
+		amdFw, err := amd_manifest.NewAMDFirmware(amd_manifest.FirmwareImage(image))
+		require.NoError(t, err)
+		return amdFw
+	}
+
+	rootKey, err := GetRootKey(newFirmware(amdRootKey), 1)
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(rootKeyID), rootKey.data.KeyID)
+
+	// the root key is taken from the level 1 directory if there is no level 2 one
+	rootKey, err = GetRootKey(newFirmware(amdRootKey), 2)
+	require.NoError(t, err)
+	assert.Equal(t, KeyID(rootKeyID), rootKey.data.KeyID)
+
+	// a root key certified by another key is not a valid root
+	notSelfSigned := append([]byte{}, amdRootKey...)
+	notSelfSigned[20] ^= 0xff
+	_, err = GetRootKey(newFirmware(notSelfSigned), 1)
+	require.Error(t, err)
+	assert.IsType(t, ErrInvalidFormat{}, err)
+	assert.Contains(t, err.Error(), "root key must have certifying key ID == key ID")
+}
+
+func TestKeySetVerifyChain(t *testing.T) {
+	rootKey, err := NewRootKey(bytes.NewBuffer(amdRootKey))
+	require.NoError(t, err)
+	keySet := NewKeySet()
+	require.NoError(t, keySet.AddKey(rootKey, AMDRootKey))
+	oemKey, err := NewTokenKey(bytes.NewBuffer(oemSigningKey), keySet)
+	require.NoError(t, err)
+	require.NoError(t, keySet.AddKey(oemKey, OEMKey))
+	assert.Empty(t, keySet.VerifyChain())
+
+	// keys without certificate cannot be chained to the root key
+	require.NoError(t, parseKeyDatabase(keyDB, keySet, nil))
+	errs := keySet.VerifyChain()
+	assert.Len(t, errs, len(keySet.AllKeyIDs())-2)
+	for _, err := range errs {
+		assert.Contains(t, err.Error(), "has no known certificate")
+	}
+
+	// the signer of the OEM key is missing
+	orphanSet := NewKeySet()
+	require.NoError(t, orphanSet.AddKey(oemKey, OEMKey))
+	errs = orphanSet.VerifyChain()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "does not contain an AMD root key")
+	var unknownSigningKeyErr *UnknownSigningKeyError
+	assert.True(t, errors.As(errs[1], &unknownSigningKeyErr))
+}
+
 func TestKeySuite(t *testing.T) {
 	suite.Run(t, new(KeySuite))
 }
@@ -9,8 +9,11 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
+	"github.com/jedib0t/go-pretty/v6/table"
+
 	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
 )
 
@@ -104,6 +107,52 @@ func (kdb KeySet) KeysetFromType(keyType KeyType) (KeySet, error) {
 	return keySet, nil
 }
 
+// KeyInfo is a summary of a key stored in KeySet
+type KeyInfo struct {
+	KeyID           KeyID
+	CertifyingKeyID KeyID
+	KeyType         KeyType
+	KeyUsageFlag    KeyUsageFlag
+	// ModulusSize is the size of the modulus in bits
+	ModulusSize uint32
+}
+
+// List returns the summary of all keys in the KeySet sorted by KeyID
+func (kdb KeySet) List() []KeyInfo {
+	keyTypes := make(map[KeyID]KeyType, len(kdb.db))
+	for keyType, keyIDs := range kdb.keyType {
+		for _, keyID := range keyIDs {
+			keyTypes[keyID] = keyType
+		}
+	}
+
+	result := make([]KeyInfo, 0, len(kdb.db))
+	for keyID, key := range kdb.db {
+		result = append(result, KeyInfo{
+			KeyID:           keyID,
+			CertifyingKeyID: KeyID(key.data.CertifyingKeyID),
+			KeyType:         keyTypes[keyID],
+			KeyUsageFlag:    key.data.KeyUsageFlag,
+			ModulusSize:     key.data.ModulusSize,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].KeyID[:], result[j].KeyID[:]) < 0
+	})
+	return result
+}
+
+// Table returns the summary of all keys in the KeySet in an ASCII table format
+func (kdb KeySet) Table() string {
+	t := table.NewWriter()
+	t.SetTitle("Keys")
+	t.AppendHeader(table.Row{"Key ID", "Certifying Key ID", "Key Type", "Key Usage Flag", "Modulus Size"})
+	for _, info := range kdb.List() {
+		t.AppendRow(table.Row{info.KeyID.Hex(), info.CertifyingKeyID.Hex(), info.KeyType, info.KeyUsageFlag, info.ModulusSize})
+	}
+	return t.Render()
+}
+
 // keyDBHeader represents the header pre-pended to keydb structure
 type keyDBHeader struct {
 	DataSize        uint32
@@ -137,7 +186,7 @@ func extractKeydbHeader(buff io.Reader) (*keyDBHeader, error) {
 // parseKeyDatabase parses a raw buffer representing a key database and adds all extracted key
 // to the associated keySet. The raw buffer must be stripped off of the PSP header and the signature
 // appended at the end.
-func parseKeyDatabase(rawDB []byte, keySet KeySet) error {
+func parseKeyDatabase(rawDB []byte, keySet KeySet, certificate *keyCertificate) error {
 
 	buff := bytes.NewBuffer(rawDB)
 	_, err := extractKeydbHeader(buff)
@@ -153,6 +202,7 @@ func parseKeyDatabase(rawDB []byte, keySet KeySet) error {
 		if err != nil {
 			return fmt.Errorf("could not extract key entry from key database: %w", err)
 		}
+		key.certificate = certificate
 		if err := keySet.AddKey(key, KeyDatabaseKey); err != nil {
 			return fmt.Errorf("cannot add key to key database: %w", err)
 		}
@@ -170,13 +220,9 @@ func getKeysFromDatabase(amdFw *amd_manifest.AMDFirmware, pspLevel uint, keySet
 	 * Public Root Keys, so we are forced to use PSP Directory
 	 * Level 1 to get them, and not have it configurable
 	 */
-	pubKeyBytes, err := ExtractPSPEntry(amdFw, 1, AMDPublicKeyEntry)
+	amdPk, err := GetRootKey(amdFw, 1)
 	if err != nil {
-		return fmt.Errorf("could not extract raw PSP entry for AMD Public Key: %w", err)
-	}
-	amdPk, err := NewRootKey(bytes.NewBuffer(pubKeyBytes))
-	if err != nil {
-		return addFirmwareItemToError(err, newPSPDirectoryEntryItem(uint8(pspLevel), AMDPublicKeyEntry))
+		return err
 	}
 
 	// All keys which get added the KeySet are supposed to be trusted. AMD root key is trusted as a result of being matched against a
@@ -210,5 +256,82 @@ func getKeysFromDatabase(amdFw *amd_manifest.AMDFirmware, pspLevel uint, keySet
 			fmt.Errorf("length of key database entry (%d) is less than pspHeader length (%d)", len(signedData), pspHeaderSize))
 	}
 
-	return parseKeyDatabase(signedData[pspHeaderSize:], keySet)
+	// the keys of the database are certified by the signature of the whole database
+	signature := signedBlob.Signature()
+	certificate := &keyCertificate{
+		signingKeyID: signature.SigningKey().data.KeyID,
+		signedData:   signedData,
+		signature:    signature.signature,
+	}
+	return parseKeyDatabase(signedData[pspHeaderSize:], keySet, certificate)
+}
+
+// VerifyChain validates that every key of the set chains up to an AMD root key of the set.
+// For each key which is not a root key, the signing key is looked up in the set and the
+// signature which certified the key when it was extracted is validated again: the key token
+// for the ABL and OEM keys, the key database for the keys extracted from it.
+//
+// One error is returned per broken link, the chain is intact if none is returned.
+func (kdb KeySet) VerifyChain() []error {
+	roots := make(map[KeyID]bool)
+	for _, keyID := range kdb.keyType[AMDRootKey] {
+		roots[keyID] = true
+	}
+
+	var errs []error
+	if len(roots) == 0 {
+		errs = append(errs, fmt.Errorf("key set does not contain an AMD root key"))
+	}
+
+	keyIDs := kdb.AllKeyIDs()
+	sort.Slice(keyIDs, func(i, j int) bool {
+		return keyIDs[i].Hex() < keyIDs[j].Hex()
+	})
+	for _, keyID := range keyIDs {
+		if roots[keyID] {
+			continue
+		}
+		if err := kdb.verifyLink(kdb.db[keyID]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := kdb.verifyReachesRoot(keyID, roots); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// verifyLink validates the signature which certifies a key against its signing key
+func (kdb KeySet) verifyLink(key *Key) error {
+	keyID := key.data.KeyID
+	if key.certificate == nil {
+		return fmt.Errorf("key %s has no known certificate", keyID.Hex())
+	}
+	signingKey := kdb.GetKey(key.certificate.signingKeyID)
+	if signingKey == nil {
+		return fmt.Errorf("could not verify key %s: %w", keyID.Hex(), &UnknownSigningKeyError{keyID: key.certificate.signingKeyID})
+	}
+	if _, err := NewSignedBlob(key.certificate.signature, key.certificate.signedData, signingKey); err != nil {
+		return fmt.Errorf("could not verify key %s: %w", keyID.Hex(), err)
+	}
+	return nil
+}
+
+// verifyReachesRoot follows the signing keys starting from keyID and checks that a root key
+// is reached. Broken links are reported by verifyLink, only loops are reported here.
+func (kdb KeySet) verifyReachesRoot(keyID KeyID, roots map[KeyID]bool) error {
+	visited := make(map[KeyID]bool)
+	for id := keyID; !roots[id]; {
+		if visited[id] {
+			return fmt.Errorf("chain of key %s does not reach a root key", keyID.Hex())
+		}
+		visited[id] = true
+		key := kdb.GetKey(id)
+		if key == nil || key.certificate == nil {
+			return nil
+		}
+		id = key.certificate.signingKeyID
+	}
+	return nil
 }
@@ -13,6 +13,9 @@ import (
 // pspHeaderSize represents the size of the header pre-pended to PSP binaries
 const pspHeaderSize = 0x100
 
+// pspBinaryCookie is the value of the headerVersion field of PSP binaries ("$PS1")
+const pspBinaryCookie = 0x31535024
+
 // signedDataStart indicates the start address of signed data content within a PSP binary
 const signedDataStart = 0x0
 
@@ -74,6 +77,11 @@ func (h *PspHeader) Version() uint32 {
 	return h.data.HeaderVersion
 }
 
+// ImageVersion returns the imageVersion field of the pspHeader structure
+func (h *PspHeader) ImageVersion() uint32 {
+	return h.data.ImageVersion
+}
+
 // PSPBinary represents a generic PSPBinary with pre-pended header structure
 type PSPBinary struct {
 
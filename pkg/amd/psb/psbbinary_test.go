@@ -8,14 +8,20 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"io"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 
+	"github.com/linuxboot/fiano/pkg/amd/apcb"
 	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -65,7 +71,7 @@ func (suite *PsbBinarySuite) TestPSBBinarySignedData() {
 	// in the key database. We could just extract the single key from the
 	// database, but it's easier to parse the database as a whole
 	keySet := NewKeySet()
-	err := parseKeyDatabase(keyDB, keySet)
+	err := parseKeyDatabase(keyDB, keySet, nil)
 	require.NoError(suite.T(), err)
 
 	psbBinary, err := newPSPBinary(smuOffChipFirmware)
@@ -119,6 +125,28 @@ func (suite *PsbBinarySuite) TestPSBBinaryPSPDirectoryLevel2Entry() {
 	require.Equal(suite.T(), expectedZeroSmuOffChipFirmwareHash, shaSmuOffChipFirmwareHash)
 }
 
+func (suite *PsbBinarySuite) TestPSBBinaryDumpDirectoryTable() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	pspFirmware := amdFw.PSPFirmware()
+
+	var buf bytes.Buffer
+	r, err := DumpDirectoryTable(amdFw, PSPDirectoryLevel2, &buf)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), pspFirmware.PSPDirectoryLevel2.Range, r)
+	require.Equal(suite.T(), uint64(buf.Len()), r.Length)
+	require.Equal(suite.T(), suite.firmwareImage[r.Offset:r.Offset+r.Length], buf.Bytes())
+	require.Equal(suite.T(), uint32(amd_manifest.PSPDirectoryTableLevel2Cookie), binary.LittleEndian.Uint32(buf.Bytes()))
+
+	buf.Reset()
+	r, err = DumpDirectoryTable(amdFw, BIOSDirectoryLevel1, &buf)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), pspFirmware.BIOSDirectoryLevel1.Range, r)
+	require.Equal(suite.T(), uint32(amd_manifest.BIOSDirectoryTableCookie), binary.LittleEndian.Uint32(buf.Bytes()))
+}
+
 func (suite *PsbBinarySuite) TestPSBBinaryPSPDirectoryLevel2EntryValidation() {
 	// Test positive validation of PSP Directory entry
 	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
@@ -213,6 +241,69 @@ func (suite *PsbBinarySuite) TestPSBBinaryPSPDirectoryLevel2EntryWrongKeys() {
 	require.True(suite.T(), errors.As(signatureValidation[0].err, &unknownSigningKeyErr))
 }
 
+func (suite *PsbBinarySuite) TestPSBBinaryKeyDatabaseSignature() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	// keyDatabaseImage returns a copy of the firmware image with the key database
+	// of PSP Directory Level 2 modified at the given offset from the beginning of the blob
+	keyDatabaseImage := func(offset uint64, value byte) *amd_manifest.AMDFirmware {
+		image := make([]byte, len(suite.firmwareImage))
+		copy(image, suite.firmwareImage)
+		amdFw, err := ParseAMDFirmware(image)
+		require.NoError(suite.T(), err)
+		for _, entry := range amdFw.PSPFirmware().PSPDirectoryLevel2.Entries {
+			if entry.Type == KeyDatabaseEntry {
+				amdFw.Firmware().ImageBytes()[entry.LocationOrValue+offset] = value
+			}
+		}
+		return amdFw
+	}
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	keySet, err := GetKeys(amdFw, 2)
+	require.NoError(suite.T(), err)
+	databaseKeys, err := keySet.KeysetFromType(KeyDatabaseKey)
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), databaseKeys.AllKeyIDs())
+
+	// a key of the database is modified, the signature by the AMD root key does not validate anymore
+	_, err = GetKeys(keyDatabaseImage(pspHeaderSize+0x60, 0xff), 2)
+	require.Error(suite.T(), err)
+	var sigErr *SignatureCheckError
+	require.True(suite.T(), errors.As(err, &sigErr))
+
+	// signatureParameters indicates the id of the signing key and is placed at 56 bytes offset
+	// from the beginning of the blob: the database is not signed by the AMD root key anymore
+	_, err = GetKeys(keyDatabaseImage(56, 0x99), 2)
+	require.Error(suite.T(), err)
+	var unknownSigningKeyErr *UnknownSigningKeyError
+	require.True(suite.T(), errors.As(err, &unknownSigningKeyErr))
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryKeySetVerifyChain() {
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	keySet, err := GetKeys(amdFw, 2)
+	require.NoError(suite.T(), err)
+	require.Empty(suite.T(), keySet.VerifyChain())
+
+	// corrupt the copy of the key database held by a database key: the link to the root key breaks
+	databaseKeys, err := keySet.KeysetFromType(KeyDatabaseKey)
+	require.NoError(suite.T(), err)
+	key := keySet.GetKey(databaseKeys.AllKeyIDs()[0])
+	certificate := *key.certificate
+	certificate.signedData = append([]byte{}, certificate.signedData...)
+	certificate.signedData[pspHeaderSize+0x60] ^= 0xff
+	key.certificate = &certificate
+
+	errs := keySet.VerifyChain()
+	require.Len(suite.T(), errs, 1)
+	require.Contains(suite.T(), errs[0].Error(), key.data.KeyID.Hex())
+	var sigErr *SignatureCheckError
+	require.True(suite.T(), errors.As(errs[0], &sigErr))
+}
+
 func (suite *PsbBinarySuite) TestPSBBinaryDumpEntry() {
 	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
 
@@ -293,6 +384,524 @@ func (suite *PsbBinarySuite) TestPSBBinaryPatchEntry() {
 	require.Equal(suite.T(), sha256.Sum256(buffImage.Bytes()[end:]), sha256.Sum256(suite.firmwareImage[end:]))
 }
 
+func (suite *PsbBinarySuite) TestPSBBinaryPatchEntryFixDirectoryChecksums() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), ValidateDirectoryChecksums(amdFw))
+
+	// corrupt the checksum of PSP directory level 2
+	checksumOffset := amdFw.PSPFirmware().PSPDirectoryLevel2.Range.Offset + 4
+	suite.firmwareImage[checksumOffset] ^= 0xff
+	require.Error(suite.T(), ValidateDirectoryChecksums(amdFw))
+
+	smuOffChipFirmwareType := amd_manifest.PSPDirectoryTableEntryType(0x12)
+	buff := bytes.NewBuffer(make([]byte, len(smuOffChipFirmware)))
+	buffImage := bytes.NewBuffer(nil)
+
+	n, err := PatchPSPEntry(amdFw, 2, smuOffChipFirmwareType, buff, buffImage, PatchOptionFixDirectoryChecksums(true))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), len(suite.firmwareImage), n)
+
+	patchedFw, err := ParseAMDFirmware(buffImage.Bytes())
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), ValidateDirectoryChecksums(patchedFw))
+
+	// the original image must be left untouched
+	require.Error(suite.T(), ValidateDirectoryChecksums(amdFw))
+
+	require.NoError(suite.T(), FixDirectoryChecksums(amdFw))
+	require.NoError(suite.T(), ValidateDirectoryChecksums(amdFw))
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryResizePSPEntry() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	for _, tc := range []struct {
+		name    string
+		entryID amd_manifest.PSPDirectoryTableEntryType
+		newSize int
+		moved   bool
+	}{
+		{name: "shrink", entryID: 0x13, newSize: 0x1000},
+		{name: "grow_into_slack", entryID: 0x12, newSize: 0x4a0},
+		{name: "grow", entryID: 0x12, newSize: 0x900, moved: true},
+	} {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			original, err := GetPSPEntry(amdFw.PSPFirmware(), 2, tc.entryID)
+			require.NoError(t, err)
+
+			newData := bytes.Repeat([]byte{0x5a}, tc.newSize)
+			buffImage := bytes.NewBuffer(nil)
+			n, err := ResizePSPEntry(amdFw, 2, tc.entryID, newData, buffImage)
+			require.NoError(t, err)
+			require.Equal(t, len(suite.firmwareImage), n)
+
+			resizedFw, err := ParseAMDFirmware(buffImage.Bytes())
+			require.NoError(t, err)
+			require.NoError(t, ValidateDirectoryChecksums(resizedFw))
+
+			data, err := ExtractPSPEntry(resizedFw, 2, tc.entryID)
+			require.NoError(t, err)
+			require.Equal(t, newData, data)
+
+			// all other entries must keep their content and alignment
+			for _, entry := range amdFw.PSPFirmware().PSPDirectoryLevel2.Entries {
+				if entry.Type == tc.entryID {
+					continue
+				}
+				resized, err := GetPSPEntry(resizedFw.PSPFirmware(), 2, entry.Type)
+				require.NoError(t, err)
+				require.Equal(t, entry.Size, resized.Size)
+				if entry.LocationOrValue >= FirmwareLen || entry.Size == 0xffffffff {
+					require.Equal(t, entry.LocationOrValue, resized.LocationOrValue)
+					continue
+				}
+				if !tc.moved || entry.LocationOrValue < original.LocationOrValue {
+					require.Equal(t, entry.LocationOrValue, resized.LocationOrValue)
+				} else {
+					require.Greater(t, resized.LocationOrValue, entry.LocationOrValue)
+					require.Equal(t, entry.LocationOrValue%relocationAlignment, resized.LocationOrValue%relocationAlignment)
+				}
+
+				expected, err := ExtractPSPEntry(amdFw, 2, entry.Type)
+				require.NoError(t, err)
+				actual, err := ExtractPSPEntry(resizedFw, 2, entry.Type)
+				require.NoError(t, err)
+				require.Equal(t, expected, actual)
+			}
+
+			// the space released by shrinking is erased
+			if end := original.LocationOrValue + uint64(original.Size); uint64(tc.newSize) < uint64(original.Size) {
+				require.True(t, isFreeSpace(buffImage.Bytes()[original.LocationOrValue+uint64(tc.newSize):end]))
+			}
+		})
+	}
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryResizePSPEntryPhysicalBIOSEntry() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	pspFirmware := amdFw.PSPFirmware()
+
+	// growing entry 0x12 moves the entries stored after it into the space following the last one
+	var moveEnd uint64
+	for _, e := range pspFirmware.PSPDirectoryLevel2.Entries {
+		if e.Size != 0xffffffff && e.LocationOrValue < FirmwareLen && e.LocationOrValue+uint64(e.Size) > moveEnd {
+			moveEnd = e.LocationOrValue + uint64(e.Size)
+		}
+	}
+
+	// switch BIOS directory level 2 to physical addresses and point its first entry to that space
+	const biosEntrySourceAddressOffset = 8
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	biosDirectory := pspFirmware.BIOSDirectoryLevel2
+	headerSize := uint64(binary.Size(biosDirectory.BIOSDirectoryTableHeader))
+	binary.LittleEndian.PutUint32(image[biosDirectory.Range.Offset+headerSize-4:], biosDirectory.Reserved&^(0x3<<directoryAddressModeShift))
+	binary.LittleEndian.PutUint64(image[biosDirectory.Range.Offset+headerSize+biosEntrySourceAddressOffset:], 1<<32-FirmwareLen+moveEnd)
+
+	modifiedFw, err := ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1<<32-FirmwareLen+moveEnd, modifiedFw.PSPFirmware().BIOSDirectoryLevel2.Entries[0].SourceAddress)
+
+	_, err = ResizePSPEntry(modifiedFw, 2, 0x12, bytes.Repeat([]byte{0x5a}, 0x900), bytes.NewBuffer(nil))
+	require.Error(suite.T(), err)
+
+	// the entry is not affected if the following entries are not moved
+	_, err = ResizePSPEntry(modifiedFw, 2, 0x12, bytes.Repeat([]byte{0x5a}, 0x4a0), bytes.NewBuffer(nil))
+	require.NoError(suite.T(), err)
+}
+
+func (suite *PsbBinarySuite) TestPSBBinarySetEFSDirectoryPointer() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	pspFirmware := amdFw.PSPFirmware()
+	oldRange := pspFirmware.PSPDirectoryLevel1.Range
+
+	// find free space for a copy of PSP directory level 1
+	structures, err := getOtherStructures(pspFirmware, pspFirmware.PSPDirectoryLevel1, FirmwareLen)
+	require.NoError(suite.T(), err)
+	newOffset := uint64(0)
+	for offset := uint64(relocationAlignment); offset+oldRange.Length <= FirmwareLen; offset += relocationAlignment {
+		candidate := bytes2.Range{Offset: offset, Length: oldRange.Length}
+		if !isFreeSpace(suite.firmwareImage[candidate.Offset:candidate.End()]) {
+			continue
+		}
+		free := true
+		for _, r := range structures {
+			if candidate.Intersect(r) {
+				free = false
+				break
+			}
+		}
+		if free {
+			newOffset = offset
+			break
+		}
+	}
+	require.NotZero(suite.T(), newOffset)
+
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	copy(image[newOffset:], image[oldRange.Offset:oldRange.End()])
+	relocatedFw, err := ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+
+	// the target must be a directory of the same type within the image
+	_, err = SetEFSDirectoryPointer(relocatedFw, PSPDirectoryLevel1, newOffset+relocationAlignment, io.Discard)
+	require.Error(suite.T(), err)
+	_, err = SetEFSDirectoryPointer(relocatedFw, PSPDirectoryLevel1, FirmwareLen, io.Discard)
+	require.Error(suite.T(), err)
+	_, err = SetEFSDirectoryPointer(relocatedFw, BIOSDirectoryLevel1, newOffset, io.Discard)
+	require.Error(suite.T(), err)
+	_, err = SetEFSDirectoryPointer(relocatedFw, PSPDirectoryLevel2, newOffset, io.Discard)
+	require.Error(suite.T(), err)
+
+	buffImage := bytes.NewBuffer(nil)
+	n, err := SetEFSDirectoryPointer(relocatedFw, PSPDirectoryLevel1, newOffset, buffImage)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), len(suite.firmwareImage), n)
+
+	// erase the original table, so that it cannot be found by scanning the image
+	result := buffImage.Bytes()
+	for i := oldRange.Offset; i < oldRange.End(); i++ {
+		result[i] = erasedByte
+	}
+	resultFw, err := ParseAMDFirmware(result)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), uint32(newOffset), resultFw.PSPFirmware().EmbeddedFirmware.PSPDirectoryTablePointer)
+	require.Equal(suite.T(), bytes2.Range{Offset: newOffset, Length: oldRange.Length}, resultFw.PSPFirmware().PSPDirectoryLevel1.Range)
+	require.Equal(suite.T(), pspFirmware.PSPDirectoryLevel1.Entries, resultFw.PSPFirmware().PSPDirectoryLevel1.Entries)
+	require.Equal(suite.T(), pspFirmware.BIOSDirectoryLevel1.Range, resultFw.PSPFirmware().BIOSDirectoryLevel1.Range)
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryGetABLStages() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	// the image has a single ABL stage, turn the unlock debug image entry into the second stage
+	tableRange := amdFw.PSPFirmware().PSPDirectoryLevel2.Range
+	unlockDebugImage, err := GetPSPEntry(amdFw.PSPFirmware(), 2, UnlockDebugImageEntry)
+	require.NoError(suite.T(), err)
+
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	for idx, entry := range amdFw.PSPFirmware().PSPDirectoryLevel2.Entries {
+		if entry.Type == UnlockDebugImageEntry {
+			entryOffset := tableRange.Offset + uint64(binary.Size(amd_manifest.PSPDirectoryTableHeader{})) + uint64(idx)*amd_manifest.PSPDirectoryTableEntrySize
+			image[entryOffset] = uint8(AGESABinary0Entry + 1)
+		}
+	}
+	header := PSPHeaderData{HeaderVersion: pspBinaryCookie, ImageVersion: 0x12345678}
+	var headerBytes bytes.Buffer
+	require.NoError(suite.T(), binary.Write(&headerBytes, binary.LittleEndian, header))
+	copy(image[unlockDebugImage.LocationOrValue:], headerBytes.Bytes())
+
+	patchedFw, err := ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	stages, err := GetABLStages(patchedFw, 2)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), stages, 2)
+
+	abl0, err := GetPSPEntry(amdFw.PSPFirmware(), 2, AGESABinary0Entry)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), uint8(0), stages[0].Index)
+	require.Equal(suite.T(), AGESABinary0Entry, stages[0].EntryType)
+	require.Equal(suite.T(), bytes2.Range{Offset: abl0.LocationOrValue, Length: uint64(abl0.Size)}, stages[0].Range)
+	require.Len(suite.T(), stages[0].Data, int(abl0.Size))
+	// the content of the stage is erased in the test image, there is no version
+	require.False(suite.T(), stages[0].HasVersion)
+
+	require.Equal(suite.T(), uint8(1), stages[1].Index)
+	require.Equal(suite.T(), AGESABinary0Entry+1, stages[1].EntryType)
+	require.Equal(suite.T(), unlockDebugImage.LocationOrValue, stages[1].Range.Offset)
+	require.True(suite.T(), stages[1].HasVersion)
+	require.Equal(suite.T(), uint32(0x12345678), stages[1].Version)
+	require.Contains(suite.T(), stages[1].String(), "version 0x12345678")
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryVerifyAgainstRoot() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	result, err := VerifyAgainstRoot(amdFw, rootKeyFingerprint)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), KeyID(rootKeyID), result.RootKeyID)
+	require.Equal(suite.T(), rootKeyFingerprint, result.RootKeyFingerprint)
+	require.True(suite.T(), result.RootKeyMatches)
+
+	// The keys validate against the root key, but the RTM volume of the test image
+	// does not match its signature, so the chain stops at the BIOS.
+	require.Len(suite.T(), result.Signatures, 1)
+	require.NotNil(suite.T(), result.Signatures[0].SigningKey())
+	require.Equal(suite.T(), KeyID(oemKeyID), result.Signatures[0].SigningKey().data.KeyID)
+	require.Error(suite.T(), result.Signatures[0].Error())
+	require.False(suite.T(), result.Passed())
+	require.Contains(suite.T(), result.Error().Error(), "RTM volume")
+	require.Contains(suite.T(), result.String(), "Verification: FAIL")
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryVerifyAgainstRootWrongKey() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	var wrongFingerprint KeyFingerprint
+	copy(wrongFingerprint[:], rootKeyFingerprint[:])
+	wrongFingerprint[0] ^= 0xff
+
+	result, err := VerifyAgainstRoot(amdFw, wrongFingerprint)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), KeyID(rootKeyID), result.RootKeyID)
+	require.False(suite.T(), result.RootKeyMatches)
+	require.False(suite.T(), result.Passed())
+	require.Empty(suite.T(), result.Signatures)
+	require.Contains(suite.T(), result.Error().Error(), "does not match the expected fingerprint")
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryVerifyAgainstRootForgedKey() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	rootKey, err := GetRootKey(amdFw, 1)
+	require.NoError(suite.T(), err)
+	entry, err := GetPSPEntry(amdFw.PSPFirmware(), 1, AMDPublicKeyEntry)
+	require.NoError(suite.T(), err)
+
+	// forge a self-signed root key which declares the expected key ID, but
+	// carries a different modulus
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	modulusOffset := entry.LocationOrValue + 64 + uint64(len(rootKey.data.Exponent))
+	image[modulusOffset] ^= 0xff
+
+	forgedFw, err := ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	result, err := VerifyAgainstRoot(forgedFw, rootKeyFingerprint)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), KeyID(rootKeyID), result.RootKeyID)
+	require.NotEqual(suite.T(), rootKeyFingerprint, result.RootKeyFingerprint)
+	require.False(suite.T(), result.RootKeyMatches)
+	require.False(suite.T(), result.Passed())
+	require.Empty(suite.T(), result.Signatures)
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryDebugUnlockReport() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	// The test image reserves erased regions for the debug unlock tokens of both levels
+	report := GetDebugUnlockReport(amdFw)
+	require.False(suite.T(), report.TokenPresent)
+	var tokenEntry *DebugUnlockEntry
+	for idx, entry := range report.Entries {
+		require.False(suite.T(), entry.Type == PSPEntryType(TokenUnlockDataEntry) && entry.Populated)
+		if entry.Level == 2 && entry.Type == PSPEntryType(TokenUnlockDataEntry) {
+			tokenEntry = &report.Entries[idx]
+		}
+	}
+	require.NotNil(suite.T(), tokenEntry)
+	require.Equal(suite.T(), "PSP_TOKEN_UNLOCK_DATA", tokenEntry.Type.String())
+
+	// Put a token into the level 2 region
+	copy(suite.firmwareImage[tokenEntry.LocationOrValue:], []byte{0x01, 0x00, 0x00, 0x00, 0xde, 0xad, 0xbe, 0xef})
+	amdFw, err = ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	report = GetDebugUnlockReport(amdFw)
+	require.True(suite.T(), report.TokenPresent)
+	require.Contains(suite.T(), report.String(), "WARNING: PSP debug unlock token is present")
+	populated := 0
+	for _, entry := range report.Entries {
+		if entry.Populated && entry.Type == PSPEntryType(TokenUnlockDataEntry) {
+			populated++
+			require.Equal(suite.T(), uint(2), entry.Level)
+		}
+	}
+	require.Equal(suite.T(), 1, populated)
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryUpdateAPCB() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	compressedAPCB, err := os.ReadFile("../apcb/testdata/apcb_binary.xz")
+	require.NoError(suite.T(), err)
+	xzReader, err := xz.NewReader(bytes.NewReader(compressedAPCB))
+	require.NoError(suite.T(), err)
+	apcbBinary, err := io.ReadAll(xzReader)
+	require.NoError(suite.T(), err)
+
+	// The APCB entries of the test image are zeroed, put a real APCB into one of them
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	entry, err := GetBIOSEntry(amdFw.PSPFirmware(), 2, amd_manifest.APCBDataEntry, 0)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), uint32(len(apcbBinary)), entry.Size)
+	copy(suite.firmwareImage[entry.SourceAddress:], apcbBinary)
+	amdFw, err = ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	modifiedAPCB, err := ExtractBIOSEntry(amdFw, 2, amd_manifest.APCBDataEntry, 0)
+	require.NoError(suite.T(), err)
+	modifiedAPCB = append([]byte{}, modifiedAPCB...)
+	require.NoError(suite.T(), apcb.UpsertToken(0x3E7D5274, 0xff, 0xffff, uint32(0x1234), modifiedAPCB))
+
+	var buffImage bytes.Buffer
+	n, err := UpdateAPCB(amdFw, 2, 0, modifiedAPCB, &buffImage)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), len(suite.firmwareImage), n)
+
+	amdFw, err = ParseAMDFirmware(buffImage.Bytes())
+	require.NoError(suite.T(), err)
+	updatedAPCB, err := ExtractBIOSEntry(amdFw, 2, amd_manifest.APCBDataEntry, 0)
+	require.NoError(suite.T(), err)
+	tokens, err := apcb.ParseAPCBBinaryTokens(updatedAPCB)
+	require.NoError(suite.T(), err)
+	var found bool
+	for _, token := range tokens {
+		if token.ID == 0x3E7D5274 {
+			found = true
+			require.Equal(suite.T(), uint32(0x1234), token.NumValue())
+		}
+	}
+	require.True(suite.T(), found)
+
+	// The checksum is updated, the whole APCB sums up to zero
+	var sum uint8
+	for _, b := range updatedAPCB[:binary.LittleEndian.Uint32(updatedAPCB[8:])] {
+		sum += b
+	}
+	require.Zero(suite.T(), sum)
+
+	// A modified APCB with a stale checksum is accepted
+	staleAPCB := append([]byte{}, updatedAPCB...)
+	staleAPCB[16] ^= 0xff
+	_, err = UpdateAPCB(amdFw, 2, 0, staleAPCB, io.Discard)
+	require.NoError(suite.T(), err)
+
+	_, err = UpdateAPCB(amdFw, 2, 0, append(updatedAPCB, 0), io.Discard)
+	require.Error(suite.T(), err)
+	_, err = UpdateAPCB(amdFw, 2, 0, make([]byte, 16), io.Discard)
+	require.Error(suite.T(), err)
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryValidateEFS() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+	statuses, err := ValidateEFS(amdFw)
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), statuses)
+	for _, status := range statuses {
+		require.NoError(suite.T(), status.Err)
+	}
+
+	// keep the PSP directory pointer, make a BIOS directory pointer refer to erased space
+	efsOffset := amdFw.PSPFirmware().EmbeddedFirmwareRange.Offset
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	dangling := uint32(FirmwareLen - relocationAlignment)
+	require.True(suite.T(), isFreeSpace(image[dangling:]))
+	binary.LittleEndian.PutUint32(image[efsOffset+0x18:], dangling)
+
+	amdFw, err = ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	statuses, err = ValidateEFS(amdFw)
+	require.Error(suite.T(), err)
+	require.Contains(suite.T(), err.Error(), "BIOSDirectoryTableFamily17hModels00h0FhPointer")
+
+	var valid, broken []string
+	for _, status := range statuses {
+		if status.Err == nil {
+			valid = append(valid, status.Name)
+		} else {
+			broken = append(broken, status.Name)
+			require.Equal(suite.T(), uint64(dangling), status.Offset)
+		}
+	}
+	require.Contains(suite.T(), valid, "PSPDirectoryTablePointer")
+	require.Equal(suite.T(), []string{"BIOSDirectoryTableFamily17hModels00h0FhPointer"}, broken)
+
+	// an entry of PSP directory located outside of the image is an error
+	pspDirectory := amdFw.PSPFirmware().PSPDirectoryLevel1
+	pspDirectoryRange := amdFw.PSPFirmware().PSPDirectoryLevel1.Range
+	idx := -1
+	for i, entry := range pspDirectory.Entries {
+		if entry.Size != 0 && entry.Size != 0xffffffff {
+			idx = i
+			break
+		}
+	}
+	require.NotEqual(suite.T(), -1, idx)
+	copy(image, suite.firmwareImage)
+	locationOffset := pspDirectoryRange.Offset + uint64(binary.Size(pspDirectory.PSPDirectoryTableHeader)) +
+		uint64(idx)*amd_manifest.PSPDirectoryTableEntrySize + pspEntryLocationOffset
+	binary.LittleEndian.PutUint64(image[locationOffset:], 0x80000000)
+
+	amdFw, err = ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	_, err = ValidateEFS(amdFw)
+	require.Error(suite.T(), err)
+	require.Contains(suite.T(), err.Error(), "PSPDirectoryTablePointer")
+}
+
+func (suite *PsbBinarySuite) TestPSBBinaryGetPSPVersion() {
+	require.Equal(suite.T(), FirmwareLen, len(suite.firmwareImage))
+
+	amdFw, err := ParseAMDFirmware(suite.firmwareImage)
+	require.NoError(suite.T(), err)
+
+	// the content of the bootloader is erased in the test image
+	_, err = GetPSPVersion(amdFw, 1)
+	require.IsType(suite.T(), ErrInvalidFormat{}, err)
+
+	bootloader, err := GetPSPEntry(amdFw.PSPFirmware(), 1, amd_manifest.PSPBootloaderFirmwareEntry)
+	require.NoError(suite.T(), err)
+	image := make([]byte, len(suite.firmwareImage))
+	copy(image, suite.firmwareImage)
+	header := PSPHeaderData{HeaderVersion: pspBinaryCookie, ImageVersion: 0x00240a43}
+	var headerBytes bytes.Buffer
+	require.NoError(suite.T(), binary.Write(&headerBytes, binary.LittleEndian, header))
+	copy(image[bootloader.LocationOrValue:], headerBytes.Bytes())
+
+	patchedFw, err := ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	version, err := GetPSPVersion(patchedFw, 1)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "0.24.A.43", version)
+
+	// without the bootloader entry
+	tableRange := amdFw.PSPFirmware().PSPDirectoryLevel1.Range
+	for idx, entry := range amdFw.PSPFirmware().PSPDirectoryLevel1.Entries {
+		if entry.Type == amd_manifest.PSPBootloaderFirmwareEntry {
+			entryOffset := tableRange.Offset + uint64(binary.Size(amd_manifest.PSPDirectoryTableHeader{})) + uint64(idx)*amd_manifest.PSPDirectoryTableEntrySize
+			image[entryOffset] = 0x7f
+		}
+	}
+	patchedFw, err = ParseAMDFirmware(image)
+	require.NoError(suite.T(), err)
+	_, err = GetPSPVersion(patchedFw, 1)
+	require.IsType(suite.T(), ErrNotFound{}, err)
+}
+
 func TestPsbBinarySuite(t *testing.T) {
 	suite.Run(t, new(PsbBinarySuite))
 }
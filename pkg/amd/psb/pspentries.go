@@ -31,9 +31,15 @@ const (
 	// SMUOffChipFirmware2Entry points to a region of firmware containing SMU offchip firmware
 	SMUOffChipFirmware2Entry amd_manifest.PSPDirectoryTableEntryType = 0x12
 
+	// AMDSecureDebugKeyEntry represents the key used to sign the debug unlock tokens
+	AMDSecureDebugKeyEntry amd_manifest.PSPDirectoryTableEntryType = 0x09
+
 	// UnlockDebugImageEntry points to a region of firmware containing PSP early secure unlock debug image
 	UnlockDebugImageEntry amd_manifest.PSPDirectoryTableEntryType = 0x13
 
+	// TokenUnlockDataEntry points to a region of firmware containing a PSP debug unlock token
+	TokenUnlockDataEntry amd_manifest.PSPDirectoryTableEntryType = 0x22
+
 	// SecurityPolicyBinaryEntry points to a region of firmware containing Security Policy Binary
 	SecurityPolicyBinaryEntry amd_manifest.PSPDirectoryTableEntryType = 0x24
 
@@ -200,6 +206,21 @@ func getPSPTable(pspFirmware *amd_manifest.PSPFirmware, pspLevel uint) (*amd_man
 	return nil, fmt.Errorf("cannot extract raw PSP entry, invalid PSP Directory Level requested: %d", pspLevel)
 }
 
+// getPSPDirectories returns all the PSP directories of the level in the order of preference.
+// There may be several directories of level 2, e.g. the A and B (recovery) copies.
+func getPSPDirectories(pspFirmware *amd_manifest.PSPFirmware, pspLevel uint) ([]*amd_manifest.PSPDirectoryTable, error) {
+	switch pspLevel {
+	case 1:
+		if pspFirmware.PSPDirectoryLevel1 == nil {
+			return nil, nil
+		}
+		return []*amd_manifest.PSPDirectoryTable{pspFirmware.PSPDirectoryLevel1}, nil
+	case 2:
+		return pspFirmware.PSPDirectoriesLevel2, nil
+	}
+	return nil, fmt.Errorf("cannot extract raw PSP entry, invalid PSP Directory Level requested: %d", pspLevel)
+}
+
 // OutputPSPEntries outputs the PSP entries in an ASCII table format
 func OutputPSPEntries(amdFw *amd_manifest.AMDFirmware) error {
 	pspDirectoryLevel1Table, err := getPSPTable(amdFw.PSPFirmware(), 1)
@@ -243,6 +264,10 @@ func OutputPSPEntries(amdFw *amd_manifest.AMDFirmware) error {
 		}
 		t.Render()
 	}
+
+	if report := GetDebugUnlockReport(amdFw); report.TokenPresent {
+		fmt.Printf("\n%s", report)
+	}
 	return nil
 }
 
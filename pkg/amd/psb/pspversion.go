@@ -0,0 +1,39 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"fmt"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// FormatPSPVersion formats the image version of a PSP binary the way AMD tools
+// report it: four hexadecimal bytes separated by dots, most significant first
+func FormatPSPVersion(version uint32) string {
+	return fmt.Sprintf("%X.%X.%X.%X", uint8(version>>24), uint8(version>>16), uint8(version>>8), uint8(version))
+}
+
+// GetPSPVersion returns the version of the PSP firmware, i.e. the formatted image version
+// found in the PSP binary header of the PSP bootloader entry of the PSP directory of the given level
+func GetPSPVersion(amdFw *amd_manifest.AMDFirmware, level uint) (string, error) {
+	data, err := ExtractPSPEntry(amdFw, level, amd_manifest.PSPBootloaderFirmwareEntry)
+	if err != nil {
+		return "", err
+	}
+
+	item := newPSPDirectoryEntryItem(uint8(level), amd_manifest.PSPBootloaderFirmwareEntry)
+	if len(data) < pspHeaderSize {
+		return "", newErrInvalidFormatWithItem(item, fmt.Errorf("entry of size %d is too small for a PSP binary header", len(data)))
+	}
+	hdr, err := newPspHeader(data)
+	if err != nil {
+		return "", newErrInvalidFormatWithItem(item, fmt.Errorf("could not parse the PSP binary header: %w", err))
+	}
+	if hdr.Version() != pspBinaryCookie {
+		return "", newErrInvalidFormatWithItem(item, fmt.Errorf("unexpected PSP binary header version 0x%x", hdr.Version()))
+	}
+	return FormatPSPVersion(hdr.ImageVersion()), nil
+}
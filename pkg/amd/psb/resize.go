@@ -0,0 +1,268 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+const (
+	// relocationAlignment is the granularity of moving entries, it keeps
+	// the 4K alignment of the relocated entries
+	relocationAlignment = 0x1000
+
+	// the offsets of the fields within a binary PSP directory entry
+	pspEntrySizeOffset     = 4
+	pspEntryLocationOffset = 8
+
+	// erasedByte fills the space released by a resized or relocated entry
+	erasedByte = 0xff
+)
+
+// addressMode defines how the location of a directory entry is interpreted
+type addressMode uint8
+
+const (
+	// addressModePhysical is an x86 physical address of the memory mapped flash
+	addressModePhysical addressMode = 0
+	// addressModeFlashOffset is an offset from the start of the image
+	addressModeFlashOffset addressMode = 1
+	// addressModeDirectoryRelative is an offset from the start of the directory header
+	addressModeDirectoryRelative addressMode = 2
+	// addressModeSlotRelative is an offset from the start of the slot containing the directory
+	addressModeSlotRelative addressMode = 3
+
+	// the address mode of a directory is stored in bits 29-30 of AdditionalInfo,
+	// the address mode of an entry is stored in bits 62-63 of its location
+	directoryAddressModeShift = 29
+	entryAddressModeShift     = 62
+	entryLocationMask         = 1<<entryAddressModeShift - 1
+)
+
+// decodeEntryLocation returns the offset within the image of the entry location stored in
+// a directory at directoryOffset. The returned flag is false if the location is not within
+// the image, for example if it is a value or a memory destination.
+func decodeEntryLocation(additionalInfo uint32, location, directoryOffset, imageSize uint64) (uint64, bool, error) {
+	mode := addressMode(additionalInfo>>directoryAddressModeShift) & 0x3
+	if mode == addressModeDirectoryRelative || mode == addressModeSlotRelative {
+		// entries of such directories specify their own address mode
+		mode = addressMode(location >> entryAddressModeShift)
+	}
+	offset := location & entryLocationMask
+	switch mode {
+	case addressModePhysical:
+		// the flash is mapped right below 4GiB, the other locations are treated as offsets
+		if mappedBase := uint64(1<<32) - imageSize; offset >= mappedBase && offset < 1<<32 {
+			offset -= mappedBase
+		}
+	case addressModeFlashOffset:
+	case addressModeDirectoryRelative:
+		offset += directoryOffset
+	default:
+		return 0, false, fmt.Errorf("unsupported address mode %d of location 0x%x", mode, location)
+	}
+	return offset, offset < imageSize, nil
+}
+
+// ResizePSPEntry replaces an entry in PSP directory with newData, which may have a different
+// size than the original entry. If the new data does not fit before the next entry of the same
+// directory, the entries stored after the resized entry are moved, so that they keep their
+// alignment, and the directory is updated accordingly. Moving the entries requires free space
+// after the last of them. Entries are never moved backwards, the space released by shrinking
+// is erased. The checksums of all directories are recalculated. The modified firmware is written
+// into `w` writer object.
+func ResizePSPEntry(amdFw *amd_manifest.AMDFirmware, pspLevel uint, entryID amd_manifest.PSPDirectoryTableEntryType, newData []byte, w io.Writer) (int, error) {
+	pspFirmware := amdFw.PSPFirmware()
+	entry, table, err := findPSPEntry(pspFirmware, pspLevel, entryID)
+	if err != nil {
+		return 0, err
+	}
+	directory, err := GetPSPDirectoryOfLevel(pspLevel)
+	if err != nil {
+		return 0, err
+	}
+
+	image := amdFw.Firmware().ImageBytes()
+	imageSize := uint64(len(image))
+	entryItem := newPSPDirectoryEntryItem(uint8(pspLevel), entryID)
+
+	start, inImage, err := decodeEntryLocation(table.AdditionalInfo, entry.LocationOrValue, table.Range.Offset, imageSize)
+	if err != nil {
+		return 0, newErrInvalidFormatWithItem(entryItem, err)
+	}
+	end := start + uint64(entry.Size)
+	if !inImage {
+		return 0, newErrInvalidFormatWithItem(entryItem, fmt.Errorf("entry location 0x%x is not within the image", entry.LocationOrValue))
+	}
+	if err := checkBoundaries(start, end, image); err != nil {
+		return 0, newErrInvalidFormatWithItem(entryItem, err)
+	}
+	if uint64(len(newData)) > 0xffffffff {
+		return 0, fmt.Errorf("new entry size %d exceeds 32 bits", len(newData))
+	}
+	newEnd := start + uint64(len(newData))
+
+	// find the entries of the directory that follow the resized one
+	var following []int
+	moveStart, moveEnd := imageSize, uint64(0)
+	for idx, e := range table.Entries {
+		if e.Size == 0xffffffff {
+			// the entry holds a value
+			continue
+		}
+		offset, inImage, err := decodeEntryLocation(table.AdditionalInfo, e.LocationOrValue, table.Range.Offset, imageSize)
+		if err != nil {
+			return 0, newErrInvalidFormatWithItem(newPSPDirectoryEntryItem(uint8(pspLevel), e.Type), err)
+		}
+		if !inImage || offset <= start {
+			continue
+		}
+		if offset < end {
+			return 0, newErrInvalidFormatWithItem(entryItem, fmt.Errorf("entry 0x%x overlaps the resized entry", e.Type))
+		}
+		if offset < moveStart {
+			moveStart = offset
+		}
+		if offset+uint64(e.Size) > moveEnd {
+			moveEnd = offset + uint64(e.Size)
+		}
+		following = append(following, idx)
+	}
+	if moveEnd > imageSize {
+		return 0, newErrInvalidFormatWithItem(newDirectoryItem(directory), fmt.Errorf("entries end at 0x%x beyond the image", moveEnd))
+	}
+
+	// the following entries are moved only if the new data grows into the next of them
+	var shift uint64
+	affected := bytes2.Range{Offset: start, Length: end - start}
+	free := bytes2.Range{Offset: end}
+	if newEnd > end {
+		free.Length = newEnd - end
+		affected.Length = newEnd - start
+	}
+	if len(following) != 0 && newEnd > moveStart {
+		shift = (newEnd - moveStart + relocationAlignment - 1) / relocationAlignment * relocationAlignment
+		free = bytes2.Range{Offset: moveEnd, Length: shift}
+		affected.Length = moveEnd + shift - start
+	}
+	if free.End() > imageSize {
+		return 0, newErrInvalidFormatWithItem(entryItem, fmt.Errorf("not enough space to grow the entry to %d bytes", len(newData)))
+	}
+
+	// check that the modified area contains only the moved entries and free space
+	if !isFreeSpace(image[free.Offset:free.End()]) {
+		return 0, newErrInvalidFormatWithItem(entryItem, fmt.Errorf("no free space at [0x%x:0x%x] to grow the entry", free.Offset, free.End()))
+	}
+	structures, err := getOtherStructures(pspFirmware, table, imageSize)
+	if err != nil {
+		return 0, newErrInvalidFormatWithItem(entryItem, err)
+	}
+	for _, r := range structures {
+		if affected.Intersect(r) {
+			return 0, newErrInvalidFormatWithItem(entryItem, fmt.Errorf("cannot modify data at [0x%x:0x%x] overlapping a structure at [0x%x:0x%x]",
+				affected.Offset, affected.End(), r.Offset, r.End()))
+		}
+	}
+
+	result := make([]byte, len(image))
+	copy(result, image)
+	fill := func(from, to uint64) {
+		for i := from; i < to; i++ {
+			result[i] = erasedByte
+		}
+	}
+	if shift > 0 {
+		fill(start, moveStart+shift)
+		copy(result[moveStart+shift:], image[moveStart:moveEnd])
+	} else {
+		fill(start, end)
+	}
+	copy(result[start:], newData)
+
+	// update the directory, the moved locations keep their address mode
+	entryOffset := func(idx int) uint64 {
+		return table.Range.Offset + uint64(binary.Size(table.PSPDirectoryTableHeader)) + uint64(idx)*amd_manifest.PSPDirectoryTableEntrySize
+	}
+	for idx, e := range table.Entries {
+		if e.Type == entryID {
+			binary.LittleEndian.PutUint32(result[entryOffset(idx)+pspEntrySizeOffset:], uint32(len(newData)))
+		}
+	}
+	if shift > 0 {
+		for _, idx := range following {
+			location := table.Entries[idx].LocationOrValue + shift
+			binary.LittleEndian.PutUint64(result[entryOffset(idx)+pspEntryLocationOffset:], location)
+		}
+	}
+	if err := fixDirectoryChecksums(pspFirmware, result, false); err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(result)
+	if err != nil {
+		return n, fmt.Errorf("could not write the resized firmware: %w", err)
+	}
+	return n, nil
+}
+
+// getOtherStructures returns the ranges of the embedded firmware structure, all directory tables,
+// and the entries of all directories except the given PSP directory. The locations of the entries
+// are decoded according to the address mode of their directory, an error is returned if one of
+// them cannot be decoded.
+func getOtherStructures(pspFirmware *amd_manifest.PSPFirmware, table *amd_manifest.PSPDirectoryTable, imageSize uint64) ([]bytes2.Range, error) {
+	result := []bytes2.Range{pspFirmware.EmbeddedFirmwareRange}
+	addEntry := func(additionalInfo uint32, directory bytes2.Range, location uint64, size uint32) error {
+		if size == 0 || size == 0xffffffff {
+			return nil
+		}
+		offset, inImage, err := decodeEntryLocation(additionalInfo, location, directory.Offset, imageSize)
+		if err != nil {
+			return err
+		}
+		if inImage {
+			result = append(result, bytes2.Range{Offset: offset, Length: uint64(size)})
+		}
+		return nil
+	}
+	pspDirectories := pspFirmware.PSPDirectoriesLevel2
+	if pspFirmware.PSPDirectoryLevel1 != nil {
+		pspDirectories = append([]*amd_manifest.PSPDirectoryTable{pspFirmware.PSPDirectoryLevel1}, pspDirectories...)
+	}
+	for _, pspDirectory := range pspDirectories {
+		result = append(result, pspDirectory.Range)
+		if pspDirectory == table {
+			continue
+		}
+		for _, e := range pspDirectory.Entries {
+			if err := addEntry(pspDirectory.AdditionalInfo, pspDirectory.Range, e.LocationOrValue, e.Size); err != nil {
+				return nil, fmt.Errorf("PSP directory entry 0x%x: %w", e.Type, err)
+			}
+		}
+	}
+	for _, biosDirectory := range []*amd_manifest.BIOSDirectoryTable{pspFirmware.BIOSDirectoryLevel1, pspFirmware.BIOSDirectoryLevel2} {
+		if biosDirectory == nil {
+			continue
+		}
+		result = append(result, biosDirectory.Range)
+		for _, e := range biosDirectory.Entries {
+			// the last field of the BIOS directory header holds the same additional info as
+			// the one of the PSP directory header
+			if err := addEntry(biosDirectory.Reserved, biosDirectory.Range, e.SourceAddress, e.Size); err != nil {
+				return nil, fmt.Errorf("BIOS directory entry 0x%x: %w", e.Type, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+func isFreeSpace(data []byte) bool {
+	return len(bytes.Trim(data, "\x00")) == 0 || len(bytes.Trim(data, "\xff")) == 0
+}
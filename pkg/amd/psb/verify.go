@@ -0,0 +1,106 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package psb
+
+import (
+	"fmt"
+	"strings"
+
+	amd_manifest "github.com/linuxboot/fiano/pkg/amd/manifest"
+)
+
+// RootVerificationResult is the outcome of VerifyAgainstRoot
+type RootVerificationResult struct {
+	// RootKeyID is the ID of the AMD root key found in the firmware
+	RootKeyID KeyID
+	// RootKeyFingerprint is the fingerprint of the AMD root key found in the firmware
+	RootKeyFingerprint KeyFingerprint
+	// RootKeyMatches tells whether RootKeyFingerprint is the expected one
+	RootKeyMatches bool
+	// Signatures holds the results of the signature checks done below the root key
+	Signatures []SignatureValidationResult
+
+	err error
+}
+
+// Passed returns true if the root key matches and the whole chain of trust validates
+func (r *RootVerificationResult) Passed() bool {
+	return r.err == nil
+}
+
+// Error returns the reason why the verification failed, nil if it passed
+func (r *RootVerificationResult) Error() error {
+	return r.err
+}
+
+// String returns a string representation of the verification result
+func (r *RootVerificationResult) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "Root key ID: 0x%s\n", r.RootKeyID.Hex())
+	fmt.Fprintf(&s, "Root key fingerprint: %s\n", r.RootKeyFingerprint.Hex())
+	fmt.Fprintf(&s, "Root key matches: %v\n", r.RootKeyMatches)
+	for idx := range r.Signatures {
+		fmt.Fprintf(&s, "%s", r.Signatures[idx].String())
+	}
+	if r.err != nil {
+		fmt.Fprintf(&s, "Verification: FAIL (%s)\n", r.err.Error())
+	} else {
+		fmt.Fprintf(&s, "Verification: PASS\n")
+	}
+	return s.String()
+}
+
+// VerifyAgainstRoot checks that the AMD root key embedded in the firmware is the one identified by
+// expectedRootKeyFingerprint and that the chain of trust from the root key down to the BIOS validates:
+// the key database, the ABL and OEM signing keys and the RTM volume signed by the OEM key. The chain is
+// checked on the level 2 directories if the firmware has them, on the level 1 directories otherwise.
+//
+// The root key is matched by the fingerprint of its key material (see Key.Fingerprint) rather than by
+// its key ID: the root key is self-signed, so a forged key can declare any key ID.
+//
+// Failed checks are reported through the returned result, an error is returned only if the root key
+// itself cannot be extracted from the firmware.
+func VerifyAgainstRoot(amdFw *amd_manifest.AMDFirmware, expectedRootKeyFingerprint KeyFingerprint) (*RootVerificationResult, error) {
+	// The AMD root key is stored only in PSP Directory Level 1
+	rootKey, err := GetRootKey(amdFw, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := rootKey.Fingerprint()
+	result := &RootVerificationResult{
+		RootKeyID:          rootKey.data.KeyID,
+		RootKeyFingerprint: fingerprint,
+		RootKeyMatches:     fingerprint == expectedRootKeyFingerprint,
+	}
+	if !result.RootKeyMatches {
+		// nothing below an unexpected root key can be trusted
+		result.err = fmt.Errorf("root key %s with fingerprint %s does not match the expected fingerprint %s",
+			result.RootKeyID.Hex(), fingerprint.Hex(), expectedRootKeyFingerprint.Hex())
+		return result, nil
+	}
+
+	level := uint(1)
+	if amdFw.PSPFirmware().BIOSDirectoryLevel2 != nil {
+		level = 2
+	}
+
+	// GetKeys validates the signatures of the keys it extracts against the root key
+	if _, err := GetKeys(amdFw, level); err != nil {
+		result.err = fmt.Errorf("could not validate the keys signed by the root key: %w", err)
+		return result, nil
+	}
+
+	rtmResult, err := ValidateRTM(amdFw, level)
+	if err != nil {
+		result.err = fmt.Errorf("could not validate the RTM volume: %w", err)
+		return result, nil
+	}
+	result.Signatures = append(result.Signatures, *rtmResult)
+	if rtmResult.Error() != nil {
+		result.err = fmt.Errorf("RTM volume signature is not valid: %w", rtmResult.Error())
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// values from the FSP 2.0 spec
+var (
+	ExtendedSignature = [4]byte{'F', 'S', 'P', 'E'}
+)
+
+// ExtendedInfoHeaderLength is the size of FSP_INFO_EXTENDED_HEADER without
+// the producer data.
+const ExtendedInfoHeaderLength = 24
+
+// ExtendedInfoHeader represents the FSP_INFO_EXTENDED_HEADER structure as
+// defined by Intel. It is followed by FspProducerDataSize bytes of producer
+// data, whose format is defined by the FSP producer.
+type ExtendedInfoHeader struct {
+	Signature           [4]byte
+	Length              uint32
+	Revision            uint8
+	Reserved            uint8
+	FspProducerID       [6]byte
+	FspProducerRevision uint32
+	FspProducerDataSize uint32
+}
+
+// ProducerData is the vendor specific data found after the extended info
+// header.
+type ProducerData struct {
+	ExtendedInfoHeader
+	// Offset of Data in the FSP image.
+	Offset uint64
+	Data   []byte
+}
+
+// NewExtendedInfoHeader creates an ExtendedInfoHeader from a byte buffer.
+func NewExtendedInfoHeader(b []byte) (*ExtendedInfoHeader, error) {
+	if len(b) < ExtendedInfoHeaderLength {
+		return nil, fmt.Errorf("short FSP Extended Info Header length %d; want at least %d", len(b), ExtendedInfoHeaderLength)
+	}
+	var hdr ExtendedInfoHeader
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+	if hdr.Signature != ExtendedSignature {
+		return nil, fmt.Errorf("invalid signature %v; want %v", hdr.Signature, ExtendedSignature)
+	}
+	if uint64(hdr.Length) < ExtendedInfoHeaderLength+uint64(hdr.FspProducerDataSize) {
+		return nil, fmt.Errorf("invalid extended header length %d; want at least %d", hdr.Length, ExtendedInfoHeaderLength+uint64(hdr.FspProducerDataSize))
+	}
+	return &hdr, nil
+}
+
+// ProducerData locates the extended info header in the FSP image and returns
+// its producer data. The extended header is expected between the beginning
+// of the image and the configuration region (or the end of the image, if
+// there is no configuration region). The image must start at the FSP base,
+// so that CfgRegionOffset is an offset within it. It returns nil if there is
+// no extended header or if it has no producer data.
+func (ih CommonInfoHeader) ProducerData(image []byte) (*ProducerData, error) {
+	end := uint64(len(image))
+	if ih.CfgRegionOffset != 0 && uint64(ih.CfgRegionOffset) < end {
+		end = uint64(ih.CfgRegionOffset)
+	}
+
+	// The extended header is the content of a raw section, so it is 4 bytes aligned.
+	for offset := uint64(0); offset+ExtendedInfoHeaderLength <= end; offset += 4 {
+		if !bytes.Equal(image[offset:offset+4], ExtendedSignature[:]) {
+			continue
+		}
+		hdr, err := NewExtendedInfoHeader(image[offset:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended header at %#x: %w", offset, err)
+		}
+		if hdr.FspProducerDataSize == 0 {
+			return nil, nil
+		}
+		dataOffset := offset + ExtendedInfoHeaderLength
+		dataEnd := dataOffset + uint64(hdr.FspProducerDataSize)
+		if dataEnd > end {
+			return nil, fmt.Errorf("producer data [%#x:%#x] exceeds the limit %#x", dataOffset, dataEnd, end)
+		}
+		return &ProducerData{
+			ExtendedInfoHeader: *hdr,
+			Offset:             dataOffset,
+			Data:               image[dataOffset:dataEnd],
+		}, nil
+	}
+	return nil, nil
+}
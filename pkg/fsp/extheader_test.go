@@ -0,0 +1,103 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makeFSPImage builds an FSP image starting with FSPTestHeaderRev6, followed
+// by an extended header with the given producer data at extOffset. The
+// configuration region is at 0x24c as specified by the header.
+func makeFSPImage(t *testing.T, extOffset int, producerData []byte) []byte {
+	image := make([]byte, 0x300)
+	copy(image, FSPTestHeaderRev6)
+	if producerData == nil {
+		return image
+	}
+	ext := ExtendedInfoHeader{
+		Signature:           ExtendedSignature,
+		Length:              uint32(ExtendedInfoHeaderLength + len(producerData)),
+		Revision:            1,
+		FspProducerID:       [6]byte{'I', 'N', 'T', 'E', 'L', ' '},
+		FspProducerRevision: 0x10,
+		FspProducerDataSize: uint32(len(producerData)),
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, ext); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(producerData)
+	copy(image[extOffset:], buf.Bytes())
+	return image
+}
+
+func TestProducerData(t *testing.T) {
+	hdr, err := NewInfoHeader(FSPTestHeaderRev6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	producerData := []byte("OEM metadata")
+	image := makeFSPImage(t, 0x80, producerData)
+
+	pd, err := hdr.ProducerData(image)
+	if err != nil {
+		t.Fatalf("ProducerData failed: %v", err)
+	}
+	if pd == nil {
+		t.Fatal("ProducerData returned nil; want producer data")
+	}
+	if pd.Offset != 0x80+ExtendedInfoHeaderLength {
+		t.Errorf("Invalid producer data offset %#x; want %#x", pd.Offset, 0x80+ExtendedInfoHeaderLength)
+	}
+	if !bytes.Equal(pd.Data, producerData) {
+		t.Errorf("Invalid producer data %q; want %q", pd.Data, producerData)
+	}
+	if !bytes.Equal(image[pd.Offset:pd.Offset+uint64(len(pd.Data))], producerData) {
+		t.Errorf("Producer data offset %#x does not point to the data", pd.Offset)
+	}
+	if string(pd.FspProducerID[:]) != "INTEL " {
+		t.Errorf("Invalid producer ID %q; want %q", pd.FspProducerID, "INTEL ")
+	}
+	if pd.FspProducerRevision != 0x10 {
+		t.Errorf("Invalid producer revision %#x; want %#x", pd.FspProducerRevision, 0x10)
+	}
+}
+
+func TestProducerDataAbsent(t *testing.T) {
+	hdr, err := NewInfoHeader(FSPTestHeaderRev6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, image := range map[string][]byte{
+		"no extended header": makeFSPImage(t, 0, nil),
+		"no producer data":   makeFSPImage(t, 0x80, []byte{}),
+		// The extended header is expected before the configuration region.
+		"after cfg region": makeFSPImage(t, 0x250, []byte("OEM metadata")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			pd, err := hdr.ProducerData(image)
+			if err != nil {
+				t.Fatalf("ProducerData failed: %v", err)
+			}
+			if pd != nil {
+				t.Errorf("ProducerData returned %+v; want nil", pd)
+			}
+		})
+	}
+}
+
+func TestProducerDataExceedsCfgRegion(t *testing.T) {
+	hdr, err := NewInfoHeader(FSPTestHeaderRev6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := makeFSPImage(t, 0x230, make([]byte, 0x40))
+	if _, err := hdr.ProducerData(image); err == nil {
+		t.Error("ProducerData succeeded; want error for data overlapping the cfg region")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"testing"
+)
+
+// FuzzNewInfoHeader checks that malformed FSP info headers are rejected with
+// an error instead of a panic. The seed corpus consists of the headers used
+// by the unit tests and of the inputs in testdata/fuzz/FuzzNewInfoHeader.
+//
+// To run the fuzzer:
+// go test -fuzz=FuzzNewInfoHeader ./pkg/fsp
+func FuzzNewInfoHeader(f *testing.F) {
+	for _, seed := range [][]byte{
+		FSPTestHeaderRev3,
+		FSPTestHeaderRev4,
+		FSPTestHeaderRev5,
+		FSPTestHeaderRev6,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		hdr, err := NewInfoHeader(b)
+		if err != nil {
+			return
+		}
+		_ = hdr.Summary()
+		if _, err := hdr.ProducerData(b); err != nil {
+			return
+		}
+	})
+}
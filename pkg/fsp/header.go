@@ -16,7 +16,6 @@ import (
 )
 
 // TODO support FSP versions < 2.0
-// TODO implement FSP_INFO_EXTENDED_HEADER
 
 // FSP 2.0 specification
 // https://www.intel.com/content/dam/www/public/us/en/documents/technical-specifications/fsp-architecture-spec-v2.pdf
@@ -137,6 +136,28 @@ func (ih CommonInfoHeader) Summary() string {
 	return s
 }
 
+// Capabilities lists the API entry points an FSP component provides.
+type Capabilities struct {
+	SupportsMultiPhaseSiInit bool
+	SupportsNotifyPhase      bool
+	SupportsTempRAMInit      bool
+	SupportsMemoryInit       bool
+	SupportsSiliconInit      bool
+}
+
+// Capabilities returns which API entry points are implemented by the
+// component. An entry point is considered present if its offset in the
+// header is non-zero.
+func (ih CommonInfoHeader) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsMultiPhaseSiInit: ih.FspMultiPhaseSiInitEntryOffset != 0,
+		SupportsNotifyPhase:      ih.NotifyPhaseEntryOffset != 0,
+		SupportsTempRAMInit:      ih.TempRAMInitEntryOffset != 0,
+		SupportsMemoryInit:       ih.FSPMemoryInitEntryOffset != 0,
+		SupportsSiliconInit:      ih.FSPSiliconInitEntryOffset != 0,
+	}
+}
+
 // ImageRevision is the image revision field of the FSP info header.
 type ImageRevision uint64
 
@@ -346,6 +367,9 @@ func NewInfoHeader(b []byte) (*CommonInfoHeader, error) {
 	if hdr.HeaderLength < l {
 		return nil, fmt.Errorf("invalid header length %d; want at least %d", hdr.HeaderLength, l)
 	}
+	if uint32(len(b)) < l {
+		return nil, fmt.Errorf("short FSP Info Header length %d; want at least %d for header revision %d", len(b), l, hdr.HeaderRevision)
+	}
 
 	// now that we know it's an info header spec 2.0, re-read the
 	// buffer to fill the whole header.
@@ -6,6 +6,7 @@ package fsp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"testing"
 )
 
@@ -338,6 +339,42 @@ func TestComponentAttribute(t *testing.T) {
 	}
 }
 
+func TestCapabilities(t *testing.T) {
+	// There is no FSP-M fixture, derive one from the FSP-S revision 4 header:
+	// set the type to FSP-M and move the entry points to FspMemoryInit.
+	fspM := append([]byte{}, FSPTestHeaderRev4...)
+	binary.LittleEndian.PutUint16(fspM[0x22:], 0x2003)
+	binary.LittleEndian.PutUint32(fspM[0x38:], 0)
+	binary.LittleEndian.PutUint32(fspM[0x3c:], 0x298)
+	binary.LittleEndian.PutUint32(fspM[0x44:], 0)
+
+	var tests = []struct {
+		name string
+		buf  []byte
+		typ  Type
+		want Capabilities
+	}{
+		{"FSP-T", FSPTestHeaderRev6, TypeT, Capabilities{SupportsTempRAMInit: true}},
+		{"FSP-M", fspM, TypeM, Capabilities{SupportsMemoryInit: true}},
+		{"FSP-S", FSPTestHeaderRev3, TypeS, Capabilities{SupportsNotifyPhase: true, SupportsSiliconInit: true}},
+		{"FSP-S multi-phase", FSPTestHeaderRev5, TypeS, Capabilities{SupportsMultiPhaseSiInit: true, SupportsNotifyPhase: true, SupportsSiliconInit: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hdr, err := NewInfoHeader(test.buf)
+			if err != nil {
+				t.Fatalf("NewInfoHeader failed to parse FSP header: %v", err)
+			}
+			if hdr.ComponentAttribute.Type() != test.typ {
+				t.Errorf("Invalid FSP type: got %v; want %v", hdr.ComponentAttribute.Type(), test.typ)
+			}
+			if got := hdr.Capabilities(); got != test.want {
+				t.Errorf("Invalid capabilities: got %+v; want %+v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestImageAttribute(t *testing.T) {
 	// graphics display not supported, dispatch mode not supported
 	ia := ImageAttribute(0)
@@ -384,3 +421,10 @@ func TestNewInfoHeaderNonZeroReserved1(t *testing.T) {
 		t.Errorf("Expected error, got nil")
 	}
 }
+
+func TestNewInfoHeaderTruncatedRev6(t *testing.T) {
+	_, err := NewInfoHeader(FSPTestHeaderRev6[:HeaderV3Length])
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
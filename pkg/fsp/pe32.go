@@ -0,0 +1,62 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"fmt"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// ComponentPE32 is the PE32 image holding the code of an FSP component.
+type ComponentPE32 struct {
+	// Offset of the PE32 image from the beginning of the component. The
+	// component is loaded at ImageBase, so the entry offsets of the info
+	// header can be translated into addresses of the PE32 image with it.
+	Offset uint64
+	Data   []byte
+}
+
+// ExtractComponentPE32 parses the firmware volume of an FSP component and
+// returns its first PE32 section. The image must start at the beginning of the
+// component, as for ProducerData, and is limited to the ImageSize of its info
+// header. FSP components execute in place, so only PE32 sections stored
+// directly in the files of the volume are considered, not those in
+// compressed or otherwise encapsulated sections.
+func ExtractComponentPE32(image []byte, component *CommonInfoHeader) (*ComponentPE32, error) {
+	if uint64(component.ImageSize) > uint64(len(image)) {
+		return nil, fmt.Errorf("image size %#x exceeds the image length %#x", component.ImageSize, len(image))
+	}
+	if component.ImageSize != 0 {
+		image = image[:component.ImageSize]
+	}
+	fv, err := uefi.NewFirmwareVolume(image, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the component firmware volume: %w", err)
+	}
+
+	// Follow the layout of the volume as NewFirmwareVolume parses it to know
+	// where each section is.
+	fileOffset := fv.DataOffset
+	for _, file := range fv.Files {
+		fileOffset = uefi.Align8(fileOffset)
+		sectionOffset := file.DataOffset
+		for _, s := range file.Sections {
+			sectionOffset = uefi.Align4(sectionOffset)
+			if s.Header.Type == uefi.SectionTypePE32 {
+				if uint64(len(s.Buf())) < s.HeaderLen() {
+					return nil, fmt.Errorf("PE32 section of file %v is too short: %d bytes", file.Header.GUID, len(s.Buf()))
+				}
+				return &ComponentPE32{
+					Offset: fileOffset + sectionOffset + s.HeaderLen(),
+					Data:   s.Data(),
+				}, nil
+			}
+			sectionOffset += uint64(s.Header.ExtendedSize)
+		}
+		fileOffset += file.Header.ExtendedSize
+	}
+	return nil, fmt.Errorf("no PE32 section found in the component firmware volume")
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestExtractComponentPE32(t *testing.T) {
+	// The SEC volume of OVMF has the same layout as an FSP component: a
+	// firmware volume with the code in a PE32 section of its first file.
+	image, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := &CommonInfoHeader{ImageSize: uint32(len(image))}
+
+	pe, err := ExtractComponentPE32(image, hdr)
+	if err != nil {
+		t.Fatalf("ExtractComponentPE32 failed: %v", err)
+	}
+	if !bytes.HasPrefix(pe.Data, []byte("MZ")) {
+		t.Errorf("PE32 image does not start with the DOS signature: % x", pe.Data[:2])
+	}
+	if end := pe.Offset + uint64(len(pe.Data)); end > uint64(len(image)) || !bytes.Equal(image[pe.Offset:end], pe.Data) {
+		t.Errorf("PE32 image is not found at offset %#x of the component", pe.Offset)
+	}
+
+	hdr.ImageSize = uint32(len(image)) + 1
+	if _, err := ExtractComponentPE32(image, hdr); err == nil {
+		t.Errorf("image size beyond the image is not reported")
+	}
+}
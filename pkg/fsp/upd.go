@@ -0,0 +1,70 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"fmt"
+)
+
+// DefaultUPD returns the default UPD (Updatable Product Data) stored in the
+// configuration region of the FSP component. The image must start at the FSP
+// base, so that CfgRegionOffset is an offset within it. It returns nil if the
+// component has no configuration region.
+func (ih CommonInfoHeader) DefaultUPD(image []byte) ([]byte, error) {
+	if ih.CfgRegionSize == 0 {
+		return nil, nil
+	}
+	start := uint64(ih.CfgRegionOffset)
+	end := start + uint64(ih.CfgRegionSize)
+	if end > uint64(len(image)) {
+		return nil, fmt.Errorf("configuration region [%#x:%#x] exceeds the image size %#x", start, end, len(image))
+	}
+	return image[start:end], nil
+}
+
+// UPDDifference is a run of consecutive bytes which differ between a UPD
+// and its defaults.
+type UPDDifference struct {
+	Offset uint64
+	Old    []byte
+	New    []byte
+}
+
+// DiffUPD compares the current UPD with the default one and returns the runs
+// of bytes which differ, in offset order. Both UPDs must have the same size.
+func DiffUPD(current, defaults []byte) ([]UPDDifference, error) {
+	if len(current) != len(defaults) {
+		return nil, fmt.Errorf("UPD size %d does not match the default UPD size %d", len(current), len(defaults))
+	}
+	var diffs []UPDDifference
+	for i := 0; i < len(current); i++ {
+		if current[i] == defaults[i] {
+			continue
+		}
+		start := i
+		for i < len(current) && current[i] != defaults[i] {
+			i++
+		}
+		diffs = append(diffs, UPDDifference{
+			Offset: uint64(start),
+			Old:    defaults[start:i],
+			New:    current[start:i],
+		})
+	}
+	return diffs, nil
+}
+
+// UPDDiffSummary prints a multi-line summary of the differences, one line
+// per run of bytes.
+func UPDDiffSummary(diffs []UPDDifference) string {
+	if len(diffs) == 0 {
+		return "UPD matches the defaults\n"
+	}
+	s := ""
+	for _, d := range diffs {
+		s += fmt.Sprintf("Offset %#04x : % x -> % x\n", d.Offset, d.Old, d.New)
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultUPD(t *testing.T) {
+	hdr, err := NewInfoHeader(FSPTestHeaderRev6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := makeFSPImage(t, 0, nil)
+	copy(image[0x24c:], "UPD_SIGN")
+
+	upd, err := hdr.DefaultUPD(image)
+	if err != nil {
+		t.Fatalf("DefaultUPD failed: %v", err)
+	}
+	if len(upd) != 0x68 || string(upd[:8]) != "UPD_SIGN" {
+		t.Errorf("got %d bytes UPD starting with %q; want 0x68 bytes starting with %q", len(upd), upd[:8], "UPD_SIGN")
+	}
+
+	if _, err := hdr.DefaultUPD(image[:0x280]); err == nil {
+		t.Errorf("expected an error for a truncated image")
+	}
+}
+
+func TestDiffUPD(t *testing.T) {
+	defaults := []byte{0x55, 0x50, 0x44, 0x00, 0x01, 0x02, 0x03, 0x04, 0x00, 0x00}
+	current := append([]byte{}, defaults...)
+	current[3] = 0x01
+	current[6] = 0xaa
+	current[7] = 0xbb
+	current[9] = 0xff
+
+	diffs, err := DiffUPD(current, defaults)
+	if err != nil {
+		t.Fatalf("DiffUPD failed: %v", err)
+	}
+	want := []UPDDifference{
+		{Offset: 3, Old: []byte{0x00}, New: []byte{0x01}},
+		{Offset: 6, Old: []byte{0x03, 0x04}, New: []byte{0xaa, 0xbb}},
+		{Offset: 9, Old: []byte{0x00}, New: []byte{0xff}},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %+v; want %+v", diffs, want)
+	}
+	if s := UPDDiffSummary(diffs); !strings.Contains(s, "Offset 0x0006 : 03 04 -> aa bb\n") {
+		t.Errorf("unexpected summary:\n%s", s)
+	}
+
+	if diffs, err := DiffUPD(defaults, defaults); err != nil || len(diffs) != 0 {
+		t.Errorf("got %v, %v for identical UPDs; want no differences", diffs, err)
+	}
+	if _, err := DiffUPD(current[:4], defaults); err == nil {
+		t.Errorf("expected an error for UPDs of different sizes")
+	}
+}
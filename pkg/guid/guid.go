@@ -89,7 +89,10 @@ func (u GUID) String() string {
 
 // MarshalJSON implements the marshaller interface.
 // This allows us to actually read and edit the json file
-func (u *GUID) MarshalJSON() ([]byte, error) {
+// It has a value receiver, so the GUID is rendered in its canonical form (see
+// String) even when it is not addressable, e.g. as a map value or as a field
+// of a struct marshaled by value.
+func (u GUID) MarshalJSON() ([]byte, error) {
 	return []byte(`{"GUID" : "` + u.String() + `"}`), nil
 }
 
@@ -5,6 +5,7 @@
 package guid
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 )
@@ -73,6 +74,20 @@ func TestMarshal(t *testing.T) {
 	}
 }
 
+func TestMarshalByValue(t *testing.T) {
+	j, err := json.Marshal(struct {
+		Field GUID
+		Map   map[string]GUID
+	}{exampleGUID, map[string]GUID{"Key": exampleGUID}})
+	if err != nil {
+		t.Fatalf("No error was expected, got %v", err)
+	}
+	expected := `{"Field":{"GUID":"` + exampleGUIDString + `"},"Map":{"Key":{"GUID":"` + exampleGUIDString + `"}}}`
+	if string(j) != expected {
+		t.Errorf("JSON strings are not equal. Expected:\n%v\ngot:\n%v", expected, string(j))
+	}
+}
+
 func TestUnmarshal(t *testing.T) {
 	var tests = []struct {
 		j   string
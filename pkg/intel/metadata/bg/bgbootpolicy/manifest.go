@@ -63,6 +63,12 @@ func (bpm *Manifest) ValidateIBB(firmware uefi.Firmware) error {
 	return nil
 }
 
+// IBBCoverage returns the firmware volumes and files which are measured as
+// part of the IBB.
+func (bpm *Manifest) IBBCoverage(firmware uefi.Firmware) ([]uefi.IBBCoveredNode, error) {
+	return uefi.IBBCoverage(firmware, bpm.IBBDataRanges(uint64(len(firmware.Buf()))))
+}
+
 // IBBDataRanges returns data ranges of IBB.
 func (bpm *Manifest) IBBDataRanges(firmwareSize uint64) pkgbytes.Ranges {
 	var result pkgbytes.Ranges
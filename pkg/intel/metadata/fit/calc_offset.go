@@ -43,3 +43,25 @@ func CalculateOffsetFromPhysAddr(physAddr uint64, imageSize uint64) uint64 {
 func CalculateTailOffsetFromPhysAddr(physAddr uint64) uint64 {
 	return consts.BasePhysAddr - physAddr
 }
+
+// AddressToOffset converts an address found in the FIT to the offset within
+// an image of size imageSize. The address is normally a physical address of
+// the image mapped right below BasePhysAddr (4GB), but some tools put offsets
+// within the image instead, so addresses lower than imageSize are returned as
+// is. Any other address points outside of the image and returns
+// *ErrAddressOutOfImage.
+//
+// Examples:
+//
+//	AddressToOffset(0xffffffc0, 0x1000) == 0xfc0
+//	AddressToOffset(0xfc0, 0x1000) == 0xfc0
+//	AddressToOffset(0xffffe000, 0x1000) -> error
+func AddressToOffset(addr uint64, imageSize uint64) (uint64, error) {
+	if imageSize <= consts.BasePhysAddr && addr >= consts.BasePhysAddr-imageSize && addr < consts.BasePhysAddr {
+		return CalculateOffsetFromPhysAddr(addr, imageSize), nil
+	}
+	if addr < imageSize {
+		return addr, nil
+	}
+	return 0, &ErrAddressOutOfImage{Address: addr, ImageSize: imageSize}
+}
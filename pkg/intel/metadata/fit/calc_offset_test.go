@@ -0,0 +1,52 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressToOffset(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		addr      uint64
+		imageSize uint64
+		offset    uint64
+	}{
+		{"top_of_4GB", 0xffffffc0, 0x1000, 0xfc0},
+		{"image_start", 0xfffff000, 0x1000, 0},
+		{"image_relative", 0xfc0, 0x1000, 0xfc0},
+		{"16MB_image", 0xff000010, 0x1000000, 0x10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			offset, err := AddressToOffset(tc.addr, tc.imageSize)
+			require.NoError(t, err)
+			require.Equal(t, tc.offset, offset)
+		})
+	}
+
+	for _, tc := range []struct {
+		name      string
+		addr      uint64
+		imageSize uint64
+	}{
+		{"below_image", 0xffffe000, 0x1000},
+		{"above_4GB", 0x100000000, 0x1000},
+		{"overflow", 2<<32 + 1, 0x400},
+		{"image_size", 0x1000, 0x1000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := AddressToOffset(tc.addr, tc.imageSize)
+			var addrErr *ErrAddressOutOfImage
+			require.Error(t, err)
+			require.True(t, errors.As(err, &addrErr), err.Error())
+			require.Equal(t, tc.addr, addrErr.Address)
+			require.Equal(t, tc.imageSize, addrErr.ImageSize)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EntryDiffKind is the kind of difference of an entry between two tables.
+type EntryDiffKind int
+
+const (
+	// EntryDiffAdded means the entry exists only in the second table.
+	EntryDiffAdded EntryDiffKind = iota
+	// EntryDiffRemoved means the entry exists only in the first table.
+	EntryDiffRemoved
+	// EntryDiffChanged means the entry exists in both tables, but its
+	// headers or data differ.
+	EntryDiffChanged
+)
+
+func (kind EntryDiffKind) String() string {
+	switch kind {
+	case EntryDiffAdded:
+		return "added"
+	case EntryDiffRemoved:
+		return "removed"
+	case EntryDiffChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("unknown_diff_kind_%d", int(kind))
+}
+
+// FieldDiff is a changed field of an entry.
+type FieldDiff struct {
+	// Field is the name of the field, the fields of the parsed data segment
+	// are prefixed with "Data.".
+	Field string
+	Old   string
+	New   string
+}
+
+// EntryDiff is a difference of a single entry between two tables.
+type EntryDiff struct {
+	Kind    EntryDiffKind
+	Type    EntryType
+	Address uint64
+
+	// Fields are the changed fields, they are set only for EntryDiffChanged.
+	Fields []FieldDiff `json:",omitempty"`
+}
+
+// String implements fmt.Stringer
+func (diff EntryDiff) String() string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%s (0x%X) at 0x%X: %s\n", diff.Type, uint8(diff.Type), diff.Address, diff.Kind))
+	for _, field := range diff.Fields {
+		result.WriteString(fmt.Sprintf("\t%s: %s -> %s\n", field.Field, field.Old, field.New))
+	}
+	return result.String()
+}
+
+// entryKey identifies an entry when matching the entries of two tables.
+// There may be multiple entries of the same type at the same address (for
+// example entries without a data segment), so they are told apart by their
+// order.
+type entryKey struct {
+	Type       EntryType
+	Address    uint64
+	Occurrence int
+}
+
+func entryKeys(entries []Entry) []entryKey {
+	result := make([]entryKey, 0, len(entries))
+	occurrences := map[entryKey]int{}
+	for _, entry := range entries {
+		hdr := &entry.GetEntryBase().Headers
+		key := entryKey{Type: hdr.Type(), Address: hdr.Address.Pointer()}
+		occurrence := occurrences[key]
+		occurrences[key]++
+		key.Occurrence = occurrence
+		result = append(result, key)
+	}
+	return result
+}
+
+// DiffTables compares two FIT tables and returns the entries which were
+// added, removed or changed. Entries are matched by their type and address.
+// For changed entries the differing header fields are reported and, if the
+// data segments differ and the entry type has a parsed representation, the
+// differing fields of the parsed data (e.g. "Data.TXTSVN" of a startup AC
+// module).
+func DiffTables(a, b []Entry) []EntryDiff {
+	keysA, keysB := entryKeys(a), entryKeys(b)
+	indexB := make(map[entryKey]int, len(keysB))
+	for idx, key := range keysB {
+		indexB[key] = idx
+	}
+	matched := make(map[entryKey]bool, len(keysA))
+
+	var result []EntryDiff
+	for idxA, key := range keysA {
+		idxB, ok := indexB[key]
+		if !ok {
+			result = append(result, EntryDiff{Kind: EntryDiffRemoved, Type: key.Type, Address: key.Address})
+			continue
+		}
+		matched[key] = true
+		if fields := diffEntries(a[idxA], b[idxB]); len(fields) > 0 {
+			result = append(result, EntryDiff{Kind: EntryDiffChanged, Type: key.Type, Address: key.Address, Fields: fields})
+		}
+	}
+	for _, key := range keysB {
+		if !matched[key] {
+			result = append(result, EntryDiff{Kind: EntryDiffAdded, Type: key.Type, Address: key.Address})
+		}
+	}
+	return result
+}
+
+func diffEntries(a, b Entry) []FieldDiff {
+	var result []FieldDiff
+	add := func(field string, oldValue, newValue interface{}) {
+		oldString, newString := fmt.Sprint(oldValue), fmt.Sprint(newValue)
+		if oldString != newString {
+			result = append(result, FieldDiff{Field: field, Old: oldString, New: newString})
+		}
+	}
+
+	hdrA, hdrB := &a.GetEntryBase().Headers, &b.GetEntryBase().Headers
+	add("Size", fmt.Sprintf("0x%X", hdrA.Size.Uint32()), fmt.Sprintf("0x%X", hdrB.Size.Uint32()))
+	add("Version", hdrA.Version, hdrB.Version)
+	add("IsChecksumValid", hdrA.IsChecksumValid(), hdrB.IsChecksumValid())
+	add("Checksum", fmt.Sprintf("0x%X", hdrA.Checksum), fmt.Sprintf("0x%X", hdrB.Checksum))
+
+	dataA, dataB := a.GetEntryBase().DataSegmentBytes, b.GetEntryBase().DataSegmentBytes
+	if bytes.Equal(dataA, dataB) {
+		return result
+	}
+	parsedA, parsedB := parseEntryDataForDiff(a), parseEntryDataForDiff(b)
+	if parsedA == nil || parsedB == nil {
+		add("DataSegmentBytes", fmt.Sprintf("%d bytes", len(dataA)), fmt.Sprintf("%d bytes (modified)", len(dataB)))
+		return result
+	}
+	fieldsA, fieldsB := flattenFields(parsedA), flattenFields(parsedB)
+	valuesB := make(map[string]string, len(fieldsB))
+	for _, field := range fieldsB {
+		valuesB[field[0]] = field[1]
+	}
+	seen := make(map[string]bool, len(fieldsA))
+	for _, field := range fieldsA {
+		seen[field[0]] = true
+		add("Data."+field[0], field[1], valuesB[field[0]])
+	}
+	for _, field := range fieldsB {
+		if !seen[field[0]] {
+			add("Data."+field[0], "", field[1])
+		}
+	}
+	return result
+}
+
+// parseEntryDataForDiff returns the parsed data segment of the entry or nil
+// if the entry type has no parsed representation or the data is invalid.
+func parseEntryDataForDiff(entry Entry) interface{} {
+	switch entry := entry.(type) {
+	case *EntrySACM:
+		if data, err := entry.ParseData(); err == nil {
+			return data
+		}
+	case *EntryBIOSPolicyRecord:
+		if data, err := entry.ParseData(); err == nil {
+			return data
+		}
+	}
+	return nil
+}
+
+// flattenFields returns the name and the printed value of every exported
+// field of a (possibly nested) structure in the order of their declaration.
+// Embedded structures do not add a prefix to the names of their fields.
+func flattenFields(v interface{}) [][2]string {
+	var result [][2]string
+	var walk func(prefix string, value reflect.Value)
+	walk = func(prefix string, value reflect.Value) {
+		for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				result = append(result, [2]string{prefix, "<nil>"})
+				return
+			}
+			value = value.Elem()
+		}
+		switch {
+		case value.Kind() == reflect.Struct:
+			for idx := 0; idx < value.NumField(); idx++ {
+				field := value.Type().Field(idx)
+				if !field.IsExported() {
+					continue
+				}
+				name := field.Name
+				if prefix != "" {
+					name = prefix + "." + name
+				}
+				if field.Anonymous {
+					name = prefix
+				}
+				walk(name, value.Field(idx))
+			}
+		case (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(b), value)
+			result = append(result, [2]string{prefix, fmt.Sprintf("0x%X", b)})
+		default:
+			result = append(result, [2]string{prefix, fmt.Sprintf("%v", value.Interface())})
+		}
+	}
+	walk("", reflect.ValueOf(v))
+	return result
+}
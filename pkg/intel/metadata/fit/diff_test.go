@@ -0,0 +1,50 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSACMEntry(t *testing.T, address uint64, txtSVN TXTSVN) *EntrySACM {
+	entry := &EntrySACM{}
+	entry.Headers.TypeAndIsChecksumValid.SetType(EntryTypeStartupACModuleEntry)
+	entry.Headers.Address.SetOffset(address, 0x1000000)
+	require.NoError(t, entry.SetData(newTestSACMData(txtSVN, nil)))
+	return entry
+}
+
+func TestDiffTables(t *testing.T) {
+	a := Entries{
+		&EntryFITHeaderEntry{},
+		newTestSACMEntry(t, 0x100000, 2),
+	}
+	b := Entries{
+		&EntryFITHeaderEntry{},
+		newTestSACMEntry(t, 0x100000, 3),
+	}
+	require.Empty(t, DiffTables(a, a))
+
+	diffs := DiffTables(a, b)
+	require.Len(t, diffs, 1)
+	require.Equal(t, EntryDiffChanged, diffs[0].Kind)
+	require.Equal(t, EntryTypeStartupACModuleEntry, diffs[0].Type)
+	require.Equal(t, []FieldDiff{{Field: "Data.TXTSVN", Old: "2", New: "3"}}, diffs[0].Fields)
+	require.Contains(t, diffs[0].String(), "Data.TXTSVN: 2 -> 3")
+
+	// the same module at another address is a different entry
+	c := Entries{
+		&EntryFITHeaderEntry{},
+		newTestSACMEntry(t, 0x200000, 2),
+	}
+	diffs = DiffTables(a, c)
+	require.Len(t, diffs, 2)
+	require.Equal(t, EntryDiffRemoved, diffs[0].Kind)
+	require.Equal(t, a[1].GetEntryBase().Headers.Address.Pointer(), diffs[0].Address)
+	require.Equal(t, EntryDiffAdded, diffs[1].Kind)
+	require.Equal(t, c[1].GetEntryBase().Headers.Address.Pointer(), diffs[1].Address)
+}
@@ -5,6 +5,10 @@
 package fit
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"io"
 )
 
@@ -27,3 +31,82 @@ func (entry *EntryBIOSPolicyRecord) CustomRecalculateHeaders() error {
 	entry.Headers.Size.SetUint32(uint32(len(entry.DataSegmentBytes)))
 	return nil
 }
+
+// LCPPolicyDataFileSignature is the signature of LCP_POLICY_DATA, see
+// "Intel Trusted Execution Technology (Intel TXT) Software Development Guide".
+var LCPPolicyDataFileSignature = [32]byte{
+	'I', 'n', 't', 'e', 'l', '(', 'R', ')', ' ', 'T', 'X', 'T', ' ',
+	'L', 'C', 'P', '_', 'P', 'O', 'L', 'I', 'C', 'Y', '_', 'D', 'A', 'T', 'A',
+}
+
+// EntryBIOSPolicyRecordData is the parsed data of BIOS Policy Record entry,
+// which is the platform supplier policy data (LCP_POLICY_DATA) of TXT
+// Launch Control Policy.
+type EntryBIOSPolicyRecordData struct {
+	FileSignature [32]byte
+	Reserved      [3]byte
+	NumLists      uint8
+	// PolicyLists are the raw LCP_LIST structures.
+	PolicyLists []byte
+}
+
+// ParseData parses the data segment of the entry.
+func (entry *EntryBIOSPolicyRecord) ParseData() (*EntryBIOSPolicyRecordData, error) {
+	var result EntryBIOSPolicyRecordData
+	r := bytes.NewReader(entry.DataSegmentBytes)
+	if err := binary.Read(r, binary.LittleEndian, &result.FileSignature); err != nil {
+		return nil, fmt.Errorf("unable to read the file signature: %w", err)
+	}
+	if result.FileSignature != LCPPolicyDataFileSignature {
+		return nil, fmt.Errorf("invalid file signature: %q", bytes.TrimRight(result.FileSignature[:], "\x00"))
+	}
+	if err := binary.Read(r, binary.LittleEndian, &result.Reserved); err != nil {
+		return nil, fmt.Errorf("unable to read the reserved field: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &result.NumLists); err != nil {
+		return nil, fmt.Errorf("unable to read the number of lists: %w", err)
+	}
+	result.PolicyLists = entry.DataSegmentBytes[len(entry.DataSegmentBytes)-r.Len():]
+	return &result, nil
+}
+
+func (entry *EntryBIOSPolicyRecord) describeData() string {
+	data, err := entry.ParseData()
+	if err != nil {
+		return fmt.Sprintf("unable to parse LCP policy data: %v", err)
+	}
+	return fmt.Sprintf("LCP policy data with %d lists", data.NumLists)
+}
+
+type entryBIOSPolicyRecordJSON struct {
+	Headers        *EntryHeaders
+	Policy         string
+	DataParsed     *EntryBIOSPolicyRecordData `json:",omitempty"`
+	DataNotParsed  []byte                     `json:"DataNotParsedBase64,omitempty"`
+	HeadersErrors  []error
+	DataParseError error
+}
+
+// MarshalJSON implements json.Marshaler
+func (entry *EntryBIOSPolicyRecord) MarshalJSON() ([]byte, error) {
+	result := entryBIOSPolicyRecordJSON{Policy: "LCP_POLICY_DATA"}
+	result.DataParsed, result.DataParseError = entry.ParseData()
+	result.Headers = &entry.Headers
+	result.HeadersErrors = make([]error, len(entry.HeadersErrors))
+	copy(result.HeadersErrors, entry.HeadersErrors)
+	result.DataNotParsed = entry.DataSegmentBytes
+	return json.Marshal(&result)
+}
+
+// UnmarshalJSON implements json.Unmarshaller
+func (entry *EntryBIOSPolicyRecord) UnmarshalJSON(b []byte) error {
+	result := entryBIOSPolicyRecordJSON{}
+	err := json.Unmarshal(b, &result)
+	if err != nil {
+		return err
+	}
+	entry.Headers = *result.Headers
+	entry.HeadersErrors = result.HeadersErrors
+	entry.DataSegmentBytes = result.DataNotParsed
+	return nil
+}
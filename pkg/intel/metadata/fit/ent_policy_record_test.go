@@ -0,0 +1,84 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lcpPolicyData is a platform supplier LCP_POLICY_DATA with a single list,
+// only the beginning of the list is kept.
+var lcpPolicyData = append(append(LCPPolicyDataFileSignature[:],
+	0x00, 0x00, 0x00, // Reserved
+	0x01, // NumLists
+),
+	0x00, 0x02, // LCP_LIST version 2.0
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+)
+
+func TestPolicyRecords(t *testing.T) {
+	biosPolicy := &EntryBIOSPolicyRecord{}
+	biosPolicy.DataSegmentBytes = lcpPolicyData
+	biosPolicy.Headers.Address.SetOffset(256, 1024)
+
+	txtPolicy := &EntryTXTPolicyRecord{}
+	txtPolicy.Headers.Address = Address64(0x80000000fffe1000)
+
+	entries := Entries{&EntryFITHeaderEntry{}, biosPolicy, txtPolicy}
+	require.NoError(t, entries.RecalculateHeaders())
+	b := make([]byte, 1024)
+	require.NoError(t, entries.Inject(b, 512))
+
+	parsedEntries, err := GetEntries(b)
+	require.NoError(t, err)
+	require.Len(t, parsedEntries, 3)
+
+	t.Run("BIOSPolicy", func(t *testing.T) {
+		require.IsType(t, &EntryBIOSPolicyRecord{}, parsedEntries[1])
+		entry := parsedEntries[1].(*EntryBIOSPolicyRecord)
+		data, err := entry.ParseData()
+		require.NoError(t, err)
+		require.Equal(t, uint8(1), data.NumLists)
+		require.Equal(t, lcpPolicyData[36:], data.PolicyLists)
+
+		entry.DataSegmentBytes = make([]byte, len(lcpPolicyData))
+		_, err = entry.ParseData()
+		require.Error(t, err)
+	})
+
+	t.Run("TXTPolicy", func(t *testing.T) {
+		require.IsType(t, &EntryTXTPolicyRecord{}, parsedEntries[2])
+		entry := parsedEntries[2].(*EntryTXTPolicyRecord)
+		data, err := entry.Parse()
+		require.NoError(t, err)
+		require.Equal(t, EntryTXTPolicyRecordDataFlatPointer(0x80000000fffe1000), data)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		s := entries.String()
+		require.True(t, strings.Contains(s, "Parsed: LCP policy data with 1 lists"), s)
+		require.True(t, strings.Contains(s, "Parsed: TXT policy pointer 0xFFFE1000, TXT enabled: true"), s)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(biosPolicy)
+		require.NoError(t, err)
+		require.True(t, strings.Contains(string(b), `"Policy":"LCP_POLICY_DATA"`), string(b))
+		var biosPolicyCopy EntryBIOSPolicyRecord
+		require.NoError(t, json.Unmarshal(b, &biosPolicyCopy))
+		require.Equal(t, biosPolicy.DataSegmentBytes, biosPolicyCopy.DataSegmentBytes)
+
+		b, err = json.Marshal(txtPolicy)
+		require.NoError(t, err)
+		require.True(t, strings.Contains(string(b), `"Policy":"FlatPointer"`), string(b))
+		var txtPolicyCopy EntryTXTPolicyRecord
+		require.NoError(t, json.Unmarshal(b, &txtPolicyCopy))
+		require.Equal(t, txtPolicy.Headers, txtPolicyCopy.Headers)
+	})
+}
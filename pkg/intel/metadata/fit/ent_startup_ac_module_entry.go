@@ -5,7 +5,11 @@
 package fit
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -385,13 +389,17 @@ func (entryData *EntrySACMData0) WriteTo(w io.Writer) (int64, error) {
 	return int64(entrySACMData0Size), nil
 }
 
-// GetRSAPubKey returns the RSA public key
+// GetRSAPubKey returns the RSA public key. The modulus is stored in
+// little-endian in the module, as the signature is.
+//
+// Earlier versions read the modulus as big-endian; callers which reversed
+// the bytes of N to work around it must stop doing so.
 func (entryData *EntrySACMData0) GetRSAPubKey() rsa.PublicKey {
 	pubKey := rsa.PublicKey{
 		N: big.NewInt(0),
 		E: int(entryData.GetRSAPubExp()),
 	}
-	pubKey.N.SetBytes(entryData.RSAPubKey[:])
+	pubKey.N.SetBytes(reverseBytes(entryData.RSAPubKey[:]))
 	return pubKey
 }
 
@@ -454,13 +462,13 @@ func (entryData *EntrySACMData3) WriteTo(w io.Writer) (int64, error) {
 	return int64(entrySACMData3Size), nil
 }
 
-// GetRSAPubKey returns the RSA public key
+// GetRSAPubKey returns the RSA public key, see EntrySACMData0.GetRSAPubKey.
 func (entryData *EntrySACMData3) GetRSAPubKey() rsa.PublicKey {
 	pubKey := rsa.PublicKey{
 		N: big.NewInt(0),
 		E: 0x10001, // see Table 9. "RSAPubExp" of https://www.intel.com/content/www/us/en/software-developers/txt-software-development-guide.html
 	}
-	pubKey.N.SetBytes(entryData.RSAPubKey[:])
+	pubKey.N.SetBytes(reverseBytes(entryData.RSAPubKey[:]))
 	return pubKey
 }
 
@@ -540,6 +548,69 @@ func (entryData *EntrySACMData) GetCommon() *EntrySACMDataCommon {
 	return nil
 }
 
+// SignedData returns the part of the startup AC module covered by its
+// signature: the common headers followed by the user area. The public key,
+// the signature and the scratch area are excluded.
+func (entryData *EntrySACMData) SignedData() ([]byte, error) {
+	common := entryData.GetCommon()
+	if common == nil {
+		return nil, fmt.Errorf("unknown startup AC module structure type %T", entryData.EntrySACMDataInterface)
+	}
+	var buf bytes.Buffer
+	if _, err := common.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("unable to compile the common headers: %w", err)
+	}
+	buf.Write(entryData.UserArea)
+	return buf.Bytes(), nil
+}
+
+// VerifySignature verifies the RSA signature of the startup AC module using
+// the public key embedded into its headers. Version 0.0 modules are signed
+// using RSASSA-PKCS1-v1_5 with SHA-256 and version 3.0 modules are signed
+// using RSASSA-PSS with SHA-384.
+func (entryData *EntrySACMData) VerifySignature() error {
+	var (
+		hashFunc crypto.Hash
+		pss      bool
+	)
+	switch entryData.EntrySACMDataInterface.(type) {
+	case *EntrySACMData0:
+		hashFunc = crypto.SHA256
+	case *EntrySACMData3:
+		hashFunc, pss = crypto.SHA384, true
+	default:
+		return &ErrUnknownACMHeaderVersion{ACHeaderVersion: entryData.GetHeaderVersion()}
+	}
+
+	signedData, err := entryData.SignedData()
+	if err != nil {
+		return err
+	}
+	h := hashFunc.New()
+	h.Write(signedData)
+	digest := h.Sum(nil)
+
+	pubKey := entryData.GetRSAPubKey()
+	sig := reverseBytes(entryData.GetRSASig())
+	if pss {
+		err = rsa.VerifyPSS(&pubKey, hashFunc, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	} else {
+		err = rsa.VerifyPKCS1v15(&pubKey, hashFunc, digest, sig)
+	}
+	if err != nil {
+		return fmt.Errorf("signature does not correspond to the pub key: %w", err)
+	}
+	return nil
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
 // EntrySACMParseSizeFrom parses SACM structure size
 func EntrySACMParseSizeFrom(r io.ReadSeeker, offset uint64) (uint32, error) {
 	sizeFieldLocalOffset := EntrySACMDataCommon{}.SizeBinaryOffset()
@@ -575,6 +646,21 @@ func (entry *EntrySACM) ParseData() (*EntrySACMData, error) {
 	return &entryData, nil
 }
 
+// SetData compiles the startup AC module into the data segment of the entry
+// and recalculates the headers. The compiled module must be exactly as large
+// as declared by its Size field, since the data segment size is parsed from it.
+func (entry *EntrySACM) SetData(data *EntrySACMData) error {
+	var buf bytes.Buffer
+	if _, err := data.WriteTo(&buf); err != nil {
+		return fmt.Errorf("unable to compile the startup AC module: %w", err)
+	}
+	if expected := data.GetSize().Size(); uint64(buf.Len()) != expected {
+		return &ErrACMInvalidSize{ExpectedSize: expected, RealSize: uint64(buf.Len())}
+	}
+	entry.DataSegmentBytes = buf.Bytes()
+	return entry.CustomRecalculateHeaders()
+}
+
 // ParseSACMData parses SACM entry and returns EntrySACMData.
 func ParseSACMData(r io.Reader) (*EntrySACMData, error) {
 
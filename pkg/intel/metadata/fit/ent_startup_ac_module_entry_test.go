@@ -7,7 +7,11 @@ package fit
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -19,6 +23,20 @@ func randBytes(size uint) []byte {
 	return b
 }
 
+// newTestSACMData returns a version 3 startup AC module with an empty key and
+// signature, followed by userArea. The header fields are set so that the
+// module is consistent with its size.
+func newTestSACMData(txtSVN TXTSVN, userArea []byte) *EntrySACMData {
+	sacm := &EntrySACMData3{}
+	sacm.HeaderVersion = ACHeaderVersion3
+	sacm.KeySize.SetSize(uint64(len(sacm.RSAPubKey)))
+	sacm.ScratchSize.SetSize(uint64(len(sacm.Scratch)))
+	sacm.HeaderLen.SetSize(uint64(entrySACMData3Size) - uint64(len(sacm.Scratch)))
+	sacm.Size.SetSize(uint64(entrySACMData3Size) + uint64(len(userArea)))
+	sacm.TXTSVN = txtSVN
+	return &EntrySACMData{EntrySACMDataInterface: sacm, UserArea: userArea}
+}
+
 func TestEntrySACM_ParseData(t *testing.T) {
 	sizeOffset := EntrySACMDataCommon{}.SizeBinaryOffset()
 	sizeEndOffset := sizeOffset + uint(binary.Size(EntrySACMDataCommon{}.Size))
@@ -96,3 +114,159 @@ func TestEntrySACM_ParseData(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestEntrySACM_SetData(t *testing.T) {
+	data := newTestSACMData(3, make([]byte, 16))
+
+	entry := &EntrySACM{}
+	entry.Headers.Size.SetUint32(1)
+	require.NoError(t, entry.SetData(data))
+	require.Len(t, entry.DataSegmentBytes, int(entrySACMData3Size)+16)
+	require.Zero(t, entry.Headers.Size.Uint32())
+
+	parsed, err := entry.ParseData()
+	require.NoError(t, err)
+	require.Equal(t, TXTSVN(3), parsed.GetTXTSVN())
+
+	data.UserArea = data.UserArea[:8]
+	err = entry.SetData(data)
+	require.IsType(t, &ErrACMInvalidSize{}, err)
+}
+
+func TestEntrySACMData_ReadWriteSize(t *testing.T) {
+	sacm0 := &EntrySACMData0{}
+	sacm0.HeaderVersion = ACHeaderVersion0
+	copy(sacm0.RSAPubKey[:], randBytes(uint(len(sacm0.RSAPubKey))))
+	sacm3 := &EntrySACMData3{}
+	sacm3.HeaderVersion = ACHeaderVersion3
+	copy(sacm3.RSAPubKey[:], randBytes(uint(len(sacm3.RSAPubKey))))
+
+	for _, test := range []struct {
+		data   EntrySACMDataInterface
+		parsed EntrySACMDataInterface
+	}{
+		{sacm0, &EntrySACMData0{}},
+		{sacm3, &EntrySACMData3{}},
+	} {
+		size := int64(binary.Size(test.data))
+
+		var buf bytes.Buffer
+		n, err := test.data.WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, size, n)
+		require.Equal(t, size, int64(buf.Len()))
+
+		// the headers are followed by the user area, which must not be consumed
+		buf.Write([]byte{1, 2, 3, 4})
+		n, err = test.parsed.ReadFrom(&buf)
+		require.NoError(t, err)
+		require.Equal(t, size, n)
+		require.Equal(t, test.data, test.parsed)
+		require.Equal(t, 4, buf.Len())
+	}
+}
+
+// The fixtures in testdata are startup AC modules signed with openssl,
+// independently of this package. The signed data is the 128 bytes of the
+// common headers followed by the user area:
+//
+//	openssl dgst -sha256 -sign key.pem                          # version 0.0, 2048 bits key
+//	openssl dgst -sha384 -sign key.pem -sigopt rsa_padding_mode:pss \
+//	    -sigopt rsa_pss_saltlen:48                              # version 3.0, 3072 bits key
+//
+// As in Intel-signed modules, the modulus and the signature are stored in
+// little-endian. The public keys are stored in the .pub.pem files.
+func TestEntrySACMData_VerifySignature(t *testing.T) {
+	for _, name := range []string{"sacm_v0", "sacm_v3"} {
+		t.Run(name, func(t *testing.T) {
+			acm, err := os.ReadFile(filepath.Join("testdata", name+".bin"))
+			require.NoError(t, err)
+			pubPEM, err := os.ReadFile(filepath.Join("testdata", name+".pub.pem"))
+			require.NoError(t, err)
+			block, _ := pem.Decode(pubPEM)
+			require.NotNil(t, block)
+			expectedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			require.NoError(t, err)
+
+			entry := &EntrySACM{EntryBase: EntryBase{DataSegmentBytes: acm}}
+			data, err := entry.ParseData()
+			require.NoError(t, err)
+			pubKey := data.GetRSAPubKey()
+			require.Equal(t, expectedKey, &pubKey)
+			require.NoError(t, data.VerifySignature())
+
+			data.UserArea[0] ^= 0xff
+			require.Error(t, data.VerifySignature())
+		})
+	}
+
+	t.Run("unsupported_version", func(t *testing.T) {
+		data := &EntrySACMData{EntrySACMDataInterface: &EntrySACMDataCommon{HeaderVersion: 0x00040000}}
+		require.IsType(t, &ErrUnknownACMHeaderVersion{}, data.VerifySignature())
+	})
+}
+
+func TestEntrySACMData_CompatibilityLists(t *testing.T) {
+	chipsetIDs := []ACMChipsetID{
+		{Flags: 1, VendorID: 0x8086, DeviceID: 0xa082, RevisionID: 0x01},
+		{VendorID: 0x8086, DeviceID: 0x9a14, ExtendedID: 0x12345678},
+	}
+	processorIDs := []ACMProcessorID{
+		{FMS: 0x806c0, FMSMask: 0xfff3ff0, PlatformMask: 0xff},
+	}
+	// version 4 table without the TPM info list
+	tableSize := binary.Size(ACMInfoTable{}) - 4
+	chipsetListOffset := uint32(entrySACMData3Size) + uint32(tableSize)
+	processorListOffset := chipsetListOffset + 4 + uint32(len(chipsetIDs)*binary.Size(ACMChipsetID{}))
+	table := ACMInfoTable{
+		UUID:            ACMInfoTableUUID,
+		ChipsetACMType:  1,
+		Version:         4,
+		Length:          uint16(tableSize),
+		ChipsetIDList:   chipsetListOffset,
+		ProcessorIDList: processorListOffset,
+	}
+
+	var userArea bytes.Buffer
+	require.NoError(t, binary.Write(&userArea, binary.LittleEndian, table))
+	userArea.Truncate(tableSize)
+	require.NoError(t, binary.Write(&userArea, binary.LittleEndian, uint32(len(chipsetIDs))))
+	require.NoError(t, binary.Write(&userArea, binary.LittleEndian, chipsetIDs))
+	require.NoError(t, binary.Write(&userArea, binary.LittleEndian, uint32(len(processorIDs))))
+	require.NoError(t, binary.Write(&userArea, binary.LittleEndian, processorIDs))
+	userArea.Write(make([]byte, 4-userArea.Len()%4))
+
+	entry := &EntrySACM{}
+	require.NoError(t, entry.SetData(newTestSACMData(0, userArea.Bytes())))
+	data, err := entry.ParseData()
+	require.NoError(t, err)
+
+	parsedTable, err := data.GetInfoTable()
+	require.NoError(t, err)
+	require.Equal(t, table, *parsedTable)
+	parsedChipsetIDs, err := data.GetChipsetIDList()
+	require.NoError(t, err)
+	require.Equal(t, chipsetIDs, parsedChipsetIDs)
+	parsedProcessorIDs, err := data.GetProcessorIDList()
+	require.NoError(t, err)
+	require.Equal(t, processorIDs, parsedProcessorIDs)
+
+	t.Run("version_3", func(t *testing.T) {
+		data.UserArea[17] = 3
+		processorIDs, err := data.GetProcessorIDList()
+		require.NoError(t, err)
+		require.Nil(t, processorIDs)
+		data.UserArea[17] = 4
+	})
+	t.Run("invalid_uuid", func(t *testing.T) {
+		data.UserArea[0] ^= 0xff
+		_, err := data.GetChipsetIDList()
+		require.Error(t, err)
+		data.UserArea[0] ^= 0xff
+	})
+	t.Run("list_beyond_module", func(t *testing.T) {
+		binary.LittleEndian.PutUint32(data.UserArea[tableSize:], 0x1000)
+		_, err := data.GetChipsetIDList()
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright 2017-2021 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// ACMInfoTableUUID is the UUID identifying the information table of an AC
+// module (ACM_UUID_V3: 7FC03AAA-46A7-18DB-AC2E-698F8D417F5A).
+var ACMInfoTableUUID = [16]byte{
+	0xaa, 0x3a, 0xc0, 0x7f, 0xa7, 0x46, 0xdb, 0x18,
+	0xac, 0x2e, 0x69, 0x8f, 0x8d, 0x41, 0x7f, 0x5a,
+}
+
+// ACMInfoTable is the information table of an AC module, stored at the
+// beginning of the user area. The offsets of the lists are relative to the
+// beginning of the module.
+//
+// See "Intel TXT Software Development Guide", Appendix A.1.
+type ACMInfoTable struct {
+	UUID            [16]byte
+	ChipsetACMType  uint8
+	Version         uint8
+	Length          uint16
+	ChipsetIDList   uint32
+	OSSINITDataVer  uint32
+	MinMLEHeaderVer uint32
+	Capabilities    uint32
+	ACMVersion      uint8
+	ACMRevision     [3]uint8
+	// ProcessorIDList is available since version 4 of the table.
+	ProcessorIDList uint32
+	// TPMInfoList is available since version 5 of the table.
+	TPMInfoList uint32
+}
+
+// ACMChipsetID is an entry of the list of chipsets supported by an AC module.
+type ACMChipsetID struct {
+	Flags      uint32
+	VendorID   uint16
+	DeviceID   uint16
+	RevisionID uint16
+	Reserved   uint16
+	ExtendedID uint32
+}
+
+// ACMProcessorID is an entry of the list of processors supported by an AC
+// module.
+type ACMProcessorID struct {
+	FMS          uint32
+	FMSMask      uint32
+	PlatformID   uint64
+	PlatformMask uint64
+}
+
+// moduleBytes returns the binary representation of the whole AC module, the
+// offsets found in the information table are relative to its beginning.
+func (entryData *EntrySACMData) moduleBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := entryData.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("unable to compile the AC module: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GetInfoTable parses the information table of the AC module. The fields
+// beyond the length of the table, e.g. ProcessorIDList of a version 3 table,
+// are left zero.
+func (entryData *EntrySACMData) GetInfoTable() (*ACMInfoTable, error) {
+	module, err := entryData.moduleBytes()
+	if err != nil {
+		return nil, err
+	}
+	offset := entryData.GetHeaderLen().Size() + entryData.GetScratchSize().Size()
+	return parseACMInfoTable(module, offset)
+}
+
+func parseACMInfoTable(module []byte, offset uint64) (*ACMInfoTable, error) {
+	minSize := uint64(binary.Size(ACMInfoTable{}.UUID) + 4)
+	if offset+minSize > uint64(len(module)) {
+		return nil, fmt.Errorf("ACM information table at 0x%x is beyond the module of size 0x%x", offset, len(module))
+	}
+	b := module[offset:]
+	var uuid [16]byte
+	copy(uuid[:], b)
+	if uuid != ACMInfoTableUUID {
+		return nil, fmt.Errorf("invalid ACM information table UUID: %X", uuid)
+	}
+	length := uint64(binary.LittleEndian.Uint16(b[18:]))
+	if length < minSize || offset+length > uint64(len(module)) {
+		return nil, fmt.Errorf("invalid ACM information table length 0x%x at 0x%x in the module of size 0x%x", length, offset, len(module))
+	}
+
+	// fields which do not fit into the length of the table are left zero
+	tableBytes := make([]byte, binary.Size(ACMInfoTable{}))
+	copy(tableBytes, b[:length])
+	var table ACMInfoTable
+	if err := binary.Read(bytes.NewReader(tableBytes), binary.LittleEndian, &table); err != nil {
+		return nil, fmt.Errorf("unable to parse ACM information table: %w", err)
+	}
+	return &table, nil
+}
+
+// readACMIDList reads a list of items of the given size at the offset of
+// the module. The list is prefixed by the count of items.
+func readACMIDList(module []byte, offset uint32, itemSize int) (uint32, []byte, error) {
+	if uint64(offset)+4 > uint64(len(module)) {
+		return 0, nil, fmt.Errorf("list at 0x%x is beyond the module of size 0x%x", offset, len(module))
+	}
+	count := binary.LittleEndian.Uint32(module[offset:])
+	end := uint64(offset) + 4 + uint64(count)*uint64(itemSize)
+	if end > uint64(len(module)) {
+		return 0, nil, fmt.Errorf("list at 0x%x of %d items of size %d is beyond the module of size 0x%x", offset, count, itemSize, len(module))
+	}
+	return count, module[offset+4 : end], nil
+}
+
+// GetChipsetIDList returns the list of chipsets supported by the AC module.
+func (entryData *EntrySACMData) GetChipsetIDList() ([]ACMChipsetID, error) {
+	table, err := entryData.GetInfoTable()
+	if err != nil {
+		return nil, err
+	}
+	module, err := entryData.moduleBytes()
+	if err != nil {
+		return nil, err
+	}
+	count, b, err := readACMIDList(module, table.ChipsetIDList, binary.Size(ACMChipsetID{}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ACM chipset ID list: %w", err)
+	}
+	result := make([]ACMChipsetID, count)
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, result); err != nil {
+		return nil, fmt.Errorf("unable to parse ACM chipset ID list: %w", err)
+	}
+	return result, nil
+}
+
+// GetProcessorIDList returns the list of processors supported by the AC
+// module. Information tables older than version 4 do not have this list, nil
+// is returned for them.
+func (entryData *EntrySACMData) GetProcessorIDList() ([]ACMProcessorID, error) {
+	table, err := entryData.GetInfoTable()
+	if err != nil {
+		return nil, err
+	}
+	if table.Version < 4 {
+		return nil, nil
+	}
+	module, err := entryData.moduleBytes()
+	if err != nil {
+		return nil, err
+	}
+	count, b, err := readACMIDList(module, table.ProcessorIDList, binary.Size(ACMProcessorID{}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ACM processor ID list: %w", err)
+	}
+	result := make([]ACMProcessorID, count)
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, result); err != nil {
+		return nil, fmt.Errorf("unable to parse ACM processor ID list: %w", err)
+	}
+	return result, nil
+}
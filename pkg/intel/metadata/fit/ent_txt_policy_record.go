@@ -7,6 +7,7 @@ package fit
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 )
@@ -92,3 +93,60 @@ func (entry *EntryTXTPolicyRecord) Parse() (EntryTXTPolicyRecordDataInterface, e
 
 	return nil, &ErrInvalidTXTPolicyRecordVersion{entry.Headers.Version}
 }
+
+func (entry *EntryTXTPolicyRecord) describeData() string {
+	data, err := entry.Parse()
+	if err != nil {
+		return fmt.Sprintf("unable to parse TXT policy: %v", err)
+	}
+	switch data := data.(type) {
+	case EntryTXTPolicyRecordDataFlatPointer:
+		return fmt.Sprintf("TXT policy pointer 0x%X, TXT enabled: %v", data.TPMPolicyPointer(), data.IsTXTEnabled())
+	case *EntryTXTPolicyRecordDataIndexedIO:
+		return fmt.Sprintf("TXT policy in indexed IO: index register 0x%X, data register 0x%X, access width %d, bit %d, index 0x%X",
+			data.IndexRegisterIOAddress, data.DataRegisterIOAddress, data.AccessWidth, data.BitPosition, data.Index)
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+type entryTXTPolicyRecordJSON struct {
+	Headers        *EntryHeaders
+	Policy         string
+	DataParsed     EntryTXTPolicyRecordDataInterface `json:",omitempty"`
+	HeadersErrors  []error
+	DataParseError error
+}
+
+// MarshalJSON implements json.Marshaler
+func (entry *EntryTXTPolicyRecord) MarshalJSON() ([]byte, error) {
+	result := entryTXTPolicyRecordJSON{}
+	result.DataParsed, result.DataParseError = entry.Parse()
+	switch result.DataParsed.(type) {
+	case EntryTXTPolicyRecordDataFlatPointer:
+		result.Policy = "FlatPointer"
+	case *EntryTXTPolicyRecordDataIndexedIO:
+		result.Policy = "IndexedIO"
+	}
+	result.Headers = &entry.Headers
+	result.HeadersErrors = make([]error, len(entry.HeadersErrors))
+	copy(result.HeadersErrors, entry.HeadersErrors)
+	return json.Marshal(&result)
+}
+
+// UnmarshalJSON implements json.Unmarshaller
+func (entry *EntryTXTPolicyRecord) UnmarshalJSON(b []byte) error {
+	// DataParsed is derived from the headers, so it is not unmarshalled.
+	result := struct {
+		Headers       *EntryHeaders
+		HeadersErrors []error
+	}{}
+	err := json.Unmarshal(b, &result)
+	if err != nil {
+		return err
+	}
+	if result.Headers != nil {
+		entry.Headers = *result.Headers
+	}
+	entry.HeadersErrors = result.HeadersErrors
+	return nil
+}
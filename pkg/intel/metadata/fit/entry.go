@@ -65,9 +65,9 @@ func mostCommonRecalculateHeadersOfEntry(entry Entry) {
 	hdr := &entryBase.Headers
 	hdr.TypeAndIsChecksumValid.SetType(entryType)
 	hdr.TypeAndIsChecksumValid.SetIsChecksumValid(true)
-	hdr.Checksum = hdr.CalculateChecksum()
 	hdr.Version = EntryVersion(0x0100)
 	hdr.Size.SetUint32(uint32(len(entryBase.DataSegmentBytes) >> 4))
+	hdr.Checksum = hdr.CalculateChecksum()
 }
 
 // EntryRecalculateHeaders recalculates headers of the entry based on its data.
@@ -106,7 +106,14 @@ func (entries Entries) RecalculateHeaders() error {
 	}
 
 	// See point 4.2.5 of the FIT specification
-	beginEntry.GetEntryBase().Headers.Size.SetUint32(uint32(len(entries)))
+	beginHeaders := &beginEntry.GetEntryBase().Headers
+	beginHeaders.Size.SetUint32(uint32(len(entries)))
+
+	// The checksum of the FIT header entry covers the whole table, so it
+	// could be calculated only when all the other headers are final.
+	if beginHeaders.IsChecksumValid() {
+		beginHeaders.Checksum = entries.Table().CalculateChecksum()
+	}
 
 	return nil
 }
@@ -123,6 +130,12 @@ func (entries Entries) Table() Table {
 	return result
 }
 
+// entryDataDescriber is implemented by entries which can decode their data
+// into a human readable form.
+type entryDataDescriber interface {
+	describeData() string
+}
+
 // String implements fmt.Stringer
 func (entries Entries) String() string {
 	var result strings.Builder
@@ -140,6 +153,9 @@ func (entries Entries) String() string {
 		if data := entry.GetEntryBase().DataSegmentBytes; len(data) > 0 {
 			result.WriteString(fmt.Sprintf("\tData: 0x%X\n", data))
 		}
+		if describer, ok := entry.(entryDataDescriber); ok {
+			result.WriteString(fmt.Sprintf("\tParsed: %s\n", describer.describeData()))
+		}
 	}
 	return result.String()
 }
@@ -242,12 +258,12 @@ func EntryDataSegmentCoordinates(entry Entry, firmware io.ReadSeeker) (uint64, u
 
 	offset, addErr := entry.GetEntryBase().Headers.getDataSegmentOffset(firmware)
 	if addErr != nil {
-		err = multierror.Append(err, fmt.Errorf("unable to get data segment offset: %w", err))
+		err = multierror.Append(err, fmt.Errorf("unable to get data segment offset: %w", addErr))
 	}
 
 	size, addErr := EntryDataSegmentSize(entry, firmware)
 	if addErr != nil {
-		err = multierror.Append(err, fmt.Errorf("unable to get data segment size: %w", err))
+		err = multierror.Append(err, fmt.Errorf("unable to get data segment size: %w", addErr))
 	}
 
 	return offset, size, err
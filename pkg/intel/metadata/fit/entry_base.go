@@ -32,6 +32,14 @@ func (entry *EntryBase) GetEntryBase() *EntryBase {
 	return entry
 }
 
+// Disable makes the firmware skip the entry, see EntryHeaders.Disable.
+//
+// Recalculating the headers (see EntryRecalculateHeaders) restores the
+// original type, so it should not be done after disabling an entry.
+func (entry *EntryBase) Disable() {
+	entry.Headers.Disable()
+}
+
 // GoString implements fmt.GoStringer
 func (entry *EntryBase) GoString() string {
 	return entry.Headers.GoString()
@@ -50,7 +58,10 @@ func (entry EntryBase) injectDataSectionTo(w io.WriteSeeker) error {
 		return fmt.Errorf("unable to detect firmware size: %w", err)
 	}
 
-	dataSectionOffset := base.Headers.Address.Offset(uint64(firmwareSize))
+	dataSectionOffset, err := AddressToOffset(base.Headers.Address.Pointer(), uint64(firmwareSize))
+	if err != nil {
+		return fmt.Errorf("unable to locate the data section: %w", err)
+	}
 	if _, err := w.Seek(int64(dataSectionOffset), io.SeekStart); err != nil {
 		return fmt.Errorf("unable to Seek(%d, %d) to write the data section: %w", int64(dataSectionOffset), io.SeekStart, err)
 	}
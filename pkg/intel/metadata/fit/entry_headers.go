@@ -318,10 +318,48 @@ func (hdr *EntryHeaders) IsChecksumValid() bool {
 	return hdr.TypeAndIsChecksumValid.IsChecksumValid()
 }
 
+// Disable makes the firmware skip the entry without changing the layout of
+// the table: the type is set to "Unused Entry (skip)" (0x7F) and bit "C_V" is
+// cleared. The other fields are preserved.
+//
+// The sum of the table changes, so the checksum of the FIT header entry
+// should be recalculated afterwards, see Table.RecalculateChecksum.
+func (hdr *EntryHeaders) Disable() {
+	hdr.TypeAndIsChecksumValid.SetType(EntryTypeSkip)
+	hdr.TypeAndIsChecksumValid.SetIsChecksumValid(false)
+}
+
 func (hdr *EntryHeaders) String() string {
 	return fmt.Sprintf("&%+v", *hdr)
 }
 
+// describeAddress returns the Address field interpreted according to the entry type.
+func (hdr *EntryHeaders) describeAddress() string {
+	switch hdr.Type() {
+	case EntryTypeFITHeaderEntry:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], hdr.Address.Pointer())
+		return fmt.Sprintf("%q", strings.TrimRight(string(b[:]), " "))
+	}
+	return fmt.Sprintf("0x%08x", hdr.Address.Pointer())
+}
+
+// describeSize returns the Size field interpreted according to the entry type.
+func (hdr *EntryHeaders) describeSize() string {
+	size := hdr.Size.Uint32()
+	switch hdr.Type() {
+	case EntryTypeFITHeaderEntry:
+		// See 4.2.5 of the FIT specification.
+		return fmt.Sprintf("%d entries", size)
+	case EntryTypeStartupACModuleEntry, EntryTypeTXTPolicyRecord:
+		// The size is not stored in the headers, see 4.4.7 and 4.9.11.
+		return "-"
+	case EntryTypeBIOSPolicyRecord, EntryTypeKeyManifestRecord, EntryTypeBootPolicyManifest:
+		return fmt.Sprintf("0x%x", size)
+	}
+	return fmt.Sprintf("0x%x", uint64(size)<<4)
+}
+
 var _ io.Writer = (*EntryHeaders)(nil)
 
 // Write implements io.Writer. It writes the headers in a binary format to `b`.
@@ -373,7 +411,7 @@ func (hdr *EntryHeaders) getDataSegmentOffset(firmware io.Seeker) (uint64, error
 		return 0, fmt.Errorf("unable to get the size of the firmware: %w", err)
 	}
 
-	return hdr.Address.Offset(uint64(firmwareSize)), nil
+	return AddressToOffset(hdr.Address.Pointer(), uint64(firmwareSize))
 }
 
 // mostCommonGetDataSegmentCoordinates returns the length of the data segment
@@ -114,3 +114,13 @@ func TestEntriesInject(t *testing.T) {
 		testResult(t, b)
 	})
 }
+
+func TestEntriesRecalculateHeadersTableChecksum(t *testing.T) {
+	entries := getSampleEntries(t)
+	require.True(t, entries[0].GetEntryBase().Headers.IsChecksumValid())
+
+	var buf bytes.Buffer
+	_, err := entries.Table().WriteTo(&buf)
+	require.NoError(t, err)
+	require.NoError(t, ValidateTableChecksum(buf.Bytes()))
+}
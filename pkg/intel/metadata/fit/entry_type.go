@@ -46,6 +46,32 @@ func (_type EntryType) String() string {
 	return fmt.Sprintf("unknown_entry_0x%X", uint8(_type))
 }
 
+var entryTypeShortNames = map[EntryType]string{
+	EntryTypeFITHeaderEntry:              "FITHeader",
+	EntryTypeMicrocodeUpdateEntry:        "Microcode",
+	EntryTypeStartupACModuleEntry:        "StartupACM",
+	EntryTypeDiagnosticACModuleEntry:     "DiagnosticACM",
+	EntryTypeBIOSStartupModuleEntry:      "BIOSStartupModule",
+	EntryTypeTPMPolicyRecord:             "TPMPolicy",
+	EntryTypeBIOSPolicyRecord:            "BIOSPolicy",
+	EntryTypeTXTPolicyRecord:             "TXTPolicy",
+	EntryTypeKeyManifestRecord:           "KeyManifest",
+	EntryTypeBootPolicyManifest:          "BootPolicy",
+	EntryTypeCSESecureBoot:               "CSESecureBoot",
+	EntryTypeFeaturePolicyDeliveryRecord: "FeaturePolicyDelivery",
+	EntryTypeJMPDebugPolicy:              "JMPDebugPolicy",
+	EntryTypeSkip:                        "Skip",
+}
+
+// ShortName returns a human readable name of the entry type as it is
+// usually referred in the FIT specification (for example "StartupACM").
+func (_type EntryType) ShortName() string {
+	if name, ok := entryTypeShortNames[_type]; ok {
+		return name
+	}
+	return _type.String()
+}
+
 var (
 	entryTypeIDToGo = map[EntryType]reflect.Type{}
 	entryTypeGoToID = map[reflect.Type]EntryType{}
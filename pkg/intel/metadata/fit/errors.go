@@ -21,6 +21,18 @@ func (err *ErrACMInvalidKeySize) Error() string {
 		err.ExpectedKeySize, err.RealKeySize)
 }
 
+// ErrACMInvalidSize means the compiled ACM does not match the size declared
+// in its headers.
+type ErrACMInvalidSize struct {
+	ExpectedSize uint64
+	RealSize     uint64
+}
+
+func (err *ErrACMInvalidSize) Error() string {
+	return fmt.Sprintf("invalid ACM size, expected:%d, real:%d",
+		err.ExpectedSize, err.RealSize)
+}
+
 // ErrUnknownACMHeaderVersion means ACM entry has invalid header version
 type ErrUnknownACMHeaderVersion struct {
 	ACHeaderVersion ACModuleHeaderVersion
@@ -39,6 +51,16 @@ func (err *ErrInvalidTXTPolicyRecordVersion) Error() string {
 	return fmt.Sprintf("invalid TXT policy record version: %v", err.EntryVersion)
 }
 
+// ErrInvalidTableChecksum means the FIT header entry claims the table
+// checksum is valid, but the bytes of the table do not sum up to zero.
+type ErrInvalidTableChecksum struct {
+	Sum uint8
+}
+
+func (err *ErrInvalidTableChecksum) Error() string {
+	return fmt.Sprintf("invalid FIT table checksum: the table bytes sum up to 0x%02X instead of 0", err.Sum)
+}
+
 // ErrExpectedFITHeadersMagic means FIT magic string was not found where
 // it was expected.
 type ErrExpectedFITHeadersMagic struct {
@@ -56,3 +78,15 @@ type ErrNotFound struct{}
 func (ErrNotFound) Error() string {
 	return "not found"
 }
+
+// ErrAddressOutOfImage means an address of the FIT is neither a physical
+// address of the image mapped at the top of 4GB nor an offset within it.
+type ErrAddressOutOfImage struct {
+	Address   uint64
+	ImageSize uint64
+}
+
+func (err *ErrAddressOutOfImage) Error() string {
+	return fmt.Sprintf("address 0x%x is outside of the image of size 0x%x mapped at [0x%x:0x%x]",
+		err.Address, err.ImageSize, consts.BasePhysAddr-err.ImageSize, uint64(consts.BasePhysAddr))
+}
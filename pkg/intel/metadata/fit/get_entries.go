@@ -25,3 +25,10 @@ func GetEntriesFrom(firmware io.ReadSeeker) (Entries, error) {
 
 	return table.GetEntriesFrom(firmware), nil
 }
+
+// GetEntriesFromReaderAt returns parsed FIT-entries of a firmware image of
+// the given size. Only the FIT pointer, the table and the data of the entries
+// are read, so the image does not have to be loaded into memory.
+func GetEntriesFromReaderAt(firmware io.ReaderAt, size int64) (Entries, error) {
+	return GetEntriesFrom(io.NewSectionReader(firmware, 0, size))
+}
@@ -10,6 +10,8 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,6 +39,26 @@ func TestGetEntries(t *testing.T) {
 	assert.Equal(t, 27, len(entries))
 }
 
+func TestGetEntriesFromReaderAt(t *testing.T) {
+	firmwareBytes, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(fitHeadersSampleBZ2)))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "firmware.bin")
+	require.NoError(t, os.WriteFile(path, firmwareBytes, 0o644))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	entries, err := GetEntriesFromReaderAt(file, int64(len(firmwareBytes)))
+	require.NoError(t, err)
+	expected, err := GetEntries(firmwareBytes)
+	require.NoError(t, err)
+	require.Equal(t, expected, entries)
+
+	_, err = GetEntriesFromReaderAt(file, 16)
+	require.Error(t, err)
+}
+
 func TestGetEntriesInvalidAddr(t *testing.T) {
 	sampleEntries := getSampleEntries(t)
 	for _, entry := range sampleEntries[1:] {
@@ -56,7 +78,7 @@ func TestGetEntriesInvalidAddr(t *testing.T) {
 		case *EntrySkip:
 			continue
 		default:
-			require.Contains(t, fmt.Sprintf("%v", entry.GetEntryBase().HeadersErrors), "index")
+			require.Contains(t, fmt.Sprintf("%v", entry.GetEntryBase().HeadersErrors), "address 0x200000001 is outside of the image")
 		}
 	}
 }
@@ -0,0 +1,45 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit/consts"
+)
+
+// InitTable returns the binary representation of a minimal FIT: a table with
+// only the FIT header entry (type 0x00), as created by `fittool init`.
+//
+// The returned bytes are supposed to be placed in an image of size imageSize
+// at the address pointer, and pointer is supposed to be written at
+// consts.FITPointerOffset from the end of the image. See also Entries.Inject,
+// which writes both.
+func InitTable(imageSize uint64, pointer uint64) ([]byte, error) {
+	offset, err := AddressToOffset(pointer, imageSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FIT pointer: %w", err)
+	}
+
+	entries := Entries{
+		&EntryFITHeaderEntry{},
+	}
+	if err := entries.RecalculateHeaders(); err != nil {
+		return nil, fmt.Errorf("unable to recalculate headers: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := entries.Table().WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("unable to compile the table: %w", err)
+	}
+	result := buf.Bytes()
+
+	if imageSize < consts.FITPointerOffset || offset+uint64(len(result)) > imageSize-consts.FITPointerOffset {
+		return nil, fmt.Errorf("the FIT at offset 0x%X (size 0x%X) does not fit before the FIT pointer of an image of size 0x%X",
+			offset, len(result), imageSize)
+	}
+	return result, nil
+}
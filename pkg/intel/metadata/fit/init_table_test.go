@@ -0,0 +1,53 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit/consts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitTable(t *testing.T) {
+	const imageSize = 0x10000
+	const fitOffset = 0x8000
+	pointer := CalculatePhysAddrFromOffset(fitOffset, imageSize)
+
+	table, err := InitTable(imageSize, pointer)
+	require.NoError(t, err)
+	require.Len(t, table, int(entryHeadersSize))
+	require.NoError(t, ValidateTableChecksum(table))
+
+	image := make([]byte, imageSize)
+	copy(image[fitOffset:], table)
+	binary.LittleEndian.PutUint64(image[imageSize-consts.FITPointerOffset:], pointer)
+
+	entries, err := GetEntries(image)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	header, ok := entries[0].(*EntryFITHeaderEntry)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), header.GetEntryBase().Headers.Size.Uint32())
+
+	// It matches the table written by Entries.Inject, like `fittool init` does.
+	injected := make([]byte, imageSize)
+	entries = Entries{&EntryFITHeaderEntry{}}
+	require.NoError(t, entries.RecalculateHeaders())
+	require.NoError(t, entries.Inject(injected, fitOffset))
+	require.Equal(t, image, injected)
+}
+
+func TestInitTableInvalidPointer(t *testing.T) {
+	const imageSize = 0x10000
+
+	_, err := InitTable(imageSize, 0xfffe0000)
+	require.Error(t, err)
+
+	// The table would overlap the FIT pointer
+	_, err = InitTable(imageSize, CalculatePhysAddrFromOffset(imageSize-consts.FITPointerOffset-8, imageSize))
+	require.Error(t, err)
+}
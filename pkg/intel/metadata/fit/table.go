@@ -33,14 +33,34 @@ func (table Table) GetEntriesFrom(firmware io.ReadSeeker) (result Entries) {
 	return
 }
 
-// String prints the fit table in a tabular form
+// String prints the fit table in a tabular form. The columns of RawString
+// are followed by the name of the entry type and by the address and the size
+// decoded according to the entry type.
 func (table Table) String() string {
+	return table.format(true)
+}
+
+// RawString prints the fit table in a tabular form using raw numeric
+// values of the headers.
+func (table Table) RawString() string {
+	return table.format(false)
+}
+
+func (table Table) format(decoded bool) string {
 	var s strings.Builder
 	// PrintFit prints the Firmware Interface Table in a tabular human readable form.
-	fmt.Fprintf(&s, "%-3s | %-32s | %-20s | %-8s | %-6s | %-15s | %-10s\n", "#", "Type", "Address", "Size", "Version", "Checksum valid", "Checksum")
-	s.WriteString("---------------------------------------------------------------------------------------------------------------\n")
+	fmt.Fprintf(&s, "%-3s | %-32s | %-20s | %-8s | %-6s | %-15s | %-10s", "#", "Type", "Address", "Size", "Version", "Checksum valid", "Checksum")
+	if decoded {
+		fmt.Fprintf(&s, " | %-21s | %-18s | %-12s", "Name", "Decoded address", "Decoded size")
+	}
+	s.WriteString("\n")
+	s.WriteString("---------------------------------------------------------------------------------------------------------------")
+	if decoded {
+		s.WriteString("--------------------------------------------------------------")
+	}
+	s.WriteString("\n")
 	for idx, entry := range table {
-		fmt.Fprintf(&s, "%-3d | %-25s (0x%02X) | %-20s | %-8d | 0x%04x  | %-15v | %-10d\n",
+		fmt.Fprintf(&s, "%-3d | %-25s (0x%02X) | %-20s | %-8d | 0x%04x  | %-15v | %-10d",
 			idx,
 			entry.Type(), uint8(entry.Type()),
 			entry.Address.String(),
@@ -48,6 +68,10 @@ func (table Table) String() string {
 			uint16(entry.Version),
 			entry.IsChecksumValid(),
 			entry.Checksum)
+		if decoded {
+			fmt.Fprintf(&s, " | %-21s | %-18s | %-12s", entry.Type().ShortName(), entry.describeAddress(), entry.describeSize())
+		}
+		s.WriteString("\n")
 	}
 	return s.String()
 }
@@ -62,6 +86,37 @@ func (table Table) First(entryType EntryType) *EntryHeaders {
 	return nil
 }
 
+// CalculateChecksum calculates the checksum of the FIT header entry (the
+// first one), which is the value making all the bytes of the table sum up to
+// zero. See point 4.2 of the FIT specification.
+func (table Table) CalculateChecksum() uint8 {
+	var buf bytes.Buffer
+	for idx, entryHeaders := range table {
+		if idx == 0 {
+			entryHeaders.Checksum = 0
+		}
+		if _, err := entryHeaders.WriteTo(&buf); err != nil {
+			panic(err)
+		}
+	}
+
+	result := uint8(0)
+	for _, _byte := range buf.Bytes() {
+		result += _byte
+	}
+	return -result
+}
+
+// RecalculateChecksum updates the checksum of the FIT header entry to be
+// consistent with the table if its bit "C_V" is set. It should be called
+// after any change to the headers of the table, including the entries count.
+func (table Table) RecalculateChecksum() {
+	if len(table) == 0 || table[0].Type() != EntryTypeFITHeaderEntry || !table[0].IsChecksumValid() {
+		return
+	}
+	table[0].Checksum = table.CalculateChecksum()
+}
+
 // Write compiles FIT headers into a binary representation and writes to "b". If len(b)
 // is less than required, then io.ErrUnexpectedEOF is returned.
 func (table Table) Write(b []byte) (n int, err error) {
@@ -103,6 +158,207 @@ func (table Table) WriteToFirmwareImage(w io.ReadWriteSeeker) (n int64, err erro
 	return table.WriteTo(w)
 }
 
+// EntrySpec describes a FIT entry to be added by Table.AppendEntry.
+type EntrySpec struct {
+	Type    EntryType
+	Address Address64
+
+	// Size is the raw value of the SIZE field, its unit depends on the entry type.
+	Size uint32
+
+	// Version is the value of the VERSION field, 0x0100 is used if it is zero.
+	Version EntryVersion
+
+	IsChecksumValid bool
+
+	// Checksum overrides the calculated value of the CHECKSUM field if set.
+	Checksum *uint8
+}
+
+func (spec EntrySpec) headers() EntryHeaders {
+	hdr := EntryHeaders{
+		Address: spec.Address,
+		Version: spec.Version,
+	}
+	if hdr.Version == 0 {
+		hdr.Version = EntryVersion(0x0100)
+	}
+	hdr.Size.SetUint32(spec.Size)
+	hdr.TypeAndIsChecksumValid.SetType(spec.Type)
+	hdr.TypeAndIsChecksumValid.SetIsChecksumValid(spec.IsChecksumValid)
+	if spec.IsChecksumValid {
+		hdr.Checksum = hdr.CalculateChecksum()
+	}
+	if spec.Checksum != nil {
+		hdr.Checksum = *spec.Checksum
+	}
+	return hdr
+}
+
+// AppendEntry adds the entry described by spec to the end of the table and
+// writes the grown table into the firmware image. The entries count (and the
+// checksum, if it is marked valid) of the FIT header entry is updated
+// accordingly.
+//
+// The table is grown in place, so an error is returned if the 16 bytes after
+// the table are not free space (all 0x00 or 0xff) or if they overlap
+// the FIT pointer.
+func (table *Table) AppendEntry(firmware io.ReadWriteSeeker, spec EntrySpec) error {
+	if len(*table) == 0 || (*table)[0].Type() != EntryTypeFITHeaderEntry {
+		return fmt.Errorf("the first entry should be of type 0x00")
+	}
+	if spec.Type == EntryTypeFITHeaderEntry || spec.Type >= 0x80 {
+		return fmt.Errorf("invalid entry type %s (0x%02X)", spec.Type, uint8(spec.Type))
+	}
+
+	firmwareSize, err := firmware.Seek(0, io.SeekEnd)
+	if err != nil || firmwareSize < 0 {
+		return fmt.Errorf("unable to determine firmware size; result: %d; err: %w", firmwareSize, err)
+	}
+	startIdx, _, err := GetHeadersTableRangeFrom(firmware)
+	if err != nil {
+		return fmt.Errorf("unable to find the beginning of the FIT: %w", err)
+	}
+
+	newEntryStartIdx := startIdx + uint64(len(*table))*uint64(entryHeadersSize)
+	newEntryEndIdx := newEntryStartIdx + uint64(entryHeadersSize)
+	if err := check.BytesRange(uint(firmwareSize), int(newEntryStartIdx), int(newEntryEndIdx)); err != nil {
+		return fmt.Errorf("the table cannot grow: %w", err)
+	}
+	pointerStartIdx, pointerEndIdx := GetPointerCoordinates(uint64(firmwareSize))
+	if newEntryStartIdx < uint64(pointerEndIdx) && uint64(pointerStartIdx) < newEntryEndIdx {
+		return fmt.Errorf("the table cannot grow: the new entry [%#x:%#x] overlaps the FIT pointer [%#x:%#x]",
+			newEntryStartIdx, newEntryEndIdx, pointerStartIdx, pointerEndIdx)
+	}
+	freeSpace, err := sliceOrCopyBytesFrom(firmware, newEntryStartIdx, newEntryEndIdx)
+	if err != nil {
+		return fmt.Errorf("unable to get the bytes after the table: %w", err)
+	}
+	if !isFreeSpace(freeSpace) {
+		return fmt.Errorf("the table cannot grow: bytes [%#x:%#x] after the table are in use",
+			newEntryStartIdx, newEntryEndIdx)
+	}
+
+	// Force a copy, so "table" is left intact in case of an error
+	newTable := append((*table)[:len(*table):len(*table)], spec.headers())
+	newTable[0].Size.SetUint32(uint32(len(newTable)))
+	newTable.RecalculateChecksum()
+
+	if _, err := newTable.WriteToFirmwareImage(firmware); err != nil {
+		return fmt.Errorf("unable to write FIT into the firmware: %w", err)
+	}
+	*table = newTable
+	return nil
+}
+
+// Relocate moves the table to the physical address newAddr in the firmware
+// image and updates the FIT pointer accordingly. The addresses of the entries
+// referring to the image are shifted by the same delta as the table, as if the
+// whole image content had been moved together with the table. The checksums
+// of the shifted entries (and of the table, if the bit "C_V" of the FIT header
+// entry is set) are recalculated. The indexes of the shifted entries are
+// returned.
+//
+// The data referenced by the shifted entries is not moved, it should already
+// be at the new addresses. In particular the size of a startup AC module is
+// read from its data, so an error is returned if there is no startup AC module
+// at the new address.
+func (table *Table) Relocate(firmware io.ReadWriteSeeker, newAddr uint64) ([]int, error) {
+	if len(*table) == 0 || (*table)[0].Type() != EntryTypeFITHeaderEntry {
+		return nil, fmt.Errorf("the first entry should be of type 0x00")
+	}
+
+	firmwareSize, err := firmware.Seek(0, io.SeekEnd)
+	if err != nil || firmwareSize < 0 {
+		return nil, fmt.Errorf("unable to determine firmware size; result: %d; err: %w", firmwareSize, err)
+	}
+	imageSize := uint64(firmwareSize)
+	if imageSize > consts.BasePhysAddr {
+		return nil, fmt.Errorf("the image of size 0x%X does not fit below 4GB", imageSize)
+	}
+	isInImage := func(addr uint64) bool {
+		return addr >= consts.BasePhysAddr-imageSize && addr < consts.BasePhysAddr
+	}
+
+	pointerStartIdx, pointerEndIdx := GetPointerCoordinates(imageSize)
+	if err := check.BytesRange(uint(imageSize), int(pointerStartIdx), int(pointerEndIdx)); err != nil {
+		return nil, fmt.Errorf("invalid fit pointer bytes range: %w", err)
+	}
+	pointerBytes, err := sliceOrCopyBytesFrom(firmware, uint64(pointerStartIdx), uint64(pointerStartIdx)+8)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get FIT pointer value: %w", err)
+	}
+	oldAddr := binary.LittleEndian.Uint64(pointerBytes)
+	if !isInImage(oldAddr) {
+		return nil, fmt.Errorf("the FIT pointer 0x%X does not point into the image", oldAddr)
+	}
+	if !isInImage(newAddr) {
+		return nil, fmt.Errorf("the new FIT address 0x%X is outside of the image mapped at [0x%X:0x%X]",
+			newAddr, consts.BasePhysAddr-imageSize, uint64(consts.BasePhysAddr))
+	}
+	delta := newAddr - oldAddr
+
+	startIdx := CalculateOffsetFromPhysAddr(newAddr, imageSize)
+	endIdx := startIdx + uint64(len(*table))*uint64(entryHeadersSize)
+	if endIdx > imageSize {
+		return nil, fmt.Errorf("the table [0x%X:0x%X] does not fit into the image of size 0x%X", startIdx, endIdx, imageSize)
+	}
+	if startIdx < uint64(pointerEndIdx) && uint64(pointerStartIdx) < endIdx {
+		return nil, fmt.Errorf("the table [%#x:%#x] overlaps the FIT pointer [%#x:%#x]",
+			startIdx, endIdx, pointerStartIdx, pointerEndIdx)
+	}
+
+	// Force a copy, so "table" is left intact in case of an error
+	newTable := append(Table{}, *table...)
+	var shifted []int
+	for idx := range newTable {
+		hdr := &newTable[idx]
+		if hdr.Type() == EntryTypeFITHeaderEntry || !isInImage(hdr.Address.Pointer()) {
+			continue
+		}
+		addr := hdr.Address.Pointer() + delta
+		if !isInImage(addr) {
+			return nil, fmt.Errorf("entry #%d would be relocated outside of the image: 0x%X", idx, addr)
+		}
+		hdr.Address = Address64(addr)
+		if hdr.Type() == EntryTypeStartupACModuleEntry {
+			offset := CalculateOffsetFromPhysAddr(addr, imageSize)
+			size, err := EntrySACMParseSizeFrom(firmware, offset)
+			if err == nil && (size == 0 || offset+uint64(size) > imageSize) {
+				err = fmt.Errorf("invalid size 0x%X", size)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("entry #%d: no startup AC module at the new address 0x%X: %w", idx, addr, err)
+			}
+		}
+		if hdr.IsChecksumValid() {
+			hdr.Checksum = hdr.CalculateChecksum()
+		}
+		shifted = append(shifted, idx)
+	}
+
+	newTable.RecalculateChecksum()
+
+	if _, err := firmware.Seek(int64(startIdx), io.SeekStart); err != nil {
+		return nil, fmt.Errorf("unable to Seek(%d, io.SeekStart) to write the table: %w", int64(startIdx), err)
+	}
+	if _, err := newTable.WriteTo(firmware); err != nil {
+		return nil, fmt.Errorf("unable to write FIT into the firmware: %w", err)
+	}
+	if _, err := firmware.Seek(pointerStartIdx, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("unable to Seek(%d, io.SeekStart) to write the FIT pointer: %w", pointerStartIdx, err)
+	}
+	if err := binary.Write(firmware, binary.LittleEndian, newAddr); err != nil {
+		return nil, fmt.Errorf("unable to write the FIT pointer: %w", err)
+	}
+	*table = newTable
+	return shifted, nil
+}
+
+func isFreeSpace(b []byte) bool {
+	return bytes.Count(b, []byte{0x00}) == len(b) || bytes.Count(b, []byte{0xff}) == len(b)
+}
+
 // ParseEntryHeadersFrom parses a single entry headers entry.
 func ParseEntryHeadersFrom(r io.Reader) (*EntryHeaders, error) {
 	entryHeaders := EntryHeaders{}
@@ -128,6 +384,40 @@ func ParseTable(b []byte) (Table, error) {
 	return result, nil
 }
 
+// ValidateTableChecksum validates the checksum of a raw FIT table (starting
+// with the FIT header entry). The checksum is verified only if the bit "C_V"
+// of the header entry is set; in this case all the bytes of the table (the
+// header entry declares the number of entries) must sum up to zero, otherwise
+// *ErrInvalidTableChecksum is returned.
+func ValidateTableChecksum(data []byte) error {
+	if uint(len(data)) < entryHeadersSize {
+		return fmt.Errorf("FIT table is too short: %d bytes", len(data))
+	}
+	hdr, err := ParseEntryHeadersFrom(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if hdr.Type() != EntryTypeFITHeaderEntry {
+		return fmt.Errorf("the first entry of the FIT table is of type %s, expected %s", hdr.Type(), EntryTypeFITHeaderEntry)
+	}
+	if !hdr.IsChecksumValid() {
+		return nil
+	}
+
+	tableSize := uint64(hdr.Size.Uint32()) * uint64(entryHeadersSize)
+	if tableSize > uint64(len(data)) {
+		return fmt.Errorf("FIT header entry declares a table of %d bytes, but only %d bytes are available", tableSize, len(data))
+	}
+	var sum uint8
+	for _, b := range data[:tableSize] {
+		sum += b
+	}
+	if sum != 0 {
+		return &ErrInvalidTableChecksum{Sum: sum}
+	}
+	return nil
+}
+
 // GetPointerCoordinates returns the position of the FIT pointer within
 // the firmware.
 func GetPointerCoordinates(firmwareSize uint64) (startIdx, endIdx int64) {
@@ -136,6 +426,33 @@ func GetPointerCoordinates(firmwareSize uint64) (startIdx, endIdx int64) {
 	return
 }
 
+// HasFIT returns true if the firmware image contains a FIT: the FIT pointer
+// refers to a location within the image which starts with a FIT header entry.
+//
+// Unlike GetTable it does not parse the table, and it never fails on
+// malformed images, so it could be used as a quick guard.
+func HasFIT(firmware []byte) bool {
+	firmwareSize := uint64(len(firmware))
+	if firmwareSize < consts.FITPointerOffset || firmwareSize > consts.BasePhysAddr {
+		return false
+	}
+	pointerStartIdx, _ := GetPointerCoordinates(firmwareSize)
+	pointer := binary.LittleEndian.Uint64(firmware[pointerStartIdx:])
+	if pointer < consts.BasePhysAddr-firmwareSize || pointer >= consts.BasePhysAddr {
+		return false
+	}
+
+	startIdx := firmwareSize - CalculateTailOffsetFromPhysAddr(pointer)
+	if startIdx+uint64(entryHeadersSize) > firmwareSize {
+		return false
+	}
+	hdr := firmware[startIdx : startIdx+uint64(entryHeadersSize)]
+	if !bytes.Equal(hdr[:len(consts.FITHeadersMagic)], []byte(consts.FITHeadersMagic)) {
+		return false
+	}
+	return TypeAndIsChecksumValid(hdr[14]).Type() == EntryTypeFITHeaderEntry
+}
+
 // GetHeadersTableRangeFrom returns the starting and ending indexes of the FIT
 // headers table within the firmware image.
 func GetHeadersTableRangeFrom(firmware io.ReadSeeker) (startIdx, endIdx uint64, err error) {
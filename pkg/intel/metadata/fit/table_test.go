@@ -0,0 +1,275 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit/consts"
+	"github.com/stretchr/testify/require"
+	"github.com/xaionaro-go/bytesextra"
+)
+
+func getSampleTable() Table {
+	newHeaders := func(entryType EntryType, addr uint64, size uint32, version EntryVersion, checksumValid bool) EntryHeaders {
+		hdr := EntryHeaders{
+			Address: Address64(addr),
+			Version: version,
+		}
+		hdr.Size.SetUint32(size)
+		hdr.TypeAndIsChecksumValid.SetType(entryType)
+		hdr.TypeAndIsChecksumValid.SetIsChecksumValid(checksumValid)
+		return hdr
+	}
+
+	return Table{
+		newHeaders(EntryTypeFITHeaderEntry, binary.LittleEndian.Uint64([]byte("_FIT_   ")), 5, 0x0100, false),
+		newHeaders(EntryTypeMicrocodeUpdateEntry, 0xffe10080, 0, 0x0100, false),
+		newHeaders(EntryTypeStartupACModuleEntry, 0xffe40000, 0, 0x0100, false),
+		newHeaders(EntryTypeKeyManifestRecord, 0xffe80000, 0x240, 0x0100, false),
+		newHeaders(EntryTypeBIOSStartupModuleEntry, 0xfff00000, 0x1000, 0x0100, true),
+	}
+}
+
+func TestTableString(t *testing.T) {
+	expected := "" +
+		"#   | Type                             | Address              | Size     | Version | Checksum valid  | Checksum   | Name                  | Decoded address    | Decoded size\n" +
+		"-----------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n" +
+		"0   | FITHeaderEntry            (0x00) | 0x2020205f5449465f   | 5        | 0x0100  | false           | 0          | FITHeader             | \"_FIT_\"            | 5 entries   \n" +
+		"1   | MicrocodeUpdateEntry      (0x01) | 0xffe10080           | 0        | 0x0100  | false           | 0          | Microcode             | 0xffe10080         | 0x0         \n" +
+		"2   | SACM                      (0x02) | 0xffe40000           | 0        | 0x0100  | false           | 0          | StartupACM            | 0xffe40000         | -           \n" +
+		"3   | KeyManifestRecord         (0x0B) | 0xffe80000           | 576      | 0x0100  | false           | 0          | KeyManifest           | 0xffe80000         | 0x240       \n" +
+		"4   | BIOSStartupModuleEntry    (0x07) | 0xfff00000           | 4096     | 0x0100  | true            | 0          | BIOSStartupModule     | 0xfff00000         | 0x10000     \n"
+	require.Equal(t, expected, getSampleTable().String())
+}
+
+func TestTableRawString(t *testing.T) {
+	expected := "" +
+		"#   | Type                             | Address              | Size     | Version | Checksum valid  | Checksum  \n" +
+		"---------------------------------------------------------------------------------------------------------------\n" +
+		"0   | FITHeaderEntry            (0x00) | 0x2020205f5449465f   | 5        | 0x0100  | false           | 0         \n" +
+		"1   | MicrocodeUpdateEntry      (0x01) | 0xffe10080           | 0        | 0x0100  | false           | 0         \n" +
+		"2   | SACM                      (0x02) | 0xffe40000           | 0        | 0x0100  | false           | 0         \n" +
+		"3   | KeyManifestRecord         (0x0B) | 0xffe80000           | 576      | 0x0100  | false           | 0         \n" +
+		"4   | BIOSStartupModuleEntry    (0x07) | 0xfff00000           | 4096     | 0x0100  | true            | 0         \n"
+	require.Equal(t, expected, getSampleTable().RawString())
+}
+
+func TestTableAppendEntry(t *testing.T) {
+	const imageSize = 0x1000
+
+	newImage := func(tableOffset uint64) ([]byte, Table) {
+		image := bytes.Repeat([]byte{0xff}, imageSize)
+		table := getSampleTable()[:2]
+		table[0].Size.SetUint32(uint32(len(table)))
+		table[0].TypeAndIsChecksumValid.SetIsChecksumValid(true)
+		table.RecalculateChecksum()
+		var buf bytes.Buffer
+		_, err := table.WriteTo(&buf)
+		require.NoError(t, err)
+		copy(image[tableOffset:], buf.Bytes())
+		pointerStartIdx, _ := GetPointerCoordinates(imageSize)
+		binary.LittleEndian.PutUint64(image[pointerStartIdx:], CalculatePhysAddrFromOffset(tableOffset, imageSize))
+		return image, table
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		image, table := newImage(0x800)
+		err := table.AppendEntry(bytesextra.NewReadWriteSeeker(image), EntrySpec{
+			Type:            EntryTypeBIOSPolicyRecord,
+			Address:         0xffe90000,
+			Size:            0x100,
+			IsChecksumValid: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, table, 3)
+
+		parsed, err := GetTable(image)
+		require.NoError(t, err)
+		require.Equal(t, table, parsed)
+		require.Equal(t, uint32(3), parsed[0].Size.Uint32())
+		startIdx, endIdx, err := GetHeadersTableRangeFrom(bytesextra.NewReadWriteSeeker(image))
+		require.NoError(t, err)
+		require.NoError(t, ValidateTableChecksum(image[startIdx:endIdx]))
+		require.Equal(t, EntryTypeBIOSPolicyRecord, parsed[2].Type())
+		require.Equal(t, EntryVersion(0x0100), parsed[2].Version)
+		require.Equal(t, parsed[2].CalculateChecksum(), parsed[2].Checksum)
+	})
+
+	t.Run("space_in_use", func(t *testing.T) {
+		image, table := newImage(0x800)
+		image[0x820] = 0x12
+		err := table.AppendEntry(bytesextra.NewReadWriteSeeker(image), EntrySpec{Type: EntryTypeSkip})
+		require.Error(t, err)
+		require.Len(t, table, 2)
+	})
+
+	t.Run("overlaps_fit_pointer", func(t *testing.T) {
+		image, table := newImage(imageSize - consts.FITPointerOffset - 0x20)
+		err := table.AppendEntry(bytesextra.NewReadWriteSeeker(image), EntrySpec{Type: EntryTypeSkip})
+		require.Error(t, err)
+		require.Len(t, table, 2)
+	})
+
+	t.Run("invalid_type", func(t *testing.T) {
+		image, table := newImage(0x800)
+		err := table.AppendEntry(bytesextra.NewReadWriteSeeker(image), EntrySpec{Type: EntryTypeFITHeaderEntry})
+		require.Error(t, err)
+	})
+}
+
+func TestValidateTableChecksum(t *testing.T) {
+	table := getSampleTable()
+	table[0].TypeAndIsChecksumValid.SetIsChecksumValid(true)
+
+	var buf bytes.Buffer
+	_, err := table.WriteTo(&buf)
+	require.NoError(t, err)
+	data := buf.Bytes()
+	var sum uint8
+	for _, b := range data {
+		sum += b
+	}
+	// the checksum of the header entry is at offset 15
+	data[15] = -sum
+	require.NoError(t, ValidateTableChecksum(data))
+	require.Equal(t, -sum, table.CalculateChecksum())
+
+	tampered := append([]byte{}, data...)
+	tampered[2*entryHeadersSize]++
+	err = ValidateTableChecksum(tampered)
+	require.Error(t, err)
+	var errChecksum *ErrInvalidTableChecksum
+	require.True(t, errors.As(err, &errChecksum))
+	require.Equal(t, uint8(1), errChecksum.Sum)
+
+	// Without the "C_V" bit the checksum is not verified.
+	tampered[14] &^= 0x80
+	require.NoError(t, ValidateTableChecksum(tampered))
+
+	// The declared table must fit into the data.
+	require.Error(t, ValidateTableChecksum(data[:len(data)-1]))
+}
+
+func TestHasFIT(t *testing.T) {
+	const imageSize = 0x1000
+
+	image := bytes.Repeat([]byte{0xff}, imageSize)
+	require.False(t, HasFIT(image))
+	require.False(t, HasFIT(nil))
+	require.False(t, HasFIT(image[:consts.FITPointerOffset-1]))
+
+	table := Entries{&EntryFITHeaderEntry{}}
+	require.NoError(t, table.RecalculateHeaders())
+	require.NoError(t, table.Inject(image, 0x800))
+	require.True(t, HasFIT(image))
+
+	// A pointer to the last bytes of the image, the header entry does not fit.
+	pointerStartIdx, _ := GetPointerCoordinates(imageSize)
+	binary.LittleEndian.PutUint64(image[pointerStartIdx:], CalculatePhysAddrFromOffset(imageSize-8, imageSize))
+	require.False(t, HasFIT(image))
+
+	// A pointer below the image, which would underflow the offset calculation.
+	binary.LittleEndian.PutUint64(image[pointerStartIdx:], 0x1000)
+	require.False(t, HasFIT(image))
+
+	// A valid pointer to something else than a FIT.
+	binary.LittleEndian.PutUint64(image[pointerStartIdx:], CalculatePhysAddrFromOffset(0x100, imageSize))
+	require.False(t, HasFIT(image))
+	binary.LittleEndian.PutUint64(image[pointerStartIdx:], CalculatePhysAddrFromOffset(0x800, imageSize))
+	require.True(t, HasFIT(image))
+	image[0x800+14] = byte(EntryTypeSkip)
+	require.False(t, HasFIT(image))
+}
+
+func TestTableRelocate(t *testing.T) {
+	const imageSize = 0x10000
+
+	sacm := &EntrySACM{}
+	require.NoError(t, sacm.SetData(newTestSACMData(0, nil)))
+
+	image := make([]byte, imageSize)
+	copy(image[0x2000:], sacm.DataSegmentBytes)
+	table := Table{
+		EntrySpec{Type: EntryTypeFITHeaderEntry, Address: Address64(binary.LittleEndian.Uint64([]byte(consts.FITHeadersMagic))), Size: 4, IsChecksumValid: true}.headers(),
+		EntrySpec{Type: EntryTypeStartupACModuleEntry, Address: Address64(CalculatePhysAddrFromOffset(0x2000, imageSize))}.headers(),
+		EntrySpec{Type: EntryTypeBIOSStartupModuleEntry, Address: Address64(CalculatePhysAddrFromOffset(0x3000, imageSize)), Size: 1, IsChecksumValid: true}.headers(),
+		// The address of a TXT policy record is not a location in the image.
+		EntrySpec{Type: EntryTypeTXTPolicyRecord, Address: 0xfed30880}.headers(),
+	}
+	var buf bytes.Buffer
+	_, err := table.WriteTo(&buf)
+	require.NoError(t, err)
+	copy(image[0x1000:], buf.Bytes())
+	pointerStartIdx, _ := GetPointerCoordinates(imageSize)
+	binary.LittleEndian.PutUint64(image[pointerStartIdx:], CalculatePhysAddrFromOffset(0x1000, imageSize))
+
+	const delta = 0x4000
+	newAddr := CalculatePhysAddrFromOffset(0x1000+delta, imageSize)
+
+	// The startup AC module was not moved.
+	orig := append([]byte{}, image...)
+	_, err = table.Relocate(bytesextra.NewReadWriteSeeker(image), newAddr)
+	require.Error(t, err)
+	require.Equal(t, orig, image)
+
+	_, err = table.Relocate(bytesextra.NewReadWriteSeeker(image), CalculatePhysAddrFromOffset(imageSize-0x20, imageSize))
+	require.Error(t, err)
+
+	copy(image[0x2000+delta:], sacm.DataSegmentBytes)
+	shifted, err := table.Relocate(bytesextra.NewReadWriteSeeker(image), newAddr)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, shifted)
+	require.Equal(t, Address64(CalculatePhysAddrFromOffset(0x2000+delta, imageSize)), table[1].Address)
+	require.Equal(t, Address64(CalculatePhysAddrFromOffset(0x3000+delta, imageSize)), table[2].Address)
+	require.Equal(t, table[2].CalculateChecksum(), table[2].Checksum)
+	require.Equal(t, Address64(0xfed30880), table[3].Address)
+
+	startIdx, endIdx, err := GetHeadersTableRangeFrom(bytesextra.NewReadWriteSeeker(image))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x1000+delta), startIdx)
+	require.NoError(t, ValidateTableChecksum(image[startIdx:endIdx]))
+	parsed, err := GetTable(image)
+	require.NoError(t, err)
+	require.Equal(t, table, parsed)
+
+	// The startup AC module still resolves at its new address.
+	entries := parsed.GetEntries(image)
+	require.Empty(t, entries[1].GetEntryBase().HeadersErrors)
+	require.Equal(t, sacm.DataSegmentBytes, entries[1].GetEntryBase().DataSegmentBytes)
+}
+
+func TestTableDisableEntry(t *testing.T) {
+	table := getSampleTable()
+	table[0].TypeAndIsChecksumValid.SetIsChecksumValid(true)
+	table.RecalculateChecksum()
+	orig := table[4]
+	table[4].Disable()
+	table.RecalculateChecksum()
+
+	var buf bytes.Buffer
+	_, err := table.WriteTo(&buf)
+	require.NoError(t, err)
+	require.NoError(t, ValidateTableChecksum(buf.Bytes()))
+	parsed, err := ParseTable(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, parsed, len(table))
+
+	disabled := parsed[4]
+	require.Equal(t, EntryTypeSkip, disabled.Type())
+	require.False(t, disabled.IsChecksumValid())
+	require.Equal(t, uint8(EntryTypeSkip), uint8(disabled.TypeAndIsChecksumValid))
+	require.Equal(t, orig.Address, disabled.Address)
+	require.Equal(t, orig.Size, disabled.Size)
+	require.Equal(t, orig.Version, disabled.Version)
+
+	// the other entries are untouched
+	for idx := 0; idx < 4; idx++ {
+		require.Equal(t, table[idx], parsed[idx])
+	}
+}
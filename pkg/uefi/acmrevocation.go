@@ -0,0 +1,92 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// ACMRevocationSignature identifies an ACM revocation structure, which is
+// shipped as part of a Boot Guard Update Package (BGUP).
+//
+// The layout of the BGUP structures is not publicly documented. Parsing them
+// is best-effort and experimental: it assumes the layout of
+// ACMRevocationHeader, which may not match every update package.
+var ACMRevocationSignature = [8]byte{'_', '_', 'B', 'G', 'U', 'P', '_', '_'}
+
+// ACMRevocationVersion is the only supported version of the structure.
+const ACMRevocationVersion = 1
+
+// ACMRevocationHeader is the fixed part of an ACM revocation structure. It is
+// followed by Count little endian uint16 values, each one is a revoked ACM
+// security version number.
+type ACMRevocationHeader struct {
+	Signature [8]byte
+	Version   uint8
+	Reserved  [3]uint8
+	Count     uint32
+}
+
+// ACMRevocation is an ACM revocation structure found in the image.
+type ACMRevocation struct {
+	// Offset is the offset of the structure in the image.
+	Offset uint64
+	Header ACMRevocationHeader
+	// RevokedSVNs are the revoked ACM security version numbers.
+	RevokedSVNs []uint16
+}
+
+// FindACMRevocations looks up the ACM revocation structures in the image by
+// their signature and parses them. It returns nil if there are none.
+//
+// The signature could also match by chance, e.g. in compressed data, so the
+// matches which fail to parse are skipped. If strict is set and no valid
+// structure is found, the error of the first invalid match is returned.
+func FindACMRevocations(image []byte, strict bool) ([]*ACMRevocation, error) {
+	var result []*ACMRevocation
+	var firstErr error
+	for start := 0; start < len(image); {
+		idx := bytes.Index(image[start:], ACMRevocationSignature[:])
+		if idx < 0 {
+			break
+		}
+		offset := uint64(start + idx)
+		start = int(offset) + len(ACMRevocationSignature)
+		r, err := parseACMRevocation(image[offset:])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid ACM revocation structure at %#x: %v", offset, err)
+			}
+			continue
+		}
+		r.Offset = offset
+		result = append(result, r)
+	}
+	if result == nil && strict && firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+func parseACMRevocation(buf []byte) (*ACMRevocation, error) {
+	var r ACMRevocation
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &r.Header); err != nil {
+		return nil, err
+	}
+	if r.Header.Version != ACMRevocationVersion {
+		return nil, fmt.Errorf("unsupported version %d, expected %d", r.Header.Version, ACMRevocationVersion)
+	}
+	headerSize := uint64(binary.Size(r.Header))
+	if size := headerSize + 2*uint64(r.Header.Count); size > uint64(len(buf)) {
+		return nil, fmt.Errorf("%d revoked SVNs exceed the buffer of %#x bytes", r.Header.Count, len(buf))
+	}
+	r.RevokedSVNs = make([]uint16, r.Header.Count)
+	for i := range r.RevokedSVNs {
+		r.RevokedSVNs[i] = binary.LittleEndian.Uint16(buf[headerSize+2*uint64(i):])
+	}
+	return &r, nil
+}
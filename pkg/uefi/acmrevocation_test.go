@@ -0,0 +1,98 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func makeACMRevocation(version uint8, count uint32, svns ...uint16) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, ACMRevocationHeader{
+		Signature: ACMRevocationSignature,
+		Version:   version,
+		Count:     count,
+	})
+	binary.Write(&buf, binary.LittleEndian, svns)
+	return buf.Bytes()
+}
+
+func TestFindACMRevocations(t *testing.T) {
+	image := bytes.Repeat([]byte{0xff}, 0x1000)
+	copy(image[0x100:], makeACMRevocation(1, 3, 1, 2, 5))
+	copy(image[0x800:], makeACMRevocation(1, 0))
+
+	revocations, err := FindACMRevocations(image, true)
+	if err != nil {
+		t.Fatalf("FindACMRevocations failed: %v", err)
+	}
+	if len(revocations) != 2 {
+		t.Fatalf("got %d revocation structures, want 2", len(revocations))
+	}
+	if r := revocations[0]; r.Offset != 0x100 || !reflect.DeepEqual(r.RevokedSVNs, []uint16{1, 2, 5}) {
+		t.Errorf("unexpected revocation structure: %+v", r)
+	}
+	if r := revocations[1]; r.Offset != 0x800 || len(r.RevokedSVNs) != 0 {
+		t.Errorf("unexpected revocation structure: %+v", r)
+	}
+}
+
+func TestFindACMRevocationsAbsent(t *testing.T) {
+	revocations, err := FindACMRevocations(bytes.Repeat([]byte{0xff}, 0x1000), true)
+	if err != nil {
+		t.Fatalf("FindACMRevocations failed: %v", err)
+	}
+	if revocations != nil {
+		t.Errorf("got %+v, want nil", revocations)
+	}
+}
+
+func TestFindACMRevocationsInvalid(t *testing.T) {
+	var tests = []struct {
+		name  string
+		image []byte
+	}{
+		{"bad version", makeACMRevocation(2, 1, 1)},
+		{"truncated SVNs", makeACMRevocation(1, 4, 1, 2)},
+		{"truncated header", ACMRevocationSignature[:]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := FindACMRevocations(test.image, true); err == nil {
+				t.Errorf("FindACMRevocations succeeded, want error")
+			}
+			revocations, err := FindACMRevocations(test.image, false)
+			if err != nil {
+				t.Errorf("FindACMRevocations failed: %v", err)
+			}
+			if revocations != nil {
+				t.Errorf("got %+v, want nil", revocations)
+			}
+		})
+	}
+}
+
+func TestFindACMRevocationsBogusMatch(t *testing.T) {
+	image := bytes.Repeat([]byte{0xff}, 0x1000)
+	// a stray signature, e.g. in compressed data
+	copy(image[0x100:], makeACMRevocation(0x5a, 1, 1))
+	copy(image[0x800:], makeACMRevocation(1, 2, 3, 4))
+
+	for _, strict := range []bool{false, true} {
+		revocations, err := FindACMRevocations(image, strict)
+		if err != nil {
+			t.Fatalf("FindACMRevocations failed: %v", err)
+		}
+		if len(revocations) != 1 {
+			t.Fatalf("got %d revocation structures, want 1", len(revocations))
+		}
+		if r := revocations[0]; r.Offset != 0x800 || !reflect.DeepEqual(r.RevokedSVNs, []uint16{3, 4}) {
+			t.Errorf("unexpected revocation structure: %+v", r)
+		}
+	}
+}
@@ -6,12 +6,17 @@ package uefi
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 )
 
 // BIOSPadding holds the padding in between firmware volumes
 // This may sometimes hold data, even though it shouldn't. We need
 // to preserve it though.
 type BIOSPadding struct {
+	dirtyFlag
+	parentLink
+
 	buf    []byte
 	Offset uint64
 
@@ -41,6 +46,11 @@ func (bp *BIOSPadding) Apply(v Visitor) error {
 	return v.Visit(bp)
 }
 
+// Kind returns FirmwareKindBIOSPadding.
+func (bp *BIOSPadding) Kind() FirmwareKind {
+	return FirmwareKindBIOSPadding
+}
+
 // ApplyChildren applies a visitor to all the direct children of the BIOSPadding
 func (bp *BIOSPadding) ApplyChildren(v Visitor) error {
 	return nil
@@ -49,6 +59,9 @@ func (bp *BIOSPadding) ApplyChildren(v Visitor) error {
 // BIOSRegion represents the Bios Region in the firmware.
 // It holds all the FVs as well as padding
 type BIOSRegion struct {
+	dirtyFlag
+	parentLink
+
 	// holds the raw data
 	buf      []byte
 	Elements []*TypedFirmware `json:",omitempty"`
@@ -80,12 +93,16 @@ func (br *BIOSRegion) FlashRegion() (fr *FlashRegion) {
 // object, if a valid one is passed, or an error. It also points to the
 // Region struct uncovered in the ifd.
 func NewBIOSRegion(buf []byte, r *FlashRegion, _ FlashRegionType) (Region, error) {
+	return newBIOSRegion(buf, r, defaultParseConfig())
+}
+
+func newBIOSRegion(buf []byte, r *FlashRegion, cfg parseConfig) (Region, error) {
 	br := BIOSRegion{FRegion: r, Length: uint64(len(buf)),
 		RegionType: RegionTypeBIOS}
 	var absOffset uint64
 
 	// Copy the buffer
-	if ReadOnly {
+	if cfg.readOnly {
 		br.buf = buf
 	} else {
 		br.buf = make([]byte, len(buf))
@@ -115,8 +132,8 @@ func NewBIOSRegion(buf []byte, r *FlashRegion, _ FlashRegionType) (Region, error
 			}
 			br.Elements = append(br.Elements, MakeTyped(bp))
 		}
-		absOffset += uint64(offset)                                  // Find start of volume relative to bios region.
-		fv, err := NewFirmwareVolume(buf[offset:], absOffset, false) // False as top level FVs are not resizable
+		absOffset += uint64(offset)                                       // Find start of volume relative to bios region.
+		fv, err := newFirmwareVolume(buf[offset:], absOffset, false, cfg) // False as top level FVs are not resizable
 		if err != nil {
 			return nil, err
 		}
@@ -128,6 +145,7 @@ func NewBIOSRegion(buf []byte, r *FlashRegion, _ FlashRegionType) (Region, error
 		buf = buf[uint64(offset)+fv.Length:]
 		br.Elements = append(br.Elements, MakeTyped(fv))
 	}
+	linkChildren(&br)
 	return &br, nil
 }
 
@@ -148,6 +166,11 @@ func (br *BIOSRegion) Apply(v Visitor) error {
 	return v.Visit(br)
 }
 
+// Kind returns FirmwareKindBIOSRegion.
+func (br *BIOSRegion) Kind() FirmwareKind {
+	return FirmwareKindBIOSRegion
+}
+
 // ApplyChildren calls the visitor on each child node of BIOSRegion.
 func (br *BIOSRegion) ApplyChildren(v Visitor) error {
 	for _, f := range br.Elements {
@@ -167,3 +190,135 @@ func (br *BIOSRegion) FirstFV() (*FirmwareVolume, error) {
 	}
 	return nil, errors.New("no firmware volumes in BIOS Region")
 }
+
+// MoveFV moves the firmware volume at offset fvOffset of the region to
+// newOffset. The space left behind becomes erased padding. The volume may only
+// be moved over padding which is erased, it is an error if it would overlap
+// another volume, padding holding data or the end of the region.
+//
+// The paddings are rebuilt around the volumes and FVOffset is updated.
+// Addresses pointing into the volume from outside of the region layout, e.g.
+// from the FIT or from code, are not updated.
+//
+// The volume is not rebased. SEC, PEI core and PEIM files execute in place
+// from the address they were linked at, so volumes holding them outside of
+// compressed sections are refused.
+func (br *BIOSRegion) MoveFV(fvOffset, newOffset uint64) error {
+	type placedFV struct {
+		offset uint64
+		fv     *FirmwareVolume
+	}
+
+	// Lay out the elements as the assembly does and fill the region with
+	// everything but the volume to move.
+	var moved *FirmwareVolume
+	var fvs []placedFV
+	layout := make([]byte, br.Length)
+	var offset uint64
+	for _, e := range br.Elements {
+		ebuf := e.Value.Buf()
+		if offset+uint64(len(ebuf)) > br.Length {
+			return fmt.Errorf("BIOS region elements exceed the region length %#x", br.Length)
+		}
+		if fv, ok := e.Value.(*FirmwareVolume); ok {
+			if offset == fvOffset {
+				moved = fv
+			} else {
+				fvs = append(fvs, placedFV{offset: offset, fv: fv})
+			}
+		}
+		copy(layout[offset:], ebuf)
+		offset += uint64(len(ebuf))
+	}
+	if moved == nil {
+		return fmt.Errorf("no firmware volume at offset %#x of the BIOS region", fvOffset)
+	}
+	xip := &xipFileFinder{}
+	if err := moved.Apply(xip); err != nil {
+		return err
+	}
+	if xip.file != nil {
+		return fmt.Errorf("firmware volume at offset %#x holds %v file %v which executes in place and cannot be moved without rebasing",
+			fvOffset, xip.file.Header.Type, xip.file.Header.GUID)
+	}
+	polarity := moved.GetErasePolarity()
+	fvLen := uint64(len(moved.Buf()))
+	for i := fvOffset; i < fvOffset+fvLen; i++ {
+		layout[i] = polarity
+	}
+	for i := offset; i < br.Length; i++ {
+		layout[i] = polarity
+	}
+
+	newEnd := newOffset + fvLen
+	if newEnd < newOffset || newEnd > br.Length {
+		return fmt.Errorf("firmware volume of %#x bytes does not fit at offset %#x of the %#x bytes BIOS region",
+			fvLen, newOffset, br.Length)
+	}
+	for _, p := range fvs {
+		if newOffset < p.offset+uint64(len(p.fv.Buf())) && p.offset < newEnd {
+			return fmt.Errorf("firmware volume moved to [%#x:%#x] would overlap firmware volume %v at [%#x:%#x]",
+				newOffset, newEnd, p.fv, p.offset, p.offset+uint64(len(p.fv.Buf())))
+		}
+	}
+	if !IsErased(layout[newOffset:newEnd], polarity) {
+		return fmt.Errorf("firmware volume moved to [%#x:%#x] would overwrite non-erased padding", newOffset, newEnd)
+	}
+
+	fvs = append(fvs, placedFV{offset: newOffset, fv: moved})
+	sort.Slice(fvs, func(i, j int) bool { return fvs[i].offset < fvs[j].offset })
+
+	// Rebuild the elements with the paddings in between the volumes.
+	var elements []*TypedFirmware
+	addPadding := func(start, end uint64) {
+		if start < end {
+			buf := make([]byte, end-start)
+			copy(buf, layout[start:end])
+			elements = append(elements, MakeTyped(&BIOSPadding{buf: buf, Offset: start}))
+		}
+	}
+	offset = 0
+	for _, p := range fvs {
+		addPadding(offset, p.offset)
+		p.fv.FVOffset = p.offset
+		elements = append(elements, MakeTyped(p.fv))
+		offset = p.offset + uint64(len(p.fv.Buf()))
+	}
+	addPadding(offset, br.Length)
+
+	br.Elements = elements
+	linkChildren(br)
+	MarkDirty(br)
+	return nil
+}
+
+// xipFileFinder finds the first file executing in place from the flash. The
+// contents of compressed and GUID defined sections are decompressed to memory
+// before they run, so they are not searched.
+type xipFileFinder struct {
+	file *File
+}
+
+func (v *xipFileFinder) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *xipFileFinder) Visit(f Firmware) error {
+	if v.file != nil {
+		return nil
+	}
+	switch f := f.(type) {
+	case *File:
+		switch f.Header.Type {
+		case FVFileTypeSECCore, FVFileTypePEICore, FVFileTypePEIM:
+			v.file = f
+			return nil
+		}
+	case *Section:
+		switch f.Header.Type {
+		case SectionTypeCompression, SectionTypeGUIDDefined:
+			return nil
+		}
+	}
+	return f.ApplyChildren(v)
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// BootGuardProfile is the Boot Guard provisioning of an image.
+type BootGuardProfile int
+
+// Boot Guard profiles
+const (
+	BootGuardNotProvisioned BootGuardProfile = iota
+	BootGuardProvisioned
+	BootGuardMeasured
+)
+
+var bootGuardProfileNames = map[BootGuardProfile]string{
+	BootGuardNotProvisioned: "not provisioned",
+	BootGuardProvisioned:    "provisioned, profile set by fuses",
+	BootGuardMeasured:       "provisioned with measurements, profile set by fuses",
+}
+
+func (p BootGuardProfile) String() string {
+	if s, ok := bootGuardProfileNames[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown Boot Guard profile %d", int(p))
+}
+
+// Structure IDs of the Boot Guard manifests and their elements.
+var (
+	keyManifestID        = []byte("__KEYM__")
+	bootPolicyManifestID = []byte("__ACBP__")
+	ibbElementID         = []byte("__IBBS__")
+)
+
+// ibbFlagAuthorityMeasure is the IBB element flag requesting the ACM to
+// extend the authority measurements into PCR 7.
+const ibbFlagAuthorityMeasure = 0x04
+
+// DetectBootGuardProfile infers the Boot Guard profile from the startup ACM,
+// key manifest and boot policy manifest FIT entries. The actual profile is
+// set by fuses, which are not part of the image, so whether verified boot is
+// enforced cannot be told from the image: a provisioned image is reported as
+// BootGuardProvisioned, or BootGuardMeasured if the IBB element of its boot
+// policy manifest requests authority measurements. The profile is
+// BootGuardNotProvisioned if FIT or any of the entries is absent.
+func DetectBootGuardProfile(image []byte) (BootGuardProfile, error) {
+	entries, err := fitEntries(image)
+	if err != nil {
+		return BootGuardNotProvisioned, nil
+	}
+	found := map[uint8]*fitEntry{}
+	for i, entry := range entries {
+		if _, ok := found[entry.Type]; !ok {
+			found[entry.Type] = &entries[i]
+		}
+	}
+	acm, km, bpm := found[fitEntryTypeStartupACM], found[fitEntryTypeKeyManifest], found[fitEntryTypeBootPolicyManifest]
+	if acm == nil || km == nil || bpm == nil {
+		return BootGuardNotProvisioned, nil
+	}
+
+	if _, err := fitEntryData(image, km, keyManifestID); err != nil {
+		return BootGuardNotProvisioned, fmt.Errorf("invalid key manifest: %v", err)
+	}
+	bpmData, err := fitEntryData(image, bpm, bootPolicyManifestID)
+	if err != nil {
+		return BootGuardNotProvisioned, fmt.Errorf("invalid boot policy manifest: %v", err)
+	}
+
+	measured := false
+	if idx := bytes.Index(bpmData, ibbElementID); idx >= 0 {
+		ibb := bpmData[idx:]
+		// The header of the IBB element is 4 bytes longer since Boot Guard 2.0 (CBnT),
+		// which uses the structure version 0x20.
+		flagsOffset := 12
+		if len(ibb) > len(ibbElementID) && ibb[len(ibbElementID)] >= 0x20 {
+			flagsOffset = 16
+		}
+		if len(ibb) < flagsOffset+4 {
+			return BootGuardNotProvisioned, fmt.Errorf("truncated IBB element in boot policy manifest")
+		}
+		measured = binary.LittleEndian.Uint32(ibb[flagsOffset:])&ibbFlagAuthorityMeasure != 0
+	}
+
+	if measured {
+		return BootGuardMeasured, nil
+	}
+	return BootGuardProvisioned, nil
+}
+
+// fitEntryData returns the data referenced by a FIT entry which must start
+// with the given structure ID. If the entry has no size, the data lasts until
+// the end of the image.
+func fitEntryData(image []byte, entry *fitEntry, id []byte) ([]byte, error) {
+	if !entry.InImage {
+		return nil, fmt.Errorf("address %#x is out of the image", entry.Offset)
+	}
+	end := uint64(len(image))
+	if entry.Size != 0 {
+		end = entry.Offset + uint64(entry.Size)
+	}
+	if end > uint64(len(image)) || entry.Offset+uint64(len(id)) > end {
+		return nil, fmt.Errorf("data at %#x of size %#x exceeds the image", entry.Offset, entry.Size)
+	}
+	data := image[entry.Offset:end]
+	if !bytes.HasPrefix(data, id) {
+		return nil, fmt.Errorf("no %s structure at %#x", id, entry.Offset)
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// putFIT writes FIT with the given entries at fitOffset and the FIT pointer.
+func putFIT(image []byte, fitOffset uint64, entries ...fitEntry) {
+	imageSize := uint64(len(image))
+	toAddr := func(offset uint64) uint64 { return fitBasePhysAddr - imageSize + offset }
+	fit := image[fitOffset:]
+	copy(fit, fitHeaderMagic)
+	copy(fit[8:], []byte{byte(len(entries) + 1), 0, 0, 0, 0, 1, 0, 0})
+	for i, e := range entries {
+		entry := fit[(i+1)*fitEntrySize:]
+		binary.LittleEndian.PutUint64(entry, toAddr(e.Offset))
+		copy(entry[8:], []byte{byte(e.Size), byte(e.Size >> 8), byte(e.Size >> 16), 0, 0, 1, e.Type, 0})
+	}
+	binary.LittleEndian.PutUint64(image[imageSize-fitPointerOffset:], toAddr(fitOffset))
+}
+
+// makeBootPolicyManifest returns a boot policy manifest of Boot Guard 2.0
+// with the given IBB element flags, which is optionally signed.
+func makeBootPolicyManifest(ibbFlags uint32, signed bool) []byte {
+	var bpm bytes.Buffer
+	bpm.Write(bootPolicyManifestID)
+	bpm.Write(make([]byte, 0x18))
+	ibb := make([]byte, 0x40)
+	copy(ibb, ibbElementID)
+	ibb[8] = 0x20
+	binary.LittleEndian.PutUint32(ibb[16:], ibbFlags)
+	bpm.Write(ibb)
+	if signed {
+		bpm.Write([]byte("__PMSG__"))
+		bpm.Write(make([]byte, 0x20))
+	}
+	return bpm.Bytes()
+}
+
+func TestDetectBootGuardProfile(t *testing.T) {
+	const (
+		imageSize = 0x10000
+		acmOffset = 0x1000
+		kmOffset  = 0x2000
+		bpmOffset = 0x3000
+		fitOffset = 0x8000
+	)
+	newImage := func(bpm []byte, types ...uint8) []byte {
+		image := bytes.Repeat([]byte{0xff}, imageSize)
+		copy(image[kmOffset:], keyManifestID)
+		copy(image[bpmOffset:], bpm)
+		var entries []fitEntry
+		for _, t := range types {
+			switch t {
+			case fitEntryTypeStartupACM:
+				entries = append(entries, fitEntry{Type: t, Offset: acmOffset})
+			case fitEntryTypeKeyManifest:
+				entries = append(entries, fitEntry{Type: t, Offset: kmOffset, Size: 0x100})
+			case fitEntryTypeBootPolicyManifest:
+				entries = append(entries, fitEntry{Type: t, Offset: bpmOffset, Size: uint32(len(bpm))})
+			}
+		}
+		putFIT(image, fitOffset, entries...)
+		return image
+	}
+	all := []uint8{fitEntryTypeStartupACM, fitEntryTypeKeyManifest, fitEntryTypeBootPolicyManifest}
+
+	var tests = []struct {
+		name    string
+		image   []byte
+		profile BootGuardProfile
+	}{
+		{"no FIT", bytes.Repeat([]byte{0xff}, imageSize), BootGuardNotProvisioned},
+		{"no manifests", newImage(nil, fitEntryTypeStartupACM), BootGuardNotProvisioned},
+		{"no BPM", newImage(nil, fitEntryTypeStartupACM, fitEntryTypeKeyManifest), BootGuardNotProvisioned},
+		{"measured", newImage(makeBootPolicyManifest(ibbFlagAuthorityMeasure, false), all...), BootGuardMeasured},
+		{"unsigned", newImage(makeBootPolicyManifest(0, false), all...), BootGuardProvisioned},
+		{"signed", newImage(makeBootPolicyManifest(0, true), all...), BootGuardProvisioned},
+		{"signed and measured", newImage(makeBootPolicyManifest(ibbFlagAuthorityMeasure|1, true), all...), BootGuardMeasured},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := DetectBootGuardProfile(test.image)
+			if err != nil {
+				t.Fatalf("DetectBootGuardProfile failed: %v", err)
+			}
+			if profile != test.profile {
+				t.Errorf("got profile %q, want %q", profile, test.profile)
+			}
+		})
+	}
+
+	t.Run("invalid BPM", func(t *testing.T) {
+		image := newImage([]byte("__XXXX__"), all...)
+		if _, err := DetectBootGuardProfile(image); err == nil {
+			t.Errorf("DetectBootGuardProfile succeeded, want error")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Compatibility16Signature is the signature of the EFI_COMPATIBILITY16_TABLE.
+var Compatibility16Signature = [4]byte{'$', 'E', 'F', 'I'}
+
+// compatibility16TableMinLength is the length of the table up to the
+// PnP installation check entry point, shorter tables are not accepted.
+const compatibility16TableMinLength = 20
+
+// Compatibility16TableFields are the fields of the EFI_COMPATIBILITY16_TABLE,
+// as defined in the Compatibility Support Module specification. Segments and
+// offsets are real mode addresses, pointers are 32 bits physical addresses.
+type Compatibility16TableFields struct {
+	Signature                   [4]byte
+	TableChecksum               uint8
+	TableLength                 uint8
+	EfiMajorRevision            uint8
+	EfiMinorRevision            uint8
+	TableMajorRevision          uint8
+	TableMinorRevision          uint8
+	Reserved                    uint16
+	Compatibility16CallSegment  uint16
+	Compatibility16CallOffset   uint16
+	PnPInstallationCheckSegment uint16
+	PnPInstallationCheckOffset  uint16
+	EfiSystemTable              uint32
+	OemIDStringPointer          uint32
+	AcpiRsdPtrPointer           uint32
+	OemRevision                 uint16
+	E820Pointer                 uint32
+	E820Length                  uint32
+	IrqRoutingTablePointer      uint32
+	IrqRoutingTableLength       uint32
+	MpTablePtr                  uint32
+	MpTableLength               uint32
+	OemIntSegment               uint16
+	OemIntOffset                uint16
+	Oem32Segment                uint16
+	Oem32Offset                 uint16
+	Oem16Segment                uint16
+	Oem16Offset                 uint16
+	TpmSegment                  uint16
+	TpmOffset                   uint16
+	IbvPointer                  uint32
+	PciExpressBase              uint32
+	LastPciBus                  uint8
+}
+
+// Compatibility16Table is the EFI_COMPATIBILITY16_TABLE found in the legacy
+// BIOS image of a CSM. The fields beyond TableLength are zero.
+type Compatibility16Table struct {
+	Compatibility16TableFields
+
+	// Offset of the table in the legacy BIOS image.
+	Offset        uint64
+	ChecksumValid bool
+}
+
+// FindCompatibility16Table looks for the EFI_COMPATIBILITY16_TABLE in a
+// legacy BIOS image, such as the content of an EFI_SECTION_COMPATIBILITY16.
+// The table is aligned on 16 bytes. It returns nil if there is no table, e.g.
+// if the image is not a CSM.
+func FindCompatibility16Table(image []byte) *Compatibility16Table {
+	for offset := 0; offset+compatibility16TableMinLength <= len(image); offset += 16 {
+		if !bytes.Equal(image[offset:offset+4], Compatibility16Signature[:]) {
+			continue
+		}
+		length := int(image[offset+5])
+		if length < compatibility16TableMinLength || offset+length > len(image) {
+			continue
+		}
+		// Fields beyond the length of the table are left zero.
+		buf := make([]byte, binary.Size(Compatibility16TableFields{}))
+		copy(buf, image[offset:offset+length])
+		t := &Compatibility16Table{Offset: uint64(offset)}
+		if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &t.Compatibility16TableFields); err != nil {
+			return nil
+		}
+		var sum uint8
+		for _, b := range image[offset : offset+length] {
+			sum += b
+		}
+		t.ChecksumValid = sum == 0
+		return t
+	}
+	return nil
+}
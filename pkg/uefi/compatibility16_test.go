@@ -0,0 +1,108 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makeLegacyBIOS returns a 4KiB legacy BIOS image with an
+// EFI_COMPATIBILITY16_TABLE at offset.
+func makeLegacyBIOS(t *testing.T, offset int) []byte {
+	fields := Compatibility16TableFields{
+		Signature:                   Compatibility16Signature,
+		EfiMajorRevision:            2,
+		EfiMinorRevision:            0,
+		TableMajorRevision:          1,
+		TableMinorRevision:          0,
+		Compatibility16CallSegment:  0xF000,
+		Compatibility16CallOffset:   0x1234,
+		PnPInstallationCheckSegment: 0xF000,
+		PnPInstallationCheckOffset:  0x5678,
+		PciExpressBase:              0xE0000000,
+		LastPciBus:                  0xFF,
+	}
+	fields.TableLength = uint8(binary.Size(fields))
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, fields); err != nil {
+		t.Fatal(err)
+	}
+	table := buf.Bytes()
+	var sum uint8
+	for _, b := range table {
+		sum += b
+	}
+	table[4] = -sum
+
+	image := make([]byte, 0x1000)
+	// A signature which is not 16 bytes aligned is ignored.
+	copy(image[offset-8:], Compatibility16Signature[:])
+	copy(image[offset:], table)
+	return image
+}
+
+func TestFindCompatibility16Table(t *testing.T) {
+	image := makeLegacyBIOS(t, 0x200)
+	table := FindCompatibility16Table(image)
+	if table == nil {
+		t.Fatal("no table found")
+	}
+	if table.Offset != 0x200 {
+		t.Errorf("got offset %#x, want %#x", table.Offset, 0x200)
+	}
+	if !table.ChecksumValid {
+		t.Errorf("checksum is not valid")
+	}
+	if table.Compatibility16CallSegment != 0xF000 || table.Compatibility16CallOffset != 0x1234 {
+		t.Errorf("got entry point %04x:%04x, want f000:1234",
+			table.Compatibility16CallSegment, table.Compatibility16CallOffset)
+	}
+	if table.PciExpressBase != 0xE0000000 || table.LastPciBus != 0xFF {
+		t.Errorf("got PCIe base %#x and last bus %#x, want 0xe0000000 and 0xff", table.PciExpressBase, table.LastPciBus)
+	}
+
+	image[0x200+0x10]++
+	if table := FindCompatibility16Table(image); table == nil || table.ChecksumValid {
+		t.Errorf("expected a table with an invalid checksum, got %+v", table)
+	}
+
+	// A truncated table is ignored.
+	if table := FindCompatibility16Table(image[:0x200+0x10]); table != nil {
+		t.Errorf("expected no table, got %+v", table)
+	}
+	if table := FindCompatibility16Table(make([]byte, 0x1000)); table != nil {
+		t.Errorf("expected no table, got %+v", table)
+	}
+}
+
+func TestNewSectionCompatibility16(t *testing.T) {
+	image := makeLegacyBIOS(t, 0x800)
+	buf := append([]byte{0, 0, 0, byte(SectionTypeCompatibility16)}, image...)
+	size := Write3Size(uint64(len(buf)))
+	copy(buf, size[:])
+
+	s, err := NewSection(buf, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Compatibility16 == nil {
+		t.Fatal("no compatibility16 table parsed")
+	}
+	if s.Compatibility16.Offset != 0x800 || s.Compatibility16.PnPInstallationCheckOffset != 0x5678 {
+		t.Errorf("unexpected table %+v", s.Compatibility16)
+	}
+
+	buf = append([]byte{0, 0, 0, byte(SectionTypeCompatibility16)}, make([]byte, 0x100)...)
+	size = Write3Size(uint64(len(buf)))
+	copy(buf, size[:])
+	if s, err = NewSection(buf, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Compatibility16 != nil {
+		t.Errorf("expected no table, got %+v", s.Compatibility16)
+	}
+}
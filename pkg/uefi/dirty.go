@@ -0,0 +1,94 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+// dirtyFlag records whether a node was modified after it was parsed. It is
+// embedded into all the Firmware implementations.
+type dirtyFlag struct {
+	dirty bool
+}
+
+// Dirty returns true if this node or one of its children was modified since
+// it was parsed, see MarkDirty. See also IsDirty.
+func (d *dirtyFlag) Dirty() bool {
+	return d.dirty
+}
+
+func (d *dirtyFlag) markDirty() {
+	d.dirty = true
+}
+
+func (d *dirtyFlag) clearDirty() {
+	d.dirty = false
+}
+
+type dirtyTracker interface {
+	Dirty() bool
+	markDirty()
+	clearDirty()
+}
+
+// MarkDirty marks f and all its ancestors (see Parent) as modified.
+//
+// The operations modifying the tree call it, e.g. moving a firmware volume,
+// inserting or removing files, or replacing the content of a section.
+// Assembling the tree (SetBuf) is not a modification by itself. Code which
+// modifies a node by other means, like editing Buf() in place or changing
+// header fields, should call MarkDirty as well.
+func MarkDirty(f Firmware) {
+	for f != nil {
+		if d, ok := f.(dirtyTracker); ok {
+			d.markDirty()
+		}
+		p, ok := f.(interface{ Parent() Firmware })
+		if !ok {
+			return
+		}
+		f = p.Parent()
+	}
+}
+
+// dirtyVisitor either looks for a dirty node or clears all the dirty flags.
+type dirtyVisitor struct {
+	clear bool
+	found bool
+}
+
+func (v *dirtyVisitor) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *dirtyVisitor) Visit(f Firmware) error {
+	if d, ok := f.(dirtyTracker); ok {
+		if v.clear {
+			d.clearDirty()
+		} else if d.Dirty() {
+			v.found = true
+			return nil
+		}
+	}
+	return f.ApplyChildren(v)
+}
+
+// IsDirty returns true if f or any of its children was modified since it was
+// parsed. Unlike Dirty, it does not rely on the parent links, so it also
+// covers trees which were built or modified without them.
+func IsDirty(f Firmware) bool {
+	v := &dirtyVisitor{}
+	_ = v.Run(f)
+	return v.found
+}
+
+// ClearDirty resets the dirty flags of f and all its children, e.g. after
+// the image was saved.
+func ClearDirty(f Firmware) {
+	_ = (&dirtyVisitor{clear: true}).Run(f)
+}
+
+// IsDirty returns true if any part of the image was modified since it was
+// parsed.
+func (f *FlashImage) IsDirty() bool {
+	return IsDirty(f)
+}
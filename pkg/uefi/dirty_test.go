@@ -0,0 +1,84 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"testing"
+)
+
+func TestDirty(t *testing.T) {
+	f, err := NewFlashImage(makeBIOSFlashImage(sampleFV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.IsDirty() {
+		t.Fatalf("freshly parsed image is dirty")
+	}
+
+	var bios *BIOSRegion
+	for _, r := range f.Regions {
+		if br, ok := r.Value.(*BIOSRegion); ok {
+			bios = br
+		}
+	}
+	if bios == nil {
+		t.Fatal("no BIOS region")
+	}
+	var fv *FirmwareVolume
+	for _, e := range bios.Elements {
+		if v, ok := e.Value.(*FirmwareVolume); ok {
+			fv = v
+			break
+		}
+	}
+	if fv == nil || len(fv.Files) < 2 {
+		t.Fatal("no firmware volume with at least two files")
+	}
+	var file, other *File
+	for _, ff := range fv.Files {
+		switch {
+		case file == nil && len(ff.Sections) > 0:
+			file = ff
+		case other == nil:
+			other = ff
+		}
+	}
+	if file == nil || other == nil {
+		t.Fatal("no file with sections found")
+	}
+	section := file.Sections[0]
+
+	// Assembling (setting a buffer) is not a modification by itself.
+	buf := append([]byte{}, section.Buf()...)
+	buf[len(buf)-1] ^= 0xff
+	section.SetBuf(buf)
+	if f.IsDirty() {
+		t.Errorf("image is dirty after setting a buffer")
+	}
+
+	// An in-place edit is recorded by MarkDirty.
+	section.Buf()[len(buf)-1] ^= 0xff
+	MarkDirty(section)
+
+	if !section.Dirty() || !IsDirty(section) {
+		t.Errorf("modified section is not dirty")
+	}
+	for name, fw := range map[string]Firmware{"file": file, "volume": fv, "BIOS region": bios, "image": f} {
+		if !IsDirty(fw) || !fw.(dirtyTracker).Dirty() {
+			t.Errorf("%s containing the modified section is not dirty", name)
+		}
+	}
+	if IsDirty(other) {
+		t.Errorf("unmodified file is dirty")
+	}
+	if !f.IsDirty() {
+		t.Errorf("image is not dirty")
+	}
+
+	ClearDirty(f)
+	if f.IsDirty() || section.Dirty() {
+		t.Errorf("image is still dirty after ClearDirty")
+	}
+}
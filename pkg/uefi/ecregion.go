@@ -0,0 +1,105 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"regexp"
+)
+
+// ECVersionPattern is used to find the version string of the Embedded
+// Controller firmware. There is no common header for EC firmware, so the
+// default pattern only catches the usual "Version x.y" style strings. It
+// can be replaced before parsing to match a vendor specific format. If the
+// pattern has a capture group, the first group is used as the version.
+var ECVersionPattern = regexp.MustCompile(`(?:VERSION|[Vv]ersion|VER|[Vv]er)[ :=]*([0-9][0-9A-Za-z]*(?:[._-][0-9A-Za-z]+)+)`)
+
+// ECRegion implements Region for the Embedded Controller firmware.
+type ECRegion struct {
+	dirtyFlag
+	parentLink
+
+	// holds the raw data
+	buf []byte
+	// Metadata for extraction and recovery
+	ExtractPath string
+	// This is a pointer to the FlashRegion struct laid out in the ifd.
+	FRegion *FlashRegion
+	// Region Type as per the IFD
+	RegionType FlashRegionType
+
+	// Blank is set if the region is fully erased.
+	Blank bool `json:",omitempty"`
+	// Version is the firmware version found with ECVersionPattern.
+	Version string `json:",omitempty"`
+}
+
+// SetFlashRegion sets the flash region.
+func (rr *ECRegion) SetFlashRegion(fr *FlashRegion) {
+	rr.FRegion = fr
+}
+
+// FlashRegion gets the flash region.
+func (rr *ECRegion) FlashRegion() (fr *FlashRegion) {
+	return rr.FRegion
+}
+
+// NewECRegion creates a new region.
+func NewECRegion(buf []byte, r *FlashRegion, rt FlashRegionType) (Region, error) {
+	rr := &ECRegion{FRegion: r, RegionType: rt}
+	rr.buf = make([]byte, len(buf))
+	copy(rr.buf, buf)
+	if IsErased(buf, Attributes.ErasePolarity) {
+		rr.Blank = true
+		return rr, nil
+	}
+	rr.Version = findECVersion(buf)
+	return rr, nil
+}
+
+func findECVersion(buf []byte) string {
+	if ECVersionPattern == nil {
+		return ""
+	}
+	m := ECVersionPattern.FindSubmatch(buf)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1 && len(m[1]) > 0:
+		return string(m[1])
+	}
+	return string(m[0])
+}
+
+// Type returns the flash region type.
+func (rr *ECRegion) Type() FlashRegionType {
+	return RegionTypeEC
+}
+
+// Buf returns the buffer.
+// Used mostly for things interacting with the Firmware interface.
+func (rr *ECRegion) Buf() []byte {
+	return rr.buf
+}
+
+// SetBuf sets the buffer.
+// Used mostly for things interacting with the Firmware interface.
+func (rr *ECRegion) SetBuf(buf []byte) {
+	rr.buf = buf
+}
+
+// Apply calls the visitor on the ECRegion.
+func (rr *ECRegion) Apply(v Visitor) error {
+	return v.Visit(rr)
+}
+
+// Kind returns FirmwareKindECRegion.
+func (rr *ECRegion) Kind() FirmwareKind {
+	return FirmwareKindECRegion
+}
+
+// ApplyChildren calls the visitor on each child node of ECRegion.
+func (rr *ECRegion) ApplyChildren(v Visitor) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func newStubECRegion(t *testing.T, content []byte) *ECRegion {
+	buf := bytes.Repeat([]byte{Attributes.ErasePolarity}, RegionBlockSize)
+	copy(buf[0x100:], content)
+	r, err := NewECRegion(buf, &FlashRegion{Base: 1, Limit: 1}, RegionTypeEC)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return r.(*ECRegion)
+}
+
+func TestNewECRegion(t *testing.T) {
+	var tests = []struct {
+		name    string
+		content []byte
+		blank   bool
+		version string
+	}{
+		{"blank", nil, true, ""},
+		{"version", []byte("\x00ITE EC firmware Version 1.07.02\x00"), false, "1.07.02"},
+		{"ver", []byte("ECFW_VER:2.3\x00"), false, "2.3"},
+		{"noversion", []byte("\x00\x01\x02\x03"), false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newStubECRegion(t, test.content)
+			if r.Type() != RegionTypeEC {
+				t.Errorf("region type: got %v, want %v", r.Type(), RegionTypeEC)
+			}
+			if r.Blank != test.blank {
+				t.Errorf("blank: got %v, want %v", r.Blank, test.blank)
+			}
+			if r.Version != test.version {
+				t.Errorf("version: got %q, want %q", r.Version, test.version)
+			}
+		})
+	}
+}
+
+func TestECRegionCustomPattern(t *testing.T) {
+	defer func(p *regexp.Regexp) { ECVersionPattern = p }(ECVersionPattern)
+	ECVersionPattern = regexp.MustCompile(`N[0-9A-Z]{2}HT[0-9]{2}W`)
+
+	r := newStubECRegion(t, []byte("\x00N2HHT31W\x00"))
+	if r.Version != "N2HHT31W" {
+		t.Errorf("version: got %q, want %q", r.Version, "N2HHT31W")
+	}
+}
+
+func TestECRegionJSON(t *testing.T) {
+	r := newStubECRegion(t, []byte("Version 1.2.3\x00"))
+	b, err := MarshalFirmware(r)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var out struct {
+		FirmwareElement struct {
+			Version string
+		}
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if out.FirmwareElement.Version != "1.2.3" {
+		t.Errorf("JSON version: got %q, want %q\n%s", out.FirmwareElement.Version, "1.2.3", b)
+	}
+
+	f, err := UnmarshalFirmware(b)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ec, ok := f.(*ECRegion); !ok || ec.Version != "1.2.3" {
+		t.Errorf("unmarshalled firmware: got %#v", f)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -122,6 +123,10 @@ var (
 	FFGUID   = guid.MustParse("FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF")
 )
 
+// ErrPadGapTooSmall is returned by MakePadFileToOffset when the gap to fill
+// is smaller than a file header, so no pad file fits in it.
+var ErrPadGapTooSmall = errors.New("gap is too small for a pad file")
+
 // FileAlignments specifies the correct alignments based on the field in the file header.
 var fileAlignments = []uint64{
 	// These alignments are not computable, we have to look them up.
@@ -254,6 +259,61 @@ func (f *File) ChecksumHeader() uint8 {
 	return sum
 }
 
+// FileChecksumStatus is the state of the body checksum of a file.
+type FileChecksumStatus uint8
+
+// Body checksum states
+const (
+	// FileChecksumNotRequired means the checksum attribute is cleared and the
+	// body checksum holds the EmptyBodyChecksum sentinel.
+	FileChecksumNotRequired FileChecksumStatus = iota
+	// FileChecksumBadSentinel means the checksum attribute is cleared, but the
+	// body checksum is not EmptyBodyChecksum.
+	FileChecksumBadSentinel
+	// FileChecksumValid means the checksum attribute is set and the body sums
+	// up to zero with the body checksum.
+	FileChecksumValid
+	// FileChecksumInvalid means the checksum attribute is set, but the body
+	// does not sum up to zero with the body checksum.
+	FileChecksumInvalid
+)
+
+func (s FileChecksumStatus) String() string {
+	switch s {
+	case FileChecksumNotRequired:
+		return "NotRequired"
+	case FileChecksumBadSentinel:
+		return "BadSentinel"
+	case FileChecksumValid:
+		return "Valid"
+	case FileChecksumInvalid:
+		return "Invalid"
+	}
+	return fmt.Sprintf("FileChecksumStatus(%d)", uint8(s))
+}
+
+// ChecksumStatus checks the body checksum of the file against its buffer.
+// The body is the data following the header (of either size), so the file
+// has to be assembled first.
+func (f *File) ChecksumStatus() FileChecksumStatus {
+	fh := f.Header
+	if !fh.Attributes.HasChecksum() {
+		if fh.Checksum.File != EmptyBodyChecksum {
+			return FileChecksumBadSentinel
+		}
+		return FileChecksumNotRequired
+	}
+	headerLen := f.HeaderLen()
+	if uint64(len(f.buf)) < headerLen {
+		return FileChecksumInvalid
+	}
+	// The body checksum is stored in the header, so it has to be added.
+	if Checksum8(f.buf[headerLen:])+fh.Checksum.File != 0 {
+		return FileChecksumInvalid
+	}
+	return FileChecksumValid
+}
+
 // FileHeaderExtended represents an EFI File header with the
 // large file attribute set.
 // We also use this as the generic header for all EFI files, regardless of whether
@@ -266,6 +326,9 @@ type FileHeaderExtended struct {
 
 // File represents an EFI File.
 type File struct {
+	dirtyFlag
+	parentLink
+
 	Header FileHeaderExtended
 	Type   string
 
@@ -279,6 +342,25 @@ type File struct {
 	DataOffset  uint64
 }
 
+// EnclosingFV returns the firmware volume containing the file. It is nil if
+// the file was not parsed as part of a firmware volume.
+func (f *File) EnclosingFV() *FirmwareVolume {
+	fv, _ := f.Parent().(*FirmwareVolume)
+	return fv
+}
+
+// EnclosingRegion returns the flash region containing the file, going up
+// through the nested firmware volumes. It is nil if the file was not parsed as
+// part of a region.
+func (f *File) EnclosingRegion() Region {
+	for p := f.Parent(); p != nil; p = p.Parent() {
+		if r, ok := p.(Region); ok {
+			return r
+		}
+	}
+	return nil
+}
+
 // Buf returns the buffer.
 // Used mostly for things interacting with the Firmware interface.
 func (f *File) Buf() []byte {
@@ -296,6 +378,11 @@ func (f *File) Apply(v Visitor) error {
 	return v.Visit(f)
 }
 
+// Kind returns FirmwareKindFile.
+func (f *File) Kind() FirmwareKind {
+	return FirmwareKindFile
+}
+
 // ApplyChildren calls the visitor on each child node of File.
 func (f *File) ApplyChildren(v Visitor) error {
 	if f.NVarStore != nil {
@@ -312,6 +399,34 @@ func (f *File) ApplyChildren(v Visitor) error {
 	return nil
 }
 
+// SectionsOfType returns the sections of the file with the given type in
+// file order. If recursive is set, sections encapsulated in other sections,
+// e.g. in compressed ones, are returned as well. Sections of nested firmware
+// volumes belong to other files and are not returned.
+func (f *File) SectionsOfType(t SectionType, recursive bool) []*Section {
+	var result []*Section
+	var walk func(sections []*Section)
+	walk = func(sections []*Section) {
+		for _, s := range sections {
+			if s.Header.Type == t {
+				result = append(result, s)
+			}
+			if !recursive {
+				continue
+			}
+			var encapsulated []*Section
+			for _, e := range s.Encapsulated {
+				if es, ok := e.Value.(*Section); ok {
+					encapsulated = append(encapsulated, es)
+				}
+			}
+			walk(encapsulated)
+		}
+	}
+	walk(f.Sections)
+	return result
+}
+
 // SetSize sets the size into the File struct.
 // If resizeFile is true, if the file is too large the file will be enlarged to make space
 // for the ExtendedHeader
@@ -425,10 +540,42 @@ func CreatePadFile(size uint64) (*File, error) {
 	return &f, nil
 }
 
+// MakePadFileToOffset creates the pad file which fills the gap between the end
+// of a file at offset from and the next file at offset to, both relative to the
+// firmware volume. Files start on 8 byte boundaries, so the pad file starts at
+// Align8(from) and to must be 8 byte aligned. If no padding is needed, nil is
+// returned.
+//
+// A gap of 8 or 16 bytes cannot be filled as it is smaller than a file header:
+// ErrPadGapTooSmall is returned and the caller has to choose a further offset,
+// e.g. the next alignment boundary.
+func MakePadFileToOffset(from, to uint64) (*File, error) {
+	start := Align8(from)
+	if to%8 != 0 {
+		return nil, fmt.Errorf("target offset %#x is not 8 byte aligned", to)
+	}
+	if to < start {
+		return nil, fmt.Errorf("target offset %#x is before the start of the pad file at %#x", to, start)
+	}
+	gap := to - start
+	if gap == 0 {
+		return nil, nil
+	}
+	if gap < FileHeaderMinLength {
+		return nil, fmt.Errorf("%w: %#x bytes between %#x and %#x, at least %#x bytes are required",
+			ErrPadGapTooSmall, gap, start, to, FileHeaderMinLength)
+	}
+	return CreatePadFile(gap)
+}
+
 // NewFile parses a sequence of bytes and returns a File
 // object, if a valid one is passed, or an error. If no error is returned and the File
 // pointer is nil, it means we've reached the volume free space at the end of the FV.
 func NewFile(buf []byte) (*File, error) {
+	return newFile(buf, defaultParseConfig())
+}
+
+func newFile(buf []byte, cfg parseConfig) (*File, error) {
 	f := File{}
 	f.DataOffset = FileHeaderMinLength
 	// Read in standard header.
@@ -464,7 +611,7 @@ func NewFile(buf []byte) (*File, error) {
 			f.Header.GUID, f.Header.ExtendedSize, buflen)
 	}
 
-	if ReadOnly {
+	if cfg.readOnly {
 		f.buf = buf[:f.Header.ExtendedSize]
 	} else {
 		// Copy out the buffer.
@@ -474,7 +621,7 @@ func NewFile(buf []byte) (*File, error) {
 	}
 
 	// Special case for NVAR Store stored in raw file
-	if f.Header.Type == FVFileTypeRaw && f.Header.GUID == *NVAR {
+	if f.Header.Type == FVFileTypeRaw && f.Header.GUID == *NVAR && !cfg.headersOnly {
 		if f.DataOffset >= uint64(len(f.buf)) {
 			return nil, fmt.Errorf("data offset %#x exceeds buffer size %#x", f.DataOffset, len(f.buf))
 		}
@@ -488,11 +635,12 @@ func NewFile(buf []byte) (*File, error) {
 
 	// Parse sections
 	if !SupportedFiles[f.Header.Type] {
+		linkChildren(&f)
 		return &f, nil
 	}
 
 	for i, offset := 0, f.DataOffset; offset < f.Header.ExtendedSize; i++ {
-		s, err := NewSection(f.buf[offset:], i)
+		s, err := newSection(f.buf[offset:], i, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing sections of file %v: %v", f.Header.GUID, err)
 		}
@@ -505,5 +653,6 @@ func NewFile(buf []byte) (*File, error) {
 		offset = Align4(offset)
 		f.Sections = append(f.Sections, s)
 	}
+	linkChildren(&f)
 	return &f, nil
 }
@@ -5,7 +5,10 @@
 package uefi
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/linuxboot/fiano/pkg/compression"
 )
 
 var (
@@ -60,3 +63,143 @@ func TestNewFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSectionsOfType(t *testing.T) {
+	newSection := func(st SectionType, encap ...Firmware) *Section {
+		s, err := CreateSection(st, []byte{byte(st)}, encap, &compression.LZMAGUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	pe1 := newSection(SectionTypePE32)
+	pe2 := newSection(SectionTypePE32)
+	pe3 := newSection(SectionTypePE32)
+	ui := newSection(SectionTypeUserInterface)
+	// GUID defined(LZMA) { PE32, Compression { PE32, UI } }, PE32
+	f := &File{Sections: []*Section{
+		newSection(SectionTypeGUIDDefined,
+			pe1,
+			newSection(SectionTypeCompression, pe2, ui),
+		),
+		pe3,
+	}}
+
+	var tests = []struct {
+		name      string
+		t         SectionType
+		recursive bool
+		out       []*Section
+	}{
+		{"PE32", SectionTypePE32, false, []*Section{pe3}},
+		{"PE32 recursive", SectionTypePE32, true, []*Section{pe1, pe2, pe3}},
+		{"UI recursive", SectionTypeUserInterface, true, []*Section{ui}},
+		{"TE recursive", SectionTypeTE, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := f.SectionsOfType(test.t, test.recursive)
+			if len(out) != len(test.out) {
+				t.Fatalf("got %d sections, want %d", len(out), len(test.out))
+			}
+			for i := range out {
+				if out[i] != test.out[i] {
+					t.Errorf("section %d mismatch, got %p, want %p", i, out[i], test.out[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMakePadFileToOffset(t *testing.T) {
+	Attributes.ErasePolarity = 0xFF
+	var tests = []struct {
+		name     string
+		from, to uint64
+		size     uint64 // expected size of the pad file, 0 if none
+		tooSmall bool
+		fails    bool
+	}{
+		{"noGap", 0x40, 0x40, 0, false, false},
+		{"unalignedFromNoGap", 0x3c, 0x40, 0, false, false},
+		{"gap8", 0x40, 0x48, 0, true, true},
+		{"gap16", 0x40, 0x50, 0, true, true},
+		{"gapHeader", 0x40, 0x58, FileHeaderMinLength, false, false},
+		{"unalignedFrom", 0x3c, 0x58, FileHeaderMinLength, false, false},
+		{"gapLarge", 0x40, 0x1000, 0xfc0, false, false},
+		{"unalignedTo", 0x40, 0x5c, 0, false, true},
+		{"backwards", 0x58, 0x40, 0, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := MakePadFileToOffset(test.from, test.to)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got a pad file of %#x bytes", len(f.Buf()))
+				}
+				if got := errors.Is(err, ErrPadGapTooSmall); got != test.tooSmall {
+					t.Errorf("errors.Is(%v, ErrPadGapTooSmall) = %v, expected %v", err, got, test.tooSmall)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.size == 0 {
+				if f != nil {
+					t.Errorf("expected no pad file, got %#x bytes", len(f.Buf()))
+				}
+				return
+			}
+			if f == nil {
+				t.Fatal("expected a pad file")
+			}
+			if f.Header.Type != FVFileTypePad || uint64(len(f.Buf())) != test.size || f.Header.ExtendedSize != test.size {
+				t.Errorf("got a %v file of %#x bytes, expected a pad file of %#x bytes", f.Header.Type, len(f.Buf()), test.size)
+			}
+			if Align8(test.from)+uint64(len(f.Buf())) != test.to {
+				t.Errorf("the pad file does not end at %#x", test.to)
+			}
+		})
+	}
+}
+
+func TestFileChecksumStatus(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	var tests = []struct {
+		name    string
+		attr    fileAttr
+		corrupt func(f *File)
+		status  FileChecksumStatus
+	}{
+		{"notRequired", 0, func(f *File) {}, FileChecksumNotRequired},
+		{"notRequiredDataChanged", 0, func(f *File) { f.buf[len(f.buf)-1]++ }, FileChecksumNotRequired},
+		{"badSentinel", 0, func(f *File) { f.Header.Checksum.File = 0 }, FileChecksumBadSentinel},
+		{"valid", 0x40, func(f *File) {}, FileChecksumValid},
+		{"invalidData", 0x40, func(f *File) { f.buf[len(f.buf)-1]++ }, FileChecksumInvalid},
+		{"invalidChecksum", 0x40, func(f *File) { f.Header.Checksum.File++ }, FileChecksumInvalid},
+		{"invalidSentinel", 0x40, func(f *File) { f.Header.Checksum.File = EmptyBodyChecksum }, FileChecksumInvalid},
+		{"largeValid", 0x41, func(f *File) {}, FileChecksumValid},
+		{"largeInvalid", 0x41, func(f *File) { f.buf[len(f.buf)-1]++ }, FileChecksumInvalid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &File{}
+			f.Header.Type = FVFileTypeFreeForm
+			f.SetSize(FileHeaderExtMinLength+uint64(len(data)), false)
+			// Set the large attribute even though the file is small, to
+			// check that the body follows the extended header.
+			f.Header.Attributes = test.attr
+			if err := f.ChecksumAndAssemble(data); err != nil {
+				t.Fatal(err)
+			}
+			if uint64(len(f.buf)) != f.HeaderLen()+uint64(len(data)) {
+				t.Fatalf("got file of %#x bytes, expected %#x", len(f.buf), f.HeaderLen()+uint64(len(data)))
+			}
+			test.corrupt(f)
+			if status := f.ChecksumStatus(); status != test.status {
+				t.Errorf("got checksum status %v, expected %v", status, test.status)
+			}
+		})
+	}
+}
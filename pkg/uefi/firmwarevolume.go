@@ -86,6 +86,9 @@ type FirmwareVolumeExtHeader struct {
 // FirmwareVolume represents a firmware volume. It combines the fixed header and
 // a variable list of blocks
 type FirmwareVolume struct {
+	dirtyFlag
+	parentLink
+
 	FirmwareVolumeFixedHeader
 	// there must be at least one that is zeroed and indicates the end of the
 	// block list
@@ -121,6 +124,11 @@ func (fv *FirmwareVolume) Apply(v Visitor) error {
 	return v.Visit(fv)
 }
 
+// Kind returns FirmwareKindFirmwareVolume.
+func (fv *FirmwareVolume) Kind() FirmwareKind {
+	return FirmwareKindFirmwareVolume
+}
+
 // ApplyChildren calls the visitor on each child node of FirmwareVolume.
 func (fv *FirmwareVolume) ApplyChildren(v Visitor) error {
 	for _, f := range fv.Files {
@@ -139,6 +147,78 @@ func (fv *FirmwareVolume) GetErasePolarity() uint8 {
 	return 0
 }
 
+// FFSVersion returns the version of the firmware file system of the volume,
+// 2 or 3, or 0 if the volume holds another file system, e.g. NVRAM.
+func (fv *FirmwareVolume) FFSVersion() int {
+	switch fv.FileSystemGUID {
+	case *FFS2:
+		return 2
+	case *FFS3:
+		return 3
+	}
+	return 0
+}
+
+// ffs2MaxSize is the largest file or section size which fits into the 24 bit
+// size field of the FFS2 headers.
+const ffs2MaxSize = 0xFFFFFF
+
+// FFS3Requirement tells whether a firmware volume needs the FFS3 file system
+// and why.
+type FFS3Requirement struct {
+	// Required is set when a file of the volume is too large for FFS2.
+	Required bool
+	// Switch is set when the volume is FFS2 and is therefore switched to
+	// FFS3 by assembling it.
+	Switch bool
+	// File is the first file too large for FFS2, Size its size.
+	File *File
+	Size uint64
+	// Reason explains the requirement in plain words.
+	Reason string
+}
+
+// FFS3Requirement reports whether the volume needs the FFS3 file system,
+// without modifying it. The sizes of the files are computed the way Assemble
+// does, from the buffers of their sections, so the report predicts the
+// result of assembling a modified tree as long as the section buffers are up
+// to date. A large section always makes its file large as well, so only the
+// files are reported.
+func (fv *FirmwareVolume) FFS3Requirement() FFS3Requirement {
+	for _, f := range fv.Files {
+		size := f.assembledSize()
+		if size <= ffs2MaxSize {
+			continue
+		}
+		r := FFS3Requirement{
+			Required: true,
+			Switch:   fv.FFSVersion() == 2,
+			File:     f,
+			Size:     size,
+		}
+		r.Reason = fmt.Sprintf("file %v is %#x bytes, more than the %#x bytes FFS2 allows",
+			f.Header.GUID, size, ffs2MaxSize)
+		return r
+	}
+	return FFS3Requirement{Reason: fmt.Sprintf("all the files fit into the %#x bytes FFS2 allows", ffs2MaxSize)}
+}
+
+// assembledSize returns the size of the file once assembled from its
+// sections, with a regular header.
+func (f *File) assembledSize() uint64 {
+	switch {
+	case f.NVarStore != nil:
+		return FileHeaderMinLength + f.NVarStore.Length
+	case len(f.Sections) == 0:
+		return uint64(len(f.buf))
+	}
+	dLen := uint64(0)
+	for _, s := range f.Sections {
+		dLen = Align4(dLen) + uint64(len(s.Buf()))
+	}
+	return FileHeaderMinLength + dLen
+}
+
 // String creates a string representation for the firmware volume.
 func (fv FirmwareVolume) String() string {
 	if fv.ExtHeaderOffset != 0 {
@@ -172,6 +252,100 @@ func (fv *FirmwareVolume) InsertFile(alignedOffset uint64, fBuf []byte) error {
 	return nil
 }
 
+// Merge appends the files of other to fv. A file whose GUID is already in fv
+// is an error, unless overwrite is set, in which case it replaces the file of
+// fv in place. The pad files of other are dropped, the padding required by
+// the file alignments is recreated when the volume is assembled. If the
+// merged files do not fit in fv, it is an error unless fv is resizable, fv
+// then grows when it is assembled. fv is left unchanged on error.
+func (fv *FirmwareVolume) Merge(other *FirmwareVolume, overwrite bool) error {
+	if other == fv {
+		return errors.New("cannot merge a firmware volume into itself")
+	}
+	if fv.GetErasePolarity() != other.GetErasePolarity() {
+		return fmt.Errorf("erase polarity mismatch, %#x vs %#x", fv.GetErasePolarity(), other.GetErasePolarity())
+	}
+
+	files := append([]*File{}, fv.Files...)
+	index := make(map[guid.GUID]int)
+	for i, f := range files {
+		if f.Header.Type != FVFileTypePad {
+			index[f.Header.GUID] = i
+		}
+	}
+	for _, f := range other.Files {
+		if f.Header.Type == FVFileTypePad {
+			continue
+		}
+		if i, ok := index[f.Header.GUID]; ok {
+			if !overwrite {
+				return fmt.Errorf("file %v is in both firmware volumes", f.Header.GUID)
+			}
+			files[i] = f
+			continue
+		}
+		index[f.Header.GUID] = len(files)
+		files = append(files, f)
+	}
+
+	if end := fv.filesEnd(files); end > fv.Length && !fv.Resizable {
+		return fmt.Errorf("out of space in firmware volume %v, merged files need %#x bytes, volume length is %#x",
+			fv, end, fv.Length)
+	}
+	fv.Files = files
+	return nil
+}
+
+// filesEnd returns the offset of the end of the files once they are laid
+// out in the volume, following the same alignment rules as the Assemble
+// visitor.
+func (fv *FirmwareVolume) filesEnd(files []*File) uint64 {
+	offset := fv.DataOffset
+	for _, f := range files {
+		alignedOffset := Align8(offset)
+		if alignBase := f.Header.Attributes.GetAlignment(); alignBase != 1 {
+			hl := f.HeaderLen()
+			dataOffset := Align(alignedOffset+hl, alignBase)
+			newOffset := dataOffset - hl
+			if gap := newOffset - alignedOffset; gap >= 8 && gap < FileHeaderMinLength {
+				// There is no room for a pad file, go to the next boundary.
+				newOffset = Align(dataOffset+1, alignBase) - hl
+			}
+			alignedOffset = newOffset
+		}
+		offset = alignedOffset + uint64(len(f.Buf()))
+	}
+	return offset
+}
+
+// FileOffsets returns the offset of each file of the volume relative to the
+// start of the volume, with the files laid out as they are stored: each one
+// starts at the next 8 byte boundary after the buffer of the previous one.
+func (fv *FirmwareVolume) FileOffsets() []uint64 {
+	offsets := make([]uint64, len(fv.Files))
+	offset := fv.DataOffset
+	for i, f := range fv.Files {
+		offset = Align8(offset)
+		offsets[i] = offset
+		offset += uint64(len(f.Buf()))
+	}
+	return offsets
+}
+
+// CheckBlockMapConsistency verifies that the length of the volume matches the
+// size covered by its block map, i.e. the sum of Count * Size of all blocks.
+func (fv *FirmwareVolume) CheckBlockMapConsistency() error {
+	var blockMapLen uint64
+	for _, block := range fv.Blocks {
+		blockMapLen += uint64(block.Count) * uint64(block.Size)
+	}
+	if blockMapLen != fv.Length {
+		return fmt.Errorf("FV length %#x does not match the block map length %#x (%v)",
+			fv.Length, blockMapLen, fv.Blocks)
+	}
+	return nil
+}
+
 // FindFirmwareVolumeOffset searches for a firmware volume signature, "_FVH"
 // using 8-byte alignment. If found, returns the offset from the start of the
 // bios region, otherwise returns -1.
@@ -194,6 +368,10 @@ func FindFirmwareVolumeOffset(data []byte) int64 {
 // NewFirmwareVolume parses a sequence of bytes and returns a FirmwareVolume
 // object, if a valid one is passed, or an error
 func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareVolume, error) {
+	return newFirmwareVolume(data, fvOffset, resizable, defaultParseConfig())
+}
+
+func parseFirmwareVolumeHeader(data []byte, fvOffset uint64, resizable bool, cfg parseConfig) (*FirmwareVolume, error) {
 	fv := FirmwareVolume{Resizable: resizable}
 
 	if len(data) < FirmwareVolumeMinSize {
@@ -220,6 +398,9 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 		blocks = append(blocks, block)
 	}
 	fv.Blocks = blocks
+	if err := fv.CheckBlockMapConsistency(); err != nil {
+		log.Warnf("%v", err)
+	}
 
 	// Set the erase polarity
 	if err := SetErasePolarity(fv.GetErasePolarity()); err != nil {
@@ -253,7 +434,7 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 	fv.FVType = FVGUIDs[fv.FileSystemGUID]
 	fv.FVOffset = fvOffset
 
-	if ReadOnly {
+	if cfg.readOnly {
 		fv.buf = data[:fv.Length]
 	} else {
 		// copy out the buffer.
@@ -261,6 +442,21 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 		fv.buf = make([]byte, fv.Length)
 		copy(fv.buf, newBuf)
 	}
+	return &fv, nil
+}
+
+// ParseFirmwareVolumeHeader parses the header of the firmware volume at the
+// start of data without parsing its files, so it also works on volumes whose
+// files are corrupt. The returned volume has no Files.
+func ParseFirmwareVolumeHeader(data []byte, fvOffset uint64) (*FirmwareVolume, error) {
+	return parseFirmwareVolumeHeader(data, fvOffset, false, defaultParseConfig())
+}
+
+func newFirmwareVolume(data []byte, fvOffset uint64, resizable bool, cfg parseConfig) (*FirmwareVolume, error) {
+	fv, err := parseFirmwareVolumeHeader(data, fvOffset, resizable, cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse the files.
 	// TODO: handle fv data alignment.
@@ -268,7 +464,7 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 	// Test if the fv type is supported.
 	if _, ok := supportedFVs[fv.FileSystemGUID]; !ok {
 		log.Warnf("unsupported fv type %v,%v not parsing it", fv.FileSystemGUID.String(), fv.FVType)
-		return &fv, nil
+		return fv, nil
 	}
 	lh := fv.Length - FileHeaderMinLength
 	var prevLen uint64
@@ -277,7 +473,7 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 		if uint64(len(data)) <= offset {
 			return nil, fmt.Errorf("offset %#x is beyond end of FV data (%#x)", offset, len(data))
 		}
-		file, err := NewFile(data[offset:])
+		file, err := newFile(data[offset:], cfg)
 		if err != nil {
 			return nil, fmt.Errorf("unable to construct firmware file at offset %#x into FV: %v", offset, err)
 		}
@@ -292,5 +488,6 @@ func NewFirmwareVolume(data []byte, fvOffset uint64, resizable bool) (*FirmwareV
 			return nil, fmt.Errorf("invalid length of file at offset %#x", offset)
 		}
 	}
-	return &fv, nil
+	linkChildren(fv)
+	return fv, nil
 }
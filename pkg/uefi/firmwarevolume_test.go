@@ -7,8 +7,11 @@ package uefi
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/linuxboot/fiano/pkg/guid"
 	"github.com/linuxboot/fiano/pkg/log"
 )
 
@@ -47,3 +50,248 @@ func TestNewFirmwareVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestFirmwareVolumeFileOffsets(t *testing.T) {
+	fv, err := NewFirmwareVolume(sampleFV, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := fv.FileOffsets()
+	if len(offsets) != len(fv.Files) || len(offsets) == 0 {
+		t.Fatalf("got %d offsets for %d files", len(offsets), len(fv.Files))
+	}
+	for i, f := range fv.Files {
+		if offsets[i]%8 != 0 {
+			t.Errorf("file %d: offset %#x is not 8 byte aligned", i, offsets[i])
+		}
+		got := fv.Buf()[offsets[i] : offsets[i]+uint64(len(f.Buf()))]
+		if !reflect.DeepEqual(got, f.Buf()) {
+			t.Errorf("file %d: buffer at offset %#x does not match the file", i, offsets[i])
+		}
+	}
+}
+
+func TestParseFirmwareVolumeHeader(t *testing.T) {
+	want, err := NewFirmwareVolume(sampleFV, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the size of the first file: the header must still parse.
+	buf := append([]byte{}, sampleFV...)
+	copy(buf[want.DataOffset+0x14:], []byte{0xFE, 0xFF, 0xFE})
+	if _, err := NewFirmwareVolume(buf, 0, false); err == nil {
+		t.Fatalf("volume with a corrupt file parses")
+	}
+	fv, err := ParseFirmwareVolumeHeader(buf, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fv.Length != want.Length || fv.DataOffset != want.DataOffset || fv.FVOffset != 0x1000 {
+		t.Errorf("got length %#x, data offset %#x, offset %#x, want %#x, %#x, 0x1000",
+			fv.Length, fv.DataOffset, fv.FVOffset, want.Length, want.DataOffset)
+	}
+	if len(fv.Files) != 0 {
+		t.Errorf("got %d files, want none", len(fv.Files))
+	}
+	if !reflect.DeepEqual(fv.Buf(), buf[:fv.Length]) {
+		t.Errorf("buffer does not match the volume")
+	}
+}
+
+func TestCheckBlockMapConsistency(t *testing.T) {
+	fv, err := NewFirmwareVolume(sampleFV, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fv.CheckBlockMapConsistency(); err != nil {
+		t.Errorf("sample FV is reported as inconsistent: %v", err)
+	}
+
+	var tests = []struct {
+		name   string
+		blocks []Block
+		length uint64
+		ok     bool
+	}{
+		{"singleBlock", []Block{{Count: 4, Size: 0x1000}}, 0x4000, true},
+		{"severalBlocks", []Block{{Count: 2, Size: 0x1000}, {Count: 1, Size: 0x10000}}, 0x12000, true},
+		{"tooShort", []Block{{Count: 2, Size: 0x1000}, {Count: 1, Size: 0x10000}}, 0x13000, false},
+		{"tooLong", []Block{{Count: 4, Size: 0x1000}}, 0x3000, false},
+		{"noBlocks", nil, 0x1000, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fv.Blocks = test.blocks
+			fv.Length = test.length
+			err := fv.CheckBlockMapConsistency()
+			if test.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !test.ok && err == nil {
+				t.Errorf("block map %v with length %#x is not reported as inconsistent", test.blocks, test.length)
+			}
+		})
+	}
+}
+
+// newTestFile returns a copy of goodFreeFormFile with the given GUID.
+func newTestFile(t *testing.T, g string) *File {
+	f, err := NewFile(goodFreeFormFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Header.GUID = *guid.MustParse(g)
+	if err := f.ChecksumAndAssemble(goodFreeFormFile[f.DataOffset:]); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// newTestFV returns a volume with the header of sampleFV, the given length
+// and files.
+func newTestFV(t *testing.T, length uint64, files ...*File) *FirmwareVolume {
+	fv, err := NewFirmwareVolume(sampleFV, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fv.Length = length
+	fv.Files = files
+	return fv
+}
+
+func TestFirmwareVolumeMerge(t *testing.T) {
+	const (
+		guid1 = "11111111-1111-1111-1111-111111111111"
+		guid2 = "22222222-2222-2222-2222-222222222222"
+		guid3 = "33333333-3333-3333-3333-333333333333"
+	)
+	// Parsing the volume also sets the erase polarity used by CreatePadFile.
+	dataOffset := sampleFVDataOffset(t)
+	pad, err := CreatePadFile(0x40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("append", func(t *testing.T) {
+		fv := newTestFV(t, 0x1000, newTestFile(t, guid1))
+		other := newTestFV(t, 0x1000, pad, newTestFile(t, guid2), newTestFile(t, guid3))
+		if err := fv.Merge(other, false); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range fv.Files {
+			got = append(got, f.Header.GUID.String())
+		}
+		if want := []string{guid1, guid2, guid3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("merged files are %v, want %v", got, want)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		f1 := newTestFile(t, guid1)
+		fv := newTestFV(t, 0x1000, f1)
+		other := newTestFV(t, 0x1000, newTestFile(t, guid1))
+		want := fmt.Sprintf("file %s is in both firmware volumes", guid1)
+		if err := fv.Merge(other, false); err == nil || err.Error() != want {
+			t.Fatalf("got error %v, want %v", err, want)
+		}
+		if len(fv.Files) != 1 || fv.Files[0] != f1 {
+			t.Errorf("volume was modified by a failed merge")
+		}
+
+		if err := fv.Merge(other, true); err != nil {
+			t.Fatal(err)
+		}
+		if len(fv.Files) != 1 || fv.Files[0] != other.Files[0] {
+			t.Errorf("file was not overwritten")
+		}
+	})
+
+	t.Run("out of space", func(t *testing.T) {
+		fileLen := uint64(len(goodFreeFormFile))
+		length := Align8(dataOffset+fileLen) + fileLen
+		fv := newTestFV(t, length, newTestFile(t, guid1))
+		other := newTestFV(t, 0x1000, newTestFile(t, guid2), newTestFile(t, guid3))
+		if err := fv.Merge(other, false); err == nil {
+			t.Fatal("expected an out of space error")
+		}
+		if len(fv.Files) != 1 {
+			t.Errorf("volume was modified by a failed merge")
+		}
+
+		fv.Resizable = true
+		if err := fv.Merge(other, false); err != nil {
+			t.Fatal(err)
+		}
+		if len(fv.Files) != 3 {
+			t.Errorf("got %d files, want 3", len(fv.Files))
+		}
+	})
+}
+
+func sampleFVDataOffset(t *testing.T) uint64 {
+	fv, err := NewFirmwareVolume(sampleFV, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fv.DataOffset
+}
+
+func TestFirmwareVolumeFFSVersion(t *testing.T) {
+	for _, test := range []struct {
+		fsGUID  guid.GUID
+		version int
+	}{
+		{*FFS2, 2},
+		{*FFS3, 3},
+		{*NVAR, 0},
+	} {
+		fv := &FirmwareVolume{}
+		fv.FileSystemGUID = test.fsGUID
+		if got := fv.FFSVersion(); got != test.version {
+			t.Errorf("FFSVersion of %v = %d, want %d", test.fsGUID, got, test.version)
+		}
+	}
+}
+
+func TestFirmwareVolumeFFS3Requirement(t *testing.T) {
+	small := &File{buf: make([]byte, 0x100)}
+	small.Header.GUID = *guid.MustParse("11111111-2222-3333-4444-555555555555")
+
+	// A 16MiB raw section makes its file larger than FFS2 allows.
+	s, err := CreateSection(SectionTypeRaw, make([]byte, 0x1000000), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	large := &File{Sections: []*Section{s}}
+	large.Header.GUID = *guid.MustParse("66666666-7777-8888-9999-AAAAAAAAAAAA")
+
+	fv := &FirmwareVolume{Files: []*File{small}}
+	fv.FileSystemGUID = *FFS2
+	if r := fv.FFS3Requirement(); r.Required || r.Switch || r.File != nil {
+		t.Errorf("volume with small files requires FFS3: %+v", r)
+	}
+
+	fv.Files = append(fv.Files, large)
+	r := fv.FFS3Requirement()
+	if !r.Required || !r.Switch {
+		t.Errorf("got Required %v, Switch %v, want both set", r.Required, r.Switch)
+	}
+	if r.File != large {
+		t.Errorf("got triggering file %v, want %v", r.File, large.Header.GUID)
+	}
+	if want := uint64(FileHeaderMinLength + len(s.Buf())); r.Size != want {
+		t.Errorf("got size %#x, want %#x", r.Size, want)
+	}
+	if !strings.Contains(r.Reason, large.Header.GUID.String()) {
+		t.Errorf("reason %q does not name the triggering file", r.Reason)
+	}
+
+	// An FFS3 volume needs no switch.
+	fv.FileSystemGUID = *FFS3
+	if r := fv.FFS3Requirement(); !r.Required || r.Switch {
+		t.Errorf("got Required %v, Switch %v for an FFS3 volume, want Required only", r.Required, r.Switch)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FIT is located through a pointer 0x40 bytes below the end of the image,
+// the FIT entries are 16 bytes large. See the "Firmware Interface Table"
+// specification for details. The fit package can not be used here
+// because it depends on this package.
+const (
+	fitPointerOffset                      = 0x40
+	fitEntrySize                          = 16
+	fitEntryTypeMicrocode                 = 0x01
+	fitEntryTypeStartupACM                = 0x02
+	fitEntryTypeKeyManifest               = 0x0b
+	fitEntryTypeBootPolicyManifest        = 0x0c
+	fitHeaderMagic                        = "_FIT_   "
+	fitBasePhysAddr                uint64 = 1 << 32
+)
+
+// fitEntry is a FIT entry with the address converted to an image offset.
+type fitEntry struct {
+	Type uint8
+	// Offset is the image offset of the data, if InImage is set. Otherwise
+	// it is the original address.
+	Offset  uint64
+	InImage bool
+	Size    uint32
+}
+
+// fitEntries returns the entries of FIT except the FIT header.
+func fitEntries(image []byte) ([]fitEntry, error) {
+	imageSize := uint64(len(image))
+	if imageSize < fitPointerOffset || imageSize > fitBasePhysAddr {
+		return nil, fmt.Errorf("image size %#x does not allow FIT", imageSize)
+	}
+	toOffset := func(addr uint64) (uint64, bool) {
+		if addr < fitBasePhysAddr-imageSize || addr >= fitBasePhysAddr {
+			return 0, false
+		}
+		return addr - (fitBasePhysAddr - imageSize), true
+	}
+
+	pointer := binary.LittleEndian.Uint64(image[imageSize-fitPointerOffset:])
+	start, ok := toOffset(pointer)
+	if !ok || start+fitEntrySize > imageSize {
+		return nil, fmt.Errorf("FIT pointer %#x is out of the image", pointer)
+	}
+	if string(image[start:start+8]) != fitHeaderMagic {
+		return nil, fmt.Errorf("no FIT header at %#x", start)
+	}
+	count := Read3Size([3]uint8{image[start+8], image[start+9], image[start+10]})
+	if start+count*fitEntrySize > imageSize {
+		return nil, fmt.Errorf("FIT with %d entries at %#x exceeds the image", count, start)
+	}
+
+	var entries []fitEntry
+	for i := uint64(1); i < count; i++ {
+		entry := image[start+i*fitEntrySize:]
+		addr := binary.LittleEndian.Uint64(entry)
+		offset, ok := toOffset(addr)
+		if !ok {
+			offset = addr
+		}
+		entries = append(entries, fitEntry{
+			Type:    entry[14] & 0x7f,
+			Offset:  offset,
+			InImage: ok,
+			Size:    uint32(Read3Size([3]uint8{entry[8], entry[9], entry[10]})),
+		})
+	}
+	return entries, nil
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"sort"
 )
@@ -25,8 +26,25 @@ const (
 	FlashDescriptorLength = 0x1000
 )
 
+var (
+	// AllowReservedGaps makes the assembly of a flash image fill the gaps
+	// between the regions, and between the last region and the end of the
+	// flash, with ReservedGapFill instead of failing. Gaps found while
+	// parsing an image are always kept as regions of unknown type, this
+	// only matters for gaps missing from the tree, e.g. reserved space
+	// between the descriptor and a BIOS region which is not adjacent to it.
+	AllowReservedGaps = false
+
+	// ReservedGapFill is the byte the gaps allowed by AllowReservedGaps are
+	// filled with.
+	ReservedGapFill byte = 0xFF
+)
+
 // FlashDescriptor is the main structure that represents an Intel Flash Descriptor.
 type FlashDescriptor struct {
+	dirtyFlag
+	parentLink
+
 	// Holds the raw buffer
 	buf                []byte
 	DescriptorMapStart uint
@@ -79,6 +97,11 @@ func (fd *FlashDescriptor) Apply(v Visitor) error {
 	return v.Visit(fd)
 }
 
+// Kind returns FirmwareKindFlashDescriptor.
+func (fd *FlashDescriptor) Kind() FirmwareKind {
+	return FirmwareKindFlashDescriptor
+}
+
 // ApplyChildren calls the visitor on each child node of FlashDescriptor.
 func (fd *FlashDescriptor) ApplyChildren(v Visitor) error {
 	return nil
@@ -126,9 +149,55 @@ func (fd *FlashDescriptor) ParseFlashDescriptor() error {
 	return nil
 }
 
+// CheckRegionConsistency checks that the region section agrees with the
+// descriptor map. It returns all the problems found, or nil if there are none.
+func (fd *FlashDescriptor) CheckRegionConsistency() []error {
+	if fd.DescriptorMap == nil || fd.Region == nil {
+		return []error{errors.New("flash descriptor is not parsed")}
+	}
+
+	var errs []error
+	frs := fd.Region.FlashRegions[:]
+	nr := int(fd.DescriptorMap.NumberOfRegions)
+	if nr > len(frs) {
+		errs = append(errs, fmt.Errorf("number of regions %d exceeds the number of region slots %d", nr, len(frs)))
+	}
+	if !frs[RegionTypeBIOS].Valid() {
+		errs = append(errs, fmt.Errorf("BIOS region is not valid: %v", &frs[RegionTypeBIOS]))
+	}
+
+	for i := range frs {
+		fr := &frs[i]
+		if !fr.Valid() {
+			continue
+		}
+		rt := FlashRegionType(i)
+		// Number of regions is 0 in newer IFDs, see NewFlashImage.
+		if nr != 0 && i >= nr {
+			errs = append(errs, fmt.Errorf("region %v %v is valid but is beyond the number of regions %d", rt, fr, nr))
+		}
+		if fr.BaseOffset() < FlashDescriptorLength {
+			errs = append(errs, fmt.Errorf("region %v %v overlaps the flash descriptor", rt, fr))
+		}
+		for j := i + 1; j < len(frs); j++ {
+			other := &frs[j]
+			if !other.Valid() {
+				continue
+			}
+			if fr.Base <= other.Limit && other.Base <= fr.Limit {
+				errs = append(errs, fmt.Errorf("region %v %v overlaps region %v %v", rt, fr, FlashRegionType(j), other))
+			}
+		}
+	}
+	return errs
+}
+
 // FlashImage is the main structure that represents an Intel Flash image. It
 // implements the Firmware interface.
 type FlashImage struct {
+	dirtyFlag
+	parentLink
+
 	// Holds the raw buffer
 	buf []byte
 	// Holds the Flash Descriptor
@@ -158,6 +227,11 @@ func (f *FlashImage) Apply(v Visitor) error {
 	return v.Visit(f)
 }
 
+// Kind returns FirmwareKindFlashImage.
+func (f *FlashImage) Kind() FirmwareKind {
+	return FirmwareKindFlashImage
+}
+
 // ApplyChildren calls the visitor on each child node of FlashImage.
 func (f *FlashImage) ApplyChildren(v Visitor) error {
 	if err := f.IFD.Apply(v); err != nil {
@@ -199,6 +273,37 @@ func (f *FlashImage) String() string {
 	)
 }
 
+// Checksums returns the CRC32 (IEEE) of the flash descriptor, of every region
+// and of every top-level firmware volume in the BIOS region. Comparing the
+// checksums of two dumps is a cheap way to find which part of the flash got
+// corrupted. Regions are keyed by "<region type>@<offset>", volumes by
+// "FV@<offset>", where offsets are relative to the start of the image. The
+// checksums are computed over the current buffers, so an edited image has to
+// be assembled first.
+func (f *FlashImage) Checksums() map[string]uint32 {
+	sums := map[string]uint32{
+		"IFD": crc32.ChecksumIEEE(f.IFD.Buf()),
+	}
+	for _, t := range f.Regions {
+		r, ok := t.Value.(Region)
+		if !ok {
+			continue
+		}
+		base := uint64(r.FlashRegion().BaseOffset())
+		sums[fmt.Sprintf("%s@%#x", r.Type(), base)] = crc32.ChecksumIEEE(r.Buf())
+		br, ok := r.(*BIOSRegion)
+		if !ok {
+			continue
+		}
+		for _, e := range br.Elements {
+			if fv, ok := e.Value.(*FirmwareVolume); ok {
+				sums[fmt.Sprintf("FV@%#x", base+fv.FVOffset)] = crc32.ChecksumIEEE(fv.Buf())
+			}
+		}
+	}
+	return sums
+}
+
 func (f *FlashImage) fillRegionGaps() error {
 	// Search for gaps and fill in with unknown regions
 	offset := uint64(FlashDescriptorLength)
@@ -239,14 +344,24 @@ func (f *FlashImage) fillRegionGaps() error {
 // and an error if any. This only works with images that operate in Descriptor
 // mode.
 func NewFlashImage(buf []byte) (*FlashImage, error) {
+	return newFlashImage(buf, true, defaultParseConfig())
+}
+
+// newFlashImage parses the flash image, copyBuf controls if the image keeps
+// a private copy of buf or references it directly.
+func newFlashImage(buf []byte, copyBuf bool, cfg parseConfig) (*FlashImage, error) {
 	if len(buf) < FlashDescriptorLength {
 		return nil, fmt.Errorf("NewFlashImage: need at least %d bytes, only %d provided:%w", FlashDescriptorLength, len(buf), ErrTooShort)
 	}
 	f := FlashImage{FlashSize: uint64(len(buf))}
 
 	// Copy out buffers
-	f.buf = make([]byte, len(buf))
-	copy(f.buf, buf)
+	if copyBuf {
+		f.buf = make([]byte, len(buf))
+		copy(f.buf, buf)
+	} else {
+		f.buf = buf
+	}
 	f.IFD.buf = make([]byte, FlashDescriptorLength)
 	copy(f.IFD.buf, buf[:FlashDescriptorLength])
 
@@ -285,7 +400,13 @@ func NewFlashImage(buf []byte) (*FlashImage, error) {
 			continue
 		}
 		if c, ok := regionConstructors[FlashRegionType(i)]; ok {
-			r, err := c(buf[fr.BaseOffset():fr.EndOffset()], &frs[i], FlashRegionType(i))
+			var r Region
+			var err error
+			if FlashRegionType(i) == RegionTypeBIOS {
+				r, err = newBIOSRegion(buf[fr.BaseOffset():fr.EndOffset()], &frs[i], cfg)
+			} else {
+				r, err = c(buf[fr.BaseOffset():fr.EndOffset()], &frs[i], FlashRegionType(i))
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -303,5 +424,6 @@ func NewFlashImage(buf []byte) (*FlashImage, error) {
 	if err := f.fillRegionGaps(); err != nil {
 		return nil, err
 	}
+	linkChildren(&f)
 	return &f, nil
 }
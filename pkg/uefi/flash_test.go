@@ -7,7 +7,10 @@ package uefi
 import (
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -133,3 +136,97 @@ func TestFillRegionGaps(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckRegionConsistency(t *testing.T) {
+	newDescriptor := func(nr uint8, regions map[FlashRegionType]FlashRegion) *FlashDescriptor {
+		fd := &FlashDescriptor{
+			DescriptorMap: &FlashDescriptorMap{NumberOfRegions: nr},
+			Region:        &FlashRegionSection{},
+		}
+		for i := range fd.Region.FlashRegions {
+			fd.Region.FlashRegions[i] = FlashRegion{Base: 0x7fff, Limit: 0}
+		}
+		for rt, r := range regions {
+			fd.Region.FlashRegions[rt] = r
+		}
+		return fd
+	}
+
+	var tests = []struct {
+		name string
+		fd   *FlashDescriptor
+		errs int
+	}{
+		{"consistent", newDescriptor(3, map[FlashRegionType]FlashRegion{
+			RegionTypeBIOS: {Base: 0x200, Limit: 0x3ff},
+			RegionTypeME:   {Base: 0x3, Limit: 0x1ff},
+			RegionTypeGBE:  {Base: 0x1, Limit: 0x2},
+		}), 0},
+		{"consistent without number of regions", newDescriptor(0, map[FlashRegionType]FlashRegion{
+			RegionTypeBIOS: {Base: 0x200, Limit: 0x3ff},
+			RegionTypeEC:   {Base: 0x1, Limit: 0x1ff},
+		}), 0},
+		{"valid region beyond number of regions", newDescriptor(2, map[FlashRegionType]FlashRegion{
+			RegionTypeBIOS: {Base: 0x200, Limit: 0x3ff},
+			RegionTypeME:   {Base: 0x3, Limit: 0x1ff},
+			RegionTypeGBE:  {Base: 0x1, Limit: 0x2},
+		}), 1},
+		{"overlapping regions", newDescriptor(0, map[FlashRegionType]FlashRegion{
+			RegionTypeBIOS: {Base: 0x200, Limit: 0x3ff},
+			RegionTypeME:   {Base: 0x1, Limit: 0x200},
+		}), 1},
+		{"region over descriptor", newDescriptor(0, map[FlashRegionType]FlashRegion{
+			RegionTypeBIOS: {Base: 0x200, Limit: 0x3ff},
+			RegionTypePD:   {Base: 0x0, Limit: 0x1},
+		}), 1},
+		{"no BIOS region", newDescriptor(0, nil), 1},
+		{"unparsed", &FlashDescriptor{}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := test.fd.CheckRegionConsistency()
+			if len(errs) != test.errs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), test.errs, errs)
+			}
+		})
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	bios := append(append([]byte{}, sampleFV...), sampleFV...)
+	a, err := NewFlashImage(makeBIOSFlashImage(bios))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the free space at the end of the second volume.
+	corrupted := makeBIOSFlashImage(bios)
+	corrupted[FlashDescriptorLength+2*len(sampleFV)-1] ^= 0xff
+	b, err := NewFlashImage(corrupted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sumsA, sumsB := a.Checksums(), b.Checksums()
+	firstFV := fmt.Sprintf("FV@%#x", FlashDescriptorLength)
+	secondFV := fmt.Sprintf("FV@%#x", FlashDescriptorLength+len(sampleFV))
+	biosRegion := fmt.Sprintf("BIOS@%#x", FlashDescriptorLength)
+	for _, key := range []string{"IFD", biosRegion, firstFV, secondFV} {
+		if _, ok := sumsA[key]; !ok {
+			t.Fatalf("no checksum for %s in %v", key, sumsA)
+		}
+	}
+	if len(sumsA) != len(sumsB) {
+		t.Fatalf("checksum count mismatch: %d vs %d", len(sumsA), len(sumsB))
+	}
+
+	var diff []string
+	for key, sum := range sumsA {
+		if sumsB[key] != sum {
+			diff = append(diff, key)
+		}
+	}
+	sort.Strings(diff)
+	if want := []string{biosRegion, secondFV}; !reflect.DeepEqual(diff, want) {
+		t.Errorf("differing checksums: got %v, want %v", diff, want)
+	}
+}
@@ -87,3 +87,28 @@ func TestFuzzInputs(t *testing.T) {
 		})
 	}
 }
+
+// FuzzNewNVarStore checks that crafted NVAR stores are rejected with an error
+// instead of a panic or an endless loop.
+//
+// To run the fuzzer:
+// go test -run XXX -fuzz=FuzzNewNVarStore ./pkg/uefi
+func FuzzNewNVarStore(f *testing.F) {
+	log.SetOutput(&nopWriter{})
+	log.SetFlags(0)
+
+	for _, seed := range [][]byte{
+		testNVarStore,
+		headerOnlyEmptyNVar,
+		stored1GUIDASCIINameNVar,
+		zeroSizeNVar,
+		append(append([]byte{}, headerOnlyEmptyNVar...), zeroSizeNVar...),
+		shortSizeNVar,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		Attributes = ROMAttributes{ErasePolarity: 0xFF}
+		_, _ = NewNVarStore(b)
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"fmt"
+
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+// IBBCoveredNode is a firmware volume or a file which overlaps the ranges
+// measured as the Initial Boot Block (IBB) by Boot Guard.
+type IBBCoveredNode struct {
+	// Node is either a *FirmwareVolume or a *File.
+	Node Firmware
+	// Range is the location of the node in the image.
+	Range bytes2.Range
+	// CoveredBytes is the number of bytes of the node which are measured.
+	CoveredBytes uint64
+}
+
+// FullyCovered tells if the whole node is measured.
+func (n IBBCoveredNode) FullyCovered() bool {
+	return n.CoveredBytes == n.Range.Length
+}
+
+// IBBCoverage reports the firmware volumes and files of the image which fall
+// within the IBB ranges, e.g. the ones returned by IBBDataRanges of a Boot
+// Guard boot policy manifest. The ranges are offsets within the image, which
+// is either a *FlashImage or a *BIOSRegion.
+//
+// Only the volumes found directly in the BIOS region have a known location in
+// the image, nested volumes, e.g. in compressed sections, are not reported.
+// The nodes are listed in the order of the image, each volume being followed
+// by its covered files.
+func IBBCoverage(f Firmware, ibbRanges bytes2.Ranges) ([]IBBCoveredNode, error) {
+	ibbRanges = append(bytes2.Ranges{}, ibbRanges...)
+	ibbRanges.SortAndMerge()
+
+	var result []IBBCoveredNode
+	switch f := f.(type) {
+	case *FlashImage:
+		for _, t := range f.Regions {
+			br, ok := t.Value.(*BIOSRegion)
+			if !ok {
+				continue
+			}
+			base := uint64(br.FlashRegion().BaseOffset())
+			result = append(result, biosRegionIBBCoverage(br, base, ibbRanges)...)
+		}
+	case *BIOSRegion:
+		result = biosRegionIBBCoverage(f, 0, ibbRanges)
+	default:
+		return nil, fmt.Errorf("IBB coverage requires a flash image or a BIOS region, got %T", f)
+	}
+	return result, nil
+}
+
+func biosRegionIBBCoverage(br *BIOSRegion, base uint64, ibbRanges bytes2.Ranges) []IBBCoveredNode {
+	var result []IBBCoveredNode
+	for _, e := range br.Elements {
+		fv, ok := e.Value.(*FirmwareVolume)
+		if !ok {
+			continue
+		}
+		fvRange := bytes2.Range{Offset: base + fv.FVOffset, Length: uint64(len(fv.Buf()))}
+		covered := coveredBytes(fvRange, ibbRanges)
+		if covered == 0 {
+			continue
+		}
+		result = append(result, IBBCoveredNode{Node: fv, Range: fvRange, CoveredBytes: covered})
+
+		offsets := fv.FileOffsets()
+		for i, file := range fv.Files {
+			fileRange := bytes2.Range{Offset: fvRange.Offset + offsets[i], Length: uint64(len(file.Buf()))}
+			if covered := coveredBytes(fileRange, ibbRanges); covered != 0 {
+				result = append(result, IBBCoveredNode{Node: file, Range: fileRange, CoveredBytes: covered})
+			}
+		}
+	}
+	return result
+}
+
+// coveredBytes returns the number of bytes of r which are within the ranges.
+func coveredBytes(r bytes2.Range, ranges bytes2.Ranges) uint64 {
+	if r.Length == 0 {
+		return 0
+	}
+	uncovered := uint64(0)
+	for _, u := range r.Exclude(ranges...) {
+		uncovered += u.Length
+	}
+	return r.Length - uncovered
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"os"
+	"testing"
+
+	bytes2 "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+func TestIBBCoverage(t *testing.T) {
+	Attributes = ROMAttributes{ErasePolarity: poisonedPolarity}
+	image, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFlashImage(makeBIOSFlashImage(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var br *BIOSRegion
+	for _, r := range f.Regions {
+		if b, ok := r.Value.(*BIOSRegion); ok {
+			br = b
+		}
+	}
+	if br == nil {
+		t.Fatal("no BIOS region")
+	}
+	var fvs []*FirmwareVolume
+	for _, e := range br.Elements {
+		if fv, ok := e.Value.(*FirmwareVolume); ok {
+			fvs = append(fvs, fv)
+		}
+	}
+	if len(fvs) < 2 {
+		t.Fatalf("got %d firmware volumes, expected at least 2", len(fvs))
+	}
+
+	// The IBB covers the last volume, which holds the SEC core.
+	fv := fvs[len(fvs)-1]
+	base := uint64(br.FlashRegion().BaseOffset())
+	fvRange := bytes2.Range{Offset: base + fv.FVOffset, Length: uint64(len(fv.Buf()))}
+	nodes, err := IBBCoverage(f, bytes2.Ranges{fvRange})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1+len(fv.Files) {
+		t.Fatalf("got %d covered nodes, expected the volume and its %d files", len(nodes), len(fv.Files))
+	}
+	if nodes[0].Node != fv || nodes[0].Range != fvRange || !nodes[0].FullyCovered() {
+		t.Errorf("got %+v, expected the fully covered volume at %v", nodes[0], fvRange)
+	}
+	for i, n := range nodes[1:] {
+		if n.Node != fv.Files[i] || !n.FullyCovered() {
+			t.Errorf("node %d: got %T at %v, expected file %v fully covered", i+1, n.Node, n.Range, fv.Files[i].Header.GUID)
+		}
+		if n.Range.Offset < fvRange.Offset || n.Range.End() > fvRange.End() {
+			t.Errorf("file %v at %v is out of the volume at %v", fv.Files[i].Header.GUID, n.Range, fvRange)
+		}
+	}
+
+	// Only the end of the volume is covered.
+	tail := bytes2.Range{Offset: fvRange.End() - 0x100, Length: 0x100}
+	nodes, err = IBBCoverage(br, bytes2.Ranges{{Offset: tail.Offset - base, Length: tail.Length}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) == 0 || nodes[0].Node != fv || nodes[0].FullyCovered() || nodes[0].CoveredBytes != 0x100 {
+		t.Errorf("got %+v, expected 0x100 bytes of the volume to be covered", nodes)
+	}
+
+	if _, err := IBBCoverage(fv, nil); err == nil {
+		t.Error("expected an error for a firmware volume")
+	}
+}
@@ -38,6 +38,9 @@ const (
 
 // MEFPT is the main structure that represents an ME Flash Partition Table.
 type MEFPT struct {
+	dirtyFlag
+	parentLink
+
 	// Holds the raw buffer
 	buf []byte
 
@@ -128,6 +131,11 @@ func (fp *MEFPT) Apply(v Visitor) error {
 	return v.Visit(fp)
 }
 
+// Kind returns FirmwareKindMEFPT.
+func (fp *MEFPT) Kind() FirmwareKind {
+	return FirmwareKindMEFPT
+}
+
 // ApplyChildren calls the visitor on each child node of MEFPT.
 func (fp *MEFPT) ApplyChildren(v Visitor) error {
 	return nil
@@ -168,6 +176,9 @@ func (fp *MEFPT) parsePartitions() error {
 
 // MERegion implements Region for a raw chunk of bytes in the firmware image.
 type MERegion struct {
+	dirtyFlag
+	parentLink
+
 	FPT *MEFPT
 	// holds the raw data
 	buf []byte
@@ -202,6 +213,7 @@ func NewMERegion(buf []byte, r *FlashRegion, rt FlashRegionType) (Region, error)
 		return rr, nil
 	}
 	rr.FPT = fp
+	linkChildren(rr)
 	// Compute FreeSpaceOffset
 	for _, p := range fp.Entries {
 		if p.OffsetIsValid() {
@@ -237,6 +249,11 @@ func (rr *MERegion) Apply(v Visitor) error {
 	return v.Visit(rr)
 }
 
+// Kind returns FirmwareKindMERegion.
+func (rr *MERegion) Kind() FirmwareKind {
+	return FirmwareKindMERegion
+}
+
 // ApplyChildren calls the visitor on each child node of MERegion.
 func (rr *MERegion) ApplyChildren(v Visitor) error {
 	if rr.FPT == nil {
@@ -0,0 +1,201 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/linuxboot/fiano/pkg/intel/microcode"
+)
+
+// microcodeAlignment is the alignment required for microcode updates.
+const microcodeAlignment = 16
+
+// MicrocodeBlob is a microcode update found by AuditMicrocode.
+type MicrocodeBlob struct {
+	// Offset is the offset of the update in the image.
+	Offset         uint64
+	Signature      uint32
+	ProcessorFlags uint32
+	Revision       uint32
+	// Date is in the packed BCD MMDDYYYY format.
+	Date uint32
+	Size uint32
+
+	// InImage is set if the update was found by scanning the image, e.g.
+	// in the microcode firmware volume.
+	InImage bool
+	// InFIT is set if the update is referenced by a FIT microcode entry.
+	InFIT bool
+
+	// Duplicate is set if the same revision for the same processor is
+	// stored at a lower offset.
+	Duplicate bool
+	// Stale is set if there is a newer revision for the same processor.
+	Stale bool
+	// NotInFIT is set if FIT exists, but does not reference the update, so
+	// it is not loaded before the reset vector.
+	NotInFIT bool
+	// Error is set if FIT references an invalid update.
+	Error string `json:",omitempty"`
+}
+
+// DateString returns the date of the update in the YYYY-MM-DD format.
+func (b *MicrocodeBlob) DateString() string {
+	return fmt.Sprintf("%04x-%02x-%02x", b.Date&0xffff, b.Date>>24, (b.Date>>16)&0xff)
+}
+
+// MicrocodeAudit is the result of AuditMicrocode.
+type MicrocodeAudit struct {
+	// HasFIT is set if the image contains a valid FIT.
+	HasFIT bool
+	// Blobs are sorted by offset.
+	Blobs []*MicrocodeBlob
+}
+
+// AuditMicrocode lists all the microcode updates found in the image, both
+// by scanning it and by following the FIT microcode entries, and flags
+// duplicated, stale and FIT-invalid copies.
+func AuditMicrocode(image []byte) *MicrocodeAudit {
+	audit := &MicrocodeAudit{}
+	blobs := map[uint64]*MicrocodeBlob{}
+
+	for offset := uint64(0); offset+microcodeAlignment <= uint64(len(image)); offset += microcodeAlignment {
+		blob, err := parseMicrocodeBlob(image, offset)
+		if err != nil {
+			continue
+		}
+		blob.InImage = true
+		blobs[offset] = blob
+		// Skip the update itself, offset is incremented by the loop.
+		offset += Align(uint64(blob.Size), microcodeAlignment) - microcodeAlignment
+	}
+
+	fitOffsets, err := fitMicrocodeOffsets(image)
+	if err == nil {
+		audit.HasFIT = true
+	}
+	for _, offset := range fitOffsets {
+		blob, ok := blobs[offset]
+		if !ok {
+			blob, err = parseMicrocodeBlob(image, offset)
+			if err != nil {
+				blob = &MicrocodeBlob{Offset: offset, Error: err.Error()}
+			}
+			blobs[offset] = blob
+		}
+		blob.InFIT = true
+	}
+
+	for _, blob := range blobs {
+		audit.Blobs = append(audit.Blobs, blob)
+	}
+	sort.Slice(audit.Blobs, func(i, j int) bool {
+		return audit.Blobs[i].Offset < audit.Blobs[j].Offset
+	})
+	audit.flag()
+	return audit
+}
+
+func (a *MicrocodeAudit) flag() {
+	for i, blob := range a.Blobs {
+		if blob.Error != "" {
+			continue
+		}
+		blob.NotInFIT = a.HasFIT && !blob.InFIT
+		for j, other := range a.Blobs {
+			if i == j || other.Error != "" || other.Signature != blob.Signature {
+				continue
+			}
+			if blob.ProcessorFlags != 0 && other.ProcessorFlags != 0 &&
+				blob.ProcessorFlags&other.ProcessorFlags == 0 {
+				// Different platforms of the same CPU model
+				continue
+			}
+			switch {
+			case other.Revision > blob.Revision:
+				blob.Stale = true
+			case other.Revision == blob.Revision && j < i:
+				blob.Duplicate = true
+			}
+		}
+	}
+}
+
+// String prints the audit result in a tabular form.
+func (a *MicrocodeAudit) String() string {
+	var s strings.Builder
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Offset\tSignature\tFlags\tRevision\tDate\tSize\tImage\tFIT\tNotes\n")
+	for _, b := range a.Blobs {
+		if b.Error != "" {
+			fmt.Fprintf(w, "%#x\t-\t-\t-\t-\t-\t%v\t%v\tinvalid: %s\n", b.Offset, b.InImage, b.InFIT, b.Error)
+			continue
+		}
+		var notes []string
+		if b.Duplicate {
+			notes = append(notes, "duplicate")
+		}
+		if b.Stale {
+			notes = append(notes, "stale")
+		}
+		if b.NotInFIT {
+			notes = append(notes, "not in FIT")
+		}
+		fmt.Fprintf(w, "%#x\t%#x\t%#x\t%#x\t%s\t%#x\t%v\t%v\t%s\n",
+			b.Offset, b.Signature, b.ProcessorFlags, b.Revision, b.DateString(), b.Size,
+			b.InImage, b.InFIT, strings.Join(notes, ", "))
+	}
+	w.Flush()
+	return s.String()
+}
+
+func parseMicrocodeBlob(image []byte, offset uint64) (*MicrocodeBlob, error) {
+	if offset+uint64(binary.Size(microcode.Header{})) > uint64(len(image)) {
+		return nil, fmt.Errorf("offset %#x is out of the image", offset)
+	}
+	buf := image[offset:]
+	// Cheap check of HeaderVersion and HeaderLoaderRevision before the full parsing.
+	if binary.LittleEndian.Uint32(buf[0:]) != 1 || binary.LittleEndian.Uint32(buf[20:]) != 1 {
+		return nil, fmt.Errorf("no microcode header at %#x", offset)
+	}
+	m, err := microcode.ParseIntelMicrocode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	size := m.HeaderTotalSize
+	if m.HeaderDataSize == 0 {
+		size = microcode.DefaultTotalSize
+	}
+	return &MicrocodeBlob{
+		Offset:         offset,
+		Signature:      m.HeaderProcessorSignature,
+		ProcessorFlags: m.HeaderProcessorFlags,
+		Revision:       m.HeaderRevision,
+		Date:           m.HeaderDate,
+		Size:           size,
+	}, nil
+}
+
+// fitMicrocodeOffsets returns the image offsets of the FIT microcode entries.
+// Out of image addresses are reported as offsets that can not be parsed.
+func fitMicrocodeOffsets(image []byte) ([]uint64, error) {
+	entries, err := fitEntries(image)
+	if err != nil {
+		return nil, err
+	}
+	var offsets []uint64
+	for _, entry := range entries {
+		if entry.Type == fitEntryTypeMicrocode {
+			offsets = append(offsets, entry.Offset)
+		}
+	}
+	return offsets, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// makeMicrocode returns a minimal valid microcode update with the given revision.
+func makeMicrocode(revision uint32) []byte {
+	m := []byte("\x01\x00\x00\x00\x24\x04\x00\x00\x22\x20\x19\x09\xa3\x06\x09\x00\x00\x00\x00\x00\x01\x00\x00\x00\x80\x00\x00\x00\x04\x00\x00\x00\x34\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(m[4:], revision)
+	var sum uint32
+	for i := 0; i < len(m); i += 4 {
+		sum += binary.LittleEndian.Uint32(m[i:])
+	}
+	binary.LittleEndian.PutUint32(m[16:], -sum)
+	return m
+}
+
+func TestAuditMicrocode(t *testing.T) {
+	const imageSize = 0x10000
+	image := bytes.Repeat([]byte{0xff}, imageSize)
+	copy(image[0x1000:], makeMicrocode(0x424))
+	copy(image[0x2000:], makeMicrocode(0x424))
+	copy(image[0x3000:], makeMicrocode(0x400))
+
+	// FIT with the header and two microcode entries, the second one
+	// points to erased space.
+	const fitOffset = 0x8000
+	toAddr := func(offset uint64) uint64 { return fitBasePhysAddr - imageSize + offset }
+	fit := image[fitOffset:]
+	copy(fit, fitHeaderMagic)
+	copy(fit[8:], []byte{3, 0, 0, 0, 0, 1, 0, 0})
+	for i, offset := range []uint64{0x1000, 0x5000} {
+		entry := fit[(i+1)*fitEntrySize:]
+		binary.LittleEndian.PutUint64(entry, toAddr(offset))
+		copy(entry[8:], []byte{0, 0, 0, 0, 0, 1, fitEntryTypeMicrocode, 0})
+	}
+	binary.LittleEndian.PutUint64(image[imageSize-fitPointerOffset:], toAddr(fitOffset))
+
+	audit := AuditMicrocode(image)
+	if !audit.HasFIT {
+		t.Errorf("FIT is not found")
+	}
+
+	var tests = []struct {
+		offset    uint64
+		inImage   bool
+		inFIT     bool
+		duplicate bool
+		stale     bool
+		notInFIT  bool
+		invalid   bool
+	}{
+		{0x1000, true, true, false, false, false, false},
+		{0x2000, true, false, true, false, true, false},
+		{0x3000, true, false, false, true, true, false},
+		{0x5000, false, true, false, false, false, true},
+	}
+	if len(audit.Blobs) != len(tests) {
+		t.Fatalf("got %d microcode blobs, want %d:\n%s", len(audit.Blobs), len(tests), audit)
+	}
+	for i, test := range tests {
+		b := audit.Blobs[i]
+		if b.Offset != test.offset || b.InImage != test.inImage || b.InFIT != test.inFIT ||
+			b.Duplicate != test.duplicate || b.Stale != test.stale || b.NotInFIT != test.notInFIT ||
+			(b.Error != "") != test.invalid {
+			t.Errorf("unexpected blob %d: %+v", i, b)
+		}
+	}
+	if b := audit.Blobs[0]; b.Signature != 0x906a3 || b.Revision != 0x424 || b.DateString() != "2022-09-19" {
+		t.Errorf("unexpected microcode header values: %+v", b)
+	}
+
+	s := audit.String()
+	for _, want := range []string{"duplicate", "stale", "not in FIT", "invalid"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("table does not contain %q:\n%s", want, s)
+		}
+	}
+}
@@ -92,6 +92,9 @@ const (
 
 // NVar represent an NVAR entry
 type NVar struct {
+	dirtyFlag
+	parentLink
+
 	Header    NVarHeader
 	GUID      guid.GUID
 	GUIDIndex *uint8 `json:",omitempty"`
@@ -144,6 +147,11 @@ func (v *NVar) Apply(vr Visitor) error {
 	return vr.Visit(v)
 }
 
+// Kind returns FirmwareKindNVar.
+func (v *NVar) Kind() FirmwareKind {
+	return FirmwareKindNVar
+}
+
 // ApplyChildren calls the visitor on each child node of NVar.
 func (v *NVar) ApplyChildren(vr Visitor) error {
 	if v.NVarStore != nil {
@@ -154,8 +162,32 @@ func (v *NVar) ApplyChildren(vr Visitor) error {
 	return nil
 }
 
+// ErrNVarNotFound is returned by NVarStore.Lookup when the store has no
+// valid variable of the requested name and GUID.
+var ErrNVarNotFound = errors.New("NVAR variable not found")
+
+// Validate checks the extended header of the entry. It returns an error if
+// the stored checksum does not match the content of the entry, or if the
+// format of the extended header is unknown.
+func (v *NVar) Validate() error {
+	if v.Checksum != nil && v.ExpectedChecksum != nil {
+		// ExpectedChecksum is what the entry, stored checksum included,
+		// is off by.
+		return fmt.Errorf("NVAR %s at offset %#x checksum failure! stored checksum was %#x instead of %#x",
+			v.Name, v.Offset, *v.Checksum, *v.Checksum+*v.ExpectedChecksum)
+	}
+	if v.UnknownExtendedHeaderFormat {
+		return fmt.Errorf("NVAR %s at offset %#x has an extended header of unknown format, attributes were %#x",
+			v.Name, v.Offset, v.Header.Attributes)
+	}
+	return nil
+}
+
 // NVarStore represent an NVAR store
 type NVarStore struct {
+	dirtyFlag
+	parentLink
+
 	Entries   []*NVar
 	GUIDStore []guid.GUID `json:",omitempty"`
 
@@ -183,6 +215,11 @@ func (s *NVarStore) Apply(v Visitor) error {
 	return v.Visit(s)
 }
 
+// Kind returns FirmwareKindNVarStore.
+func (s *NVarStore) Kind() FirmwareKind {
+	return FirmwareKindNVarStore
+}
+
 // ApplyChildren calls the visitor on each child node of NVarStore.
 func (s *NVarStore) ApplyChildren(v Visitor) error {
 	for _, nv := range s.Entries {
@@ -193,30 +230,33 @@ func (s *NVarStore) ApplyChildren(v Visitor) error {
 	return nil
 }
 
-func (s *NVarStore) getGUIDFromStore(i uint8) guid.GUID {
+// getGUIDFromStore returns the GUID at index i of the GUID store, reading it
+// from the end of the store buffer if needed. The GUID store grows downward
+// from the end of the buffer while the variables grow upward, so reading the
+// GUID must not make the GUID store overlap the variables ending at varEnd.
+func (s *NVarStore) getGUIDFromStore(i uint8, varEnd uint64) (guid.GUID, error) {
 	var GUID guid.GUID
-	if len(s.GUIDStore) < int(i+1) {
+	if len(s.GUIDStore) <= int(i) {
+		bufLen := uint64(len(s.buf))
+		storeSize := uint64(binary.Size(GUID)) * (uint64(i) + 1)
+		if storeSize > bufLen {
+			return *ZeroGUID, fmt.Errorf("GUID store index %d is beyond the NVAR store of size %#x", i, bufLen)
+		}
+		if storeOffset := bufLen - storeSize; storeOffset < varEnd {
+			return *ZeroGUID, fmt.Errorf("GUID store index %d overlaps the variables: GUID store would start at %#x, variables end at %#x",
+				i, storeOffset, varEnd)
+		}
 		// Read GUID in reverse order from the buffer
-		r := bytes.NewReader(s.buf)
-		if _, err := r.Seek(-int64(binary.Size(GUID))*int64(i+1), io.SeekEnd); err != nil {
-			// not returning an error as this is really unlikely, in most
-			// overflow case we will read NVAR content as GUID as the store
-			// buffer is expected to be big enough...
-			return *ZeroGUID
-		}
-		a := make([]guid.GUID, int(i+1)-len(s.GUIDStore))
+		r := bytes.NewReader(s.buf[bufLen-storeSize:])
+		a := make([]guid.GUID, int(i)+1-len(s.GUIDStore))
 		for j := int(i) - len(s.GUIDStore); j >= 0; j-- {
-			// no error check as the Seek will fail first
 			if err := binary.Read(r, binary.LittleEndian, &a[j]); err != nil {
-				break
+				return *ZeroGUID, err
 			}
 		}
 		s.GUIDStore = append(s.GUIDStore, a...)
 	}
-	if int(i) >= len(s.GUIDStore) {
-		return *ZeroGUID
-	}
-	return s.GUIDStore[i]
+	return s.GUIDStore[i], nil
 }
 
 func (v *NVar) parseHeader(buf []byte) error {
@@ -232,6 +272,11 @@ func (v *NVar) parseHeader(buf []byte) error {
 		return fmt.Errorf("NVAR Size bigger than remaining size")
 	}
 	v.DataOffset = int64(binary.Size(v.Header))
+	// A size smaller than the header would make the store parsing loop
+	// forever or slice past the entry.
+	if int64(v.Header.Size) < v.DataOffset {
+		return fmt.Errorf("NVAR Size %#x smaller than header size %#x", v.Header.Size, v.DataOffset)
+	}
 	return nil
 }
 
@@ -389,7 +434,11 @@ func (v *NVar) parseGUID(s *NVarStore) error {
 			return err
 		}
 		v.GUIDIndex = &guidIndex
-		v.GUID = s.getGUIDFromStore(guidIndex)
+		GUID, err := s.getGUIDFromStore(guidIndex, v.Offset+uint64(v.Header.Size))
+		if err != nil {
+			return err
+		}
+		v.GUID = GUID
 		v.DataOffset += int64(binary.Size(guidIndex))
 	}
 	return nil
@@ -433,6 +482,7 @@ func (v *NVar) parseContent(buf []byte) error {
 		return fmt.Errorf("error parsing NVAR store in var %v: %v", v.Name, err)
 	}
 	v.NVarStore = ns
+	linkChildren(v)
 	return nil
 }
 
@@ -590,10 +640,26 @@ func NewNVarStore(buf []byte) (*NVarStore, error) {
 		s.FreeSpaceOffset += uint64(v.Header.Size)
 		s.GUIDStoreOffset = s.Length - uint64(binary.Size(guid.GUID{}))*uint64(len(s.GUIDStore))
 	}
+	linkChildren(&s)
 
 	return &s, nil
 }
 
+// Validate checks the entries of the store, including the ones of the stores
+// nested in variables, and returns the errors found.
+func (s *NVarStore) Validate() []error {
+	var errs []error
+	for _, v := range s.Entries {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if v.NVarStore != nil {
+			errs = append(errs, v.NVarStore.Validate()...)
+		}
+	}
+	return errs
+}
+
 // GetGUIDStoreBuf returns the binary representation of the GUIDStore
 func (s *NVarStore) GetGUIDStoreBuf() ([]byte, error) {
 	guidStoreWBuf := new(bytes.Buffer)
@@ -605,3 +671,80 @@ func (s *NVarStore) GetGUIDStoreBuf() ([]byte, error) {
 	}
 	return guidStoreWBuf.Bytes(), nil
 }
+
+// Lookup returns the effective entry of the variable with the given name and
+// GUID together with its content. A variable whose value was updated is a
+// link entry pointing to the entry holding the new value, usually a
+// data-only one, so the chain of links is followed up to its last entry. The
+// content excludes the extended header of the entry.
+//
+// An error wrapping ErrNVarNotFound is returned if there is no such variable,
+// any other error means that the store is inconsistent.
+func (s *NVarStore) Lookup(name string, g guid.GUID) (*NVar, []byte, error) {
+	for _, v := range s.definitions() {
+		if v.Name == name && v.GUID == g {
+			return s.resolve(v, s.entriesByOffset())
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s %v", ErrNVarNotFound, name, g)
+}
+
+// definitions returns the newest valid definition of each variable of the
+// store, in the order the variables were first defined. Data-only entries
+// inherit the name and GUID of their link but are not definitions.
+func (s *NVarStore) definitions() []*NVar {
+	type key struct {
+		name string
+		g    guid.GUID
+	}
+	var defs []*NVar
+	index := make(map[key]int)
+	for _, e := range s.Entries {
+		if !e.IsValid() || e.Header.Attributes&NVarEntryDataOnly != 0 {
+			continue
+		}
+		k := key{e.Name, e.GUID}
+		if i, ok := index[k]; ok {
+			defs[i] = e
+			continue
+		}
+		index[k] = len(defs)
+		defs = append(defs, e)
+	}
+	return defs
+}
+
+func (s *NVarStore) entriesByOffset() map[uint64]*NVar {
+	byOffset := make(map[uint64]*NVar, len(s.Entries))
+	for _, e := range s.Entries {
+		byOffset[e.Offset] = e
+	}
+	return byOffset
+}
+
+// resolve follows the chain of links starting at the definition v up to its
+// last entry, and returns that entry with its content.
+func (s *NVarStore) resolve(v *NVar, byOffset map[uint64]*NVar) (*NVar, []byte, error) {
+	name, g := v.Name, v.GUID
+	for steps := 0; v.NextOffset != 0; steps++ {
+		if steps >= len(s.Entries) {
+			return nil, nil, fmt.Errorf("loop in the links of NVAR %s %v", name, g)
+		}
+		next, ok := byOffset[v.NextOffset]
+		if !ok || !next.IsValid() {
+			return nil, nil, fmt.Errorf("NVAR %s %v at offset %#x links to no valid entry at offset %#x",
+				name, g, v.Offset, v.NextOffset)
+		}
+		v = next
+	}
+
+	end := int64(len(v.buf))
+	if v.ExtAttributes != nil {
+		end = v.ExtOffset
+	}
+	if v.DataOffset > end {
+		return nil, nil, fmt.Errorf("NVAR %s %v at offset %#x: data offset %#x beyond the data end %#x",
+			name, g, v.Offset, v.DataOffset, end)
+	}
+	return v, v.buf[v.DataOffset:end], nil
+}
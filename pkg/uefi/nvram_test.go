@@ -7,6 +7,7 @@ package uefi
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"testing"
 
 	"github.com/linuxboot/fiano/pkg/guid"
@@ -50,6 +51,8 @@ var (
 	badMissingNameEndNVAR    = append(append(append(signatureNVarBuf[:], []byte{15, 0}...), noNextNVarBuf...), []byte{byte(NVarEntryValid | NVarEntryASCIIName), 0, byte('T'), byte('e'), byte('s'), byte('t')}...)
 	stored0GUIDASCIINameNVar = append(append(append(signatureNVarBuf[:], []byte{16, 0}...), noNextNVarBuf...), []byte{byte(NVarEntryValid | NVarEntryASCIIName), 0, byte('T'), byte('e'), byte('s'), byte('t'), 0}...)
 	stored1GUIDASCIINameNVar = append(append(append(signatureNVarBuf[:], []byte{16, 0}...), noNextNVarBuf...), []byte{byte(NVarEntryValid | NVarEntryASCIIName), 1, byte('T'), byte('e'), byte('s'), byte('t'), 0}...)
+	zeroSizeNVar             = append(append(append(signatureNVarBuf[:], []byte{0, 0}...), noNextNVarBuf...), byte(NVarEntryValid|NVarEntryDataOnly))
+	shortSizeNVar            = append(append(append(signatureNVarBuf[:], []byte{4, 0}...), noNextNVarBuf...), byte(NVarEntryValid|NVarEntryASCIIName))
 )
 var (
 	testNVarStore = append(append(headerOnlyEmptyNVar, stored0GUIDASCIINameNVar...), erased16NVarBuf...)
@@ -65,6 +68,8 @@ func TestNVar_parseHeader(t *testing.T) {
 		{"erasedSmallNVarBuf", erasedSmallNVarBuf, "unexpected EOF"},
 		{"erased16NVarBuf", erased16NVarBuf, "NVAR Signature not found"},
 		{"badIncompleteNVar", badIncompleteNVar, "NVAR Size bigger than remaining size"},
+		{"zeroSizeNVar", zeroSizeNVar, "NVAR Size 0x0 smaller than header size 0xa"},
+		{"shortSizeNVar", shortSizeNVar, "NVAR Size 0x4 smaller than header size 0xa"},
 		{"goodEmptyNVar", headerOnlyEmptyNVar, ""},
 	}
 	for _, test := range tests {
@@ -175,13 +180,13 @@ func TestNewNVar_Store(t *testing.T) {
 		{"badMissingGUIDNVar", 0, badMissingGUIDNVar, "EOF", FullNVarEntry, 16, nil, ""},
 		{"badMissingNameEndNVAR", 0, badMissingNameEndNVAR, "EOF", FullNVarEntry, 15, nil, ""},
 		{"stored0GUIDASCIINameNVar", 0, stored0GUIDASCIINameNVar, "", FullNVarEntry, 16, FFGUID, "Test"},
-		{"stored1GUIDASCIINameNVar", 0, stored1GUIDASCIINameNVar, "", FullNVarEntry, 16, ZeroGUID, "Test"},
+		{"stored1GUIDASCIINameNVar", 0, stored1GUIDASCIINameNVar, "GUID store index 1 overlaps the variables: GUID store would start at 0x0, variables end at 0x10", FullNVarEntry, 16, nil, ""},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			storedVar := NVar{GUID: *guid.MustParse("2df19db9-a1b4-4b02-b4bb-5ddb4866e13f"), Name: "Stored", Type: LinkNVarEntry, NextOffset: 123}
 			invalidVar := NVar{Type: InvalidNVarEntry}
-			s := NVarStore{buf: erased16NVarBuf}
+			s := NVarStore{buf: append(make([]byte, 16), erased16NVarBuf...)}
 			s.Entries = append(s.Entries, &invalidVar, &storedVar)
 			Attributes.ErasePolarity = 0xFF
 			v, err := newNVar(test.buf, test.offset, &s)
@@ -261,8 +266,11 @@ func TestNVar_NewNVarStore(t *testing.T) {
 		{"erasedSmallNVarBuf", erasedSmallNVarBuf, "", 0},
 		{"erased16NVarBuf", erased16NVarBuf, "", 0},
 		{"badIncompleteNVar", badIncompleteNVar, "error parsing NVAR entry at offset 0x0: NVAR Size bigger than remaining size", 0},
+		{"zeroSizeNVar", append(append([]byte{}, headerOnlyEmptyNVar...), zeroSizeNVar...), "error parsing NVAR entry at offset 0xa: NVAR Size 0x0 smaller than header size 0xa", 0},
 		{"goodEmptyNVar", headerOnlyEmptyNVar, "", 1},
 		{"testNVarStore", testNVarStore, "", 2},
+		{"GUIDStoreOverlap", append(append([]byte{}, stored1GUIDASCIINameNVar...), erased16NVarBuf...), "error parsing NVAR entry at offset 0x0: GUID store index 1 overlaps the variables: GUID store would start at 0x0, variables end at 0x10", 0},
+		{"GUIDIndexPastStore", stored1GUIDASCIINameNVar, "error parsing NVAR entry at offset 0x0: GUID store index 1 is beyond the NVAR store of size 0x10", 0},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -348,3 +356,137 @@ func TestNVarStore_GetGUIDStoreBuf(t *testing.T) {
 		})
 	}
 }
+
+func TestNVarStore_Lookup(t *testing.T) {
+	Attributes.ErasePolarity = 0xFF
+	setupGUID := guid.MustParse("EC87D643-EBA4-4BB5-A1E5-3F3E36B20DA9")
+	storeGUID := guid.MustParse("8BE4DF61-93CA-11D2-AA0D-00E098032B8C")
+
+	nvar := func(next uint32, attr NVarAttribute, body ...[]byte) []byte {
+		var data []byte
+		for _, b := range body {
+			data = append(data, b...)
+		}
+		size := 10 + len(data)
+		nextBytes := Write3Size(uint64(next))
+		if next == 0 {
+			nextBytes = [3]uint8{0xFF, 0xFF, 0xFF}
+		}
+		b := append(append([]byte{}, signatureNVarBuf...), byte(size), byte(size>>8))
+		b = append(append(b, nextBytes[:]...), byte(attr))
+		return append(b, data...)
+	}
+	// Setup is updated by a link to a data-only entry holding the new value.
+	setupLink := nvar(35, NVarEntryValid|NVarEntryASCIIName|NVarEntryGUID, setupGUID[:], []byte("Setup\x00"), []byte("old"))
+	setupData := nvar(0, NVarEntryValid|NVarEntryDataOnly, []byte("new"))
+	// Test takes its GUID from the GUID store.
+	test := nvar(0, NVarEntryValid|NVarEntryASCIIName, []byte{0}, []byte("Test\x00"), []byte("abc"))
+	// Broken links to nowhere.
+	broken := nvar(0x100, NVarEntryValid|NVarEntryASCIIName|NVarEntryGUID, setupGUID[:], []byte("Broken\x00"), []byte("x"))
+	// A deleted variable.
+	deleted := nvar(0, NVarEntryASCIIName|NVarEntryGUID, setupGUID[:], []byte("Deleted\x00"), []byte("x"))
+
+	var buf []byte
+	for _, b := range [][]byte{setupLink, setupData, test, broken, deleted} {
+		buf = append(buf, b...)
+	}
+	buf = append(buf, erased16NVarBuf...)
+	buf = append(buf, storeGUID[:]...)
+
+	s, err := NewNVarStore(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name     string
+		g        guid.GUID
+		content  string
+		offset   uint64
+		notFound bool
+		err      bool
+	}{
+		{"Setup", *setupGUID, "new", 35, false, false},
+		{"Test", *storeGUID, "abc", 48, false, false},
+		{"Test", *setupGUID, "", 0, true, true},
+		{"Missing", *setupGUID, "", 0, true, true},
+		{"Deleted", *setupGUID, "", 0, true, true},
+		{"Broken", *setupGUID, "", 0, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name+"_"+test.g.String(), func(t *testing.T) {
+			v, content, err := s.Lookup(test.name, test.g)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, expected error: %v", err, test.err)
+			}
+			if errors.Is(err, ErrNVarNotFound) != test.notFound {
+				t.Fatalf("got error %v, expected not found: %v", err, test.notFound)
+			}
+			if err != nil {
+				return
+			}
+			if string(content) != test.content {
+				t.Errorf("got content %q, expected %q", content, test.content)
+			}
+			if v.Offset != test.offset {
+				t.Errorf("got entry at offset %#x, expected %#x", v.Offset, test.offset)
+			}
+		})
+	}
+}
+
+// extHeaderNVar builds a full NVAR entry named Name whose extended header
+// holds extAttr and a checksum, corrupted by checksumDelta.
+func extHeaderNVar(extAttr NVarExtAttribute, checksumDelta uint8) []byte {
+	// header, GUID index, name, data, extended attributes, checksum, extended header size
+	b := append(append([]byte{}, signatureNVarBuf...), 0, 0)
+	b = append(b, noNextNVarBuf...)
+	b = append(b, byte(NVarEntryValid|NVarEntryASCIIName|NVarEntryExtHeader|NVarEntryAuthWrite), 0)
+	b = append(b, "Name\x00data"...)
+	b = append(b, byte(extAttr), 0, 4, 0)
+	b[4] = byte(len(b))
+	// The checksum skips the signature and the next field.
+	var sum uint8
+	for i := 4; i < len(b); i++ {
+		if i >= 6 && i <= 8 {
+			continue
+		}
+		sum += b[i]
+	}
+	b[len(b)-3] = -sum + checksumDelta
+	return b
+}
+
+func TestNVar_Validate(t *testing.T) {
+	Attributes.ErasePolarity = 0xFF
+	var tests = []struct {
+		name string
+		buf  []byte
+		msg  string
+	}{
+		{"goodChecksum", extHeaderNVar(NVarEntryExtChecksum, 0), ""},
+		{"badChecksum", extHeaderNVar(NVarEntryExtChecksum, 1), "NVAR Name at offset 0x0 checksum failure! stored checksum was 0xf7 instead of 0xf6"},
+		{"unknownFormat", extHeaderNVar(0, 0), "NVAR Name at offset 0x0 has an extended header of unknown format, attributes were 0xd2"},
+		{"noExtHeader", stored0GUIDASCIINameNVar, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewNVarStore(append(append([]byte{}, test.buf...), erased16NVarBuf...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Entries) != 1 {
+				t.Fatalf("got %d entries, expected 1", len(s.Entries))
+			}
+			err = s.Entries[0].Validate()
+			if err == nil && test.msg != "" {
+				t.Errorf("Error was not returned, expected %v", test.msg)
+			} else if err != nil && err.Error() != test.msg {
+				t.Errorf("Mismatched Error returned, expected \n%v\n got \n%v\n", test.msg, err.Error())
+			}
+			if errs := s.Validate(); (len(errs) != 0) != (test.msg != "") {
+				t.Errorf("store validation returned %v, expected error %q", errs, test.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"fmt"
+)
+
+// OpenFile maps the flash image file at path into memory read-only and
+// parses it, so large images are not read into the heap. The returned
+// function releases the mapping; neither the FlashImage nor any buffer
+// obtained from it may be used after it is called.
+//
+// The buffers of the image point to read-only memory, writing into them
+// crashes the program. Use CopyForEdit to get an image that can be modified.
+// The BIOS region is still copied unless ReadOnly is set.
+func OpenFile(path string) (*FlashImage, func() error, error) {
+	buf, closeFn, err := mapFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to map %q: %w", path, err)
+	}
+	f, err := newFlashImage(buf, false, defaultParseConfig())
+	if err != nil {
+		_ = closeFn()
+		return nil, nil, err
+	}
+	return f, closeFn, nil
+}
+
+// CopyForEdit returns a FlashImage parsed from a private copy of the image,
+// which can be modified even if f is backed by a read-only mapping.
+func (f *FlashImage) CopyForEdit() (*FlashImage, error) {
+	buf := make([]byte, len(f.buf))
+	copy(buf, f.buf)
+	return newFlashImage(buf, false, defaultParseConfig())
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package uefi
+
+import (
+	"os"
+)
+
+// mapFile falls back to reading the whole file on systems without mmap.
+func mapFile(path string) ([]byte, func() error, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(buf) == 0 {
+		return nil, nil, ErrTooShort
+	}
+	return buf, func() error { return nil }, nil
+}
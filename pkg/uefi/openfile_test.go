@@ -0,0 +1,95 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeFlashImage builds a minimal descriptor mode image with only a BIOS region.
+func makeFlashImage() []byte {
+	buf := bytes.Repeat([]byte{0xff}, 3*RegionBlockSize)
+	copy(buf[:FlashDescriptorLength], make([]byte, FlashDescriptorLength))
+	copy(buf[16:], FlashSignature)
+	// FLMAP0: region section at 0x40, FLMAP1: master section at 0x80
+	buf[22] = 0x04
+	buf[24] = 0x08
+	for i := 0; i < len(FlashRegionSection{}.FlashRegions); i++ {
+		binary.LittleEndian.PutUint16(buf[0x44+4*i:], 0x7fff)
+	}
+	binary.LittleEndian.PutUint16(buf[0x44+4*int(RegionTypeBIOS):], 1)
+	binary.LittleEndian.PutUint16(buf[0x46+4*int(RegionTypeBIOS):], 2)
+	return buf
+}
+
+// makeBIOSFlashImage builds an image like makeFlashImage whose BIOS region
+// holds bios followed by erased space.
+func makeBIOSFlashImage(bios []byte) []byte {
+	biosSize := Align(uint64(len(bios))+1, RegionBlockSize)
+	buf := append(makeFlashImage()[:FlashDescriptorLength], bytes.Repeat([]byte{0xff}, int(biosSize))...)
+	binary.LittleEndian.PutUint16(buf[0x46+4*int(RegionTypeBIOS):], uint16(len(buf)/RegionBlockSize-1))
+	copy(buf[FlashDescriptorLength:], bios)
+	return buf
+}
+
+func TestOpenFile(t *testing.T) {
+	image := makeFlashImage()
+	path := filepath.Join(t.TempDir(), "image.rom")
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, closeFn, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("unable to open image: %v", err)
+	}
+	if !bytes.Equal(f.Buf(), image) {
+		t.Errorf("mapped image does not match the file")
+	}
+	if len(f.Regions) != 1 || f.Regions[0].Value.(Region).Type() != RegionTypeBIOS {
+		t.Errorf("expected a single BIOS region, got %v", f.Regions)
+	}
+
+	edit, err := f.CopyForEdit()
+	if err != nil {
+		t.Fatalf("unable to copy image: %v", err)
+	}
+	edit.Buf()[FlashDescriptorLength] = 0
+	if f.Buf()[FlashDescriptorLength] != 0xff {
+		t.Errorf("editing the copy modified the mapped image")
+	}
+
+	if err := closeFn(); err != nil {
+		t.Errorf("unable to close image: %v", err)
+	}
+	if err := closeFn(); err != nil {
+		t.Errorf("closing twice failed: %v", err)
+	}
+	if edit.Buf()[FlashDescriptorLength] != 0 {
+		t.Errorf("copy was changed by closing the mapped image")
+	}
+}
+
+func TestOpenFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.rom")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	short := filepath.Join(dir, "short.rom")
+	if err := os.WriteFile(short, make([]byte, 0x100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.rom"), empty, short} {
+		if _, _, err := OpenFile(path); err == nil {
+			t.Errorf("expected an error opening %q", path)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package uefi
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mapFile(path string) ([]byte, func() error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The mapping stays valid after the file is closed.
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fi.Size()
+	if size <= 0 {
+		return nil, nil, ErrTooShort
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("file is too big: %d bytes", size)
+	}
+
+	buf, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, func() error {
+		if buf == nil {
+			return nil
+		}
+		err := syscall.Munmap(buf)
+		buf = nil
+		return err
+	}, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+// parentLink records the node holding a Firmware in the tree. It is embedded
+// into all the Firmware implementations. The link is informational only, it
+// is not serialized and nothing relies on it to assemble an image.
+type parentLink struct {
+	parent Firmware
+}
+
+// Parent returns the node holding this one, or nil for the root of the tree.
+func (p *parentLink) Parent() Firmware {
+	return p.parent
+}
+
+func (p *parentLink) setParent(f Firmware) {
+	p.parent = f
+}
+
+type parentTracker interface {
+	setParent(f Firmware)
+}
+
+// parentVisitor sets the parent of the nodes it visits. If recursive is set,
+// the children of the visited nodes are linked as well.
+type parentVisitor struct {
+	parent    Firmware
+	recursive bool
+}
+
+func (v *parentVisitor) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *parentVisitor) Visit(f Firmware) error {
+	if p, ok := f.(parentTracker); ok {
+		p.setParent(v.parent)
+	}
+	if !v.recursive {
+		return nil
+	}
+	return f.ApplyChildren(&parentVisitor{parent: f, recursive: true})
+}
+
+// linkChildren sets the parent of the direct children of f. The parsers call
+// it once the children of a node are known.
+func linkChildren(f Firmware) {
+	_ = f.ApplyChildren(&parentVisitor{parent: f})
+}
+
+// LinkParents sets the parent links of all the nodes below f. The parsers
+// set the links, this is only needed for trees which were built or modified
+// by other means, e.g. by inserting new files.
+func LinkParents(f Firmware) {
+	_ = f.ApplyChildren(&parentVisitor{parent: f, recursive: true})
+}
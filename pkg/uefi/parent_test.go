@@ -0,0 +1,100 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"os"
+	"testing"
+)
+
+// parentChecker checks that the children of every node link back to it and
+// counts the kinds of the nodes it finds.
+type parentChecker struct {
+	t     *testing.T
+	kinds map[FirmwareKind]int
+}
+
+func (v *parentChecker) Run(f Firmware) error {
+	if p := f.Parent(); p != nil {
+		v.t.Errorf("root %v has parent %v", f.Kind(), p.Kind())
+	}
+	return f.Apply(v)
+}
+
+func (v *parentChecker) Visit(f Firmware) error {
+	v.kinds[f.Kind()]++
+	return f.ApplyChildren(&childChecker{v, f})
+}
+
+type childChecker struct {
+	*parentChecker
+	parent Firmware
+}
+
+func (v *childChecker) Visit(f Firmware) error {
+	if p := f.Parent(); p != v.parent {
+		v.t.Errorf("%v: expected parent %v (%p), got %p", f.Kind(), v.parent.Kind(), v.parent, p)
+	}
+	return v.parentChecker.Visit(f)
+}
+
+func checkParents(t *testing.T, f Firmware) map[FirmwareKind]int {
+	t.Helper()
+	v := &parentChecker{t: t, kinds: map[FirmwareKind]int{}}
+	if err := v.Run(f); err != nil {
+		t.Fatal(err)
+	}
+	return v.kinds
+}
+
+func TestParents(t *testing.T) {
+	image, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFlashImage(makeBIOSFlashImage(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := checkParents(t, f)
+	for _, k := range []FirmwareKind{
+		FirmwareKindFlashDescriptor,
+		FirmwareKindBIOSRegion,
+		FirmwareKindFirmwareVolume,
+		FirmwareKindFile,
+		FirmwareKindSection,
+	} {
+		if kinds[k] == 0 {
+			t.Errorf("no %v found", k)
+		}
+	}
+
+	// The links are restored when unmarshaling.
+	b, err := MarshalFirmware(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := UnmarshalFirmware(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParents(t, u)
+
+	// New nodes are linked by LinkParents.
+	fv, err := f.Regions[0].Value.(*BIOSRegion).FirstFV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &File{}
+	fv.Files = append(fv.Files, file)
+	if file.Parent() != nil {
+		t.Fatalf("file is linked before LinkParents")
+	}
+	LinkParents(f)
+	if file.Parent() != fv {
+		t.Errorf("file is not linked to its firmware volume")
+	}
+}
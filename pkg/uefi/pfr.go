@@ -0,0 +1,127 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Tags of the Intel Platform Firmware Resilience (PFR) structures.
+const (
+	PFRBlock0Tag = 0xB6EAFD19
+	PFRBlock1Tag = 0xF27F28D7
+	PFRPFMTag    = 0x02B3CE1D
+)
+
+// PFRSignatureBlockSize is the size of the signature block (block 0 and
+// block 1) preceding the protected content.
+const PFRSignatureBlockSize = 1024
+
+// pfrBlock0Size is the size of block 0, block 1 follows it.
+const pfrBlock0Size = 128
+
+// PFR protected content types
+const (
+	PFRContentCPLDUpdate = 0
+	PFRContentPCHPFM     = 1
+	PFRContentPCHUpdate  = 2
+	PFRContentBMCPFM     = 3
+	PFRContentBMCUpdate  = 4
+)
+
+// PFRBlock0 is the first part of the PFR signature block, it describes the
+// protected content following the signature block.
+type PFRBlock0 struct {
+	Tag           uint32
+	ContentLength uint32
+	ContentType   uint32
+	Reserved0     uint32
+	SHA256        [32]byte
+	SHA384        [48]byte
+	Reserved1     [32]byte
+}
+
+// PFRPFMHeader is the header of a Platform Firmware Manifest.
+type PFRPFMHeader struct {
+	Tag             uint32
+	SVN             uint8
+	BKCVersion      uint8
+	MajorRevision   uint8
+	MinorRevision   uint8
+	Reserved        uint32
+	OEMSpecificData [16]byte
+	Length          uint32
+}
+
+// PFRBlock is a PFR signature block found in the image.
+type PFRBlock struct {
+	// Offset is the offset of the signature block in the image.
+	Offset uint64
+	Block0 PFRBlock0
+	// PFM is set if the protected content is a Platform Firmware Manifest.
+	PFM *PFRPFMHeader `json:",omitempty"`
+}
+
+// ContentTypeString returns the name of the protected content type.
+func (b *PFRBlock) ContentTypeString() string {
+	switch b.Block0.ContentType & 0xff {
+	case PFRContentCPLDUpdate:
+		return "CPLD update"
+	case PFRContentPCHPFM:
+		return "PCH PFM"
+	case PFRContentPCHUpdate:
+		return "PCH update"
+	case PFRContentBMCPFM:
+		return "BMC PFM"
+	case PFRContentBMCUpdate:
+		return "BMC update"
+	}
+	return fmt.Sprintf("unknown (%#x)", b.Block0.ContentType)
+}
+
+// FindPFRBlocks looks up the PFR signature blocks in the image. Candidates
+// are found by the block 0 tag and are reported only if they are followed by
+// the block 1 tag. It returns nil if there are no PFR blocks.
+func FindPFRBlocks(image []byte) []*PFRBlock {
+	var tag [4]byte
+	binary.LittleEndian.PutUint32(tag[:], PFRBlock0Tag)
+
+	var result []*PFRBlock
+	for start := 0; start < len(image); {
+		idx := bytes.Index(image[start:], tag[:])
+		if idx < 0 {
+			break
+		}
+		offset := start + idx
+		start = offset + len(tag)
+		if b := parsePFRBlock(image, uint64(offset)); b != nil {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func parsePFRBlock(image []byte, offset uint64) *PFRBlock {
+	if offset+PFRSignatureBlockSize > uint64(len(image)) {
+		return nil
+	}
+	if binary.LittleEndian.Uint32(image[offset+pfrBlock0Size:]) != PFRBlock1Tag {
+		return nil
+	}
+	b := &PFRBlock{Offset: offset}
+	if err := binary.Read(bytes.NewReader(image[offset:]), binary.LittleEndian, &b.Block0); err != nil {
+		return nil
+	}
+	content := image[offset+PFRSignatureBlockSize:]
+	if len(content) >= 4 && binary.LittleEndian.Uint32(content) == PFRPFMTag {
+		var pfm PFRPFMHeader
+		if err := binary.Read(bytes.NewReader(content), binary.LittleEndian, &pfm); err == nil {
+			b.PFM = &pfm
+		}
+	}
+	return b
+}
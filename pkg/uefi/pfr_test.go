@@ -0,0 +1,62 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makePFRBlock returns a signature block followed by a PFM header.
+func makePFRBlock(contentType uint32, svn uint8) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, PFRBlock0{
+		Tag:           PFRBlock0Tag,
+		ContentLength: 0x400,
+		ContentType:   contentType,
+	})
+	binary.Write(&buf, binary.LittleEndian, uint32(PFRBlock1Tag))
+	buf.Write(make([]byte, PFRSignatureBlockSize-buf.Len()))
+	binary.Write(&buf, binary.LittleEndian, PFRPFMHeader{
+		Tag:           PFRPFMTag,
+		SVN:           svn,
+		MajorRevision: 1,
+		Length:        0x400,
+	})
+	return buf.Bytes()
+}
+
+func TestFindPFRBlocks(t *testing.T) {
+	image := bytes.Repeat([]byte{0xff}, 0x10000)
+	copy(image[0x1000:], makePFRBlock(PFRContentPCHPFM, 3))
+	// A block 0 tag without block 1 is ignored.
+	binary.LittleEndian.PutUint32(image[0x4000:], PFRBlock0Tag)
+
+	blocks := FindPFRBlocks(image)
+	if len(blocks) != 1 {
+		t.Fatalf("got %d PFR blocks, want 1", len(blocks))
+	}
+	b := blocks[0]
+	if b.Offset != 0x1000 || b.Block0.ContentLength != 0x400 {
+		t.Errorf("unexpected PFR block: %+v", b)
+	}
+	if s := b.ContentTypeString(); s != "PCH PFM" {
+		t.Errorf("got content type %q, want %q", s, "PCH PFM")
+	}
+	if b.PFM == nil || b.PFM.SVN != 3 || b.PFM.MajorRevision != 1 || b.PFM.Length != 0x400 {
+		t.Errorf("unexpected PFM header: %+v", b.PFM)
+	}
+}
+
+func TestFindPFRBlocksAbsent(t *testing.T) {
+	if blocks := FindPFRBlocks(bytes.Repeat([]byte{0xff}, 0x1000)); blocks != nil {
+		t.Errorf("got %+v, want nil", blocks)
+	}
+	// Truncated signature block
+	if blocks := FindPFRBlocks(makePFRBlock(PFRContentPCHPFM, 0)[:0x100]); blocks != nil {
+		t.Errorf("got %+v, want nil", blocks)
+	}
+}
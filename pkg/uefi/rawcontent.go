@@ -0,0 +1,87 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Kinds of content recognized in raw sections.
+const (
+	RawContentBitmap = "BMP"
+	RawContentPNG    = "PNG"
+	RawContentJPEG   = "JPEG"
+	RawContentSMBIOS = "SMBIOS"
+	// ACPI tables are reported as RawContentACPIPrefix followed by the
+	// table signature, e.g. "ACPI SSDT".
+	RawContentACPIPrefix = "ACPI "
+)
+
+const (
+	acpiTableHeaderSize = 36
+	acpiFACSMinSize     = 64
+)
+
+// acpiSignatures are the signatures of the ACPI tables recognized in raw
+// sections. Only well-known signatures are accepted, four arbitrary
+// characters at the start of a blob would give too many false positives.
+var acpiSignatures = map[string]bool{
+	"APIC": true, "BERT": true, "BGRT": true, "CEDT": true, "CPEP": true,
+	"CRAT": true, "CSRT": true, "DBG2": true, "DBGP": true, "DMAR": true,
+	"DSDT": true, "ECDT": true, "EINJ": true, "ERST": true, "FACP": true,
+	"FACS": true, "FPDT": true, "GTDT": true, "HEST": true, "HMAT": true,
+	"HPET": true, "IORT": true, "IVRS": true, "LPIT": true, "MADT": true,
+	"MCFG": true, "MSCT": true, "NFIT": true, "PCCT": true, "PPTT": true,
+	"PSDT": true, "RASF": true, "RSDT": true, "SBST": true, "SDEV": true,
+	"SLIC": true, "SLIT": true, "SPCR": true, "SPMI": true, "SRAT": true,
+	"SSDT": true, "TCPA": true, "TPM2": true, "UEFI": true, "WAET": true,
+	"WDAT": true, "WDRT": true, "WPBT": true, "WSMT": true, "XSDT": true,
+}
+
+var (
+	pngSignature  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	jpegSignature = []byte{0xff, 0xd8, 0xff}
+)
+
+// ACPITableSignature returns the signature of the ACPI table data starts
+// with, or an empty string if data does not start with a well-known ACPI
+// table whose length fits into data.
+func ACPITableSignature(data []byte) string {
+	if len(data) < 8 {
+		return ""
+	}
+	signature := string(data[:4])
+	length := binary.LittleEndian.Uint32(data[4:])
+	minLength := uint32(acpiTableHeaderSize)
+	if signature == "FACS" {
+		minLength = acpiFACSMinSize
+	}
+	if acpiSignatures[signature] && length >= minLength && uint64(length) <= uint64(len(data)) {
+		return signature
+	}
+	return ""
+}
+
+// SniffRawContent makes a best-effort guess about what the data of a raw
+// section holds. It returns an empty string if the content is not
+// recognized.
+func SniffRawContent(data []byte) string {
+	if signature := ACPITableSignature(data); signature != "" {
+		return RawContentACPIPrefix + signature
+	}
+	switch {
+	case len(data) >= 14 && data[0] == 'B' && data[1] == 'M' &&
+		uint64(binary.LittleEndian.Uint32(data[2:])) <= uint64(len(data)):
+		return RawContentBitmap
+	case bytes.HasPrefix(data, pngSignature):
+		return RawContentPNG
+	case bytes.HasPrefix(data, jpegSignature):
+		return RawContentJPEG
+	case IsSMBIOSTables(data):
+		return RawContentSMBIOS
+	}
+	return ""
+}
@@ -6,6 +6,9 @@ package uefi
 
 // RawRegion implements Region for a raw chunk of bytes in the firmware image.
 type RawRegion struct {
+	dirtyFlag
+	parentLink
+
 	// holds the raw data
 	buf []byte
 	// Metadata for extraction and recovery
@@ -56,6 +59,11 @@ func (rr *RawRegion) Apply(v Visitor) error {
 	return v.Visit(rr)
 }
 
+// Kind returns FirmwareKindRawRegion.
+func (rr *RawRegion) Kind() FirmwareKind {
+	return FirmwareKindRawRegion
+}
+
 // ApplyChildren calls the visitor on each child node of RawRegion.
 func (rr *RawRegion) ApplyChildren(v Visitor) error {
 	return nil
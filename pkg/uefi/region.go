@@ -104,7 +104,7 @@ var regionConstructors = map[FlashRegionType]func(buf []byte, r *FlashRegion, rt
 	RegionTypeDevExp1:   NewRawRegion,
 	RegionTypeBIOS2:     NewRawRegion,
 	RegionTypeMicrocode: NewRawRegion,
-	RegionTypeEC:        NewRawRegion,
+	RegionTypeEC:        NewECRegion,
 	RegionTypeDevExp2:   NewRawRegion,
 	RegionTypeIE:        NewRawRegion,
 	RegionTypeTGBE1:     NewRawRegion,
@@ -6,6 +6,7 @@ package uefi
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -110,13 +111,22 @@ type SectionGUIDDefinedHeader struct {
 type SectionGUIDDefined struct {
 	SectionGUIDDefinedHeader
 
+	// HeaderData holds the bytes between the GUID defined header and
+	// DataOffset, for instance authentication information. They are written
+	// back when the section header is regenerated.
+	HeaderData []byte `json:",omitempty"`
+
 	// Metadata
 	Compression string
+
+	// encapDigest is the SHA-256 digest of the decoded data of a parsed
+	// section, nil if the data could not be decoded.
+	encapDigest []byte
 }
 
 // GetBinHeaderLen returns the length of the binary typ specific header
 func (s *SectionGUIDDefined) GetBinHeaderLen() uint32 {
-	return uint32(unsafe.Sizeof(s.SectionGUIDDefinedHeader))
+	return uint32(unsafe.Sizeof(s.SectionGUIDDefinedHeader)) + uint32(len(s.HeaderData))
 }
 
 // TypeHeader interface forces type specific headers to report their length
@@ -187,6 +197,9 @@ type DepExOp struct {
 
 // Section represents a Firmware File Section
 type Section struct {
+	dirtyFlag
+	parentLink
+
 	Header SectionExtHeader
 	Type   string
 	buf    []byte
@@ -209,8 +222,21 @@ type Section struct {
 	// For EFI_SECTION_DXE_DEPEX, EFI_SECTION_PEI_DEPEX, and EFI_SECTION_MM_DEPEX
 	DepEx []DepExOp `json:",omitempty"`
 
-	// Encapsulated firmware
+	// For EFI_SECTION_RAW, a best-effort guess of the content, such as
+	// "ACPI SSDT" or "BMP". It is empty if the content is not recognized.
+	RawContent string `json:",omitempty"`
+
+	// For EFI_SECTION_COMPATIBILITY16, the table describing the CSM entry
+	// points. It is nil if the legacy BIOS image holds no such table.
+	Compatibility16 *Compatibility16Table `json:",omitempty"`
+
+	// Encapsulated firmware. It is empty for a GUID defined section parsed by
+	// ParseHeadersOnly until ParseEncapsulated is called.
 	Encapsulated []*TypedFirmware `json:",omitempty"`
+
+	// encapPending is set if the encapsulated sections were not parsed
+	// because of ParseHeadersOnly, see ParseEncapsulated.
+	encapPending bool
 }
 
 // String returns the String value of the section if it makes sense,
@@ -221,6 +247,8 @@ func (s *Section) String() string {
 		return s.Name
 	case SectionTypeVersion:
 		return "Version " + s.Version
+	case SectionTypeRaw:
+		return s.RawContent
 	}
 	return ""
 }
@@ -229,6 +257,7 @@ func (s *Section) String() string {
 func (s *Section) SetType(t SectionType) {
 	s.Header.Type = t
 	s.Type = t.String()
+	MarkDirty(s)
 }
 
 // Buf returns the buffer.
@@ -248,6 +277,11 @@ func (s *Section) Apply(v Visitor) error {
 	return v.Visit(s)
 }
 
+// Kind returns FirmwareKindSection.
+func (s *Section) Kind() FirmwareKind {
+	return FirmwareKindSection
+}
+
 // ApplyChildren calls the visitor on each child node of Section.
 func (s *Section) ApplyChildren(v Visitor) error {
 	for _, f := range s.Encapsulated {
@@ -294,10 +328,29 @@ func CreateSection(t SectionType, buf []byte, encap []Firmware, g *guid.GUID) (*
 		guidDefHeader.Attributes = uint16(GUIDEDSectionProcessingRequired)
 		s.TypeSpecific = &TypeSpecificHeader{SectionTypeGUIDDefined, guidDefHeader}
 	}
+	linkChildren(s)
 
 	return s, nil
 }
 
+// HeaderLen returns the length of the common section header depending on the
+// section size. The type specific header is not included.
+func (s *Section) HeaderLen() uint64 {
+	if s.Header.Size == [3]uint8{0xFF, 0xFF, 0xFF} {
+		return SectionExtMinLength
+	}
+	return SectionMinLength
+}
+
+// Data returns the part of the buffer following the common section header,
+// or nil if the buffer is shorter than the header.
+func (s *Section) Data() []byte {
+	if uint64(len(s.buf)) < s.HeaderLen() {
+		return nil
+	}
+	return s.buf[s.HeaderLen():]
+}
+
 // GenSecHeader generates a full binary header for the section data.
 // It assumes that the passed in section struct already contains section data in the buffer,
 // the section type in the Type field, and the type specific header in the TypeSpecific field.
@@ -320,11 +373,14 @@ func (s *Section) GenSecHeader() error {
 	if s.Header.Type == SectionTypeGUIDDefined {
 		gd := s.TypeSpecific.Header.(*SectionGUIDDefined)
 		gd.DataOffset = uint16(headerLen)
+		// The buffer no longer holds the data the digest was computed from.
+		gd.encapDigest = nil
 		// append type specific header in front of data
 		tsh := new(bytes.Buffer)
 		if err = binary.Write(tsh, binary.LittleEndian, &gd.SectionGUIDDefinedHeader); err != nil {
 			return err
 		}
+		tsh.Write(gd.HeaderData)
 		s.buf = append(tsh.Bytes(), s.buf...)
 	}
 
@@ -343,6 +399,36 @@ func (s *Section) GenSecHeader() error {
 	return nil
 }
 
+// GUIDDefinedUnchanged returns true if s is a GUID defined section whose
+// buffer still holds the type specific header and header data it was parsed
+// with, and whose decoded data is equal to data. Such a section can keep its
+// buffer as is, re-encoding the data is not guaranteed to give back the
+// original bytes.
+func (s *Section) GUIDDefinedUnchanged(data []byte) bool {
+	if s.Header.Type != SectionTypeGUIDDefined || s.TypeSpecific == nil {
+		return false
+	}
+	gd, ok := s.TypeSpecific.Header.(*SectionGUIDDefined)
+	if !ok || gd.encapDigest == nil {
+		return false
+	}
+	headerLen := uint64(unsafe.Sizeof(SectionHeader{}))
+	if len(s.buf) >= SectionMinLength && bytes.Equal(s.buf[:3], []byte{0xFF, 0xFF, 0xFF}) {
+		headerLen = uint64(unsafe.Sizeof(SectionExtHeader{}))
+	}
+	ts := new(bytes.Buffer)
+	if err := binary.Write(ts, binary.LittleEndian, &gd.SectionGUIDDefinedHeader); err != nil {
+		return false
+	}
+	ts.Write(gd.HeaderData)
+	if uint64(len(s.buf)) < headerLen+uint64(ts.Len()) || uint64(gd.DataOffset) != headerLen+uint64(ts.Len()) ||
+		!bytes.Equal(s.buf[headerLen:headerLen+uint64(ts.Len())], ts.Bytes()) {
+		return false
+	}
+	digest := sha256.Sum256(data)
+	return bytes.Equal(digest[:], gd.encapDigest)
+}
+
 // ErrOversizeHdr is the error returned by NewSection when the header is oversize.
 type ErrOversizeHdr struct {
 	hdrsiz uintptr
@@ -356,6 +442,10 @@ func (e *ErrOversizeHdr) Error() string {
 // NewSection parses a sequence of bytes and returns a Section
 // object, if a valid one is passed, or an error.
 func NewSection(buf []byte, fileOrder int) (*Section, error) {
+	return newSection(buf, fileOrder, defaultParseConfig())
+}
+
+func newSection(buf []byte, fileOrder int, cfg parseConfig) (*Section, error) {
 	s := Section{FileOrder: fileOrder}
 	// Read in standard header.
 	r := bytes.NewReader(buf)
@@ -398,7 +488,7 @@ func NewSection(buf []byte, fileOrder int) (*Section, error) {
 			s.Header.ExtendedSize, buflen)
 	}
 
-	if ReadOnly {
+	if cfg.readOnly {
 		s.buf = buf[:s.Header.ExtendedSize]
 	} else {
 		// Copy out the buffer.
@@ -415,34 +505,24 @@ func NewSection(buf []byte, fileOrder int) (*Section, error) {
 			return nil, err
 		}
 		s.TypeSpecific = &TypeSpecificHeader{Type: SectionTypeGUIDDefined, Header: typeSpec}
+		if tsEnd := uint64(headerSize) + uint64(unsafe.Sizeof(typeSpec.SectionGUIDDefinedHeader)); uint64(typeSpec.DataOffset) > tsEnd &&
+			uint64(typeSpec.DataOffset) <= uint64(len(s.buf)) {
+			typeSpec.HeaderData = append([]byte{}, s.buf[tsEnd:typeSpec.DataOffset]...)
+		}
 
 		// Determine how to interpret the section based on the GUID.
-		var encapBuf []byte
 		if typeSpec.Attributes&uint16(GUIDEDSectionProcessingRequired) != 0 && !DisableDecompression {
-			if compressor := compression.CompressorFromGUID(&typeSpec.GUID); compressor != nil {
-				typeSpec.Compression = compressor.Name()
-				var err error
-				encapBuf, err = compressor.Decode(buf[typeSpec.DataOffset:])
-				if err != nil {
-					log.Errorf("%v", err)
-					typeSpec.Compression = "UNKNOWN"
-					encapBuf = []byte{}
+			if cfg.headersOnly {
+				// Decompressed lazily by ParseEncapsulated.
+				if compressor := compression.CompressorFromGUID(&typeSpec.GUID); compressor != nil {
+					typeSpec.Compression = compressor.Name()
 				}
-			} else {
-				typeSpec.Compression = "UNKNOWN"
+				s.encapPending = true
+				break
 			}
-		}
-
-		for i, offset := 0, uint64(0); offset < uint64(len(encapBuf)); i++ {
-			encapS, err := NewSection(encapBuf[offset:], i)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing encapsulated section #%d at offset %d: %v",
-					i, offset, err)
+			if err := s.parseGUIDDefined(typeSpec, cfg); err != nil {
+				return nil, err
 			}
-			// Align to 4 bytes for now. The PI Spec doesn't say what alignment it should be
-			// but UEFITool aligns to 4 bytes, and this seems to work on everything I have.
-			offset = Align4(offset + uint64(encapS.Header.ExtendedSize))
-			s.Encapsulated = append(s.Encapsulated, MakeTyped(encapS))
 		}
 
 	case SectionTypeUserInterface:
@@ -462,7 +542,7 @@ func NewSection(buf []byte, fileOrder int) (*Section, error) {
 		if len(s.buf) <= int(headerSize) {
 			return nil, &ErrOversizeHdr{hdrsiz: headerSize, bufsiz: len(s.buf)}
 		}
-		fv, err := NewFirmwareVolume(s.buf[headerSize:], 0, true)
+		fv, err := newFirmwareVolume(s.buf[headerSize:], 0, true, cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -476,11 +556,82 @@ func NewSection(buf []byte, fileOrder int) (*Section, error) {
 		if s.DepEx, err = parseDepEx(s.buf[headerSize:]); err != nil {
 			log.Warnf("%v", err)
 		}
+
+	case SectionTypeRaw:
+		if len(s.buf) > int(headerSize) && !cfg.headersOnly {
+			s.RawContent = SniffRawContent(s.buf[headerSize:])
+		}
+
+	case SectionTypeCompatibility16:
+		if len(s.buf) > int(headerSize) && !cfg.headersOnly {
+			s.Compatibility16 = FindCompatibility16Table(s.buf[headerSize:])
+		}
 	}
 
+	linkChildren(&s)
 	return &s, nil
 }
 
+// parseGUIDDefined decompresses the content of a GUID defined section and
+// parses the encapsulated sections.
+func (s *Section) parseGUIDDefined(typeSpec *SectionGUIDDefined, cfg parseConfig) error {
+	if uint64(typeSpec.DataOffset) > uint64(len(s.buf)) {
+		return fmt.Errorf("data offset %#x exceeds the section size %#x", typeSpec.DataOffset, len(s.buf))
+	}
+	var encapBuf []byte
+	if compressor := compression.CompressorFromGUID(&typeSpec.GUID); compressor != nil {
+		typeSpec.Compression = compressor.Name()
+		var err error
+		encapBuf, err = compressor.Decode(s.buf[typeSpec.DataOffset:])
+		if err != nil {
+			log.Errorf("%v", err)
+			typeSpec.Compression = "UNKNOWN"
+			encapBuf = []byte{}
+		} else {
+			digest := sha256.Sum256(encapBuf)
+			typeSpec.encapDigest = digest[:]
+		}
+	} else {
+		typeSpec.Compression = "UNKNOWN"
+	}
+
+	for i, offset := 0, uint64(0); offset < uint64(len(encapBuf)); i++ {
+		encapS, err := newSection(encapBuf[offset:], i, cfg)
+		if err != nil {
+			return fmt.Errorf("error parsing encapsulated section #%d at offset %d: %v",
+				i, offset, err)
+		}
+		// Align to 4 bytes for now. The PI Spec doesn't say what alignment it should be
+		// but UEFITool aligns to 4 bytes, and this seems to work on everything I have.
+		offset = Align4(offset + uint64(encapS.Header.ExtendedSize))
+		s.Encapsulated = append(s.Encapsulated, MakeTyped(encapS))
+	}
+	return nil
+}
+
+// ParseEncapsulated decompresses and parses the encapsulated sections of a
+// GUID defined section parsed by ParseHeadersOnly. It must be called before
+// the encapsulated sections of such a section are accessed, they are not
+// decompressed on access. It does nothing for other sections, whose
+// encapsulated sections are parsed by NewSection.
+//
+// The encapsulated sections are parsed in headers-only mode as well.
+func (s *Section) ParseEncapsulated() error {
+	if !s.encapPending {
+		return nil
+	}
+	typeSpec, ok := s.TypeSpecific.Header.(*SectionGUIDDefined)
+	if !ok {
+		return fmt.Errorf("section of type %v has no GUID defined header", s.Header.Type)
+	}
+	if err := s.parseGUIDDefined(typeSpec, headersOnlyConfig); err != nil {
+		return err
+	}
+	s.encapPending = false
+	linkChildren(s)
+	return nil
+}
+
 func parseDepEx(b []byte) ([]DepExOp, error) {
 	depEx := []DepExOp{}
 	r := bytes.NewBuffer(b)
@@ -5,6 +5,7 @@
 package uefi
 
 import (
+	"encoding/binary"
 	"fmt"
 	"reflect"
 	"testing"
@@ -94,6 +95,34 @@ func TestNewSection(t *testing.T) {
 	}
 }
 
+func TestSectionHeaderLen(t *testing.T) {
+	extSec := append([]byte{0xFF, 0xFF, 0xFF, byte(SectionTypeRaw), 12, 0, 0, 0}, 1, 2, 3, 4)
+	var tests = []struct {
+		name      string
+		buf       []byte
+		headerLen uint64
+		data      []byte
+	}{
+		{"tinySec", tinySec, SectionMinLength, []byte{}},
+		{"smallSec", smallSec, SectionMinLength, smallSec[SectionMinLength:]},
+		{"extSec", extSec, SectionExtMinLength, []byte{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewSection(test.buf, 0)
+			if err != nil {
+				t.Fatalf("Unable to parse section object %v, got %v", test.buf, err.Error())
+			}
+			if s.HeaderLen() != test.headerLen {
+				t.Errorf("Header length mismatch, expected %d, got %d", test.headerLen, s.HeaderLen())
+			}
+			if !reflect.DeepEqual(s.Data(), test.data) {
+				t.Errorf("Data mismatch, expected %v, got %v", test.data, s.Data())
+			}
+		})
+	}
+}
+
 func TestParseDepEx(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -220,3 +249,49 @@ func TestParseDepEx(t *testing.T) {
 		})
 	}
 }
+
+func TestRawContent(t *testing.T) {
+	ssdt := make([]byte, 40)
+	copy(ssdt, "SSDT")
+	binary.LittleEndian.PutUint32(ssdt[4:], uint32(len(ssdt)))
+	// An ACPI table whose length exceeds the data is not recognized.
+	truncatedDSDT := make([]byte, 40)
+	copy(truncatedDSDT, "DSDT")
+	binary.LittleEndian.PutUint32(truncatedDSDT[4:], 0x100)
+	bmp := make([]byte, 64)
+	copy(bmp, "BM")
+	binary.LittleEndian.PutUint32(bmp[2:], uint32(len(bmp)))
+
+	var tests = []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"SSDT", ssdt, "ACPI SSDT"},
+		{"TruncatedDSDT", truncatedDSDT, ""},
+		{"Bitmap", bmp, RawContentBitmap},
+		{"PNG", append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, make([]byte, 8)...), RawContentPNG},
+		{"Zeros", make([]byte, 40), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := CreateSection(SectionTypeRaw, test.data, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.GenSecHeader(); err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := NewSection(s.Buf(), 0)
+			if err != nil {
+				t.Fatalf("unable to parse section: %v", err)
+			}
+			if parsed.RawContent != test.want {
+				t.Errorf("RawContent: got %q, want %q", parsed.RawContent, test.want)
+			}
+			if parsed.String() != test.want {
+				t.Errorf("String: got %q, want %q", parsed.String(), test.want)
+			}
+		})
+	}
+}
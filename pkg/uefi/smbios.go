@@ -0,0 +1,261 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SMBIOS structure types with named string fields, see the DMTF SMBIOS
+// reference specification (DSP0134).
+const (
+	SMBIOSTypeBIOSInformation      = 0
+	SMBIOSTypeSystemInformation    = 1
+	SMBIOSTypeBaseboardInformation = 2
+	SMBIOSTypeSystemEnclosure      = 3
+	SMBIOSTypeEndOfTable           = 127
+)
+
+const smbiosHeaderSize = 4
+
+// smbiosStringFields maps the names of the string fields of the well-known
+// structure types to their offset in the structure. The byte at the offset
+// is the 1-based index of the string in the string-set, 0 if there is none.
+var smbiosStringFields = map[uint8]map[string]int{
+	SMBIOSTypeBIOSInformation: {
+		"Vendor":          0x04,
+		"BIOSVersion":     0x05,
+		"BIOSReleaseDate": 0x08,
+	},
+	SMBIOSTypeSystemInformation: {
+		"Manufacturer": 0x04,
+		"ProductName":  0x05,
+		"Version":      0x06,
+		"SerialNumber": 0x07,
+		"SKUNumber":    0x19,
+		"Family":       0x1A,
+	},
+	SMBIOSTypeBaseboardInformation: {
+		"Manufacturer":      0x04,
+		"Product":           0x05,
+		"Version":           0x06,
+		"SerialNumber":      0x07,
+		"AssetTag":          0x08,
+		"LocationInChassis": 0x0A,
+	},
+	SMBIOSTypeSystemEnclosure: {
+		"Manufacturer": 0x04,
+		"Version":      0x06,
+		"SerialNumber": 0x07,
+		"AssetTag":     0x08,
+	},
+}
+
+// SMBIOSStructure is an SMBIOS structure: a header, a formatted area and a
+// string-set.
+type SMBIOSStructure struct {
+	Type   uint8
+	Handle uint16
+	// Formatted is the formatted area following the 4 bytes header.
+	Formatted []byte
+	Strings   []string
+}
+
+// ParseSMBIOSTables parses the SMBIOS structures data starts with, up to and
+// including the end-of-table structure. It fails if a structure is
+// malformed or if there is no end-of-table structure.
+func ParseSMBIOSTables(data []byte) ([]*SMBIOSStructure, error) {
+	var structures []*SMBIOSStructure
+	for offset := 0; ; {
+		s, length, err := parseSMBIOSStructure(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMBIOS structure at offset %#x: %v", offset, err)
+		}
+		structures = append(structures, s)
+		offset += length
+		if s.Type == SMBIOSTypeEndOfTable {
+			return structures, nil
+		}
+	}
+}
+
+// parseSMBIOSStructure parses the structure data starts with and returns it
+// with its total length, including the string-set.
+func parseSMBIOSStructure(data []byte) (*SMBIOSStructure, int, error) {
+	if len(data) < smbiosHeaderSize {
+		return nil, 0, errors.New("no end-of-table structure")
+	}
+	length := int(data[1])
+	if length < smbiosHeaderSize {
+		return nil, 0, fmt.Errorf("length %d is shorter than the header", length)
+	}
+	if length+2 > len(data) {
+		return nil, 0, fmt.Errorf("length %d exceeds the data", length)
+	}
+	s := &SMBIOSStructure{
+		Type:      data[0],
+		Handle:    binary.LittleEndian.Uint16(data[2:]),
+		Formatted: append([]byte{}, data[smbiosHeaderSize:length]...),
+	}
+
+	// The string-set ends with two NUL bytes, which are the only content
+	// of an empty string-set.
+	offset := length
+	if data[offset] == 0 && data[offset+1] == 0 {
+		return s, offset + 2, nil
+	}
+	for {
+		end := bytes.IndexByte(data[offset:], 0)
+		if end < 0 {
+			return nil, 0, errors.New("unterminated string-set")
+		}
+		if end == 0 {
+			return s, offset + 1, nil
+		}
+		s.Strings = append(s.Strings, string(data[offset:offset+end]))
+		offset += end + 1
+	}
+}
+
+// IsSMBIOSTables returns true if data holds SMBIOS structures up to an
+// end-of-table structure, followed only by erased bytes. The string fields of
+// the well-known structures have to refer to existing strings.
+func IsSMBIOSTables(data []byte) bool {
+	structures, err := ParseSMBIOSTables(data)
+	if err != nil || len(structures) < 2 {
+		return false
+	}
+	rest := data[len(EncodeSMBIOSTables(structures)):]
+	if !IsErased(rest, 0x00) && !IsErased(rest, 0xFF) {
+		return false
+	}
+	for _, s := range structures {
+		for _, offset := range smbiosStringFields[s.Type] {
+			if idx, ok := s.stringIndex(offset); ok && int(idx) > len(s.Strings) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// EncodeSMBIOSTables returns the binary representation of the structures.
+func EncodeSMBIOSTables(structures []*SMBIOSStructure) []byte {
+	var buf bytes.Buffer
+	for _, s := range structures {
+		buf.WriteByte(s.Type)
+		buf.WriteByte(uint8(smbiosHeaderSize + len(s.Formatted)))
+		binary.Write(&buf, binary.LittleEndian, s.Handle)
+		buf.Write(s.Formatted)
+		if len(s.Strings) == 0 {
+			buf.WriteByte(0)
+		}
+		for _, str := range s.Strings {
+			buf.WriteString(str)
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// stringIndex returns the string index stored at offset of the structure,
+// ok is false if the formatted area is too short to hold it.
+func (s *SMBIOSStructure) stringIndex(offset int) (idx uint8, ok bool) {
+	if offset < smbiosHeaderSize || offset-smbiosHeaderSize >= len(s.Formatted) {
+		return 0, false
+	}
+	return s.Formatted[offset-smbiosHeaderSize], true
+}
+
+// Fields returns the named string fields of the structure which are set.
+// Only the string fields of the structure types listed in
+// smbiosStringFields are known.
+func (s *SMBIOSStructure) Fields() map[string]string {
+	fields := map[string]string{}
+	for name, offset := range smbiosStringFields[s.Type] {
+		if idx, ok := s.stringIndex(offset); ok && idx != 0 && int(idx) <= len(s.Strings) {
+			fields[name] = s.Strings[idx-1]
+		}
+	}
+	return fields
+}
+
+// SetField sets the named string field of the structure. The new string is
+// appended to the string-set if the field had no string or if its string is
+// shared with another field. An empty value clears the field.
+func (s *SMBIOSStructure) SetField(name, value string) error {
+	offset, ok := smbiosStringFields[s.Type][name]
+	if !ok {
+		return fmt.Errorf("unknown string field %q of SMBIOS structure type %d", name, s.Type)
+	}
+	idx, ok := s.stringIndex(offset)
+	if !ok {
+		return fmt.Errorf("SMBIOS structure type %d of length %d has no field %q",
+			s.Type, smbiosHeaderSize+len(s.Formatted), name)
+	}
+	if bytes.IndexByte([]byte(value), 0) >= 0 {
+		return fmt.Errorf("SMBIOS string %q contains a NUL byte", value)
+	}
+	if value == "" {
+		s.Formatted[offset-smbiosHeaderSize] = 0
+		return nil
+	}
+
+	shared := false
+	for other, otherOffset := range smbiosStringFields[s.Type] {
+		if otherIdx, ok := s.stringIndex(otherOffset); ok && other != name && otherIdx == idx {
+			shared = true
+		}
+	}
+	if idx == 0 || int(idx) > len(s.Strings) || shared {
+		if len(s.Strings) >= 0xFF {
+			return fmt.Errorf("SMBIOS structure type %d has too many strings", s.Type)
+		}
+		s.Strings = append(s.Strings, value)
+		s.Formatted[offset-smbiosHeaderSize] = uint8(len(s.Strings))
+		return nil
+	}
+	s.Strings[idx-1] = value
+	return nil
+}
+
+// SMBIOSFieldNames returns the names of the known string fields of the
+// structure type, sorted.
+func SMBIOSFieldNames(t uint8) []string {
+	var names []string
+	for name := range smbiosStringFields[t] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SMBIOSTables parses the SMBIOS structures held by a raw section.
+func (s *Section) SMBIOSTables() ([]*SMBIOSStructure, error) {
+	if s.Header.Type != SectionTypeRaw {
+		return nil, fmt.Errorf("section type %v cannot hold SMBIOS tables", s.Header.Type)
+	}
+	if uint64(len(s.buf)) < s.HeaderLen() {
+		return nil, fmt.Errorf("section is too short: %d bytes", len(s.buf))
+	}
+	return ParseSMBIOSTables(s.Data())
+}
+
+// SetSMBIOSTables replaces the content of a raw section with the structures
+// and regenerates the section header, so the size of the section follows the
+// edited strings. The file holding the section is resized on assembly.
+func (s *Section) SetSMBIOSTables(structures []*SMBIOSStructure) error {
+	if s.Header.Type != SectionTypeRaw {
+		return fmt.Errorf("section type %v cannot hold SMBIOS tables", s.Header.Type)
+	}
+	s.SetBuf(EncodeSMBIOSTables(structures))
+	MarkDirty(s)
+	return s.GenSecHeader()
+}
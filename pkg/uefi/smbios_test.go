@@ -0,0 +1,167 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// smbiosTables returns a minimal SMBIOS table: BIOS information, system
+// information without family and the end-of-table structure.
+func smbiosTables() []byte {
+	var buf []byte
+	// Type 0, length 0x12, handle 0.
+	buf = append(buf, 0, 0x12, 0, 0)
+	buf = append(buf, 1, 2, 0x00, 0xE0, 3, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0)
+	buf = append(buf, "LinuxBoot\x00v1.0\x0001/02/2023\x00\x00"...)
+	// Type 1, length 0x1B, handle 1.
+	buf = append(buf, 1, 0x1B, 1, 0)
+	buf = append(buf, 1, 2, 3, 4)
+	buf = append(buf, make([]byte, 16)...) // UUID
+	buf = append(buf, 6, 3, 0)             // wake-up type, SKU number, family
+	buf = append(buf, "ACME\x00Board\x00v1.0\x00S1234\x00\x00"...)
+	// Type 127, length 4, handle 2.
+	buf = append(buf, 127, 4, 2, 0, 0, 0)
+	return buf
+}
+
+func TestParseSMBIOSTables(t *testing.T) {
+	data := smbiosTables()
+	structures, err := ParseSMBIOSTables(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(structures) != 3 {
+		t.Fatalf("got %d structures, want 3", len(structures))
+	}
+	for i, want := range []uint8{0, 1, 127} {
+		if structures[i].Type != want || structures[i].Handle != uint16(i) {
+			t.Errorf("structure %d: got type %d handle %d, want type %d handle %d",
+				i, structures[i].Type, structures[i].Handle, want, i)
+		}
+	}
+
+	wantBIOS := map[string]string{"Vendor": "LinuxBoot", "BIOSVersion": "v1.0", "BIOSReleaseDate": "01/02/2023"}
+	if got := structures[0].Fields(); !reflect.DeepEqual(got, wantBIOS) {
+		t.Errorf("BIOS fields: got %v, want %v", got, wantBIOS)
+	}
+	// The SKU number shares the version string.
+	wantSystem := map[string]string{"Manufacturer": "ACME", "ProductName": "Board", "Version": "v1.0", "SerialNumber": "S1234", "SKUNumber": "v1.0"}
+	if got := structures[1].Fields(); !reflect.DeepEqual(got, wantSystem) {
+		t.Errorf("system fields: got %v, want %v", got, wantSystem)
+	}
+
+	if got := EncodeSMBIOSTables(structures); !bytes.Equal(got, data) {
+		t.Errorf("encoding does not round trip:\ngot  %q\nwant %q", got, data)
+	}
+	if !IsSMBIOSTables(append(data, 0xFF, 0xFF)) {
+		t.Errorf("SMBIOS tables followed by erased bytes are not detected")
+	}
+	if IsSMBIOSTables(append(data, 'x')) {
+		t.Errorf("SMBIOS tables followed by data are detected")
+	}
+}
+
+func TestParseSMBIOSTablesInvalid(t *testing.T) {
+	data := smbiosTables()
+	var tests = []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"NoEndOfTable", data[:len(data)-6]},
+		{"ShortLength", append([]byte{0, 3, 0, 0}, data...)},
+		{"UnterminatedStrings", data[:0x12+4]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseSMBIOSTables(test.data); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSMBIOSSetField(t *testing.T) {
+	structures, err := ParseSMBIOSTables(smbiosTables())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	system := structures[1]
+
+	if err := system.SetField("ProductName", "A much longer product name"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The version string is shared with the SKU number, so it gets a new
+	// string instead of changing both.
+	if err := system.SetField("Version", "v2.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := system.SetField("Family", "Servers"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := system.SetField("Vendor", "x"); err == nil {
+		t.Errorf("expected an error for a field of another type")
+	}
+	if err := system.SetField("SerialNumber", "a\x00b"); err == nil {
+		t.Errorf("expected an error for a string with a NUL byte")
+	}
+
+	reparsed, err := ParseSMBIOSTables(EncodeSMBIOSTables(structures))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"Manufacturer": "ACME",
+		"ProductName":  "A much longer product name",
+		"Version":      "v2.0",
+		"SerialNumber": "S1234",
+		"SKUNumber":    "v1.0",
+		"Family":       "Servers",
+	}
+	if got := reparsed[1].Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSectionSMBIOSTables(t *testing.T) {
+	data := smbiosTables()
+	buf := append([]byte{byte(len(data) + 4), 0, 0, byte(SectionTypeRaw)}, data...)
+	s, err := NewSection(buf, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.RawContent != RawContentSMBIOS {
+		t.Fatalf("got raw content %q, want %q", s.RawContent, RawContentSMBIOS)
+	}
+
+	structures, err := s.SMBIOSTables()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := structures[0].SetField("BIOSVersion", "v1.1-custom"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.SetSMBIOSTables(structures); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reparsed, err := NewSection(s.Buf(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := uint32(len(data) + 4 + len("-custom")); reparsed.Header.ExtendedSize != want {
+		t.Errorf("got section size %d, want %d", reparsed.Header.ExtendedSize, want)
+	}
+	structures, err = reparsed.SMBIOSTables()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := structures[0].Fields()["BIOSVersion"]; got != "v1.1-custom" {
+		t.Errorf("got BIOS version %q, want %q", got, "v1.1-custom")
+	}
+}
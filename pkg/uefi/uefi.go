@@ -31,6 +31,26 @@ var (
 	DisableDecompression = false
 )
 
+// parseConfig holds the settings of a single parse, they are passed down to
+// the parsers of the nested elements.
+type parseConfig struct {
+	// readOnly makes the elements share their buffers with the parsed
+	// buffer, see ReadOnly.
+	readOnly bool
+	// headersOnly skips the decompression and the interpretation of the
+	// content of the sections, see ParseHeadersOnly.
+	headersOnly bool
+}
+
+// defaultParseConfig returns the settings of the exported parsers, which
+// follow the package variables.
+func defaultParseConfig() parseConfig {
+	return parseConfig{readOnly: ReadOnly}
+}
+
+// headersOnlyConfig is used by ParseHeadersOnly and Section.ParseEncapsulated.
+var headersOnlyConfig = parseConfig{readOnly: true, headersOnly: true}
+
 // ROMAttributes is used to hold global variables that apply across the whole image.
 // We have to do this to avoid passing too many things down each time.
 type ROMAttributes struct {
@@ -84,6 +104,61 @@ type Firmware interface {
 	// Apply a visitor to all the direct children of the Firmware
 	// (excluding the Firmware itself).
 	ApplyChildren(v Visitor) error
+
+	// Kind identifies the concrete type of the Firmware, so callers can
+	// branch on it without type assertions.
+	Kind() FirmwareKind
+
+	// Parent returns the node holding the Firmware, or nil for the root of
+	// the tree. It is set during parsing.
+	Parent() Firmware
+}
+
+// FirmwareKind identifies the concrete type of a Firmware node. The values
+// are stable, new kinds are only ever appended.
+type FirmwareKind uint8
+
+// Firmware kinds, one per Firmware implementation.
+const (
+	FirmwareKindUnknown FirmwareKind = iota
+	FirmwareKindFlashImage
+	FirmwareKindFlashDescriptor
+	FirmwareKindBIOSRegion
+	FirmwareKindBIOSPadding
+	FirmwareKindMERegion
+	FirmwareKindMEFPT
+	FirmwareKindECRegion
+	FirmwareKindRawRegion
+	FirmwareKindFirmwareVolume
+	FirmwareKindFile
+	FirmwareKindSection
+	FirmwareKindNVarStore
+	FirmwareKindNVar
+)
+
+var firmwareKindNames = map[FirmwareKind]string{
+	FirmwareKindFlashImage:      "FlashImage",
+	FirmwareKindFlashDescriptor: "FlashDescriptor",
+	FirmwareKindBIOSRegion:      "BIOSRegion",
+	FirmwareKindBIOSPadding:     "BIOSPadding",
+	FirmwareKindMERegion:        "MERegion",
+	FirmwareKindMEFPT:           "MEFPT",
+	FirmwareKindECRegion:        "ECRegion",
+	FirmwareKindRawRegion:       "RawRegion",
+	FirmwareKindFirmwareVolume:  "FirmwareVolume",
+	FirmwareKindFile:            "File",
+	FirmwareKindSection:         "Section",
+	FirmwareKindNVarStore:       "NVarStore",
+	FirmwareKindNVar:            "NVar",
+}
+
+// String returns the name of the kind, which is also the name of the Go type
+// implementing it.
+func (k FirmwareKind) String() string {
+	if name, ok := firmwareKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
 }
 
 // TypedFirmware includes the Firmware interface's type when exporting it to
@@ -129,6 +204,7 @@ type marshalFirmware struct {
 var firmwareTypes = map[string]func() Firmware{
 	"*uefi.BIOSRegion":      func() Firmware { return &BIOSRegion{} },
 	"*uefi.BIOSPadding":     func() Firmware { return &BIOSPadding{} },
+	"*uefi.ECRegion":        func() Firmware { return &ECRegion{} },
 	"*uefi.File":            func() Firmware { return &File{} },
 	"*uefi.FirmwareVolume":  func() Firmware { return &FirmwareVolume{} },
 	"*uefi.FlashDescriptor": func() Firmware { return &FlashDescriptor{} },
@@ -160,21 +236,45 @@ func UnmarshalFirmware(b []byte) (Firmware, error) {
 		return nil, fmt.Errorf("unknown Firmware type '%s', unable to unmarshal", m.FType)
 	}
 	f := factory()
-	err := json.Unmarshal(m.FirmwareElement, &f)
-	return f, err
+	if err := json.Unmarshal(m.FirmwareElement, &f); err != nil {
+		return f, err
+	}
+	LinkParents(f)
+	return f, nil
 }
 
 // Parse exposes a high-level parser for generic firmware types. It does not
 // implement any parser itself, but it calls known parsers that implement the
 // Firmware interface.
 func Parse(buf []byte) (Firmware, error) {
+	return parse(buf, defaultParseConfig())
+}
+
+func parse(buf []byte, cfg parseConfig) (Firmware, error) {
 	if _, err := FindSignature(buf); err == nil {
 		// Intel rom.
-		return NewFlashImage(buf)
+		return newFlashImage(buf, true, cfg)
 	}
 	// Non intel image such as edk2's OVMF
 	// We don't know how to parse this header, so treat it as a large BIOSRegion
-	return NewBIOSRegion(buf, nil, RegionTypeBIOS)
+	return newBIOSRegion(buf, nil, cfg)
+}
+
+// ParseHeadersOnly parses the firmware like Parse, but only builds the tree
+// of firmware volumes, files and sections from their headers. The content of
+// the sections is not decompressed nor interpreted (e.g. NVAR stores and raw
+// sections are left as is), which makes it much faster to list the content
+// of big images.
+//
+// GUID defined sections which require processing are not decompressed: their
+// Encapsulated field stays empty, and visitors walking the tree do not see the
+// sections inside, until Section.ParseEncapsulated is called on them.
+//
+// The parsed firmware shares its buffers with buf (see ReadOnly), it is meant
+// for listing and must not be modified. The package variables such as
+// ReadOnly are not changed, a full parse may run meanwhile.
+func ParseHeadersOnly(buf []byte) (Firmware, error) {
+	return parse(buf, headersOnlyConfig)
 }
 
 // Checksum8 does a 8 bit checksum of the slice passed in.
@@ -7,6 +7,8 @@ package uefi
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -108,6 +110,41 @@ var (
 	threeBuf  = []byte{3, 3, 3}
 )
 
+func TestFirmwareKind(t *testing.T) {
+	var tests = []struct {
+		f    Firmware
+		kind FirmwareKind
+	}{
+		{&FlashImage{}, FirmwareKindFlashImage},
+		{&FlashDescriptor{}, FirmwareKindFlashDescriptor},
+		{&BIOSRegion{}, FirmwareKindBIOSRegion},
+		{&BIOSPadding{}, FirmwareKindBIOSPadding},
+		{&MERegion{}, FirmwareKindMERegion},
+		{&MEFPT{}, FirmwareKindMEFPT},
+		{&ECRegion{}, FirmwareKindECRegion},
+		{&RawRegion{}, FirmwareKindRawRegion},
+		{&FirmwareVolume{}, FirmwareKindFirmwareVolume},
+		{&File{}, FirmwareKindFile},
+		{&Section{}, FirmwareKindSection},
+		{&NVarStore{}, FirmwareKindNVarStore},
+		{&NVar{}, FirmwareKindNVar},
+	}
+	for _, test := range tests {
+		typeName := reflect.TypeOf(test.f).Elem().Name()
+		t.Run(typeName, func(t *testing.T) {
+			if kind := test.f.Kind(); kind != test.kind {
+				t.Errorf("got kind %v, want %v", kind, test.kind)
+			}
+			if name := test.kind.String(); name != typeName {
+				t.Errorf("got kind name %q, want %q", name, typeName)
+			}
+		})
+	}
+	if name := FirmwareKind(0xff).String(); name != "Unknown" {
+		t.Errorf("got kind name %q for an unknown kind, want \"Unknown\"", name)
+	}
+}
+
 func TestChecksum8(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -228,3 +265,175 @@ func TestAlign8(t *testing.T) {
 		}
 	}
 }
+
+// sectionCounter counts the sections of a firmware and collects the GUID
+// defined sections whose encapsulated sections were not parsed.
+type sectionCounter struct {
+	sections int
+	pending  []*Section
+}
+
+func (v *sectionCounter) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *sectionCounter) Visit(f Firmware) error {
+	if s, ok := f.(*Section); ok {
+		v.sections++
+		if s.encapPending {
+			v.pending = append(v.pending, s)
+		}
+	}
+	return f.ApplyChildren(v)
+}
+
+func TestParseHeadersOnly(t *testing.T) {
+	image, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parse settings are not global, a full parse can run meanwhile.
+	var full Firmware
+	var fullErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		full, fullErr = Parse(image)
+	}()
+	headers, err := ParseHeadersOnly(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if fullErr != nil {
+		t.Fatal(fullErr)
+	}
+	fullCount := &sectionCounter{}
+	if err := fullCount.Run(full); err != nil {
+		t.Fatal(err)
+	}
+	if ReadOnly {
+		t.Errorf("ReadOnly was modified")
+	}
+	headersCount := &sectionCounter{}
+	if err := headersCount.Run(headers); err != nil {
+		t.Fatal(err)
+	}
+	if len(headersCount.pending) == 0 {
+		t.Fatal("no compressed section left to parse")
+	}
+	if len(fullCount.pending) != 0 {
+		t.Errorf("full parse left %d compressed sections", len(fullCount.pending))
+	}
+	if headersCount.sections >= fullCount.sections {
+		t.Errorf("headers-only parse found %d sections, full parse %d", headersCount.sections, fullCount.sections)
+	}
+
+	// Parsing the compressed sections on demand gives the full tree.
+	for len(headersCount.pending) > 0 {
+		for _, s := range headersCount.pending {
+			if len(s.Encapsulated) != 0 {
+				t.Fatal("encapsulated sections parsed before ParseEncapsulated")
+			}
+			if s.TypeSpecific.Header.(*SectionGUIDDefined).Compression == "" {
+				t.Error("compression is not set")
+			}
+			if err := s.ParseEncapsulated(); err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Encapsulated) == 0 {
+				t.Fatal("no encapsulated sections parsed")
+			}
+		}
+		headersCount = &sectionCounter{}
+		if err := headersCount.Run(headers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if headersCount.sections != fullCount.sections {
+		t.Errorf("expected %d sections after parsing the compressed sections, got %d", fullCount.sections, headersCount.sections)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	image, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Parse(image); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("headers_only", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseHeadersOnly(image); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// fvTracker checks that every file links back to the firmware volume it was
+// found in, and records the most deeply nested file.
+type fvTracker struct {
+	t      *testing.T
+	fvs    []*FirmwareVolume
+	nested *File
+	depth  int
+}
+
+func (v *fvTracker) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *fvTracker) Visit(f Firmware) error {
+	switch f := f.(type) {
+	case *FirmwareVolume:
+		v.fvs = append(v.fvs, f)
+		defer func() { v.fvs = v.fvs[:len(v.fvs)-1] }()
+	case *File:
+		if got, want := f.EnclosingFV(), v.fvs[len(v.fvs)-1]; got != want {
+			v.t.Errorf("file %v: enclosing FV is %p, expected %p", f.Header.GUID, got, want)
+		}
+		if len(v.fvs) > v.depth {
+			v.nested, v.depth = f, len(v.fvs)
+		}
+	}
+	return f.ApplyChildren(v)
+}
+
+func TestFileEnclosingFV(t *testing.T) {
+	image, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, ok := f.(*BIOSRegion)
+	if !ok {
+		t.Fatalf("expected a BIOS region, got %T", f)
+	}
+
+	v := &fvTracker{t: t}
+	if err := v.Run(br); err != nil {
+		t.Fatal(err)
+	}
+	if v.depth < 2 {
+		t.Fatalf("no file found in a nested firmware volume")
+	}
+	if got := v.nested.EnclosingRegion(); got != Region(br) {
+		t.Errorf("enclosing region of nested file is %v, expected the BIOS region", got)
+	}
+
+	// Files that are not parsed from a firmware volume have no parents.
+	file := &File{}
+	if file.EnclosingFV() != nil || file.EnclosingRegion() != nil {
+		t.Errorf("unparsed file has parent links")
+	}
+}
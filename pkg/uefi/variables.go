@@ -0,0 +1,80 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// VariableInfo is the effective value of a variable stored in an NVAR store.
+type VariableInfo struct {
+	GUID       guid.GUID
+	Name       string
+	Attributes NVarAttribute
+	Value      []byte
+	// Source describes the store holding the variable: the GUID of the
+	// file holding the store, followed by the names of the variables
+	// holding the nested stores, separated by slashes.
+	Source string
+	// Offset of the entry holding the effective value in its store.
+	Offset uint64
+}
+
+// variablesVisitor collects the variables of all the NVAR stores.
+type variablesVisitor struct {
+	vars []VariableInfo
+}
+
+func (v *variablesVisitor) Run(f Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *variablesVisitor) Visit(f Firmware) error {
+	if file, ok := f.(*File); ok && file.NVarStore != nil {
+		v.collect(file.NVarStore, file.Header.GUID.String())
+		return nil
+	}
+	return f.ApplyChildren(v)
+}
+
+// collect appends the effective variables of the store, see
+// NVarStore.Lookup. Variables whose links are broken have no effective value
+// and are skipped.
+func (v *variablesVisitor) collect(s *NVarStore, source string) {
+	byOffset := s.entriesByOffset()
+	for _, def := range s.definitions() {
+		last, value, err := s.resolve(def, byOffset)
+		if err != nil {
+			continue
+		}
+		v.vars = append(v.vars, VariableInfo{
+			GUID:       def.GUID,
+			Name:       def.Name,
+			Attributes: def.Header.Attributes,
+			Value:      append([]byte{}, value...),
+			Source:     source,
+			Offset:     last.Offset,
+		})
+		if last.NVarStore != nil {
+			v.collect(last.NVarStore, source+"/"+def.Name)
+		}
+	}
+}
+
+// Variables returns the effective variables of all the NVAR stores found in
+// f, in the order of the stores and of the variables in their store. Each
+// variable is listed once per store holding it, with the value of its newest
+// definition. VSS variable stores are not parsed and thus not listed.
+func Variables(f Firmware) []VariableInfo {
+	v := &variablesVisitor{}
+	_ = v.Run(f)
+	return v.vars
+}
+
+// AllVariables returns the effective variables of all the NVAR stores of
+// the image, see Variables.
+func (f *FlashImage) AllVariables() []VariableInfo {
+	return Variables(f)
+}
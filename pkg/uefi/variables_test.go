@@ -0,0 +1,87 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uefi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// makeNVar returns an NVAR entry with an ASCII name and an inline GUID, or a
+// data-only entry if name is empty. next is the offset of the next entry of
+// the link chain relative to this one, 0 if there is none.
+func makeNVar(g guid.GUID, name string, data []byte, next uint64) []byte {
+	attr := NVarEntryValid
+	var body []byte
+	if name == "" {
+		attr |= NVarEntryDataOnly
+	} else {
+		attr |= NVarEntryGUID | NVarEntryASCIIName
+		body = append(append(g[:], name...), 0)
+	}
+	body = append(body, data...)
+	nextField := [3]uint8{0xFF, 0xFF, 0xFF}
+	if next != 0 {
+		nextField = Write3Size(next)
+	}
+	size := 10 + len(body)
+	buf := append([]byte("NVAR"), uint8(size), uint8(size>>8))
+	buf = append(buf, nextField[:]...)
+	buf = append(buf, uint8(attr))
+	return append(buf, body...)
+}
+
+func TestAllVariables(t *testing.T) {
+	Attributes.ErasePolarity = 0xFF
+	g1 := *guid.MustParse("8BE4DF61-93CA-11D2-AA0D-00E098032B8C")
+	g2 := *guid.MustParse("4599D26F-1A11-49B8-B91F-858745CFF824")
+
+	// The first store holds an updated variable, a deleted one and a
+	// variable defined twice.
+	linkOffset := uint64(len(makeNVar(g1, "Boot0000", []byte("old"), 0)))
+	store1 := makeNVar(g1, "Boot0000", []byte("old"), linkOffset)
+	store1 = append(store1, makeNVar(guid.GUID{}, "", []byte("new"), 0)...)
+	deleted := makeNVar(g1, "Deleted", []byte("gone"), 0)
+	deleted[9] &^= uint8(NVarEntryValid)
+	store1 = append(store1, deleted...)
+	store1 = append(store1, makeNVar(g2, "Lang", []byte("fra"), 0)...)
+	newestOffset := uint64(len(store1))
+	store1 = append(store1, makeNVar(g2, "Lang", []byte("eng"), 0)...)
+	store1 = append(store1, bytes.Repeat([]byte{0xFF}, 16)...)
+	store2 := append(makeNVar(g2, "Setup", []byte{1, 2, 3}, 0), bytes.Repeat([]byte{0xFF}, 16)...)
+
+	var files []*File
+	for _, buf := range [][]byte{store1, store2} {
+		s, err := NewNVarStore(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &File{NVarStore: s}
+		f.Header.GUID = *NVAR
+		f.Header.Type = FVFileTypeRaw
+		files = append(files, f)
+	}
+	fv := &FirmwareVolume{Files: files}
+	image := &FlashImage{Regions: []*TypedFirmware{MakeTyped(&BIOSRegion{Elements: []*TypedFirmware{MakeTyped(fv)}})}}
+
+	vars := image.AllVariables()
+	if len(vars) != 3 {
+		t.Fatalf("expected 3 variables, got %+v", vars)
+	}
+	want := []VariableInfo{
+		{GUID: g1, Name: "Boot0000", Value: []byte("new"), Source: NVAR.String(), Offset: linkOffset},
+		{GUID: g2, Name: "Lang", Value: []byte("eng"), Source: NVAR.String(), Offset: newestOffset},
+		{GUID: g2, Name: "Setup", Value: []byte{1, 2, 3}, Source: NVAR.String(), Offset: 0},
+	}
+	for i, w := range want {
+		got := vars[i]
+		if got.GUID != w.GUID || got.Name != w.Name || !bytes.Equal(got.Value, w.Value) ||
+			got.Source != w.Source || got.Offset != w.Offset {
+			t.Errorf("variable %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+}
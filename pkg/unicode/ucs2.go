@@ -20,7 +20,7 @@ func UCS2ToUTF8(input []byte) string {
 		return string(input)
 	}
 	// Remove null terminator if one exists.
-	if output[len(output)-1] == 0 {
+	if len(output) > 0 && output[len(output)-1] == 0 {
 		output = output[:len(output)-1]
 	}
 	return string(output)
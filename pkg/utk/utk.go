@@ -7,6 +7,8 @@ package utk
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/linuxboot/fiano/pkg/uefi"
@@ -18,6 +20,9 @@ func Run(args ...string) error {
 	if len(args) == 0 {
 		return errors.New("at least one argument is required")
 	}
+	if args[0] == "jsondiff" {
+		return jsonDiff(os.Stdout, args[1:])
+	}
 
 	v, err := visitors.ParseCLI(args[1:])
 	if err != nil {
@@ -57,3 +62,30 @@ func Run(args ...string) error {
 	// Execute the instructions from the command line.
 	return visitors.ExecuteCLI(parsedRoot, v)
 }
+
+// jsonDiff prints the differences between two JSON dumps of a firmware tree.
+// It fails if the dumps differ, so it can be used in scripts.
+func jsonDiff(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return errors.New("jsondiff requires two JSON files")
+	}
+	a, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	diffs, err := visitors.DiffJSON(a, b)
+	if err != nil {
+		return err
+	}
+	for _, d := range diffs {
+		fmt.Fprintln(w, d)
+	}
+	if len(diffs) != 0 {
+		return fmt.Errorf("%d differences found", len(diffs))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// Well-known names of the apriori files. The PEI and DXE dispatchers run the
+// modules listed in these files first, in the listed order.
+var (
+	PEIAprioriGUID = *guid.MustParse("1B45CC0A-156A-428A-AF62-49864DA0E6E6")
+	DXEAprioriGUID = *guid.MustParse("FC510EE7-FFDC-11D4-BD41-0080C73C8881")
+)
+
+// AprioriEntry is a single module listed in an apriori file.
+type AprioriEntry struct {
+	GUID guid.GUID
+	// Name comes from the user interface section of the module, it is
+	// empty if the module is not in the image or has no name.
+	Name string `json:",omitempty"`
+}
+
+// AprioriFile is a decoded apriori file.
+type AprioriFile struct {
+	// Phase is either PEI or DXE.
+	Phase string
+	// Entries are in dispatch order.
+	Entries []AprioriEntry
+}
+
+// aprioriFinder is the visitor behind FindApriori.
+type aprioriFinder struct {
+	Files []*AprioriFile
+
+	// JSON is written to this writer.
+	W io.Writer
+
+	names map[guid.GUID]string
+}
+
+// FindApriori locates the PEI and DXE apriori files in f and decodes the
+// list of modules they contain. Module names are resolved using the user
+// interface sections of the image.
+func FindApriori(f uefi.Firmware) ([]*AprioriFile, error) {
+	v := &aprioriFinder{}
+	if err := v.Run(f); err != nil {
+		return nil, err
+	}
+	return v.Files, nil
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *aprioriFinder) Run(f uefi.Firmware) error {
+	v.Files = nil
+	v.names = map[guid.GUID]string{}
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	// Names are resolved once the whole image is walked, modules usually
+	// come after the apriori file.
+	for _, a := range v.Files {
+		for i := range a.Entries {
+			a.Entries[i].Name = v.names[a.Entries[i].GUID]
+		}
+	}
+	if v.W != nil {
+		b, err := json.MarshalIndent(v.Files, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(v.W, string(b))
+	}
+	return nil
+}
+
+// Visit applies the aprioriFinder visitor to any Firmware type.
+func (v *aprioriFinder) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		for _, s := range f.SectionsOfType(uefi.SectionTypeUserInterface, true) {
+			v.names[f.Header.GUID] = s.Name
+		}
+		var phase string
+		switch f.Header.GUID {
+		case PEIAprioriGUID:
+			phase = "PEI"
+		case DXEAprioriGUID:
+			phase = "DXE"
+		default:
+			return f.ApplyChildren(v)
+		}
+		a := &AprioriFile{Phase: phase}
+		for _, s := range f.SectionsOfType(uefi.SectionTypeRaw, true) {
+			entries, err := decodeApriori(s)
+			if err != nil {
+				return fmt.Errorf("%s apriori file: %v", phase, err)
+			}
+			a.Entries = append(a.Entries, entries...)
+		}
+		v.Files = append(v.Files, a)
+		return nil
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+// decodeApriori decodes the array of GUIDs in a raw section.
+func decodeApriori(s *uefi.Section) ([]AprioriEntry, error) {
+	if uint64(len(s.Buf())) < s.HeaderLen() {
+		return nil, fmt.Errorf("section too short: %d bytes", len(s.Buf()))
+	}
+	buf := s.Data()
+	if len(buf)%guid.Size != 0 {
+		return nil, fmt.Errorf("section data size %d is not a multiple of the GUID size", len(buf))
+	}
+	entries := make([]AprioriEntry, 0, len(buf)/guid.Size)
+	for ; len(buf) > 0; buf = buf[guid.Size:] {
+		var e AprioriEntry
+		copy(e.GUID[:], buf)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func init() {
+	RegisterCLI("apriori", "list the modules of the PEI and DXE apriori files in dispatch order", 0, func(args []string) (uefi.Visitor, error) {
+		return &aprioriFinder{
+			W: os.Stdout,
+		}, nil
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestFindApriori(t *testing.T) {
+	f := parseImage(t)
+
+	// Make the PEI apriori file list two modules.
+	matches := find(t, f, &PEIAprioriGUID)
+	if len(matches) != 1 {
+		t.Fatalf("got %d PEI apriori files; expected 1", len(matches))
+	}
+	file := matches[0].(*uefi.File)
+	data := append(append([]byte{}, dxeCoreGUID[:]...), testGUID[:]...)
+	s, err := uefi.CreateSection(uefi.SectionTypeRaw, data, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	file.Sections = []*uefi.Section{s}
+
+	files, err := FindApriori(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d apriori files; expected 2", len(files))
+	}
+
+	pei := files[0]
+	if pei.Phase != "PEI" {
+		t.Errorf("got phase %q; expected PEI", pei.Phase)
+	}
+	want := []AprioriEntry{
+		{GUID: *dxeCoreGUID, Name: "DxeCore"},
+		{GUID: *testGUID, Name: "SecMain"},
+	}
+	if !reflect.DeepEqual(pei.Entries, want) {
+		t.Errorf("got PEI entries %v; expected %v", pei.Entries, want)
+	}
+
+	dxe := files[1]
+	if dxe.Phase != "DXE" || len(dxe.Entries) == 0 {
+		t.Fatalf("got %s apriori file with %d entries; expected a non-empty DXE one", dxe.Phase, len(dxe.Entries))
+	}
+	if dxe.Entries[0].Name != "DevicePathDxe" {
+		t.Errorf("got first DXE entry %v; expected DevicePathDxe", dxe.Entries[0])
+	}
+}
+
+func TestFindAprioriBadSize(t *testing.T) {
+	f := parseImage(t)
+	file := find(t, f, &DXEAprioriGUID)[0].(*uefi.File)
+	s, err := uefi.CreateSection(uefi.SectionTypeRaw, dxeCoreGUID[:10], nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	file.Sections = []*uefi.Section{s}
+
+	if _, err := FindApriori(f); err == nil {
+		t.Errorf("expected an error for a truncated GUID")
+	}
+}
@@ -7,7 +7,9 @@ package visitors
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 
 	"github.com/linuxboot/fiano/pkg/compression"
@@ -21,7 +23,8 @@ import (
 type Assemble struct {
 	// This is set when a file or section >=16MiB is encountered during assembly.
 	// This tells the enclosing FV to use the FFSV3 GUID instead of the FFSV2 GUID,
-	// and the enclosing FV resets it.
+	// and the enclosing FV resets it. FirmwareVolume.FFS3Requirement reports
+	// the switch, and the file triggering it, without assembling.
 	// TODO: figure out if, in the case where the FVs are triply nested, must the FVs further up
 	// also use the FFSV3 GUID? In that case we should fix this since only the innermost
 	// enclosing FV changes to FFSV3
@@ -93,18 +96,19 @@ func (v *Assemble) Visit(f uefi.Firmware) error {
 				fileDataOffset := uefi.Align(alignedOffset+hl, alignBase)
 				// Calculate the starting offset of the file
 				newOffset := fileDataOffset - hl
-				if gap := (newOffset - alignedOffset); gap >= 8 && gap < uefi.FileHeaderMinLength {
+				// Add a pad file starting from alignedOffset to newOffset
+				pfile, err := uefi.MakePadFileToOffset(alignedOffset, newOffset)
+				if errors.Is(err, uefi.ErrPadGapTooSmall) {
 					// We need to re align to the next boundary cause we can't put a pad file in here.
 					// Who thought this was a good idea?
 					fileDataOffset = uefi.Align(fileDataOffset+1, alignBase)
 					newOffset = fileDataOffset - hl
+					pfile, err = uefi.MakePadFileToOffset(alignedOffset, newOffset)
 				}
-				if newOffset != alignedOffset {
-					// Add a pad file starting from alignedOffset to newOffset
-					pfile, err := uefi.CreatePadFile(newOffset - alignedOffset)
-					if err != nil {
-						return err
-					}
+				if err != nil {
+					return err
+				}
+				if pfile != nil {
 					if err = f.InsertFile(alignedOffset, pfile.Buf()); err != nil {
 						return fmt.Errorf("file %s: %v", pfile.Header.GUID, err)
 					}
@@ -308,8 +312,17 @@ func (v *Assemble) Visit(f uefi.Firmware) error {
 		// Special processing for some section types
 		switch f.Header.Type {
 		case uefi.SectionTypeGUIDDefined:
+			if f.GUIDDefinedUnchanged(secData) {
+				// Keep the original encoding and header bytes.
+				if f.Header.ExtendedSize > 0xFFFFFF {
+					v.useFFS3 = true
+				}
+				return nil
+			}
 			ts := f.TypeSpecific.Header.(*uefi.SectionGUIDDefined)
-			if ts.Attributes&uint16(uefi.GUIDEDSectionProcessingRequired) != 0 {
+			if ts.Attributes&uint16(uefi.GUIDEDSectionProcessingRequired) == 0 {
+				f.SetBuf(secData)
+			} else {
 				compressor := compression.CompressorFromGUID(&ts.GUID)
 				if compressor == nil {
 					return fmt.Errorf("unknown guid defined from section %v, should not have encapsulated sections", f)
@@ -430,72 +443,183 @@ func (v *Assemble) Visit(f uefi.Firmware) error {
 		return nil
 
 	case *uefi.FlashImage:
-		ifdbuf := f.IFD.Buf()
-		// Assemble regions.
-		// We need to sort them since a) we don't really know the order until we parse the block numbers
-		// and b) the order may have changed anyway.
-		if !f.IFD.Region.FlashRegions[uefi.RegionTypeBIOS].Valid() {
-			return fmt.Errorf("no BIOS region: invalid region parameters %v",
-				f.IFD.Region.FlashRegions[uefi.RegionTypeBIOS])
+		if err = orderRegions(f); err != nil {
+			return err
 		}
-
-		// Point FlashRegion to struct read from IFD rather than json.
-		nr := int(f.IFD.DescriptorMap.NumberOfRegions)
+		fBuf := make([]byte, 0)
+		fBuf = append(fBuf, f.IFD.Buf()...)
 		for _, t := range f.Regions {
-			r := t.Value.(uefi.Region)
-
-			if r.Type() == uefi.RegionTypeUnknown {
-				continue
-			}
-			if nr != 0 && int(r.Type()) > nr {
-				// Region exceeds original number of regions.
-				// TODO: handle this in some way by increasing the number of regions.
-				continue
-			}
-			if int(r.Type()) >= len(f.IFD.Region.FlashRegions) {
-				// This is some new unknown region, there's no IFD entry
-				continue
-			}
-			r.SetFlashRegion(&f.IFD.Region.FlashRegions[r.Type()])
+			fBuf = append(fBuf, t.Value.Buf()...)
 		}
 
-		// Sort Regions, prepare to set flash buffer
-		sort.Slice(f.Regions, func(i, j int) bool {
-			ri := f.Regions[i].Value.(uefi.Region)
-			rj := f.Regions[j].Value.(uefi.Region)
-			return ri.FlashRegion().Base < rj.FlashRegion().Base
-		})
+		f.SetBuf(fBuf)
+		return nil
+
+	}
 
-		// Search for gaps
-		// if there are gaps or overlaps, fail immediately
-		offset := uint64(uefi.FlashDescriptorLength)
-		fBuf := make([]byte, 0)
-		fBuf = append(fBuf, ifdbuf...)
-		for _, t := range f.Regions {
-			r := t.Value.(uefi.Region)
-			nextBase := uint64(r.FlashRegion().BaseOffset())
-			if nextBase < offset {
-				// Something is wrong, overlapping regions
-				// TODO: print a better error message describing what it overlaps with
-				return fmt.Errorf("overlapping regions! region %v overlaps with the previous region", r)
-			}
-			if nextBase > offset {
-				// There is a gap
+	return err
+
+}
+
+// orderRegions points the regions of f to the flash regions read from the IFD
+// and sorts them by base address. It fails if the regions overlap or if there
+// are gaps between them, unless uefi.AllowReservedGaps is set, in which case
+// the gaps are filled with new regions of unknown type.
+func orderRegions(f *uefi.FlashImage) error {
+	// We need to sort them since a) we don't really know the order until we parse the block numbers
+	// and b) the order may have changed anyway.
+	if !f.IFD.Region.FlashRegions[uefi.RegionTypeBIOS].Valid() {
+		return fmt.Errorf("no BIOS region: invalid region parameters %v",
+			f.IFD.Region.FlashRegions[uefi.RegionTypeBIOS])
+	}
+
+	// Point FlashRegion to struct read from IFD rather than json.
+	nr := int(f.IFD.DescriptorMap.NumberOfRegions)
+	for _, t := range f.Regions {
+		r := t.Value.(uefi.Region)
+
+		if r.Type() == uefi.RegionTypeUnknown {
+			continue
+		}
+		if nr != 0 && int(r.Type()) > nr {
+			// Region exceeds original number of regions.
+			// TODO: handle this in some way by increasing the number of regions.
+			continue
+		}
+		if int(r.Type()) >= len(f.IFD.Region.FlashRegions) {
+			// This is some new unknown region, there's no IFD entry
+			continue
+		}
+		r.SetFlashRegion(&f.IFD.Region.FlashRegions[r.Type()])
+	}
+
+	// Sort Regions, prepare to set flash buffer
+	sort.Slice(f.Regions, func(i, j int) bool {
+		ri := f.Regions[i].Value.(uefi.Region)
+		rj := f.Regions[j].Value.(uefi.Region)
+		return ri.FlashRegion().Base < rj.FlashRegion().Base
+	})
+
+	// Search for gaps
+	// if there are overlaps, or gaps which are not allowed, fail immediately
+	offset := uint64(uefi.FlashDescriptorLength)
+	regions := make([]*uefi.TypedFirmware, 0, len(f.Regions))
+	for _, t := range f.Regions {
+		r := t.Value.(uefi.Region)
+		nextBase := uint64(r.FlashRegion().BaseOffset())
+		if nextBase < offset {
+			// Something is wrong, overlapping regions
+			// TODO: print a better error message describing what it overlaps with
+			return fmt.Errorf("overlapping regions! region %v overlaps with the previous region", r)
+		}
+		if nextBase > offset {
+			// There is a gap
+			if !uefi.AllowReservedGaps {
 				return fmt.Errorf("gap between regions from %v to %v", offset, nextBase)
 			}
-			offset = uint64(r.FlashRegion().EndOffset())
-			fBuf = append(fBuf, r.Buf()...)
+			gap, err := reservedGap(offset, nextBase)
+			if err != nil {
+				return err
+			}
+			regions = append(regions, gap)
 		}
-		// check for the last region
-		if offset != f.FlashSize {
+		offset = uint64(r.FlashRegion().EndOffset())
+		regions = append(regions, t)
+	}
+	// check for the last region
+	if offset != f.FlashSize {
+		if !uefi.AllowReservedGaps || offset > f.FlashSize {
 			return fmt.Errorf("gap between at end of flash from %v to %v", offset, f.FlashSize)
 		}
+		gap, err := reservedGap(offset, f.FlashSize)
+		if err != nil {
+			return err
+		}
+		regions = append(regions, gap)
+	}
+	f.Regions = regions
+	return nil
+}
 
-		f.SetBuf(fBuf)
-		return nil
-
+// reservedGap creates a region of unknown type covering the flash from offset
+// to end, filled with uefi.ReservedGapFill. This is the same representation
+// the parser uses for the gaps it finds.
+func reservedGap(offset, end uint64) (*uefi.TypedFirmware, error) {
+	fr := &uefi.FlashRegion{
+		Base:  uint16(offset / uefi.RegionBlockSize),
+		Limit: uint16(end/uefi.RegionBlockSize) - 1,
+	}
+	r, err := uefi.NewRawRegion(bytes.Repeat([]byte{uefi.ReservedGapFill}, int(end-offset)), fr, uefi.RegionTypeUnknown)
+	if err != nil {
+		return nil, err
 	}
+	return uefi.MakeTyped(r), nil
+}
 
-	return err
+// AssembleTo assembles f like the Assemble visitor and writes the resulting
+// image to w. The flash image and the BIOS region span the whole image, so
+// instead of being reconstructed in memory they are written piece by piece
+// once their children are assembled. This keeps the peak memory usage close
+// to the size of the largest firmware volume. The buffers of these two nodes
+// are left untouched. Any other node is assembled in memory and its buffer
+// is written to w.
+func AssembleTo(f uefi.Firmware, w io.Writer) error {
+	v := &Assemble{}
+	return v.writeTo(f, w)
+}
+
+func (v *Assemble) writeTo(f uefi.Firmware, w io.Writer) error {
+	switch f := f.(type) {
+	case *uefi.FlashImage:
+		if err := f.IFD.Apply(v); err != nil {
+			return err
+		}
+		if err := orderRegions(f); err != nil {
+			return err
+		}
+		if _, err := w.Write(f.IFD.Buf()); err != nil {
+			return err
+		}
+		for _, t := range f.Regions {
+			if err := v.writeTo(t.Value, w); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *uefi.BIOSRegion:
+		if err := f.ApplyChildren(v); err != nil {
+			return err
+		}
+		firstFV, err := f.FirstFV()
+		if err != nil {
+			return err
+		}
+		if err = uefi.SetErasePolarity(firstFV.GetErasePolarity()); err != nil {
+			return err
+		}
+		offset := uint64(0)
+		for _, e := range f.Elements {
+			ebuf := e.Value.Buf()
+			offset += uint64(len(ebuf))
+			if offset > f.Length {
+				return fmt.Errorf("BIOS region elements exceed the region length %#x", f.Length)
+			}
+			if _, err = w.Write(ebuf); err != nil {
+				return err
+			}
+		}
+		// Erase the rest of the region, like the in-memory assembly does.
+		rest := make([]byte, f.Length-offset)
+		uefi.Erase(rest, uefi.Attributes.ErasePolarity)
+		_, err = w.Write(rest)
+		return err
 
+	default:
+		if err := f.Apply(v); err != nil {
+			return err
+		}
+		_, err := w.Write(f.Buf())
+		return err
+	}
 }
@@ -5,9 +5,13 @@
 package visitors
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"os"
 	"testing"
 
+	"github.com/linuxboot/fiano/pkg/compression"
 	"github.com/linuxboot/fiano/pkg/guid"
 	"github.com/linuxboot/fiano/pkg/uefi"
 )
@@ -46,3 +50,334 @@ func TestBadDepex(t *testing.T) {
 		})
 	}
 }
+
+// makeFlashImage wraps bios in a flash image with a single BIOS region
+// starting at block biosBase. Blocks between the descriptor and the BIOS
+// region are erased.
+func makeFlashImage(bios []byte, biosBase int) []byte {
+	biosSize := uefi.Align(uint64(len(bios))+1, uefi.RegionBlockSize)
+	flash := bytes.Repeat([]byte{0xff}, biosBase*uefi.RegionBlockSize+int(biosSize))
+	copy(flash, make([]byte, uefi.FlashDescriptorLength))
+	copy(flash[16:], uefi.FlashSignature)
+	flash[22] = 0x04 // region section at 0x40
+	flash[24] = 0x08 // master section at 0x80
+	for i := 0; i < len(uefi.FlashRegionSection{}.FlashRegions); i++ {
+		binary.LittleEndian.PutUint16(flash[0x44+4*i:], 0x7fff)
+	}
+	binary.LittleEndian.PutUint16(flash[0x44+4*int(uefi.RegionTypeBIOS):], uint16(biosBase))
+	binary.LittleEndian.PutUint16(flash[0x46+4*int(uefi.RegionTypeBIOS):], uint16(len(flash)/uefi.RegionBlockSize-1))
+	copy(flash[biosBase*uefi.RegionBlockSize:], bios)
+	return flash
+}
+
+func TestAssembleTo(t *testing.T) {
+	fv, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flash := makeFlashImage(fv, 1)
+
+	ovmf, err := os.ReadFile("../../integration/roms/OVMF.rom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		image []byte
+		kind  string
+	}{
+		{"flash image", flash, "*uefi.FlashImage"},
+		{"BIOS region", ovmf, "*uefi.BIOSRegion"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buffered, err := uefi.Parse(test.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buffered.Apply(&Assemble{}); err != nil {
+				t.Fatalf("unable to assemble in memory: %v", err)
+			}
+
+			streamed, err := uefi.Parse(test.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if got := fmt.Sprintf("%T", streamed); got != test.kind {
+				t.Fatalf("expected the image to parse as %s, got %s", test.kind, got)
+			}
+			if err := AssembleTo(streamed, &out); err != nil {
+				t.Fatalf("unable to assemble to writer: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), buffered.Buf()) {
+				t.Errorf("streamed output (%d bytes) differs from in-memory output (%d bytes)",
+					out.Len(), len(buffered.Buf()))
+			}
+		})
+	}
+}
+
+func TestAssembleGUIDDefinedRoundTrip(t *testing.T) {
+	raw, err := uefi.CreateSection(uefi.SectionTypeRaw, []byte("fiano raw section data"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = raw.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := compression.CompressorFromGUID(&compression.LZMAGUID).Encode(raw.Buf())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build a GUID defined section with 8 bytes of authentication data
+	// between the header and the data.
+	authData := []byte{0xa5, 0x5a, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	dataOffset := 4 + 20 + len(authData)
+	buf := make([]byte, dataOffset, dataOffset+len(encoded))
+	size := uefi.Write3Size(uint64(dataOffset + len(encoded)))
+	copy(buf, size[:])
+	buf[3] = byte(uefi.SectionTypeGUIDDefined)
+	copy(buf[4:], compression.LZMAGUID[:])
+	binary.LittleEndian.PutUint16(buf[20:], uint16(dataOffset))
+	binary.LittleEndian.PutUint16(buf[22:], uint16(uefi.GUIDEDSectionProcessingRequired))
+	copy(buf[24:], authData)
+	buf = append(buf, encoded...)
+
+	s, err := uefi.NewSection(buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gd := s.TypeSpecific.Header.(*uefi.SectionGUIDDefined); !bytes.Equal(gd.HeaderData, authData) {
+		t.Fatalf("expected header data %x, got %x", authData, gd.HeaderData)
+	}
+	if len(s.Encapsulated) != 1 {
+		t.Fatalf("expected 1 encapsulated section, got %d", len(s.Encapsulated))
+	}
+
+	// Unmodified, the section must be kept byte for byte.
+	if err = (&Assemble{}).Run(s); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Buf(), buf) {
+		t.Errorf("unmodified section changed after assembly:\nexpected %x\ngot      %x", buf, s.Buf())
+	}
+
+	// Modified, the section is encoded again but keeps its header data.
+	inner := s.Encapsulated[0].Value.(*uefi.Section)
+	inner.SetBuf([]byte("modified data"))
+	if err = inner.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err = (&Assemble{}).Run(s); err != nil {
+		t.Fatal(err)
+	}
+	out := s.Buf()
+	if got := binary.LittleEndian.Uint16(out[20:]); got != uint16(dataOffset) {
+		t.Errorf("expected data offset %#x, got %#x", dataOffset, got)
+	}
+	if !bytes.Equal(out[24:dataOffset], authData) {
+		t.Errorf("expected header data %x, got %x", authData, out[24:dataOffset])
+	}
+	reparsed, err := uefi.NewSection(out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reparsed.Encapsulated) != 1 {
+		t.Fatalf("expected 1 encapsulated section, got %d", len(reparsed.Encapsulated))
+	}
+	if got := reparsed.Encapsulated[0].Value.Buf()[4:]; string(got) != "modified data" {
+		t.Errorf("expected the modified data, got %q", got)
+	}
+}
+
+func TestAssembleFlashDescriptorRegion(t *testing.T) {
+	fv, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := uefi.NewFlashImage(makeFlashImage(fv, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The IFD has no checksum of its own, so the edited region section only
+	// has to be written back and still agree with the descriptor map.
+	// Keep the PD region disabled (base above limit) but change its value.
+	pd := uefi.FlashRegion{Base: 0x7ffe, Limit: 0x0001}
+	f.IFD.Region.FlashRegions[uefi.RegionTypePD] = pd
+	if err = (&Assemble{}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	reparsed, err := uefi.NewFlashImage(f.Buf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reparsed.IFD.Region.FlashRegions[uefi.RegionTypePD]; got != pd {
+		t.Errorf("expected PD region %v after assembly, got %v", &pd, &got)
+	}
+	if errs := reparsed.IFD.CheckRegionConsistency(); errs != nil {
+		t.Errorf("flash descriptor is inconsistent after assembly: %v", errs)
+	}
+}
+
+func TestAssembleReservedGaps(t *testing.T) {
+	fv, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave 2 blocks of reserved space between the descriptor and the BIOS region.
+	image := makeFlashImage(fv, 3)
+	biosStart := 3 * uefi.RegionBlockSize
+
+	parse := func() *uefi.FlashImage {
+		f, err := uefi.NewFlashImage(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Drop the region the parser created for the reserved space, as if
+		// the tree was built without it.
+		var regions []*uefi.TypedFirmware
+		for _, r := range f.Regions {
+			if r.Value.(uefi.Region).Type() != uefi.RegionTypeUnknown {
+				regions = append(regions, r)
+			}
+		}
+		if len(regions) != 1 {
+			t.Fatalf("expected a single BIOS region, got %d regions", len(regions))
+		}
+		f.Regions = regions
+		return f
+	}
+
+	defer func(allow bool, fill byte) {
+		uefi.AllowReservedGaps, uefi.ReservedGapFill = allow, fill
+	}(uefi.AllowReservedGaps, uefi.ReservedGapFill)
+
+	uefi.AllowReservedGaps = false
+	expectedErr := fmt.Sprintf("gap between regions from %v to %v", uefi.FlashDescriptorLength, biosStart)
+	if err := (&Assemble{}).Run(parse()); err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+
+	uefi.AllowReservedGaps = true
+	uefi.ReservedGapFill = 0x00
+	f := parse()
+	if err := (&Assemble{}).Run(f); err != nil {
+		t.Fatalf("unable to assemble with reserved gaps: %v", err)
+	}
+	out := f.Buf()
+	if len(out) != len(image) {
+		t.Fatalf("expected %d bytes, got %d", len(image), len(out))
+	}
+	if !bytes.Equal(out[uefi.FlashDescriptorLength:biosStart], make([]byte, biosStart-uefi.FlashDescriptorLength)) {
+		t.Errorf("reserved gap is not filled with 0x00")
+	}
+	if !bytes.Equal(out[biosStart:], image[biosStart:]) {
+		t.Errorf("BIOS region changed after assembly")
+	}
+	if len(f.Regions) != 2 || f.Regions[0].Value.(uefi.Region).Type() != uefi.RegionTypeUnknown {
+		t.Errorf("expected the gap to be added as an unknown region, got %v", f.Regions)
+	}
+
+	// The streamed output fills the gap the same way.
+	var streamed bytes.Buffer
+	if err := AssembleTo(parse(), &streamed); err != nil {
+		t.Fatalf("unable to assemble to writer with reserved gaps: %v", err)
+	}
+	if !bytes.Equal(streamed.Bytes(), out) {
+		t.Errorf("streamed output differs from in-memory output")
+	}
+}
+
+func TestBIOSRegionMoveFV(t *testing.T) {
+	secFV, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(image []byte) *uefi.BIOSRegion {
+		f, err := uefi.Parse(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		br, ok := f.(*uefi.BIOSRegion)
+		if !ok {
+			t.Fatalf("expected the image to parse as a BIOS region, got %T", f)
+		}
+		return br
+	}
+
+	// The SEC core executes in place, only the volume without it can move.
+	br := parse(secFV)
+	remove := &Remove{Predicate: FindFileTypePredicate(uefi.FVFileTypeSECCore), Pad: true}
+	if err := remove.Run(br); err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Apply(&Assemble{}); err != nil {
+		t.Fatal(err)
+	}
+	fv := br.Buf()
+	fvLen := uint64(len(fv))
+	image := bytes.Repeat([]byte{0xFF}, int(fvLen)+0x10000)
+	copy(image, fv)
+	secImage := bytes.Repeat([]byte{0xFF}, len(secFV)+0x10000)
+	copy(secImage, secFV)
+
+	br = parse(image)
+	if err := br.MoveFV(0, 0x8000); err != nil {
+		t.Fatalf("unable to move the FV: %v", err)
+	}
+	if !uefi.IsDirty(br) {
+		t.Errorf("BIOS region is not dirty after the move")
+	}
+	if err := br.Apply(&Assemble{}); err != nil {
+		t.Fatalf("unable to assemble: %v", err)
+	}
+	out := br.Buf()
+	if uint64(len(out)) != uint64(len(image)) {
+		t.Fatalf("assembled region is %#x bytes, expected %#x", len(out), len(image))
+	}
+	if !bytes.Equal(out[0x8000:0x8000+fvLen], fv) {
+		t.Errorf("FV is not at offset 0x8000 of the assembled region")
+	}
+	if !uefi.IsErased(out[:0x8000], 0xFF) || !uefi.IsErased(out[0x8000+fvLen:], 0xFF) {
+		t.Errorf("space around the moved FV is not erased")
+	}
+	reparsed := parse(out)
+	first, err := reparsed.FirstFV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.FVOffset != 0x8000 {
+		t.Errorf("reparsed FV is at offset %#x, expected 0x8000", first.FVOffset)
+	}
+
+	// Overlaps
+	twoFVs := bytes.Repeat([]byte{0xFF}, 2*int(fvLen)+0x10000)
+	copy(twoFVs, fv)
+	copy(twoFVs[fvLen:], fv)
+	withData := append([]byte{}, image...)
+	withData[len(withData)-0x100] = 0x42
+	for _, test := range []struct {
+		name      string
+		image     []byte
+		fvOffset  uint64
+		newOffset uint64
+	}{
+		{"other FV", twoFVs, 0, 0x10000},
+		{"non-erased padding", withData, 0, 0x10000},
+		{"end of region", image, 0, 0x10000 + 0x1000},
+		{"no FV", image, 0x1000, 0x2000},
+		{"SEC core", secImage, 0, 0x8000},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			br := parse(test.image)
+			if err := br.MoveFV(test.fvOffset, test.newOffset); err == nil {
+				t.Errorf("moving the FV at %#x to %#x did not fail", test.fvOffset, test.newOffset)
+			}
+		})
+	}
+}
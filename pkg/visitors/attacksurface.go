@@ -0,0 +1,156 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// signedSectionGUIDs are the GUIDs of the GUID defined sections wrapping
+// signed content.
+var signedSectionGUIDs = map[guid.GUID]bool{
+	// EFI_FIRMWARE_CONTENTS_SIGNED_GUID
+	*guid.MustParse("0F9D89E8-9259-4F76-A5AF-0C89E34023DF"): true,
+	// EFI_CERT_TYPE_RSA2048_SHA256_GUID
+	*guid.MustParse("A7717414-C616-4977-9420-844712A735BF"): true,
+}
+
+// Phases of the executable modules reported by AttackSurface.
+var modulePhases = map[uefi.FVFileType]string{
+	uefi.FVFileTypeSECCore:            "SEC",
+	uefi.FVFileTypePEICore:            "PEI",
+	uefi.FVFileTypePEIM:               "PEI",
+	uefi.FVFileTypeCombinedPEIMDriver: "PEI",
+	uefi.FVFileTypeDXECore:            "DXE",
+	uefi.FVFileTypeDriver:             "DXE",
+	uefi.FVFileTypeApplication:        "DXE",
+	uefi.FVFileTypeSMM:                "SMM",
+	uefi.FVFileTypeCombinedSMMDXE:     "SMM",
+	uefi.FVFileTypeSMMCore:            "SMM",
+	uefi.FVFileTypeSMMStandalone:      "SMM",
+	uefi.FVFileTypeSMMCoreStandalone:  "SMM",
+}
+
+// PhaseSurface summarizes the executable modules of one boot phase.
+type PhaseSurface struct {
+	Modules int
+	// ExecutableBytes is the size of the PE32 and TE images.
+	ExecutableBytes uint64
+	// Signed is the number of modules with a signed GUID defined section.
+	Signed int
+}
+
+func (p *PhaseSurface) add(o PhaseSurface) {
+	p.Modules += o.Modules
+	p.ExecutableBytes += o.ExecutableBytes
+	p.Signed += o.Signed
+}
+
+// AttackSurface counts the executable modules of each boot phase, as a
+// summary of the security posture of the image. Combined PEIM/DXE drivers
+// are counted as PEI modules and combined SMM/DXE drivers as SMM modules.
+type AttackSurface struct {
+	// Optionally write the result as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	SEC   PhaseSurface
+	PEI   PhaseSurface
+	DXE   PhaseSurface
+	SMM   PhaseSurface
+	Total PhaseSurface
+
+	cur *PhaseSurface
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *AttackSurface) Run(f uefi.Firmware) error {
+	v.SEC, v.PEI, v.DXE, v.SMM, v.Total = PhaseSurface{}, PhaseSurface{}, PhaseSurface{}, PhaseSurface{}, PhaseSurface{}
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	for _, p := range []PhaseSurface{v.SEC, v.PEI, v.DXE, v.SMM} {
+		v.Total.add(p)
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(v.W, string(b))
+	return err
+}
+
+// Visit applies the AttackSurface visitor to any Firmware type.
+func (v *AttackSurface) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		phase, ok := modulePhases[f.Header.Type]
+		if !ok {
+			return f.ApplyChildren(v)
+		}
+		module := &PhaseSurface{Modules: 1}
+		prev := v.cur
+		v.cur = module
+		var err error
+		if len(f.Sections) == 0 {
+			// Sections of some file types (e.g. PEIMs) are not parsed
+			// by default.
+			err = visitRawSections(f, v)
+		} else {
+			err = f.ApplyChildren(v)
+		}
+		v.cur = prev
+		if err != nil {
+			return err
+		}
+		switch phase {
+		case "SEC":
+			v.SEC.add(*module)
+		case "PEI":
+			v.PEI.add(*module)
+		case "DXE":
+			v.DXE.add(*module)
+		case "SMM":
+			v.SMM.add(*module)
+		}
+		return nil
+
+	case *uefi.Section:
+		if v.cur == nil {
+			return f.ApplyChildren(v)
+		}
+		switch f.Header.Type {
+		case uefi.SectionTypePE32, uefi.SectionTypeTE:
+			v.cur.ExecutableBytes += uint64(len(f.Data()))
+		case uefi.SectionTypeGUIDDefined:
+			if f.TypeSpecific == nil {
+				break
+			}
+			if gd, ok := f.TypeSpecific.Header.(*uefi.SectionGUIDDefined); ok && signedSectionGUIDs[gd.GUID] {
+				v.cur.Signed = 1
+			}
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+func init() {
+	RegisterCLI("attack-surface", "count the executable modules and their size by boot phase as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &AttackSurface{W: os.Stdout}, nil
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestAttackSurface(t *testing.T) {
+	f := parseImage(t)
+
+	var out bytes.Buffer
+	v := &AttackSurface{W: &out}
+	if err := v.Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.SEC.Modules != 1 {
+		t.Errorf("expected 1 SEC module, got %d", v.SEC.Modules)
+	}
+	if v.PEI.Modules == 0 || v.DXE.Modules == 0 {
+		t.Errorf("expected PEI and DXE modules, got %d and %d", v.PEI.Modules, v.DXE.Modules)
+	}
+	for name, p := range map[string]PhaseSurface{"SEC": v.SEC, "PEI": v.PEI, "DXE": v.DXE} {
+		if p.ExecutableBytes == 0 {
+			t.Errorf("%s modules have no executable bytes", name)
+		}
+	}
+	if want := v.SEC.Modules + v.PEI.Modules + v.DXE.Modules + v.SMM.Modules; v.Total.Modules != want {
+		t.Errorf("expected %d modules in total, got %d", want, v.Total.Modules)
+	}
+	if v.Total.Signed != 0 {
+		t.Errorf("expected no signed modules in OVMF, got %d", v.Total.Signed)
+	}
+
+	var decoded AttackSurface
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Total != v.Total {
+		t.Errorf("JSON total %+v does not match %+v", decoded.Total, v.Total)
+	}
+}
+
+func TestAttackSurfaceSigned(t *testing.T) {
+	pe32, err := uefi.CreateSection(uefi.SectionTypePE32, []byte("MZ not really an image"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pe32.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := uefi.CreateSection(uefi.SectionTypeGUIDDefined, nil, []uefi.Firmware{pe32},
+		guid.MustParse("A7717414-C616-4977-9420-844712A735BF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	smm := &uefi.File{Sections: []*uefi.Section{signed}}
+	smm.Header.Type = uefi.FVFileTypeSMM
+	dxe := &uefi.File{Sections: []*uefi.Section{pe32}}
+	dxe.Header.Type = uefi.FVFileTypeDriver
+	fv := &uefi.FirmwareVolume{Files: []*uefi.File{smm, dxe}}
+
+	v := &AttackSurface{}
+	if err := v.Run(fv); err != nil {
+		t.Fatal(err)
+	}
+	want := PhaseSurface{Modules: 1, ExecutableBytes: uint64(len("MZ not really an image")), Signed: 1}
+	if v.SMM != want {
+		t.Errorf("expected SMM %+v, got %+v", want, v.SMM)
+	}
+	want.Signed = 0
+	if v.DXE != want {
+		t.Errorf("expected DXE %+v, got %+v", want, v.DXE)
+	}
+	if v.Total.Modules != 2 || v.Total.Signed != 1 {
+		t.Errorf("unexpected total %+v", v.Total)
+	}
+}
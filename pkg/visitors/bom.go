@@ -0,0 +1,136 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// BOMEntry is a single component of the bill of materials.
+type BOMEntry struct {
+	GUID guid.GUID
+	// Name comes from the user interface section.
+	Name string `json:",omitempty"`
+	// Version and BuildNumber come from the version section.
+	Version     string `json:",omitempty"`
+	BuildNumber uint16 `json:",omitempty"`
+	// Type is the file type without the EFI_FV_FILETYPE_ prefix, e.g. PEIM.
+	Type string
+	Size uint64
+}
+
+// BOM produces a bill of materials listing all the files of the image with
+// their name and version. Pad files are omitted.
+type BOM struct {
+	// Optionally write the result to W in the given Format, which is either
+	// "csv" or "json".
+	W      io.Writer `json:"-"`
+	Format string    `json:"-"`
+
+	// Output
+	Entries []*BOMEntry
+
+	cur *BOMEntry
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *BOM) Run(f uefi.Firmware) error {
+	v.Entries = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	switch v.Format {
+	case "csv":
+		w := csv.NewWriter(v.W)
+		if err := w.Write([]string{"GUID", "Name", "Version", "BuildNumber", "Type", "Size"}); err != nil {
+			return err
+		}
+		for _, e := range v.Entries {
+			if err := w.Write([]string{
+				e.GUID.String(),
+				e.Name,
+				e.Version,
+				strconv.FormatUint(uint64(e.BuildNumber), 10),
+				e.Type,
+				strconv.FormatUint(e.Size, 10),
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case "json":
+		b, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(v.W, string(b))
+		return err
+	}
+	return fmt.Errorf("unknown BOM format %q, expected csv or json", v.Format)
+}
+
+// Visit applies the BOM visitor to any Firmware type.
+func (v *BOM) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		if f.Header.Type == uefi.FVFileTypePad {
+			return nil
+		}
+		prev := v.cur
+		defer func() { v.cur = prev }()
+		v.cur = &BOMEntry{
+			GUID: f.Header.GUID,
+			Type: strings.TrimPrefix(f.Type, "EFI_FV_FILETYPE_"),
+			Size: f.Header.ExtendedSize,
+		}
+		v.Entries = append(v.Entries, v.cur)
+		if len(f.Sections) == 0 && executableFileTypes[f.Header.Type] {
+			// See ModuleList.Visit
+			return visitRawSections(f, v)
+		}
+		return f.ApplyChildren(v)
+
+	case *uefi.Section:
+		if v.cur != nil {
+			switch f.Header.Type {
+			case uefi.SectionTypeUserInterface:
+				v.cur.Name = f.Name
+			case uefi.SectionTypeVersion:
+				v.cur.Version = f.Version
+				v.cur.BuildNumber = f.BuildNumber
+			}
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+func init() {
+	RegisterCLI("bom", "export a bill of materials of all files, args: --format csv|json", 2, func(args []string) (uefi.Visitor, error) {
+		if args[0] != "--format" {
+			return nil, fmt.Errorf("expected --format, got %q", args[0])
+		}
+		if args[1] != "csv" && args[1] != "json" {
+			return nil, fmt.Errorf("unknown BOM format %q, expected csv or json", args[1])
+		}
+		return &BOM{W: os.Stdout, Format: args[1]}, nil
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestBOM(t *testing.T) {
+	f := parseImage(t)
+
+	b := &BOM{}
+	if err := b.Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	var dxe *BOMEntry
+	var versioned int
+	for _, e := range b.Entries {
+		if e.Type == "FFS_PAD" {
+			t.Errorf("pad file %v is listed", e.GUID)
+		}
+		if e.Version != "" {
+			versioned++
+		}
+		if e.GUID == *dxeCoreGUID {
+			dxe = e
+		}
+	}
+	if versioned == 0 {
+		t.Errorf("no versioned files found")
+	}
+	if dxe == nil {
+		t.Fatal("DxeCore not found")
+	}
+	if dxe.Name != "DxeCore" || dxe.Version != "1.0" || dxe.Type != "DXE_CORE" || dxe.Size == 0 {
+		t.Errorf("unexpected DxeCore entry: %+v", dxe)
+	}
+}
+
+func TestBOMFormats(t *testing.T) {
+	f := parseImage(t)
+
+	var out bytes.Buffer
+	if err := (&BOM{W: &out, Format: "csv"}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) < 2 || records[0][0] != "GUID" {
+		t.Fatalf("unexpected CSV output: %v", records)
+	}
+	var found bool
+	for _, r := range records[1:] {
+		if r[0] == dxeCoreGUID.String() {
+			found = true
+			if r[1] != "DxeCore" || r[2] != "1.0" {
+				t.Errorf("unexpected DxeCore record: %v", r)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("DxeCore is not in the CSV output")
+	}
+
+	out.Reset()
+	if err := (&BOM{W: &out, Format: "json"}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+	var b BOM
+	if err := json.Unmarshal(out.Bytes(), &b); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(b.Entries) != len(records)-1 {
+		t.Errorf("JSON has %d entries, CSV has %d", len(b.Entries), len(records)-1)
+	}
+
+	if err := (&BOM{W: &out, Format: "xml"}).Run(f); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestBOMCLI(t *testing.T) {
+	for _, args := range [][]string{{"bom", "--format", "csv"}, {"bom", "--format", "json"}} {
+		v, err := ParseCLI(args)
+		if err != nil {
+			t.Errorf("%v: %v", args, err)
+			continue
+		}
+		if _, ok := v[0].(*BOM); !ok {
+			t.Errorf("%v: got %T", args, v[0])
+		}
+	}
+	for _, args := range [][]string{{"bom", "csv", "--format"}, {"bom", "--format", "xml"}} {
+		if _, err := ParseCLI(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
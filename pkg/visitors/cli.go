@@ -71,6 +71,9 @@ func ExecuteCLI(f uefi.Firmware, v []uefi.Visitor) error {
 		if err := v[i].Run(f); err != nil {
 			return err
 		}
+		// Visitors may add or replace nodes, keep the parent links up to
+		// date for the following ones.
+		uefi.LinkParents(f)
 	}
 	return nil
 }
@@ -0,0 +1,164 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// CompressedSection describes the sizes of a compressed section.
+type CompressedSection struct {
+	// File is the GUID of the file containing the section.
+	File guid.GUID
+	// Name comes from the user interface section of the file.
+	Name        string `json:",omitempty"`
+	Compression string
+	// CompressedSize is the size of the compressed data without the
+	// section header.
+	CompressedSize   uint64
+	DecompressedSize uint64
+	// Ratio is CompressedSize divided by DecompressedSize.
+	Ratio float64
+	// Savings is DecompressedSize minus CompressedSize, it is negative for
+	// sections which do not compress.
+	Savings int64
+}
+
+// CompressionReport reports the compression ratio of all compressed
+// sections, sorted by savings in the descending order. Only the sections
+// decompressed during parsing are reported, except for
+// EFI_SECTION_COMPRESSION sections, whose decompressed size is stored in the
+// header.
+type CompressionReport struct {
+	// Optionally write the result as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	Sections []*CompressedSection
+
+	curFile *uefi.File
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *CompressionReport) Run(f uefi.Firmware) error {
+	v.Sections = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	sort.SliceStable(v.Sections, func(i, j int) bool {
+		return v.Sections[i].Savings > v.Sections[j].Savings
+	})
+
+	if v.W == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(v.Sections, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(v.W, string(b))
+	return err
+}
+
+// Visit applies the CompressionReport visitor to any Firmware type.
+func (v *CompressionReport) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		prev := v.curFile
+		defer func() { v.curFile = prev }()
+		v.curFile = f
+		first := len(v.Sections)
+		if err := f.ApplyChildren(v); err != nil {
+			return err
+		}
+		var name string
+		if ui := f.SectionsOfType(uefi.SectionTypeUserInterface, true); len(ui) > 0 {
+			name = ui[0].Name
+		}
+		for _, s := range v.Sections[first:] {
+			if s.File == f.Header.GUID {
+				s.Name = name
+			}
+		}
+		return nil
+
+	case *uefi.Section:
+		if s := v.compressedSection(f); s != nil {
+			v.Sections = append(v.Sections, s)
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+func (v *CompressionReport) compressedSection(s *uefi.Section) *CompressedSection {
+	headerSize := s.HeaderLen()
+	buflen := uint64(len(s.Buf()))
+
+	result := &CompressedSection{}
+	if v.curFile != nil {
+		result.File = v.curFile.Header.GUID
+	}
+	switch s.Header.Type {
+	case uefi.SectionTypeGUIDDefined:
+		if s.TypeSpecific == nil {
+			return nil
+		}
+		guidDefined, ok := s.TypeSpecific.Header.(*uefi.SectionGUIDDefined)
+		if !ok || guidDefined.Compression == "" || guidDefined.Compression == "UNKNOWN" {
+			return nil
+		}
+		if uint64(guidDefined.DataOffset) > buflen {
+			return nil
+		}
+		result.Compression = guidDefined.Compression
+		result.CompressedSize = buflen - uint64(guidDefined.DataOffset)
+		// The decompressed buffer is the sequence of encapsulated sections
+		// aligned to 4 bytes, see uefi.NewSection.
+		for i, e := range s.Encapsulated {
+			if i > 0 {
+				result.DecompressedSize = uefi.Align4(result.DecompressedSize)
+			}
+			result.DecompressedSize += uint64(len(e.Value.Buf()))
+		}
+
+	case uefi.SectionTypeCompression:
+		// EFI_COMPRESSION_SECTION: UncompressedLength (4 bytes) and CompressionType (1 byte)
+		if buflen < headerSize+5 {
+			return nil
+		}
+		result.Compression = "EFI"
+		if s.Buf()[headerSize+4] == 0 {
+			result.Compression = "NONE"
+		}
+		result.CompressedSize = buflen - headerSize - 5
+		result.DecompressedSize = uint64(binary.LittleEndian.Uint32(s.Buf()[headerSize:]))
+
+	default:
+		return nil
+	}
+
+	if result.DecompressedSize != 0 {
+		result.Ratio = float64(result.CompressedSize) / float64(result.DecompressedSize)
+	}
+	result.Savings = int64(result.DecompressedSize) - int64(result.CompressedSize)
+	return result
+}
+
+func init() {
+	RegisterCLI("compression-report", "report compressed and decompressed sizes of compressed sections as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &CompressionReport{W: os.Stdout}, nil
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestCompressionReport(t *testing.T) {
+	f := parseImage(t)
+
+	var out bytes.Buffer
+	v := &CompressionReport{W: &out}
+	if err := v.Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	var lzma int
+	for i, s := range v.Sections {
+		if s.Compression == "LZMA" {
+			lzma++
+		}
+		if s.CompressedSize == 0 || s.DecompressedSize <= s.CompressedSize {
+			t.Errorf("unexpected sizes of section %d: %+v", i, s)
+		}
+		if s.Ratio <= 0 || s.Ratio >= 1 {
+			t.Errorf("unexpected ratio of section %d: %+v", i, s)
+		}
+		if i > 0 && v.Sections[i-1].Savings < s.Savings {
+			t.Errorf("sections are not sorted by savings: %d < %d", v.Sections[i-1].Savings, s.Savings)
+		}
+	}
+	if lzma == 0 {
+		t.Errorf("no LZMA sections found")
+	}
+
+	var sections []*CompressedSection
+	if err := json.Unmarshal(out.Bytes(), &sections); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(sections) != len(v.Sections) {
+		t.Errorf("got %d sections in JSON, want %d", len(sections), len(v.Sections))
+	}
+}
@@ -151,6 +151,10 @@ func (v *Extract) Visit(f uefi.Firmware) error {
 		v2.DirPath = filepath.Join(v.DirPath, "me")
 		f.ExtractPath, err = v2.extractBinary(f.Buf(), "meregion.bin")
 
+	case *uefi.ECRegion:
+		v2.DirPath = filepath.Join(v.DirPath, "ec")
+		f.ExtractPath, err = v2.extractBinary(f.Buf(), "ecregion.bin")
+
 	case *uefi.RawRegion:
 		v2.DirPath = filepath.Join(v.DirPath, f.Type().String())
 		f.ExtractPath, err = v2.extractBinary(f.Buf(), fmt.Sprintf("%#x.bin", f.FlashRegion().BaseOffset()))
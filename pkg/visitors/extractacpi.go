@@ -0,0 +1,165 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/log"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// ExtractedACPITable is an ACPI table written by ExtractACPI.
+type ExtractedACPITable struct {
+	Signature string
+	// OEMID is empty for tables without the standard header, like the FACS.
+	OEMID  string `json:",omitempty"`
+	Length uint32
+	Path   string
+}
+
+// ExtractACPI writes the ACPI tables found in raw and freeform sections to
+// Dir, one SIGNATURE_OEMID.aml file per table, so they can be fed to a
+// disassembler. A section may hold several tables back to back. Tables with
+// a bad checksum are skipped with a warning. When several tables get the same
+// name, a counter is appended to the name of all but the first one.
+type ExtractACPI struct {
+	// Dir is created if it does not exist.
+	Dir string
+
+	// Optionally write the list of extracted tables as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	Tables []ExtractedACPITable
+
+	names map[string]int
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *ExtractACPI) Run(f uefi.Firmware) error {
+	v.Tables = nil
+	v.names = map[string]int{}
+	if err := os.MkdirAll(v.Dir, 0755); err != nil {
+		return err
+	}
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(v.Tables, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(v.W, string(b))
+	return err
+}
+
+// Visit applies the ExtractACPI visitor to any Firmware type.
+func (v *ExtractACPI) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		if len(f.Sections) == 0 {
+			return visitRawSections(f, v)
+		}
+		return f.ApplyChildren(v)
+
+	case *uefi.Section:
+		headerSize := f.HeaderLen()
+		switch f.Header.Type {
+		case uefi.SectionTypeRaw:
+		case uefi.SectionTypeFreeformSubtypeGUID:
+			headerSize += guid.Size
+		default:
+			return f.ApplyChildren(v)
+		}
+		if buf := f.Buf(); uint64(len(buf)) > headerSize {
+			return v.extractTables(buf[headerSize:])
+		}
+		return nil
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+// extractTables writes the tables data starts with, it stops at the first
+// byte which is not the start of a table.
+func (v *ExtractACPI) extractTables(data []byte) error {
+	for {
+		signature := uefi.ACPITableSignature(data)
+		if signature == "" {
+			return nil
+		}
+		length := binary.LittleEndian.Uint32(data[4:])
+		table := data[:length]
+		data = data[length:]
+
+		// The FACS has neither a checksum nor an OEM ID.
+		var oemID string
+		if signature != "FACS" {
+			var sum uint8
+			for _, b := range table {
+				sum += b
+			}
+			if sum != 0 {
+				log.Warnf("skipping %s table of %d bytes: invalid checksum", signature, length)
+				continue
+			}
+			oemID = acpiName(table[10:16])
+		}
+
+		name := signature
+		if oemID != "" {
+			name += "_" + oemID
+		}
+		if n := v.names[name]; n > 0 {
+			v.names[name]++
+			name = fmt.Sprintf("%s_%d", name, n)
+		} else {
+			v.names[name] = 1
+		}
+		path := filepath.Join(v.Dir, name+".aml")
+		if err := os.WriteFile(path, table, 0666); err != nil {
+			return err
+		}
+		v.Tables = append(v.Tables, ExtractedACPITable{
+			Signature: signature,
+			OEMID:     oemID,
+			Length:    length,
+			Path:      path,
+		})
+	}
+}
+
+// acpiName turns a space padded identifier of an ACPI table into something
+// safe to use in a file name.
+func acpiName(b []byte) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.TrimRight(string(b), " \x00"))
+}
+
+func init() {
+	RegisterCLI("extract-acpi", "extract the ACPI tables of raw and freeform sections to the directory `dir`", 1, func(args []string) (uefi.Visitor, error) {
+		return &ExtractACPI{
+			Dir: args[0],
+			W:   os.Stdout,
+		}, nil
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestExtractACPI(t *testing.T) {
+	f := parseImage(t)
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	v := &ExtractACPI{Dir: dir, W: &out}
+	if err := v.Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	var ssdts []ExtractedACPITable
+	for _, table := range v.Tables {
+		if table.Signature == "SSDT" {
+			ssdts = append(ssdts, table)
+		}
+	}
+	if len(ssdts) != 2 {
+		t.Fatalf("expected 2 SSDTs, got %v", v.Tables)
+	}
+	if ssdts[0].Path == ssdts[1].Path {
+		t.Errorf("both SSDTs were written to %s", ssdts[0].Path)
+	}
+	for _, ssdt := range ssdts {
+		if ssdt.OEMID == "" {
+			t.Errorf("SSDT at %s has no OEM ID", ssdt.Path)
+		}
+		if base := filepath.Base(ssdt.Path); base[:len("SSDT_"+ssdt.OEMID)] != "SSDT_"+ssdt.OEMID {
+			t.Errorf("unexpected file name %s for OEM ID %s", base, ssdt.OEMID)
+		}
+		b, err := os.ReadFile(ssdt.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint32(len(b)) != ssdt.Length || string(b[:4]) != "SSDT" {
+			t.Errorf("%s: expected a %d bytes SSDT, got %d bytes starting with %q", ssdt.Path, ssdt.Length, len(b), b[:4])
+		}
+		var sum uint8
+		for _, c := range b {
+			sum += c
+		}
+		if sum != 0 {
+			t.Errorf("%s: invalid checksum", ssdt.Path)
+		}
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"Signature": "SSDT"`)) {
+		t.Errorf("JSON output does not list the SSDTs:\n%s", out.String())
+	}
+}
+
+func TestExtractACPIMultipleTables(t *testing.T) {
+	// An SSDT with a single byte of AML.
+	ssdt := make([]byte, 37)
+	copy(ssdt, "SSDT")
+	binary.LittleEndian.PutUint32(ssdt[4:], uint32(len(ssdt)))
+	copy(ssdt[10:], "FIANO ")
+	var sum uint8
+	for _, b := range ssdt {
+		sum += b
+	}
+	ssdt[9] = -sum
+
+	bad := append([]byte{}, ssdt...)
+	bad[36]++
+
+	// Two identical tables, then one with a bad checksum, then padding.
+	data := append(append(append(append([]byte{}, ssdt...), ssdt...), bad...), 0xff, 0xff, 0xff, 0xff)
+	s, err := uefi.CreateSection(uefi.SectionTypeRaw, data, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	v := &ExtractACPI{Dir: dir}
+	if err := v.Run(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Tables) != 2 {
+		t.Fatalf("expected 2 tables, got %v", v.Tables)
+	}
+	for i, name := range []string{"SSDT_FIANO.aml", "SSDT_FIANO_1.aml"} {
+		if v.Tables[i].Path != filepath.Join(dir, name) {
+			t.Errorf("table #%d: expected path %s, got %s", i, filepath.Join(dir, name), v.Tables[i].Path)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, ssdt) {
+			t.Errorf("%s: content differs from the table", name)
+		}
+	}
+}
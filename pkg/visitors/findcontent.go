@@ -0,0 +1,126 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// ContentMatch is a section whose content contains the searched pattern.
+type ContentMatch struct {
+	// File is the file containing the section, it is nil for sections
+	// outside of files.
+	File    *uefi.File `json:"-"`
+	Section *uefi.Section
+	// Offset of the pattern from the start of the section header.
+	Offset int
+}
+
+// contentFinder is the visitor behind FindContent.
+type contentFinder struct {
+	Pattern    []byte
+	Decompress bool
+
+	Matches []*ContentMatch
+
+	// JSON is written to this writer.
+	W io.Writer
+
+	currentFile *uefi.File
+}
+
+// FindContent returns all sections of f containing the pattern, one match
+// per occurrence. If decompress is true, the decoded content of compressed
+// sections is searched, otherwise their compressed buffer is.
+func FindContent(f uefi.Firmware, pattern []byte, decompress bool) ([]*ContentMatch, error) {
+	v := &contentFinder{
+		Pattern:    pattern,
+		Decompress: decompress,
+	}
+	if err := v.Run(f); err != nil {
+		return nil, err
+	}
+	return v.Matches, nil
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *contentFinder) Run(f uefi.Firmware) error {
+	if len(v.Pattern) == 0 {
+		return fmt.Errorf("empty pattern")
+	}
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	if v.W != nil {
+		b, err := json.MarshalIndent(v.Matches, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(v.W, string(b))
+	}
+	return nil
+}
+
+// Visit applies the contentFinder visitor to any Firmware type.
+func (v *contentFinder) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		prev := v.currentFile
+		v.currentFile = f
+		err := f.ApplyChildren(v)
+		v.currentFile = prev
+		return err
+
+	case *uefi.Section:
+		// Encapsulation sections are searched through their children, so
+		// that a hit is reported only once in the innermost section.
+		if len(f.Encapsulated) != 0 && (v.Decompress || !isCompressedSection(f)) {
+			return f.ApplyChildren(v)
+		}
+		buf := f.Buf()
+		for offset := 0; ; {
+			i := bytes.Index(buf[offset:], v.Pattern)
+			if i < 0 {
+				break
+			}
+			v.Matches = append(v.Matches, &ContentMatch{
+				File:    v.currentFile,
+				Section: f,
+				Offset:  offset + i,
+			})
+			offset += i + 1
+		}
+		return nil
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+// isCompressedSection reports whether the encapsulated sections of s were
+// decoded from a compressed buffer.
+func isCompressedSection(s *uefi.Section) bool {
+	if s.Header.Type != uefi.SectionTypeGUIDDefined || s.TypeSpecific == nil {
+		return false
+	}
+	guidDefined, ok := s.TypeSpecific.Header.(*uefi.SectionGUIDDefined)
+	return ok && guidDefined.Compression != "" && guidDefined.Compression != "UNKNOWN"
+}
+
+func init() {
+	RegisterCLI("find_content", "find sections containing a string, searching decompressed content", 1, func(args []string) (uefi.Visitor, error) {
+		return &contentFinder{
+			Pattern:    []byte(args[0]),
+			Decompress: true,
+			W:          os.Stdout,
+		}, nil
+	})
+}
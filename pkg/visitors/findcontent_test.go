@@ -0,0 +1,49 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/unicode"
+)
+
+func TestFindContent(t *testing.T) {
+	f := parseImage(t)
+	// The UI section of the DxeCore is inside the LZMA compressed DXE FV.
+	pattern := unicode.UTF8ToUCS2("DxeCore")
+
+	matches, err := FindContent(f, pattern, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, m := range matches {
+		if !bytes.Equal(m.Section.Buf()[m.Offset:m.Offset+len(pattern)], pattern) {
+			t.Errorf("pattern not found at offset %#x of the matched section", m.Offset)
+		}
+		if m.File != nil && m.File.Header.GUID == *dxeCoreGUID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("DxeCore UI section not found in %d matches", len(matches))
+	}
+
+	matches, err = FindContent(f, pattern, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range matches {
+		if m.File != nil && m.File.Header.GUID == *dxeCoreGUID {
+			t.Errorf("DxeCore UI section found without decompression")
+		}
+	}
+
+	if _, err := FindContent(f, nil, true); err == nil {
+		t.Errorf("expected an error for an empty pattern")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// FileHash is the SHA-256 digest of a firmware file.
+type FileHash struct {
+	GUID guid.GUID
+	// Type is the file type without the EFI_FV_FILETYPE_ prefix, e.g. PEIM.
+	Type   string
+	SHA256 string
+}
+
+// Hash computes the SHA-256 digest of every file of the image, including the
+// files of nested volumes. The digests are computed over the assembled
+// buffers, so an edited image has to be assembled first.
+type Hash struct {
+	// IncludeHeader hashes the file header as well, otherwise only the data
+	// following the header (of either size) is hashed.
+	IncludeHeader bool
+	// IncludePad hashes the pad files as well, they are skipped otherwise.
+	IncludePad bool
+
+	// Optionally write the result as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	Files []FileHash
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *Hash) Run(f uefi.Firmware) error {
+	v.Files = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(v.Files, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(v.W, string(b))
+	return err
+}
+
+// Visit applies the Hash visitor to any Firmware type.
+func (v *Hash) Visit(f uefi.Firmware) error {
+	file, ok := f.(*uefi.File)
+	if !ok || (file.Header.Type == uefi.FVFileTypePad && !v.IncludePad) {
+		return f.ApplyChildren(v)
+	}
+
+	buf := file.Buf()
+	if !v.IncludeHeader {
+		hl := file.HeaderLen()
+		if hl > uint64(len(buf)) {
+			return fmt.Errorf("file %v: header length %#x exceeds buffer size %#x", file.Header.GUID, hl, len(buf))
+		}
+		buf = buf[hl:]
+	}
+	sum := sha256.Sum256(buf)
+	v.Files = append(v.Files, FileHash{
+		GUID:   file.Header.GUID,
+		Type:   strings.TrimPrefix(file.Type, "EFI_FV_FILETYPE_"),
+		SHA256: hex.EncodeToString(sum[:]),
+	})
+	return f.ApplyChildren(v)
+}
+
+func init() {
+	RegisterCLI("hash", "print the SHA-256 of the data of every file except pad files as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &Hash{W: os.Stdout}, nil
+	})
+	RegisterCLI("hash-with", "same as hash, args: comma separated options include-header,include-pad", 1, func(args []string) (uefi.Visitor, error) {
+		v := &Hash{W: os.Stdout}
+		for _, opt := range strings.Split(args[0], ",") {
+			switch opt {
+			case "include-header":
+				v.IncludeHeader = true
+			case "include-pad":
+				v.IncludePad = true
+			default:
+				return nil, fmt.Errorf("unknown hash option %q, expected include-header or include-pad", opt)
+			}
+		}
+		return v, nil
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestHash(t *testing.T) {
+	f := parseImage(t)
+	files := find(t, f, dxeCoreGUID)
+	if len(files) != 1 {
+		t.Fatalf("got %d DXE core files; expected 1", len(files))
+	}
+	dxe := files[0].(*uefi.File)
+
+	hashOf := func(v *Hash) string {
+		if err := v.Run(f); err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range v.Files {
+			if h.GUID == *dxeCoreGUID {
+				return h.SHA256
+			}
+		}
+		t.Fatal("DXE core not hashed")
+		return ""
+	}
+	countPads := func(v *Hash) int {
+		pads := 0
+		for _, h := range v.Files {
+			if h.Type == "FFS_PAD" {
+				pads++
+			}
+		}
+		return pads
+	}
+
+	data := sha256.Sum256(dxe.Buf()[dxe.HeaderLen():])
+	whole := sha256.Sum256(dxe.Buf())
+	v := &Hash{}
+	if got := hashOf(v); got != hex.EncodeToString(data[:]) {
+		t.Errorf("got %s for the data of the DXE core, expected %x", got, data)
+	}
+	if pads := countPads(v); pads != 0 {
+		t.Errorf("got %d pad files, expected none", pads)
+	}
+	v = &Hash{IncludeHeader: true, IncludePad: true}
+	if got := hashOf(v); got != hex.EncodeToString(whole[:]) {
+		t.Errorf("got %s for the DXE core, expected %x", got, whole)
+	}
+	if pads := countPads(v); pads == 0 {
+		t.Error("no pad files were hashed")
+	}
+
+	// The JSON output lists the same digests.
+	var out bytes.Buffer
+	if err := (&Hash{W: &out}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+	var dec []FileHash
+	if err := json.Unmarshal(out.Bytes(), &dec); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(dec) == 0 {
+		t.Error("no files in the JSON output")
+	}
+}
+
+func TestHashLargeFile(t *testing.T) {
+	// The FFSv3 header of a large file is not part of its data.
+	parseImage(t) // sets the erase polarity
+	pad, err := uefi.CreatePadFile(0x1000100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pad.Header.Attributes.IsLarge() {
+		t.Fatal("expected a large file")
+	}
+	v := &Hash{IncludePad: true}
+	if err := v.Run(pad); err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(pad.Buf()[uefi.FileHeaderExtMinLength:])
+	if len(v.Files) != 1 || v.Files[0].SHA256 != hex.EncodeToString(want[:]) {
+		t.Errorf("got %v, expected one file with digest %x", v.Files, want)
+	}
+}
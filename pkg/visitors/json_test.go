@@ -7,6 +7,8 @@ package visitors
 import (
 	"bytes"
 	"encoding/json"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +31,54 @@ func TestJSON(t *testing.T) {
 		t.Errorf("invalid json: %q", out.String())
 	}
 }
+
+// TestJSONCanonicalGUIDs tests that every GUID in the JSON output is rendered
+// in the canonical upper case form, so dumps can be diffed.
+func TestJSONCanonicalGUIDs(t *testing.T) {
+	f := parseImage(t)
+
+	out := &bytes.Buffer{}
+	if err := f.Apply(&JSON{W: out}); err != nil {
+		t.Fatal(err)
+	}
+	var dec interface{}
+	if err := json.Unmarshal(out.Bytes(), &dec); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+
+	canonical := regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12}$`)
+	found := map[string]bool{}
+	var walk func(v interface{}, path []string)
+	walk = func(v interface{}, path []string) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if s, ok := v["GUID"].(string); ok && len(v) == 1 {
+				if !canonical.MatchString(s) {
+					t.Errorf("GUID %q at %s is not canonical", s, strings.Join(path, "."))
+				}
+				if len(path) >= 3 {
+					found[strings.Join(path[len(path)-3:], ".")] = true
+				}
+				return
+			}
+			for k, child := range v {
+				walk(child, append(path, k))
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child, path)
+			}
+		}
+	}
+	walk(dec, nil)
+
+	for _, path := range []string{
+		"Elements.Value.FileSystemGUID", // firmware volume
+		"Files.Header.GUID",             // file
+		"TypeSpecific.Header.GUID",      // GUID defined section
+	} {
+		if !found[path] {
+			t.Errorf("no GUID found at %s", path)
+		}
+	}
+}
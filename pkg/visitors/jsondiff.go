@@ -0,0 +1,201 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONDifference is a difference between two JSON dumps of a firmware tree.
+// A is nil if the value only exists in the second dump, B is nil if it only
+// exists in the first one.
+type JSONDifference struct {
+	Path string
+	A, B interface{}
+}
+
+func (d JSONDifference) String() string {
+	switch {
+	case d.A == nil:
+		return fmt.Sprintf("+ %s: %s", d.Path, jsonString(d.B))
+	case d.B == nil:
+		return fmt.Sprintf("- %s: %s", d.Path, jsonString(d.A))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", d.Path, jsonString(d.A), jsonString(d.B))
+}
+
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// DiffJSON compares two firmware trees dumped as JSON, either by the json
+// visitor or by uefi.MarshalFirmware (e.g. the summary.json of an extracted
+// image). The comparison is semantic: the formatting and the order of the
+// object keys do not matter, and the files and firmware volumes are matched
+// by GUID rather than by position when their GUIDs are unique. The
+// differences are sorted by path.
+func DiffJSON(a, b []byte) ([]JSONDifference, error) {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return nil, fmt.Errorf("unable to parse the first JSON: %v", err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return nil, fmt.Errorf("unable to parse the second JSON: %v", err)
+	}
+
+	var diffs []JSONDifference
+	diffJSONValues("", unwrapFirmwareJSON(va), unwrapFirmwareJSON(vb), &diffs)
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+// unwrapFirmwareJSON strips the type wrapper added by uefi.MarshalFirmware,
+// so both kinds of dumps can be compared with each other.
+func unwrapFirmwareJSON(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 2 {
+		return v
+	}
+	if _, ok := m["FType"]; !ok {
+		return v
+	}
+	if e, ok := m["FirmwareElement"]; ok {
+		return e
+	}
+	return v
+}
+
+func diffJSONValues(path string, a, b interface{}, diffs *[]JSONDifference) {
+	a, b = unwrapFirmwareJSON(a), unwrapFirmwareJSON(b)
+	switch ta := a.(type) {
+	case map[string]interface{}:
+		if tb, ok := b.(map[string]interface{}); ok {
+			diffJSONObjects(path, ta, tb, diffs)
+			return
+		}
+	case []interface{}:
+		if tb, ok := b.([]interface{}); ok {
+			diffJSONArrays(path, ta, tb, diffs)
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, JSONDifference{Path: path, A: a, B: b})
+	}
+}
+
+func diffJSONObjects(path string, a, b map[string]interface{}, diffs *[]JSONDifference) {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		va, okA := a[k]
+		vb, okB := b[k]
+		switch {
+		case !okA:
+			*diffs = append(*diffs, JSONDifference{Path: p, B: vb})
+		case !okB:
+			*diffs = append(*diffs, JSONDifference{Path: p, A: va})
+		default:
+			diffJSONValues(p, va, vb, diffs)
+		}
+	}
+}
+
+func diffJSONArrays(path string, a, b []interface{}, diffs *[]JSONDifference) {
+	idsA, okA := jsonIdentities(a)
+	idsB, okB := jsonIdentities(b)
+	if !okA || !okB {
+		// Compare by position.
+		for i := 0; i < len(a) || i < len(b); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(a):
+				*diffs = append(*diffs, JSONDifference{Path: p, B: b[i]})
+			case i >= len(b):
+				*diffs = append(*diffs, JSONDifference{Path: p, A: a[i]})
+			default:
+				diffJSONValues(p, a[i], b[i], diffs)
+			}
+		}
+		return
+	}
+
+	// Compare by identity.
+	for id, i := range idsA {
+		p := fmt.Sprintf("%s[%s]", path, id)
+		if j, ok := idsB[id]; ok {
+			diffJSONValues(p, a[i], b[j], diffs)
+		} else {
+			*diffs = append(*diffs, JSONDifference{Path: p, A: a[i]})
+		}
+	}
+	for id, j := range idsB {
+		if _, ok := idsA[id]; !ok {
+			p := fmt.Sprintf("%s[%s]", path, id)
+			*diffs = append(*diffs, JSONDifference{Path: p, B: b[j]})
+		}
+	}
+}
+
+// jsonIdentities maps the identity of every element of the array to its
+// index. It returns false if any element has no identity or if an identity
+// is not unique, e.g. because of pad files.
+func jsonIdentities(arr []interface{}) (map[string]int, bool) {
+	ids := map[string]int{}
+	for i, v := range arr {
+		id := jsonIdentity(v)
+		if id == "" {
+			return nil, false
+		}
+		if _, ok := ids[id]; ok {
+			return nil, false
+		}
+		ids[id] = i
+	}
+	return ids, true
+}
+
+// jsonIdentity returns the GUID of a file or a firmware volume, or an empty
+// string for any other value.
+func jsonIdentity(v interface{}) string {
+	m, ok := unwrapFirmwareJSON(v).(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if h, ok := m["Header"].(map[string]interface{}); ok {
+		if g := jsonGUID(h["GUID"]); g != "" {
+			return g
+		}
+	}
+	return jsonGUID(m["FVName"])
+}
+
+func jsonGUID(v interface{}) string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	s, _ := m["GUID"].(string)
+	return strings.ToUpper(s)
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestDiffJSON(t *testing.T) {
+	f := parseImage(t)
+	a, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same tree with another formatting and wrapped by MarshalFirmware.
+	same, err := uefi.MarshalFirmware(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := DiffJSON(a, same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("got differences between identical dumps: %v", diffs)
+	}
+
+	// Rename the UI section of the DXE core.
+	files := find(t, f, dxeCoreGUID)
+	if len(files) != 1 {
+		t.Fatalf("got %d DXE core files; expected 1", len(files))
+	}
+	var ui *uefi.Section
+	for _, s := range files[0].(*uefi.File).Sections {
+		if s.Header.Type == uefi.SectionTypeUserInterface {
+			ui = s
+		}
+	}
+	if ui == nil {
+		t.Fatal("no UI section in the DXE core")
+	}
+	oldName := ui.Name
+	ui.Name = "RenamedDxeCore"
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err = DiffJSON(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("got %d differences; expected 1: %v", len(diffs), diffs)
+	}
+	d := diffs[0]
+	if !strings.Contains(d.Path, dxeCoreGUID.String()) || !strings.HasSuffix(d.Path, ".Name") {
+		t.Errorf("got path %q; expected the name of a section of %v", d.Path, dxeCoreGUID)
+	}
+	if d.A != oldName || d.B != "RenamedDxeCore" {
+		t.Errorf("got %v -> %v; expected %q -> %q", d.A, d.B, oldName, "RenamedDxeCore")
+	}
+}
+
+func TestDiffJSONFormatting(t *testing.T) {
+	a := []byte(`{"Files": [{"Header": {"GUID": {"GUID": "D6A2CB7F-6A18-4E2F-B43B-9920A733700A"}}, "Size": 1},
+		{"Header": {"GUID": {"GUID": "DF1CCEF6-F301-4A63-9661-FC6030DCC880"}}, "Size": 2}], "Length": 3}`)
+	b := []byte(`{"Length":3,"Files":[{"Size":2,"Header":{"GUID":{"GUID":"DF1CCEF6-F301-4A63-9661-FC6030DCC880"}}},` +
+		`{"Size":1,"Header":{"GUID":{"GUID":"D6A2CB7F-6A18-4E2F-B43B-9920A733700A"}}}]}`)
+	diffs, err := DiffJSON(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("got differences between reordered dumps: %v", diffs)
+	}
+}
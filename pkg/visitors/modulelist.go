@@ -0,0 +1,209 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// executableFileTypes are the file types listed by ModuleList.
+var executableFileTypes = map[uefi.FVFileType]bool{
+	uefi.FVFileTypeSECCore:            true,
+	uefi.FVFileTypePEICore:            true,
+	uefi.FVFileTypeDXECore:            true,
+	uefi.FVFileTypePEIM:               true,
+	uefi.FVFileTypeDriver:             true,
+	uefi.FVFileTypeCombinedPEIMDriver: true,
+	uefi.FVFileTypeApplication:        true,
+	uefi.FVFileTypeSMM:                true,
+	uefi.FVFileTypeCombinedSMMDXE:     true,
+	uefi.FVFileTypeSMMCore:            true,
+	uefi.FVFileTypeSMMStandalone:      true,
+	uefi.FVFileTypeSMMCoreStandalone:  true,
+}
+
+// machineTypes maps the PE/TE machine field to the UEFI architecture name.
+var machineTypes = map[uint16]string{
+	0x014c: "IA32",
+	0x0200: "IPF",
+	0x0ebc: "EBC",
+	0x8664: "X64",
+	0x01c2: "ARM",
+	0xaa64: "AARCH64",
+	0x5064: "RISCV64",
+	0x6264: "LOONGARCH64",
+}
+
+// Module describes a single executable file found in the image.
+type Module struct {
+	GUID guid.GUID
+	// Type is the file type without the EFI_FV_FILETYPE_ prefix, e.g. PEIM.
+	Type string
+	// Name comes from the user interface section.
+	Name string `json:",omitempty"`
+	// Format is either PE32 or TE.
+	Format string `json:",omitempty"`
+	Arch   string `json:",omitempty"`
+	// DepEx is the decoded PEI, DXE or MM dependency expression.
+	DepEx []uefi.DepExOp `json:",omitempty"`
+}
+
+// ModuleList lists all executable modules with their dependencies.
+type ModuleList struct {
+	// Optionally write the result to W, as JSON if JSON is set or as a
+	// table otherwise.
+	W    io.Writer `json:"-"`
+	JSON bool      `json:"-"`
+
+	// Output
+	Modules []*Module
+
+	cur *Module
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *ModuleList) Run(f uefi.Firmware) error {
+	v.Modules = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	if v.JSON {
+		b, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(v.W, string(b))
+		return err
+	}
+
+	w := tabwriter.NewWriter(v.W, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "GUID\tType\tName\tArch\tDepEx\n")
+	for _, m := range v.Modules {
+		arch := m.Arch
+		if m.Format != "" {
+			arch = fmt.Sprintf("%s (%s)", m.Arch, m.Format)
+		}
+		fmt.Fprintf(w, "%v\t%s\t%s\t%s\t%s\n", m.GUID, m.Type, m.Name, arch, depExString(m.DepEx))
+	}
+	return w.Flush()
+}
+
+// Visit applies the ModuleList visitor to any Firmware type.
+func (v *ModuleList) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		if !executableFileTypes[f.Header.Type] {
+			return f.ApplyChildren(v)
+		}
+		prev := v.cur
+		defer func() { v.cur = prev }()
+		v.cur = &Module{
+			GUID: f.Header.GUID,
+			Type: strings.TrimPrefix(f.Type, "EFI_FV_FILETYPE_"),
+		}
+		v.Modules = append(v.Modules, v.cur)
+		if len(f.Sections) == 0 {
+			// Sections of some file types (e.g. PEIMs) are not parsed
+			// by default, so parse them here without modifying the file.
+			return visitRawSections(f, v)
+		}
+		return f.ApplyChildren(v)
+
+	case *uefi.Section:
+		if v.cur == nil {
+			return f.ApplyChildren(v)
+		}
+		switch f.Header.Type {
+		case uefi.SectionTypeUserInterface:
+			v.cur.Name = f.Name
+		case uefi.SectionTypeDXEDepEx, uefi.SectionTypePEIDepEx, uefi.SectionMMDepEx:
+			v.cur.DepEx = f.DepEx
+		case uefi.SectionTypePE32, uefi.SectionTypeTE:
+			if data := f.Data(); len(data) > 0 {
+				v.cur.Format, v.cur.Arch = imageArch(data)
+			}
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+// visitRawSections parses the sections of a file whose sections were not
+// parsed and applies the visitor to them without modifying the file.
+func visitRawSections(f *uefi.File, v uefi.Visitor) error {
+	buf := f.Buf()
+	for i, offset := 0, f.DataOffset; offset < uint64(len(buf)); i++ {
+		s, err := uefi.NewSection(buf[offset:], i)
+		if err != nil || s.Header.ExtendedSize == 0 {
+			// Not every file has sections, nothing more to report.
+			return nil
+		}
+		if err := s.Apply(v); err != nil {
+			return err
+		}
+		offset = uefi.Align4(offset + uint64(s.Header.ExtendedSize))
+	}
+	return nil
+}
+
+// imageArch returns the format (PE32 or TE) and the architecture of an executable image.
+func imageArch(b []byte) (string, string) {
+	var format string
+	var machine uint16
+	switch {
+	case len(b) >= 4 && string(b[:2]) == "VZ":
+		format = "TE"
+		machine = binary.LittleEndian.Uint16(b[2:])
+	case len(b) >= 0x40 && string(b[:2]) == "MZ":
+		peOffset := uint64(binary.LittleEndian.Uint32(b[0x3c:]))
+		if peOffset+6 > uint64(len(b)) || string(b[peOffset:peOffset+4]) != "PE\x00\x00" {
+			return "", ""
+		}
+		format = "PE32"
+		machine = binary.LittleEndian.Uint16(b[peOffset+4:])
+	default:
+		return "", ""
+	}
+	if arch, ok := machineTypes[machine]; ok {
+		return format, arch
+	}
+	return format, fmt.Sprintf("UNKNOWN (%#x)", machine)
+}
+
+func depExString(depEx []uefi.DepExOp) string {
+	ops := make([]string, 0, len(depEx))
+	for _, op := range depEx {
+		if op.GUID != nil {
+			ops = append(ops, fmt.Sprintf("%s %v", op.OpCode, op.GUID))
+		} else {
+			ops = append(ops, string(op.OpCode))
+		}
+	}
+	return strings.Join(ops, " ")
+}
+
+func init() {
+	RegisterCLI("modules", "list executable modules with their type, name, architecture and depex", 0, func(args []string) (uefi.Visitor, error) {
+		return &ModuleList{W: os.Stdout}, nil
+	})
+	RegisterCLI("modules-json", "list executable modules as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &ModuleList{W: os.Stdout, JSON: true}, nil
+	})
+}
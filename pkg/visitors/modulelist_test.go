@@ -0,0 +1,98 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestModuleList(t *testing.T) {
+	f := parseImage(t)
+
+	l := &ModuleList{}
+	if err := l.Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	var peim, dxe *Module
+	for _, m := range l.Modules {
+		switch {
+		case m.Type == "PEIM" && peim == nil:
+			peim = m
+		case m.GUID == *dxeCoreGUID:
+			dxe = m
+		}
+	}
+	if peim == nil {
+		t.Fatal("no PEIM found")
+	}
+	if dxe == nil {
+		t.Fatal("DxeCore not found")
+	}
+
+	if peim.Name == "" {
+		t.Errorf("PEIM %v has no name", peim.GUID)
+	}
+	if peim.Arch == "" || peim.Format == "" {
+		t.Errorf("PEIM %v has no architecture or format: %q %q", peim.GUID, peim.Arch, peim.Format)
+	}
+	if dxe.Type != "DXE_CORE" {
+		t.Errorf("DxeCore type: got %q, expected DXE_CORE", dxe.Type)
+	}
+	if dxe.Name != "DxeCore" {
+		t.Errorf("DxeCore name: got %q, expected DxeCore", dxe.Name)
+	}
+	if dxe.Arch != "X64" || dxe.Format != "PE32" {
+		t.Errorf("DxeCore arch: got %q (%q), expected X64 (PE32)", dxe.Arch, dxe.Format)
+	}
+
+	var hasDepEx bool
+	for _, m := range l.Modules {
+		if m.Type == "DRIVER" && len(m.DepEx) > 0 {
+			hasDepEx = true
+			break
+		}
+	}
+	if !hasDepEx {
+		t.Errorf("no DXE driver with a dependency expression found")
+	}
+}
+
+func TestModuleListOutput(t *testing.T) {
+	f := parseImage(t)
+
+	var b bytes.Buffer
+	if err := (&ModuleList{W: &b}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "DxeCore") {
+		t.Errorf("table output does not contain DxeCore:\n%s", b.String())
+	}
+
+	b.Reset()
+	if err := (&ModuleList{W: &b, JSON: true}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+	var l ModuleList
+	if err := json.Unmarshal(b.Bytes(), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Modules) == 0 {
+		t.Errorf("no modules in JSON output")
+	}
+}
+
+func TestImageArch(t *testing.T) {
+	te := []byte{'V', 'Z', 0x4c, 0x01}
+	if format, arch := imageArch(te); format != "TE" || arch != "IA32" {
+		t.Errorf("got %q %q, expected TE IA32", format, arch)
+	}
+	if format, arch := imageArch(make([]byte, 16)); format != "" || arch != "" {
+		t.Errorf("got %q %q for a non-executable buffer", format, arch)
+	}
+}
@@ -56,6 +56,7 @@ func compactNVarStore(s *uefi.NVarStore) error {
 	// replace entries and GUID store
 	s.Entries = newEntries
 	s.GUIDStore = guidStore
+	uefi.MarkDirty(s)
 
 	// Assemble the tree just to make sure things are right
 	// It will do the mandatory second Assemble of NVar and update the Offsets
@@ -63,7 +64,10 @@ func compactNVarStore(s *uefi.NVarStore) error {
 	return a.Run(s)
 }
 
-// NVRamCompact compact nvram content by removing old version of variables
+// NVRamCompact compact nvram content by removing old version of variables.
+// Each NVAR store is rewritten with a single full entry holding the latest
+// value of each variable, the stale links are dropped, FreeSpaceOffset is
+// updated and the freed space is erased with Attributes.ErasePolarity.
 type NVRamCompact struct {
 }
 
@@ -55,3 +55,73 @@ func TestNVRamCompact(t *testing.T) {
 	}
 
 }
+
+func TestNVRamCompactStore(t *testing.T) {
+	path := "../../integration/roms/nvartest/"
+
+	pd := ParseDir{BasePath: path}
+	parsedRoot, err := pd.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Assemble{}
+	if err = a.Run(parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	compact := &NVRamCompact{}
+	if err = compact.Run(parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	find := &Find{Predicate: func(f uefi.Firmware) bool {
+		_, ok := f.(*uefi.NVarStore)
+		return ok
+	}}
+	if err = find.Run(parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if len(find.Matches) == 0 {
+		t.Fatal("no NVAR store found")
+	}
+	for _, m := range find.Matches {
+		s := m.(*uefi.NVarStore)
+
+		// Only the final values are left, as full entries.
+		var size uint64
+		for _, v := range s.Entries {
+			if v.Type != uefi.FullNVarEntry {
+				t.Errorf("NVAR %s is of type %v, want %v", v.Name, v.Type, uefi.FullNVarEntry)
+			}
+			if v.NextOffset != 0 {
+				t.Errorf("NVAR %s still links to %#x", v.Name, v.NextOffset)
+			}
+			size += uint64(len(v.Buf()))
+		}
+		if s.FreeSpaceOffset != size {
+			t.Errorf("free space offset is %#x, want %#x", s.FreeSpaceOffset, size)
+		}
+
+		// The freed space is erased.
+		buf := s.Buf()
+		for i := s.FreeSpaceOffset; i < s.GUIDStoreOffset; i++ {
+			if buf[i] != uefi.Attributes.ErasePolarity {
+				t.Fatalf("byte %#x of the free space is %#x, want %#x", i, buf[i], uefi.Attributes.ErasePolarity)
+			}
+		}
+
+		// The compacted store parses back to the same entries.
+		parsed, err := uefi.NewNVarStore(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed.Entries) != len(s.Entries) {
+			t.Fatalf("parsed %d NVAR from the compacted store, want %d", len(parsed.Entries), len(s.Entries))
+		}
+		for i, v := range parsed.Entries {
+			if v.Name != s.Entries[i].Name || v.GUID != s.Entries[i].GUID {
+				t.Errorf("NVAR %d is %v:%s, want %v:%s", i, v.GUID, v.Name, s.Entries[i].GUID, s.Entries[i].Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// FileOverlap describes a file whose declared size does not fit in the
+// layout of its firmware volume.
+type FileOverlap struct {
+	// FV is the name of the firmware volume, FVOffset its offset in the
+	// BIOS region (0 for nested volumes).
+	FV       string
+	FVOffset uint64
+	File     guid.GUID
+	// Offset and End delimit the file as declared by its header, relative
+	// to the start of the volume.
+	Offset uint64
+	End    uint64
+	// Other is the file overlapped by this one, if any.
+	Other   *guid.GUID `json:",omitempty"`
+	Problem string
+}
+
+// OverlapAudit checks that the files of all firmware volumes, including nested
+// ones, fit in their volume. The file headers are read from the buffer of each
+// volume, following the declared sizes like the parser does, so the volume is
+// checked as it is stored. A file is reported when its declared size is
+// smaller than its header, extends over the next file or past the end of the
+// volume, or does not end at another file header or at the free space. Files
+// found in the free space, e.g. behind an erased header, are reported too. Run
+// returns an error at the end if any file does not fit.
+//
+// The parser rejects volumes whose files do not fit, so an image holding such
+// a volume cannot be visited: use AuditFirmwareVolumes on its raw bytes.
+type OverlapAudit struct {
+	// Optionally write the overlaps as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	Overlaps []*FileOverlap
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *OverlapAudit) Run(f uefi.Firmware) error {
+	v.Overlaps = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W != nil {
+		b, err := json.MarshalIndent(v.Overlaps, "", "\t")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(v.W, string(b)); err != nil {
+			return err
+		}
+	}
+	if len(v.Overlaps) != 0 {
+		return fmt.Errorf("%d files do not fit in their firmware volume", len(v.Overlaps))
+	}
+	return nil
+}
+
+// Visit applies the OverlapAudit visitor to any Firmware type.
+func (v *OverlapAudit) Visit(f uefi.Firmware) error {
+	if fv, ok := f.(*uefi.FirmwareVolume); ok {
+		v.audit(fv)
+	}
+	return f.ApplyChildren(v)
+}
+
+func (v *OverlapAudit) audit(fv *uefi.FirmwareVolume) {
+	v.Overlaps = append(v.Overlaps, auditFileLayout(fv)...)
+}
+
+// AuditFirmwareVolumes checks the files of the top level firmware volumes
+// found in buf, e.g. an FV file or a BIOS region, like OverlapAudit does, but
+// without parsing the files, so that corrupt volumes can be checked too.
+func AuditFirmwareVolumes(buf []byte) ([]*FileOverlap, error) {
+	var (
+		overlaps []*FileOverlap
+		base     uint64
+	)
+	for {
+		offset := uefi.FindFirmwareVolumeOffset(buf)
+		if offset < 0 {
+			break
+		}
+		fv, err := uefi.ParseFirmwareVolumeHeader(buf[offset:], base+uint64(offset))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the firmware volume at %#x: %w", base+uint64(offset), err)
+		}
+		if fv.Length == 0 {
+			return nil, fmt.Errorf("firmware volume at %#x has length 0", base+uint64(offset))
+		}
+		overlaps = append(overlaps, auditFileLayout(fv)...)
+		base += uint64(offset) + fv.Length
+		buf = buf[uint64(offset)+fv.Length:]
+	}
+	return overlaps, nil
+}
+
+// rawFileHeader is a file header read from the buffer of a volume.
+type rawFileHeader struct {
+	GUID      guid.GUID
+	Size      uint64
+	HeaderLen uint64
+	// Free is set if the header marks the start of the free space.
+	Free bool
+	// Valid is set if the header checksum is correct.
+	Valid bool
+}
+
+func readFileHeader(buf []byte, offset uint64) (h rawFileHeader, ok bool) {
+	if offset+uefi.FileHeaderMinLength > uint64(len(buf)) {
+		return h, false
+	}
+	b := buf[offset:]
+	copy(h.GUID[:], b)
+	h.HeaderLen = uefi.FileHeaderMinLength
+	h.Size = uefi.Read3Size([3]uint8{b[0x14], b[0x15], b[0x16]})
+	if h.Size == 0xFFFFFF {
+		if offset+uefi.FileHeaderExtMinLength > uint64(len(buf)) {
+			return h, false
+		}
+		h.HeaderLen = uefi.FileHeaderExtMinLength
+		h.Size = binary.LittleEndian.Uint64(b[uefi.FileHeaderMinLength:])
+		if h.Size == math.MaxUint64 {
+			h.Free = true
+			return h, true
+		}
+	}
+	// The header checksum excludes IntegrityCheck.File and State.
+	h.Valid = uefi.Checksum8(b[:h.HeaderLen])-b[0x11]-b[0x17] == 0
+	return h, true
+}
+
+// fileEndsAt tells whether a file may end at offset of the volume: at its end,
+// at a valid file header or at the free space.
+func fileEndsAt(fv *uefi.FirmwareVolume, offset uint64) bool {
+	offset = uefi.Align8(offset)
+	if offset >= fv.Length-uefi.FileHeaderMinLength {
+		return offset <= fv.Length
+	}
+	h, ok := readFileHeader(fv.Buf(), offset)
+	return ok && (h.Free || h.Valid && h.Size >= h.HeaderLen)
+}
+
+// findFileHeader returns the offset of the first valid file header in
+// [from, to) which itself is followed by a file header, the free space or the
+// end of the volume, or 0 if there is none.
+func findFileHeader(fv *uefi.FirmwareVolume, from, to uint64) uint64 {
+	to = min(to, fv.Length-uefi.FileHeaderMinLength)
+	for offset := uefi.Align8(from); offset < to; offset += 8 {
+		h, ok := readFileHeader(fv.Buf(), offset)
+		if !ok || h.Free || !h.Valid || h.Size < h.HeaderLen {
+			continue
+		}
+		if end := offset + h.Size; end <= fv.Length && fileEndsAt(fv, end) {
+			return offset
+		}
+	}
+	return 0
+}
+
+// auditFileLayout walks the file headers stored in the buffer of the volume.
+func auditFileLayout(fv *uefi.FirmwareVolume) []*FileOverlap {
+	if fv.FFSVersion() == 0 || fv.Length < uefi.FileHeaderMinLength || uint64(len(fv.Buf())) < fv.Length {
+		return nil
+	}
+
+	var overlaps []*FileOverlap
+	report := func(h rawFileHeader, offset uint64, problem string, args ...interface{}) *FileOverlap {
+		overlap := &FileOverlap{
+			FV:       fv.String(),
+			FVOffset: fv.FVOffset,
+			File:     h.GUID,
+			Offset:   offset,
+			End:      offset + h.Size,
+			Problem:  fmt.Sprintf(problem, args...),
+		}
+		overlaps = append(overlaps, overlap)
+		return overlap
+	}
+
+	offset := uefi.Align8(fv.DataOffset)
+	for offset < fv.Length-uefi.FileHeaderMinLength {
+		h, ok := readFileHeader(fv.Buf(), offset)
+		if !ok {
+			break
+		}
+		if h.Free {
+			// Nothing but the free space may follow.
+			next := findFileHeader(fv, offset+8, fv.Length)
+			if next == 0 {
+				break
+			}
+			h, _ = readFileHeader(fv.Buf(), next)
+			report(h, next, "is stored in the free space at %#x", offset)
+			offset = next
+			continue
+		}
+		if !h.Valid {
+			report(h, offset, "has an invalid header checksum")
+			break
+		}
+		if h.Size < h.HeaderLen {
+			report(h, offset, "declared size %#x is smaller than the file header", h.Size)
+			break
+		}
+
+		end := offset + h.Size
+		if end <= fv.Length && fileEndsAt(fv, end) {
+			offset = uefi.Align8(end)
+			continue
+		}
+		if next := findFileHeader(fv, offset+h.HeaderLen, end); next != 0 {
+			other, _ := readFileHeader(fv.Buf(), next)
+			report(h, offset, "overlaps the next file at %#x", next).Other = &other.GUID
+			offset = next
+			continue
+		}
+		if end > fv.Length {
+			report(h, offset, "extends past the end of the volume at %#x", fv.Length)
+		} else {
+			report(h, offset, "is not followed by a file header or the free space at %#x", uefi.Align8(end))
+		}
+		break
+	}
+	return overlaps
+}
+
+func init() {
+	RegisterCLI("overlap-audit", "list the files which overlap each other or the free space of their firmware volume as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &OverlapAudit{W: os.Stdout}, nil
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// corruptFV writes a copy of the SEC FV of OVMF, changed by corrupt, to disk
+// and reads it back.
+func corruptFV(t *testing.T, corrupt func(buf []byte, fv *uefi.FirmwareVolume, offsets []uint64)) []byte {
+	t.Helper()
+	buf, err := os.ReadFile("../../integration/roms/ovmfSECFV.fv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fv, err := uefi.NewFirmwareVolume(buf, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fv.Files) < 3 {
+		t.Fatalf("expected at least 3 files in the SEC FV, got %d", len(fv.Files))
+	}
+	offsets := make([]uint64, len(fv.Files))
+	offset := fv.DataOffset
+	for i, f := range fv.Files {
+		offset = uefi.Align8(offset)
+		offsets[i] = offset
+		offset += uint64(len(f.Buf()))
+	}
+	corrupt(buf, fv, offsets)
+
+	path := filepath.Join(t.TempDir(), "corrupt.fv")
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buf, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// growFile adds n bytes to the declared size of the small file at offset and
+// fixes the header checksum.
+func growFile(t *testing.T, buf []byte, offset uint64, n uint64) {
+	t.Helper()
+	h := buf[offset : offset+uefi.FileHeaderMinLength]
+	size := uefi.Read3Size([3]uint8{h[0x14], h[0x15], h[0x16]}) + n
+	if size >= 0xFFFFFF {
+		t.Fatalf("file at %#x is too large", offset)
+	}
+	h[0x14], h[0x15], h[0x16] = uint8(size), uint8(size>>8), uint8(size>>16)
+	h[0x10] = 0
+	h[0x10] = -(uefi.Checksum8(h) - h[0x11] - h[0x17])
+}
+
+func TestOverlapAudit(t *testing.T) {
+	if err := (&OverlapAudit{}).Run(parseImage(t)); err != nil {
+		t.Fatalf("unexpected overlaps in OVMF: %v", err)
+	}
+
+	var fv *uefi.FirmwareVolume
+	clean := corruptFV(t, func(_ []byte, parsed *uefi.FirmwareVolume, _ []uint64) {
+		fv = parsed
+	})
+	if overlaps, err := AuditFirmwareVolumes(clean); err != nil || len(overlaps) != 0 {
+		t.Fatalf("unexpected overlaps in the SEC FV: %+v, %v", overlaps, err)
+	}
+	last := len(fv.Files) - 1
+
+	for _, tt := range []struct {
+		name    string
+		corrupt func(buf []byte, offsets []uint64)
+		parses  bool
+		file    int
+		other   int
+	}{
+		{
+			name: "overlap",
+			corrupt: func(buf []byte, offsets []uint64) {
+				growFile(t, buf, offsets[1], 0x100)
+			},
+			file:  1,
+			other: 2,
+		},
+		{
+			name: "past end",
+			corrupt: func(buf []byte, offsets []uint64) {
+				growFile(t, buf, offsets[last], fv.Length-offsets[last])
+			},
+			file:  last,
+			other: -1,
+		},
+		{
+			// Erasing the header of the second file turns it into free
+			// space, which must not hold the files behind it.
+			name: "free space",
+			corrupt: func(buf []byte, offsets []uint64) {
+				copy(buf[offsets[1]:], bytes.Repeat([]byte{0xFF}, uefi.FileHeaderMinLength))
+			},
+			parses: true,
+			file:   2,
+			other:  -1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := corruptFV(t, func(buf []byte, _ *uefi.FirmwareVolume, offsets []uint64) {
+				tt.corrupt(buf, offsets)
+			})
+			overlaps, err := AuditFirmwareVolumes(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(overlaps) != 1 {
+				t.Fatalf("got %d overlaps, want 1: %+v", len(overlaps), overlaps)
+			}
+			o := overlaps[0]
+			if o.File != fv.Files[tt.file].Header.GUID {
+				t.Errorf("got file %v, want %v", o.File, fv.Files[tt.file].Header.GUID)
+			}
+			switch {
+			case tt.other < 0 && o.Other != nil:
+				t.Errorf("unexpected overlapped file %v", *o.Other)
+			case tt.other >= 0 && (o.Other == nil || *o.Other != fv.Files[tt.other].Header.GUID):
+				t.Errorf("got overlapped file %v, want %v", o.Other, fv.Files[tt.other].Header.GUID)
+			}
+
+			parsed, err := uefi.NewFirmwareVolume(buf, 0, false)
+			if !tt.parses {
+				if err == nil {
+					t.Errorf("the corrupt FV parses")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The visitor reports the same on the parsed volume.
+			var out bytes.Buffer
+			v := &OverlapAudit{W: &out}
+			if err := v.Run(parsed); err == nil {
+				t.Errorf("overlaps are not reported")
+			}
+			var reported []*FileOverlap
+			if err := json.Unmarshal(out.Bytes(), &reported); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if len(reported) != 1 || reported[0].File != o.File {
+				t.Errorf("unexpected overlaps in JSON: %+v", reported)
+			}
+		})
+	}
+}
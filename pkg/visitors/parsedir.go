@@ -80,6 +80,9 @@ func (v *ParseDir) Visit(f uefi.Firmware) error {
 	case *uefi.MERegion:
 		fBuf, err = v.readBuf(f.ExtractPath)
 
+	case *uefi.ECRegion:
+		fBuf, err = v.readBuf(f.ExtractPath)
+
 	case *uefi.RawRegion:
 		fBuf, err = v.readBuf(f.ExtractPath)
 
@@ -99,6 +99,7 @@ func (v *Remove) Visit(f uefi.Firmware) error {
 					} else {
 						f.Files = append(f.Files[:i], f.Files[i+1:]...)
 					}
+					uefi.MarkDirty(f)
 					v.printf("Remove: %d files now\n", len(f.Files))
 
 					// Creates a stack of undoes in case there are multiple FVs.
@@ -166,6 +166,7 @@ func repackFV(fv *uefi.FirmwareVolume) error {
 
 	// Set new file as the only firmware file in the original fv.
 	fv.Files = append([]*uefi.File{}, file)
+	uefi.MarkDirty(fv)
 	return nil
 }
 
@@ -68,6 +68,7 @@ func (v *ReplacePE32) Visit(f uefi.Firmware) error {
 			if err := f.GenSecHeader(); err != nil {
 				return err
 			}
+			uefi.MarkDirty(f)
 		}
 		return f.ApplyChildren(v)
 
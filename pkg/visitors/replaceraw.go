@@ -0,0 +1,123 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// ReplaceRaw replaces the content of the raw section of the file matching
+// Predicate with NewContent, e.g. to swap a logo, an ACPI table or a
+// configuration blob. The section is either a raw section or a freeform
+// subtype GUID section, whose subtype GUID is kept. The enclosing sections
+// and volumes are rebuilt by Assemble.
+type ReplaceRaw struct {
+	// Input
+	Predicate  func(f uefi.Firmware) bool
+	NewContent []byte
+
+	// Output
+	Matches []uefi.Firmware
+
+	// Private
+	sections []*uefi.Section
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *ReplaceRaw) Run(f uefi.Firmware) error {
+	// Run "find" to generate a list of matches to replace.
+	find := Find{
+		Predicate: v.Predicate,
+	}
+	if err := find.Run(f); err != nil {
+		return err
+	}
+
+	v.Matches = find.Matches
+	if len(find.Matches) == 0 {
+		return errors.New("no matches found for replacement")
+	}
+	if len(find.Matches) > 1 {
+		return errors.New("multiple matches found! There can be only one. Use find to list all matches")
+	}
+
+	v.sections = nil
+	if err := v.Matches[0].Apply(v); err != nil {
+		return err
+	}
+	if len(v.sections) != 1 {
+		return fmt.Errorf("matching file has %d raw sections, expected exactly one", len(v.sections))
+	}
+	return replaceRawContent(v.sections[0], v.NewContent)
+}
+
+// Visit applies the ReplaceRaw visitor to any Firmware type.
+func (v *ReplaceRaw) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+
+	case *uefi.File:
+		return f.ApplyChildren(v)
+
+	case *uefi.Section:
+		switch f.Header.Type {
+		case uefi.SectionTypeRaw, uefi.SectionTypeFreeformSubtypeGUID:
+			v.sections = append(v.sections, f)
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		// Must be applied to a File to have any effect.
+		return nil
+	}
+}
+
+// replaceRawContent sets the data of the raw or freeform subtype GUID
+// section s to content and regenerates its header.
+func replaceRawContent(s *uefi.Section, content []byte) error {
+	var data []byte
+	if s.Header.Type == uefi.SectionTypeFreeformSubtypeGUID {
+		if len(s.Data()) < guid.Size {
+			return fmt.Errorf("freeform section too short for its subtype GUID: %d bytes", len(s.Buf()))
+		}
+		data = append(data, s.Data()[:guid.Size]...)
+	}
+	data = append(data, content...)
+
+	s.SetBuf(data)
+	s.Encapsulated = nil // Should already be empty
+	if err := s.GenSecHeader(); err != nil {
+		return err
+	}
+	if s.Header.Type == uefi.SectionTypeRaw {
+		s.RawContent = uefi.SniffRawContent(content)
+	}
+	uefi.MarkDirty(s)
+	return nil
+}
+
+func init() {
+	RegisterCLI("replace_raw", "replace the content of the raw section of a file given a GUID and new file", 2, func(args []string) (uefi.Visitor, error) {
+		pred, err := FindFilePredicate(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		filename := args[1]
+		newContent, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ReplaceRaw{
+			Predicate:  pred,
+			NewContent: newContent,
+		}, nil
+	})
+}
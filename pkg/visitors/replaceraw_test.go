@@ -0,0 +1,105 @@
+// Copyright 2024 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestReplaceRaw(t *testing.T) {
+	f := parseImage(t)
+
+	// The PEI apriori file holds a raw section, within a compressed volume.
+	content := []byte("fiano replaced raw section content")
+	replace := &ReplaceRaw{
+		Predicate:  FindFileGUIDPredicate(PEIAprioriGUID),
+		NewContent: content,
+	}
+	if err := replace.Run(f); err != nil {
+		t.Fatal(err)
+	}
+	if len(replace.Matches) != 1 {
+		t.Fatalf("got %d matches; expected 1", len(replace.Matches))
+	}
+	if err := (&Assemble{}).Run(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parse the assembled image and make sure the section holds the new content.
+	reparsed, err := uefi.Parse(f.Buf())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := find(t, reparsed, &PEIAprioriGUID)
+	if len(results) != 1 {
+		t.Fatalf("got %d matches; expected 1", len(results))
+	}
+	sections := results[0].(*uefi.File).SectionsOfType(uefi.SectionTypeRaw, true)
+	if len(sections) != 1 {
+		t.Fatalf("got %d raw sections; expected 1", len(sections))
+	}
+	if got := sections[0].Buf()[4:]; !bytes.Equal(got, content) {
+		t.Errorf("got raw section content %q; expected %q", got, content)
+	}
+}
+
+func TestReplaceRawFreeform(t *testing.T) {
+	subtype := guid.MustParse("D3E5A3C0-2B2E-4B44-9C34-1C2C9E8C3A11")
+	s, err := uefi.CreateSection(uefi.SectionTypeFreeformSubtypeGUID, append(subtype[:], "old"...), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.GenSecHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replaceRawContent(s, []byte("new content")); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]byte{}, subtype[:]...), "new content"...)
+	want = append([]byte{byte(4 + len(want)), 0, 0, byte(uefi.SectionTypeFreeformSubtypeGUID)}, want...)
+	if got := s.Buf(); !bytes.Equal(got, want) {
+		t.Errorf("got %x; expected %x", got, want)
+	}
+}
+
+func TestReplaceRawErrors(t *testing.T) {
+	f := parseImage(t)
+
+	var tests = []struct {
+		name  string
+		match string
+		err   string
+	}{
+		{"No Matches", "no-match-string",
+			"no matches found for replacement"},
+		{"Multiple Matches", ".*",
+			"multiple matches found! There can be only one. Use find to list all matches"},
+		{"No Raw Section", dxeCoreGUID.String(),
+			"matching file has 0 raw sections, expected exactly one"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pred, err := FindFilePredicate(test.match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replace := &ReplaceRaw{
+				Predicate:  pred,
+				NewContent: []byte("banana"),
+			}
+			err = replace.Run(f)
+			if err == nil {
+				t.Fatalf("Expected Error (%v), got nil", test.err)
+			} else if err.Error() != test.err {
+				t.Fatalf("Mismatched Error: Expected %v, got %v", test.err, err.Error())
+			}
+		})
+	}
+}
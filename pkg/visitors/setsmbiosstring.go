@@ -0,0 +1,90 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// SetSMBIOSString sets a string field of the SMBIOS structures of type Type
+// in all raw sections holding SMBIOS tables. The sections are resized to fit
+// the new string, the image has to be assembled again afterwards.
+type SetSMBIOSString struct {
+	// Input
+	Type  uint8
+	Field string
+	Value string
+
+	// Output
+	Matches []*uefi.Section
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *SetSMBIOSString) Run(f uefi.Firmware) error {
+	v.Matches = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	if len(v.Matches) == 0 {
+		return fmt.Errorf("no SMBIOS structure of type %d found", v.Type)
+	}
+	return nil
+}
+
+// Visit applies the SetSMBIOSString visitor to any Firmware type.
+func (v *SetSMBIOSString) Visit(f uefi.Firmware) error {
+	s, ok := f.(*uefi.Section)
+	if !ok || s.RawContent != uefi.RawContentSMBIOS {
+		return f.ApplyChildren(v)
+	}
+
+	structures, err := s.SMBIOSTables()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, st := range structures {
+		if st.Type != v.Type {
+			continue
+		}
+		if err := st.SetField(v.Field, v.Value); err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	v.Matches = append(v.Matches, s)
+	return s.SetSMBIOSTables(structures)
+}
+
+func init() {
+	RegisterCLI("set-smbios-string", "set a string field of the embedded SMBIOS structures of a type, args: type field value", 3, func(args []string) (uefi.Visitor, error) {
+		t, err := strconv.ParseUint(args[0], 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMBIOS structure type %q: %v", args[0], err)
+		}
+		fields := uefi.SMBIOSFieldNames(uint8(t))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("SMBIOS structure type %d has no known string fields", t)
+		}
+		for _, name := range fields {
+			if name == args[1] {
+				return &SetSMBIOSString{
+					Type:  uint8(t),
+					Field: args[1],
+					Value: args[2],
+				}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown field %q of SMBIOS structure type %d, known fields: %s",
+			args[1], t, strings.Join(fields, ", "))
+	})
+}
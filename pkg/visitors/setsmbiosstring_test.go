@@ -0,0 +1,52 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestSetSMBIOSString(t *testing.T) {
+	// System information with manufacturer and product name, followed by
+	// the end-of-table structure.
+	tables := []byte{1, 0x1B, 0, 0, 1, 2, 0, 0}
+	tables = append(tables, make([]byte, 16+3)...)
+	tables = append(tables, "ACME\x00Board\x00\x00"...)
+	tables = append(tables, 127, 4, 1, 0, 0, 0)
+	s, err := uefi.NewSection(append([]byte{byte(len(tables) + 4), 0, 0, byte(uefi.SectionTypeRaw)}, tables...), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &SetSMBIOSString{Type: 1, Field: "ProductName", Value: "Custom board"}
+	if err := v.Run(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(v.Matches))
+	}
+	structures, err := s.SMBIOSTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := structures[0].Fields()["ProductName"]; got != "Custom board" {
+		t.Errorf("expected product name %q, got %q", "Custom board", got)
+	}
+
+	v = &SetSMBIOSString{Type: 2, Field: "Product", Value: "x"}
+	if err := v.Run(s); err == nil {
+		t.Errorf("expected an error for a missing structure type")
+	}
+}
+
+func TestSetSMBIOSStringOVMF(t *testing.T) {
+	f := parseImage(t)
+	v := &SetSMBIOSString{Type: 1, Field: "ProductName", Value: "x"}
+	if err := v.Run(f); err == nil {
+		t.Errorf("expected an error as OVMF has no embedded SMBIOS tables")
+	}
+}
@@ -69,6 +69,11 @@ func (v *Table) Visit(f uefi.Firmware) error {
 		return v.printFirmware(f, "ME", "", "", offset, offset)
 	case *uefi.MEFPT:
 		return v.printFirmware(f, "$FPT", "", "", v.offset, 0)
+	case *uefi.ECRegion:
+		if f.FRegion != nil {
+			offset = uint64(f.FRegion.BaseOffset())
+		}
+		return v.printFirmware(f, "EC", f.Version, "", offset, offset)
 	case *uefi.RawRegion:
 		if f.FRegion != nil {
 			offset = uint64(f.FRegion.BaseOffset())
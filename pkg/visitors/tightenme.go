@@ -101,6 +101,8 @@ func (v *TightenME) process() error {
 		return fmt.Errorf("could not create BIOS Padding: %v", err)
 	}
 	v.br.Elements = append([]*uefi.TypedFirmware{uefi.MakeTyped(bp)}, v.br.Elements...)
+	uefi.MarkDirty(v.mer)
+	uefi.MarkDirty(v.br)
 	// Assemble will regenerate IFD so regions will be updated in the image
 
 	return nil
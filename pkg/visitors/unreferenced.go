@@ -0,0 +1,129 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// dispatchedFileTypes are the file types run by the PEI and DXE dispatchers.
+var dispatchedFileTypes = map[uefi.FVFileType]bool{
+	uefi.FVFileTypePEIM:               true,
+	uefi.FVFileTypeDriver:             true,
+	uefi.FVFileTypeCombinedPEIMDriver: true,
+	uefi.FVFileTypeSMM:                true,
+	uefi.FVFileTypeCombinedSMMDXE:     true,
+}
+
+// Unreferenced lists the PEI and DXE modules whose GUID is neither listed in
+// an apriori file nor used in the dependency expression of another module.
+// Dependency expressions mostly refer to protocols and PPIs rather than to
+// files, so this is only a heuristic: the reported modules are possibly
+// unreferenced and are candidates for a closer look, not for a blind removal.
+type Unreferenced struct {
+	// Optionally write the result as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	PossiblyUnreferenced []*Module
+
+	modules    []*Module
+	referenced map[guid.GUID]bool
+	cur        *Module
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *Unreferenced) Run(f uefi.Firmware) error {
+	v.PossiblyUnreferenced = nil
+	v.modules = nil
+	v.referenced = map[guid.GUID]bool{}
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+	for _, m := range v.modules {
+		if !v.referenced[m.GUID] {
+			v.PossiblyUnreferenced = append(v.PossiblyUnreferenced, m)
+		}
+	}
+
+	if v.W == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(v.W, string(b))
+	return err
+}
+
+// Visit applies the Unreferenced visitor to any Firmware type.
+func (v *Unreferenced) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		if f.Header.GUID == PEIAprioriGUID || f.Header.GUID == DXEAprioriGUID {
+			for _, s := range f.SectionsOfType(uefi.SectionTypeRaw, true) {
+				entries, err := decodeApriori(s)
+				if err != nil {
+					return err
+				}
+				for _, e := range entries {
+					v.referenced[e.GUID] = true
+				}
+			}
+			return nil
+		}
+		prev := v.cur
+		defer func() { v.cur = prev }()
+		v.cur = nil
+		if dispatchedFileTypes[f.Header.Type] {
+			v.cur = &Module{
+				GUID: f.Header.GUID,
+				Type: strings.TrimPrefix(f.Type, "EFI_FV_FILETYPE_"),
+			}
+			v.modules = append(v.modules, v.cur)
+		}
+		if len(f.Sections) == 0 {
+			return visitRawSections(f, v)
+		}
+		return f.ApplyChildren(v)
+
+	case *uefi.Section:
+		switch f.Header.Type {
+		case uefi.SectionTypeUserInterface:
+			if v.cur != nil {
+				v.cur.Name = f.Name
+			}
+		case uefi.SectionTypeDXEDepEx, uefi.SectionTypePEIDepEx, uefi.SectionMMDepEx:
+			if v.cur != nil {
+				v.cur.DepEx = f.DepEx
+			}
+			for _, op := range f.DepEx {
+				if op.GUID != nil {
+					v.referenced[*op.GUID] = true
+				}
+			}
+		}
+		return f.ApplyChildren(v)
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+func init() {
+	RegisterCLI("unreferenced", "list PEI and DXE modules possibly not referenced by an apriori file or a dependency expression", 0, func(args []string) (uefi.Visitor, error) {
+		return &Unreferenced{
+			W: os.Stdout,
+		}, nil
+	})
+}
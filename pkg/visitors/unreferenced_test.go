@@ -0,0 +1,63 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+var (
+	devicePathDxeGUID = guid.MustParse("9B680FCE-AD6B-4F3A-B60B-F59899003443")
+	dxeIplGUID        = guid.MustParse("86D70125-BAA3-4296-A62F-602BEBBB9081")
+)
+
+func unreferencedGUIDs(t *testing.T, f uefi.Firmware) map[guid.GUID]bool {
+	u := &Unreferenced{}
+	if err := u.Run(f); err != nil {
+		t.Fatal(err)
+	}
+	result := map[guid.GUID]bool{}
+	for _, m := range u.PossiblyUnreferenced {
+		result[m.GUID] = true
+	}
+	return result
+}
+
+func TestUnreferenced(t *testing.T) {
+	f := parseImage(t)
+
+	unreferenced := unreferencedGUIDs(t, f)
+	// DevicePathDxe is listed in the DXE apriori file.
+	if unreferenced[*devicePathDxeGUID] {
+		t.Errorf("DevicePathDxe is reported as unreferenced")
+	}
+	if !unreferenced[*dxeIplGUID] {
+		t.Errorf("DxeIpl is not reported as unreferenced")
+	}
+	if unreferenced[*dxeCoreGUID] {
+		t.Errorf("DxeCore is not dispatched and must not be reported")
+	}
+
+	// Reference DxeIpl from the dependency expression of another module.
+	var depEx *uefi.Section
+	for _, m := range find(t, f, devicePathDxeGUID) {
+		file := m.(*uefi.File)
+		if sections := file.SectionsOfType(uefi.SectionTypeDXEDepEx, true); len(sections) > 0 {
+			depEx = sections[0]
+		}
+	}
+	if depEx == nil {
+		t.Fatal("no DXE dependency expression found")
+	}
+	depEx.DepEx = append([]uefi.DepExOp{{OpCode: "PUSH", GUID: dxeIplGUID}}, depEx.DepEx...)
+
+	unreferenced = unreferencedGUIDs(t, f)
+	if unreferenced[*dxeIplGUID] {
+		t.Errorf("DxeIpl is reported as unreferenced although it is in a dependency expression")
+	}
+}
@@ -17,8 +17,7 @@ import (
 // Validate performs extra checks on the firmware image.
 type Validate struct {
 	// An optional Writer for writing errors when validation is complete.
-	// When the writer it set, Run will also call os.Exit(1) upon finding
-	// an error.
+	// When the writer is set, Run also returns an error upon finding one.
 	W io.Writer
 
 	// List of validation errors.
@@ -33,9 +32,9 @@ func (v *Validate) Run(f uefi.Firmware) error {
 
 	if v.W != nil && len(v.Errors) != 0 {
 		for _, e := range v.Errors {
-			fmt.Println(e)
+			fmt.Fprintln(v.W, e)
 		}
-		os.Exit(1)
+		return fmt.Errorf("validation found %d error(s)", len(v.Errors))
 	}
 	return nil
 }
@@ -169,18 +168,32 @@ func (v *Validate) Visit(f uefi.Firmware) error {
 		}
 
 		// Body Checksum
-		if !fh.Attributes.HasChecksum() && fh.Checksum.File != uefi.EmptyBodyChecksum {
+		switch f.ChecksumStatus() {
+		case uefi.FileChecksumBadSentinel:
 			v.Errors = append(v.Errors, fmt.Errorf("file %v body checksum failure! Attribute was not set, but sum was %v instead of %v",
 				fh.GUID, fh.Checksum.File, uefi.EmptyBodyChecksum))
-		} else if fh.Attributes.HasChecksum() {
-			headerSize := uefi.FileHeaderMinLength
-			if fh.Attributes.IsLarge() {
-				headerSize = uefi.FileHeaderExtMinLength
+		case uefi.FileChecksumInvalid:
+			v.Errors = append(v.Errors, fmt.Errorf("file %v body checksum failure! sum was %v",
+				fh.GUID, uefi.Checksum8(f.Buf()[f.HeaderLen():])+fh.Checksum.File))
+		}
+
+		// Section alignment
+		// Sections are parsed and assembled at 4 byte aligned offsets with 0x00 padding. Non-zero
+		// padding means that the sections were not aligned when the file was produced.
+		offset := f.DataOffset
+		for i, s := range f.Sections {
+			end := offset + uint64(s.Header.ExtendedSize)
+			if end > buflen {
+				break
 			}
-			if sum := uefi.Checksum8(f.Buf()[headerSize:]); sum != 0 { // TODO: use the Payload function which does not exist yet
-				v.Errors = append(v.Errors, fmt.Errorf("file %v body checksum failure! sum was %v",
-					fh.GUID, sum))
+			for j := end; j < uefi.Align4(end) && j < buflen; j++ {
+				if f.Buf()[j] != 0 {
+					v.Errors = append(v.Errors, fmt.Errorf("file %v section %d at offset %#x is not followed by 4 byte alignment padding at %#x",
+						fh.GUID, i, offset, end))
+					break
+				}
 			}
+			offset = uefi.Align4(end)
 		}
 
 	case *uefi.Section:
@@ -205,6 +218,11 @@ func (v *Validate) Visit(f uefi.Firmware) error {
 			break
 		}
 
+	case *uefi.NVar:
+		if err := f.Validate(); err != nil {
+			v.Errors = append(v.Errors, err)
+		}
+
 	case *uefi.BIOSRegion:
 		if f.FlashRegion() != nil && !f.FlashRegion().Valid() {
 			v.Errors = append(v.Errors, fmt.Errorf("BIOSRegion is not valid, region was %v", *f.FlashRegion()))
@@ -241,6 +259,14 @@ func (v *Validate) Visit(f uefi.Firmware) error {
 			v.Errors = append(v.Errors, fmt.Errorf("region is not valid, region was %v", *f.FlashRegion()))
 		}
 
+	case *uefi.ECRegion:
+		if f.FlashRegion() == nil {
+			v.Errors = append(v.Errors, errors.New("region position is nil"))
+		}
+		if !f.FlashRegion().Valid() {
+			v.Errors = append(v.Errors, fmt.Errorf("region is not valid, region was %v", *f.FlashRegion()))
+		}
+
 	case *uefi.RawRegion:
 		if f.FlashRegion() == nil {
 			v.Errors = append(v.Errors, errors.New("region position is nil"))
@@ -254,6 +280,6 @@ func (v *Validate) Visit(f uefi.Firmware) error {
 
 func init() {
 	RegisterCLI("validate", "perform extra validation checks", 0, func(args []string) (uefi.Visitor, error) {
-		return &Validate{}, nil
+		return &Validate{W: os.Stdout}, nil
 	})
 }
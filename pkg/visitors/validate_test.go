@@ -5,6 +5,7 @@
 package visitors
 
 import (
+	"bytes"
 	"testing"
 
 	utk_test "github.com/linuxboot/fiano/integration"
@@ -42,6 +43,17 @@ func TestValidateFV(t *testing.T) {
 	}
 }
 
+// misalignedFreeFormFile has a section which is not padded to 4 bytes, the
+// bytes in place of the padding belong to the next section.
+var misalignedFreeFormFile []byte
+
+func init() {
+	misalignedFreeFormFile = make([]byte, len(goodFreeFormFile))
+	copy(misalignedFreeFormFile, goodFreeFormFile)
+	paddingOffset := uefi.FileHeaderMinLength + len(linuxSec) + len(smallSec)
+	copy(misalignedFreeFormFile[paddingOffset:], []byte{0x04, 0x00})
+}
+
 func TestValidateFile(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -51,6 +63,7 @@ func TestValidateFile(t *testing.T) {
 		{"emptyPadFile", emptyPadFile, nil},
 		{"badFreeFormFile", badFreeFormFile, []string{"file FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF header checksum failure! sum was 54"}},
 		{"goodFreeFormFile", goodFreeFormFile, nil},
+		{"misalignedFreeFormFile", misalignedFreeFormFile, []string{"file FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF section 1 at offset 0x28 is not followed by 4 byte alignment padding at 0x3e"}},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -74,3 +87,45 @@ func TestValidateFile(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNVar(t *testing.T) {
+	uefi.Attributes.ErasePolarity = 0xFF
+	// NVAR "Name" with an extended header holding a checksum off by one.
+	buf := []byte{'N', 'V', 'A', 'R', 20, 0, 0xFF, 0xFF, 0xFF,
+		byte(uefi.NVarEntryValid | uefi.NVarEntryASCIIName | uefi.NVarEntryExtHeader | uefi.NVarEntryAuthWrite), 0}
+	buf = append(buf, "Name\x00"...)
+	buf = append(buf, byte(uefi.NVarEntryExtChecksum), 0, 4, 0)
+	var sum uint8
+	for i, b := range buf {
+		if i >= 4 && (i < 6 || i > 8) {
+			sum += b
+		}
+	}
+	buf[len(buf)-3] = -sum + 1
+	// Free space, then the GUID store holding the GUID of index 0.
+	store := append(buf, make([]byte, 32)...)
+	uefi.Erase(store[len(buf):], 0xFF)
+	s, err := uefi.NewNVarStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &Validate{}
+	if err := v.Run(s); err != nil {
+		t.Fatal(err)
+	}
+	want := "NVAR Name at offset 0x0 checksum failure! stored checksum was 0x95 instead of 0x94"
+	if len(v.Errors) != 1 || v.Errors[0].Error() != want {
+		t.Errorf("Errors mismatched, wanted \n%v\n, got \n%v\n", want, v.Errors)
+	}
+
+	// With a writer, the errors are printed and Run fails.
+	var out bytes.Buffer
+	v = &Validate{W: &out}
+	if err := v.Run(s); err == nil {
+		t.Errorf("Run did not fail")
+	}
+	if out.String() != want+"\n" {
+		t.Errorf("Output mismatched, wanted \n%v\n, got \n%v\n", want, out.String())
+	}
+}
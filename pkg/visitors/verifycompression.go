@@ -0,0 +1,146 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/compression"
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// CompressionFailure describes a section which does not decompress.
+type CompressionFailure struct {
+	// File is the GUID of the file containing the section.
+	File guid.GUID
+	// Name comes from the user interface section of the file.
+	Name string `json:",omitempty"`
+	// Section is the path of the section in the file made of the indexes
+	// of the encapsulating sections, e.g. "1/0" is the first section
+	// encapsulated in the second section of the file.
+	Section     string
+	Compression string
+	Error       string
+}
+
+// VerifyCompression decompresses every GUID-defined section which requires
+// processing and has a known compressor, and collects the sections which
+// fail to decompress. Unlike parsing, the check is done even if
+// uefi.DisableDecompression is set. The whole image is always checked, Run
+// returns an error at the end if any section failed.
+type VerifyCompression struct {
+	// Optionally write the failures as JSON to W.
+	W io.Writer `json:"-"`
+
+	// Output
+	Failures []*CompressionFailure
+
+	curFile *uefi.File
+	path    []string
+}
+
+// Run wraps Visit and performs some setup and teardown tasks.
+func (v *VerifyCompression) Run(f uefi.Firmware) error {
+	v.Failures = nil
+	if err := f.Apply(v); err != nil {
+		return err
+	}
+
+	if v.W != nil {
+		b, err := json.MarshalIndent(v.Failures, "", "\t")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(v.W, string(b)); err != nil {
+			return err
+		}
+	}
+	if len(v.Failures) != 0 {
+		return fmt.Errorf("%d sections fail to decompress", len(v.Failures))
+	}
+	return nil
+}
+
+// Visit applies the VerifyCompression visitor to any Firmware type.
+func (v *VerifyCompression) Visit(f uefi.Firmware) error {
+	switch f := f.(type) {
+	case *uefi.File:
+		prevFile, prevPath := v.curFile, v.path
+		defer func() { v.curFile, v.path = prevFile, prevPath }()
+		v.curFile = f
+		for i, s := range f.Sections {
+			v.path = []string{strconv.Itoa(i)}
+			if err := s.Apply(v); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *uefi.Section:
+		v.check(f)
+		path := v.path
+		defer func() { v.path = path }()
+		for i, e := range f.Encapsulated {
+			v.path = append(path[:len(path):len(path)], strconv.Itoa(i))
+			if err := e.Value.Apply(v); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return f.ApplyChildren(v)
+	}
+}
+
+func (v *VerifyCompression) check(s *uefi.Section) {
+	if s.Header.Type != uefi.SectionTypeGUIDDefined || s.TypeSpecific == nil {
+		return
+	}
+	guidDefined, ok := s.TypeSpecific.Header.(*uefi.SectionGUIDDefined)
+	if !ok || guidDefined.Attributes&uint16(uefi.GUIDEDSectionProcessingRequired) == 0 {
+		return
+	}
+	compressor := compression.CompressorFromGUID(&guidDefined.GUID)
+	if compressor == nil {
+		// Not a compressed section, e.g. a signed one.
+		return
+	}
+
+	var err error
+	if buf := s.Buf(); int(guidDefined.DataOffset) > len(buf) {
+		err = fmt.Errorf("data offset %#x exceeds the section size %#x", guidDefined.DataOffset, len(buf))
+	} else {
+		_, err = compressor.Decode(buf[guidDefined.DataOffset:])
+	}
+	if err == nil {
+		return
+	}
+
+	failure := &CompressionFailure{
+		Section:     strings.Join(v.path, "/"),
+		Compression: compressor.Name(),
+		Error:       err.Error(),
+	}
+	if v.curFile != nil {
+		failure.File = v.curFile.Header.GUID
+		if ui := v.curFile.SectionsOfType(uefi.SectionTypeUserInterface, true); len(ui) > 0 {
+			failure.Name = ui[0].Name
+		}
+	}
+	v.Failures = append(v.Failures, failure)
+}
+
+func init() {
+	RegisterCLI("verify-compression", "check that all compressed sections decompress and list the failing ones as JSON", 0, func(args []string) (uefi.Visitor, error) {
+		return &VerifyCompression{W: os.Stdout}, nil
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 the LinuxBoot Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package visitors
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+func TestVerifyCompression(t *testing.T) {
+	f := parseImage(t)
+
+	v := &VerifyCompression{}
+	if err := v.Run(f); err != nil {
+		t.Fatalf("unexpected failures in OVMF: %v, %+v", err, v.Failures)
+	}
+
+	// Corrupt the LZMA properties of the first compressed section.
+	isCompressed := func(f uefi.Firmware) bool {
+		s, ok := f.(*uefi.Section)
+		if !ok || s.TypeSpecific == nil {
+			return false
+		}
+		guidDefined, ok := s.TypeSpecific.Header.(*uefi.SectionGUIDDefined)
+		return ok && guidDefined.Compression == "LZMA"
+	}
+	matches := &Find{Predicate: isCompressed}
+	if err := matches.Run(f); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches.Matches) == 0 {
+		t.Fatal("no LZMA compressed section found")
+	}
+	file := matches.Matches[0].(*uefi.File)
+	var section *uefi.Section
+	var index int
+	for i, s := range file.Sections {
+		if isCompressed(s) {
+			section, index = s, i
+			break
+		}
+	}
+	if section == nil {
+		t.Fatalf("LZMA compressed section of file %v is not a direct child", file.Header.GUID)
+	}
+	buf := append([]byte{}, section.Buf()...)
+	buf[section.TypeSpecific.Header.(*uefi.SectionGUIDDefined).DataOffset] = 0xFF
+	section.SetBuf(buf)
+
+	var out bytes.Buffer
+	v = &VerifyCompression{W: &out}
+	if err := v.Run(f); err == nil {
+		t.Errorf("corrupt section is not reported")
+	}
+	if len(v.Failures) != 1 {
+		t.Fatalf("got %d failures, want 1: %+v", len(v.Failures), v.Failures)
+	}
+	failure := v.Failures[0]
+	if failure.File != file.Header.GUID || failure.Compression != "LZMA" || failure.Error == "" {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+	if want := strconv.Itoa(index); failure.Section != want {
+		t.Errorf("got section path %q, want %q", failure.Section, want)
+	}
+
+	var failures []*CompressionFailure
+	if err := json.Unmarshal(out.Bytes(), &failures); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(failures) != 1 {
+		t.Errorf("got %d failures in JSON, want 1", len(failures))
+	}
+}